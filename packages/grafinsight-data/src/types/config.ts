@@ -124,6 +124,7 @@ export interface GrafInsightConfig {
   featureToggles: FeatureToggles;
   licenseInfo: LicenseInfo;
   http2Enabled: boolean;
+  liveTransport: 'websocket' | 'sse';
   dateFormats?: SystemDateFormatSettings;
   sentry: SentryConfig;
   customTheme?: any;
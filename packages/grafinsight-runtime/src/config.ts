@@ -61,6 +61,7 @@ export class GrafInsightBootConfig implements GrafInsightConfig {
   licenseInfo: LicenseInfo = {} as LicenseInfo;
   rendererAvailable = false;
   http2Enabled = false;
+  liveTransport: 'websocket' | 'sse' = 'websocket';
   dateFormats?: SystemDateFormatSettings;
   sentry = {
     enabled: false,
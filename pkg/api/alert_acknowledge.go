@@ -0,0 +1,129 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/openinsight-project/grafinsight/pkg/api/response"
+	"github.com/openinsight-project/grafinsight/pkg/bus"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/services/alerting"
+	"github.com/openinsight-project/grafinsight/pkg/util"
+)
+
+// acknowledgePage is the page of acknowledge links. Links are only opened
+// with GET requests, which mail scanners and chat link previews also send,
+// so the page asks to confirm the acknowledgement, which is a POST request.
+var acknowledgePage = template.Must(template.New("acknowledge").Parse(`<!DOCTYPE html>
+<html lang="en">
+  <head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width">
+    <meta name="robots" content="noindex">
+    <title>GrafInsight - Acknowledge alert</title>
+  </head>
+  <body>
+    {{if .Error}}
+      <h1>Cannot acknowledge alert</h1>
+      <p>{{.Error}}</p>
+    {{else if .Acknowledged}}
+      <h1>Alert acknowledged</h1>
+      <p>{{.AlertName}} is acknowledged, its notifications will not be escalated.</p>
+    {{else}}
+      <h1>Acknowledge alert</h1>
+      <p>Acknowledge {{.AlertName}} to stop the escalation of its notifications.</p>
+      <form method="post">
+        <button type="submit">Acknowledge</button>
+      </form>
+    {{end}}
+  </body>
+</html>
+`))
+
+type acknowledgePageData struct {
+	AlertName    string
+	Acknowledged bool
+	Error        string
+}
+
+// GET /api/alerts/acknowledge/:token
+func AcknowledgeAlertWithTokenPage(c *models.ReqContext) response.Response {
+	alert, status, err := getAlertForAcknowledgeToken(c.Params(":token"))
+	if err != nil {
+		return acknowledgePageResponse(status, acknowledgePageData{Error: err.Error()})
+	}
+
+	return acknowledgePageResponse(200, acknowledgePageData{AlertName: alert.Name})
+}
+
+// POST /api/alerts/acknowledge/:token
+func AcknowledgeAlertWithToken(c *models.ReqContext) response.Response {
+	// The confirm page posts a form, and is answered with a page
+	isForm := c.Req.Header.Get("Content-Type") == "application/x-www-form-urlencoded"
+
+	alert, status, err := getAlertForAcknowledgeToken(c.Params(":token"))
+	if err != nil {
+		if isForm {
+			return acknowledgePageResponse(status, acknowledgePageData{Error: err.Error()})
+		}
+		return response.Error(status, err.Error(), nil)
+	}
+
+	cmd := models.AcknowledgeAlertNotificationsCommand{
+		OrgId:   alert.OrgId,
+		AlertId: alert.Id,
+	}
+
+	if err := bus.DispatchCtx(c.Req.Context(), &cmd); err != nil {
+		return response.Error(500, "Failed to acknowledge alert", err)
+	}
+
+	if isForm {
+		return acknowledgePageResponse(200, acknowledgePageData{AlertName: alert.Name, Acknowledged: true})
+	}
+	return response.JSON(200, util.DynMap{
+		"alertId": alert.Id,
+		"message": "Alert acknowledged",
+	})
+}
+
+// getAlertForAcknowledgeToken returns the alert of an acknowledge token, or
+// the error to respond with and its status.
+func getAlertForAcknowledgeToken(token string) (*models.Alert, int, error) {
+	ack, err := alerting.ParseAcknowledgeToken(token, time.Now())
+	if err != nil {
+		if errors.Is(err, alerting.ErrExpiredAcknowledgeToken) {
+			return nil, 400, errors.New("acknowledge link has expired")
+		}
+		return nil, 400, errors.New("invalid acknowledge link")
+	}
+
+	query := models.GetAlertByIdQuery{Id: ack.AlertID}
+	if err := bus.Dispatch(&query); err != nil || query.Result.OrgId != ack.OrgID {
+		return nil, 404, errors.New("alert not found")
+	}
+
+	if err := ack.Validate(query.Result); err != nil {
+		if errors.Is(err, alerting.ErrOutdatedAcknowledgeToken) {
+			return nil, 409, errors.New("acknowledge link is for an earlier incident of the alert")
+		}
+		return nil, 400, errors.New("invalid acknowledge link")
+	}
+
+	return query.Result, 200, nil
+}
+
+func acknowledgePageResponse(status int, data acknowledgePageData) response.Response {
+	var body bytes.Buffer
+	if err := acknowledgePage.Execute(&body, data); err != nil {
+		return response.Error(500, "Failed to render acknowledge page", err)
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "text/html; charset=UTF-8")
+	header.Set("Cache-Control", "no-store")
+	return response.CreateNormalResponse(header, body.Bytes(), status)
+}
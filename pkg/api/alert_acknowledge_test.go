@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openinsight-project/grafinsight/pkg/api/routing"
+	"github.com/openinsight-project/grafinsight/pkg/bus"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/services/alerting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcknowledgeAlertWithToken(t *testing.T) {
+	setUp := func(t *testing.T, stateChanges int64) (*scenarioContext, *[]*models.AcknowledgeAlertNotificationsCommand) {
+		t.Cleanup(bus.ClearBusHandlers)
+
+		bus.AddHandler("test", func(query *models.GetAlertByIdQuery) error {
+			query.Result = &models.Alert{Id: 42, OrgId: 2, Name: "High CPU", StateChanges: stateChanges}
+			return nil
+		})
+
+		var acknowledged []*models.AcknowledgeAlertNotificationsCommand
+		bus.AddHandlerCtx("test", func(ctx context.Context, cmd *models.AcknowledgeAlertNotificationsCommand) error {
+			acknowledged = append(acknowledged, cmd)
+			return nil
+		})
+
+		sc := setupScenarioContext(t, "/api/alerts/acknowledge/:token")
+		sc.m.Get("/api/alerts/acknowledge/:token", routing.Wrap(AcknowledgeAlertWithTokenPage))
+		sc.m.Post("/api/alerts/acknowledge/:token", routing.Wrap(AcknowledgeAlertWithToken))
+		return sc, &acknowledged
+	}
+
+	request := func(t *testing.T, sc *scenarioContext, method string, token string, form bool) {
+		t.Helper()
+
+		req, err := http.NewRequest(method, "/api/alerts/acknowledge/"+token, nil)
+		require.NoError(t, err)
+		if form {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		sc.resp = httptest.NewRecorder()
+		sc.req = req
+		sc.exec()
+	}
+
+	token := alerting.NewAcknowledgeToken(2, 42, 3, time.Now().Add(time.Hour))
+
+	t.Run("GET should ask to confirm without acknowledging", func(t *testing.T) {
+		sc, acknowledged := setUp(t, 3)
+
+		request(t, sc, "GET", token, false)
+		assert.Equal(t, 200, sc.resp.Code)
+		assert.Equal(t, "text/html; charset=UTF-8", sc.resp.Header().Get("Content-Type"))
+		assert.True(t, strings.Contains(sc.resp.Body.String(), `<form method="post">`))
+		assert.True(t, strings.Contains(sc.resp.Body.String(), "High CPU"))
+		assert.Empty(t, *acknowledged)
+	})
+
+	t.Run("POST should acknowledge", func(t *testing.T) {
+		sc, acknowledged := setUp(t, 3)
+
+		request(t, sc, "POST", token, true)
+		assert.Equal(t, 200, sc.resp.Code)
+		assert.True(t, strings.Contains(sc.resp.Body.String(), "Alert acknowledged"))
+		require.Len(t, *acknowledged, 1)
+		assert.Equal(t, int64(2), (*acknowledged)[0].OrgId)
+		assert.Equal(t, int64(42), (*acknowledged)[0].AlertId)
+
+		request(t, sc, "POST", token, false)
+		assert.Equal(t, 200, sc.resp.Code)
+		assert.JSONEq(t, `{"alertId": 42, "message": "Alert acknowledged"}`, sc.resp.Body.String())
+	})
+
+	t.Run("links of earlier incidents should not acknowledge", func(t *testing.T) {
+		sc, acknowledged := setUp(t, 5)
+
+		request(t, sc, "GET", token, false)
+		assert.Equal(t, 409, sc.resp.Code)
+
+		request(t, sc, "POST", token, false)
+		assert.Equal(t, 409, sc.resp.Code)
+		assert.Empty(t, *acknowledged)
+	})
+
+	t.Run("invalid links should not acknowledge", func(t *testing.T) {
+		sc, acknowledged := setUp(t, 3)
+
+		request(t, sc, "POST", "3"+token[1:], true)
+		assert.Equal(t, 400, sc.resp.Code)
+		assert.True(t, strings.Contains(sc.resp.Body.String(), "invalid acknowledge link"))
+		assert.Empty(t, *acknowledged)
+	})
+}
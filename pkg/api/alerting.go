@@ -12,6 +12,7 @@ import (
 	"github.com/openinsight-project/grafinsight/pkg/services/alerting"
 	"github.com/openinsight-project/grafinsight/pkg/services/guardian"
 	"github.com/openinsight-project/grafinsight/pkg/services/search"
+	"github.com/openinsight-project/grafinsight/pkg/setting"
 	"github.com/openinsight-project/grafinsight/pkg/util"
 )
 
@@ -186,6 +187,21 @@ func GetAlert(c *models.ReqContext) response.Response {
 	return response.JSON(200, &query.Result)
 }
 
+// GET /api/alerts/:alertId/flapping
+func GetAlertFlappingStats(c *models.ReqContext) response.Response {
+	query := models.GetAlertByIdQuery{Id: c.ParamsInt64(":alertId")}
+	if err := bus.Dispatch(&query); err != nil {
+		return response.Error(500, "Get alert failed", err)
+	}
+
+	stats, err := alerting.GetFlappingStats(c.Req.Context(), query.Result)
+	if err != nil {
+		return response.Error(500, "Failed to get alert state history", err)
+	}
+
+	return response.JSON(200, stats)
+}
+
 func GetAlertNotifiers(c *models.ReqContext) response.Response {
 	return response.JSON(200, alerting.GetNotifiers())
 }
@@ -497,6 +513,61 @@ func PauseAlert(c *models.ReqContext, dto dtos.PauseAlertCommand) response.Respo
 	return response.JSON(200, result)
 }
 
+// GET /api/alerts/dry-run-log
+func GetAlertNotificationDryRunLog(c *models.ReqContext) response.Response {
+	query := models.GetAlertNotificationDryRunsQuery{
+		OrgId:       c.OrgId,
+		AlertId:     c.QueryInt64("alertId"),
+		NotifierUid: c.Query("notifierUid"),
+		From:        c.QueryInt64("from"),
+		To:          c.QueryInt64("to"),
+		Limit:       c.QueryInt64("limit"),
+	}
+
+	if err := bus.DispatchCtx(c.Req.Context(), &query); err != nil {
+		return response.Error(500, "Failed to get dry run log", err)
+	}
+
+	return response.JSON(200, util.DynMap{
+		"dryRun":  setting.IsAlertingDryRunOrg(c.OrgId),
+		"entries": query.Result,
+	})
+}
+
+// POST /api/alerts/:alertId/acknowledge
+func AcknowledgeAlert(c *models.ReqContext) response.Response {
+	alertID := c.ParamsInt64("alertId")
+
+	query := models.GetAlertByIdQuery{Id: alertID}
+	if err := bus.Dispatch(&query); err != nil {
+		return response.Error(500, "Get Alert failed", err)
+	}
+
+	guardian := guardian.New(query.Result.DashboardId, c.OrgId, c.SignedInUser)
+	if canEdit, err := guardian.CanEdit(); err != nil || !canEdit {
+		if err != nil {
+			return response.Error(500, "Error while checking permissions for Alert", err)
+		}
+
+		return response.Error(403, "Access denied to this dashboard and alert", nil)
+	}
+
+	cmd := models.AcknowledgeAlertNotificationsCommand{
+		OrgId:   c.OrgId,
+		AlertId: alertID,
+		UserId:  c.UserId,
+	}
+
+	if err := bus.DispatchCtx(c.Req.Context(), &cmd); err != nil {
+		return response.Error(500, "Failed to acknowledge alert", err)
+	}
+
+	return response.JSON(200, util.DynMap{
+		"alertId": alertID,
+		"message": "Alert acknowledged",
+	})
+}
+
 // POST /api/admin/pause-all-alerts
 func PauseAllAlerts(c *models.ReqContext, dto dtos.PauseAllAlertsCommand) response.Response {
 	updateCmd := models.PauseAllAlertCommand{
@@ -111,6 +111,10 @@ func (hs *HTTPServer) registerRoutes() {
 	r.Get("/api/user/invite/:code", routing.Wrap(GetInviteInfoByCode))
 	r.Post("/api/user/invite/complete", bind(dtos.CompleteInviteForm{}), routing.Wrap(hs.CompleteInvite))
 
+	// alert acknowledgement links sent in notifications
+	r.Get("/api/alerts/acknowledge/:token", routing.Wrap(AcknowledgeAlertWithTokenPage))
+	r.Post("/api/alerts/acknowledge/:token", routing.Wrap(AcknowledgeAlertWithToken))
+
 	// reset password
 	r.Get("/user/password/send-reset-email", hs.Index)
 	r.Get("/user/password/reset", hs.Index)
@@ -206,6 +210,10 @@ func (hs *HTTPServer) registerRoutes() {
 			// prefs
 			orgRoute.Get("/preferences", routing.Wrap(GetOrgPreferences))
 			orgRoute.Put("/preferences", bind(dtos.UpdatePrefsCmd{}), routing.Wrap(UpdateOrgPreferences))
+
+			// email templates
+			orgRoute.Get("/email-templates", routing.Wrap(GetEmailTemplates))
+			orgRoute.Post("/email-templates/preview", bind(dtos.RenderEmailPreviewCmd{}), routing.Wrap(RenderEmailPreview))
 		}, reqOrgAdmin)
 
 		// current org without requirement of user to be org admin
@@ -359,12 +367,22 @@ func (hs *HTTPServer) registerRoutes() {
 		// DataSource w/ expressions
 		apiRoute.Post("/ds/query", bind(dtos.MetricRequest{}), routing.Wrap(hs.QueryMetricsV2))
 
+		// Live
+		if hs.Cfg.IsLiveEnabled() {
+			apiRoute.Post("/live/push/:streamId", reqEditorRole, routing.Wrap(hs.Live.HandleHTTPPush))
+			apiRoute.Post("/live/query", bind(models.LiveQueryStreamCommand{}), routing.Wrap(hs.Live.HandleQueryStream))
+			apiRoute.Get("/live/dashboards/uid/:uid/viewers", routing.Wrap(hs.Live.HandleDashboardViewers))
+		}
+
 		apiRoute.Group("/alerts", func(alertsRoute routing.RouteRegister) {
 			alertsRoute.Post("/test", bind(dtos.AlertTestCommand{}), routing.Wrap(AlertTest))
 			alertsRoute.Post("/:alertId/pause", reqEditorRole, bind(dtos.PauseAlertCommand{}), routing.Wrap(PauseAlert))
+			alertsRoute.Post("/:alertId/acknowledge", reqEditorRole, routing.Wrap(AcknowledgeAlert))
 			alertsRoute.Get("/:alertId", ValidateOrgAlert, routing.Wrap(GetAlert))
+			alertsRoute.Get("/:alertId/flapping", ValidateOrgAlert, routing.Wrap(GetAlertFlappingStats))
 			alertsRoute.Get("/", routing.Wrap(GetAlerts))
 			alertsRoute.Get("/states-for-dashboard", routing.Wrap(GetAlertStatesForDashboard))
+			alertsRoute.Get("/dry-run-log", reqOrgAdmin, routing.Wrap(GetAlertNotificationDryRunLog))
 		})
 
 		apiRoute.Get("/alert-notifiers", reqEditorRole, routing.Wrap(GetAlertNotifiers))
@@ -427,6 +445,12 @@ func (hs *HTTPServer) registerRoutes() {
 		adminRoute.Post("/ldap/sync/:id", routing.Wrap(hs.PostSyncUserWithLDAP))
 		adminRoute.Get("/ldap/:username", routing.Wrap(hs.GetUserFromLDAP))
 		adminRoute.Get("/ldap/status", routing.Wrap(hs.GetLDAPStatus))
+
+		if hs.Cfg.IsLiveEnabled() {
+			adminRoute.Get("/live", routing.Wrap(hs.Live.HandleAdminGetStats))
+			adminRoute.Delete("/live/clients/:clientId", routing.Wrap(hs.Live.HandleAdminDisconnectClient))
+			adminRoute.Delete("/live/users/:userId", routing.Wrap(hs.Live.HandleAdminDisconnectUser))
+		}
 	}, reqGrafinsightAdmin)
 
 	// rendering
@@ -290,6 +290,9 @@ func (hs *HTTPServer) PostDashboard(c *models.ReqContext, cmd models.SaveDashboa
 
 	dashboard, err := dashboards.NewService().SaveDashboard(dashItem, allowUiUpdate)
 	if err != nil {
+		if errors.Is(err, models.ErrDashboardVersionMismatch) {
+			return hs.dashboardVersionMismatchResponse(c, dash, err)
+		}
 		return dashboardSaveErrorToApiResponse(err)
 	}
 
@@ -304,6 +307,7 @@ func (hs *HTTPServer) PostDashboard(c *models.ReqContext, cmd models.SaveDashboa
 	// Tell everyone listening that the dashboard changed
 	if hs.Live.IsEnabled() {
 		err := hs.Live.GrafinsightScope.Dashboards.DashboardSaved(
+			dashboard.OrgId,
 			dashboard.Uid,
 			c.UserId,
 		)
@@ -331,6 +335,41 @@ func (hs *HTTPServer) PostDashboard(c *models.ReqContext, cmd models.SaveDashboa
 	})
 }
 
+// dashboardVersionMismatchResponse tells who changed the dashboard since it
+// was loaded, and who is editing it right now, so the user can decide whether
+// to overwrite their changes.
+func (hs *HTTPServer) dashboardVersionMismatchResponse(c *models.ReqContext, dash *models.Dashboard, err error) response.Response {
+	existing, rsp := getDashboardHelper(c.OrgId, "", dash.Id, dash.Uid)
+	if rsp != nil {
+		return dashboardSaveErrorToApiResponse(err)
+	}
+
+	updatedBy := getUserLogin(existing.UpdatedBy)
+	body := util.DynMap{
+		"status":    models.ErrDashboardVersionMismatch.Status,
+		"message":   fmt.Sprintf("The dashboard has been changed by %s since it was loaded", updatedBy),
+		"version":   existing.Version,
+		"updated":   existing.Updated,
+		"updatedBy": updatedBy,
+	}
+
+	if hs.Live.IsEnabled() {
+		viewers, err := hs.Live.GrafinsightScope.DashboardViewers.Viewers(c.OrgId, existing.Uid)
+		if err != nil {
+			hs.log.Warn("Unable to get dashboard viewers", "uid", existing.Uid, "error", err)
+		}
+		editing := []string{}
+		for _, viewer := range viewers {
+			if viewer.Editing && viewer.UserID != c.UserId {
+				editing = append(editing, viewer.Login)
+			}
+		}
+		body["editing"] = editing
+	}
+
+	return response.JSON(models.ErrDashboardVersionMismatch.StatusCode, body)
+}
+
 func dashboardSaveErrorToApiResponse(err error) response.Response {
 	var dashboardErr models.DashboardErr
 	if ok := errors.As(err, &dashboardErr); ok {
@@ -846,6 +846,40 @@ func TestDashboardAPIEndpoint(t *testing.T) {
 					})
 			}
 		})
+
+		t.Run("Given a dashboard changed by someone else since it was loaded", func(t *testing.T) {
+			cmd := models.SaveDashboardCommand{
+				OrgId: 1,
+				Dashboard: simplejson.NewFromAny(map[string]interface{}{
+					"uid":     "uid",
+					"title":   "Dash",
+					"version": 1,
+				}),
+			}
+			mock := &dashboards.FakeDashboardService{
+				SaveDashboardError: models.ErrDashboardVersionMismatch,
+			}
+
+			postDashboardScenario(t, "When calling POST on", "/api/dashboards", "/api/dashboards", mock, cmd, func(sc *scenarioContext) {
+				bus.AddHandler("test", func(query *models.GetDashboardQuery) error {
+					query.Result = &models.Dashboard{Id: 2, Uid: "uid", OrgId: 1, Version: 3, UpdatedBy: 5}
+					return nil
+				})
+				bus.AddHandler("test", func(query *models.GetUserByIdQuery) error {
+					query.Result = &models.User{Id: query.Id, Login: "bob"}
+					return nil
+				})
+
+				callPostDashboard(sc)
+				assert.Equal(t, 412, sc.resp.Code)
+
+				result := sc.ToJSON()
+				assert.Equal(t, "version-mismatch", result.Get("status").MustString())
+				assert.Equal(t, "bob", result.Get("updatedBy").MustString())
+				assert.Equal(t, 3, result.Get("version").MustInt())
+				assert.Equal(t, "The dashboard has been changed by bob since it was loaded", result.Get("message").MustString())
+			})
+		})
 	})
 
 	t.Run("Given two dashboards being compared", func(t *testing.T) {
@@ -0,0 +1,6 @@
+package dtos
+
+type RenderEmailPreviewCmd struct {
+	Template string                 `json:"template" binding:"Required"`
+	Data     map[string]interface{} `json:"data"`
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/openinsight-project/grafinsight/pkg/api/dtos"
+	"github.com/openinsight-project/grafinsight/pkg/api/response"
+	"github.com/openinsight-project/grafinsight/pkg/bus"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+)
+
+// GET /api/org/email-templates
+func GetEmailTemplates(c *models.ReqContext) response.Response {
+	query := models.GetEmailTemplatesQuery{OrgId: c.OrgId}
+	if err := bus.Dispatch(&query); err != nil {
+		return response.Error(500, "Failed to get email templates", err)
+	}
+
+	return response.JSON(200, query.Result)
+}
+
+// POST /api/org/email-templates/preview
+func RenderEmailPreview(c *models.ReqContext, cmd dtos.RenderEmailPreviewCmd) response.Response {
+	query := models.RenderEmailPreviewQuery{
+		OrgId:    c.OrgId,
+		Template: cmd.Template,
+		Data:     cmd.Data,
+	}
+
+	if err := bus.Dispatch(&query); err != nil {
+		if errors.Is(err, models.ErrEmailTemplateNotFound) {
+			return response.Error(404, "Email template not found", err)
+		}
+		return response.Error(400, "Failed to render email template", err)
+	}
+
+	return response.JSON(200, query.Result)
+}
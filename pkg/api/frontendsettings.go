@@ -229,6 +229,7 @@ func (hs *HTTPServer) getFrontendSettingsMap(c *models.ReqContext) (map[string]i
 			"env":           setting.Env,
 		},
 		"featureToggles":          hs.Cfg.FeatureToggles,
+		"liveTransport":           hs.Cfg.Live.Transport,
 		"rendererAvailable":       hs.RenderService.IsAvailable(),
 		"http2Enabled":            hs.Cfg.Protocol == setting.HTTP2Scheme,
 		"sentry":                  hs.Cfg.Sentry,
@@ -13,6 +13,7 @@ import (
 	"github.com/openinsight-project/grafinsight/pkg/bus"
 	"github.com/openinsight-project/grafinsight/pkg/components/simplejson"
 	"github.com/openinsight-project/grafinsight/pkg/tsdb"
+	"github.com/openinsight-project/grafinsight/pkg/tsdb/querycache"
 	"github.com/openinsight-project/grafinsight/pkg/util"
 )
 
@@ -72,10 +73,14 @@ func (hs *HTTPServer) QueryMetricsV2(c *models.ReqContext, reqDTO dtos.MetricReq
 		return response.Error(http.StatusForbidden, "Access denied", err)
 	}
 
-	resp, err := tsdb.HandleRequest(c.Req.Context(), ds, request)
+	ctx, cacheReq := querycache.WithRequest(c.Req.Context(), c.Req.Header.Get("Cache-Control"))
+	resp, err := tsdb.HandleRequest(ctx, ds, request)
 	if err != nil {
 		return response.Error(http.StatusInternalServerError, "Metric request error", err)
 	}
+	if status := cacheReq.Status(); status != "" {
+		c.Resp.Header().Set("X-Cache", string(status))
+	}
 
 	statusCode := http.StatusOK
 	for _, res := range resp.Results {
@@ -194,10 +199,14 @@ func (hs *HTTPServer) QueryMetrics(c *models.ReqContext, reqDto dtos.MetricReque
 		})
 	}
 
-	resp, err := tsdb.HandleRequest(c.Req.Context(), ds, request)
+	ctx, cacheReq := querycache.WithRequest(c.Req.Context(), c.Req.Header.Get("Cache-Control"))
+	resp, err := tsdb.HandleRequest(ctx, ds, request)
 	if err != nil {
 		return response.Error(http.StatusInternalServerError, "Metric request error", err)
 	}
+	if status := cacheReq.Status(); status != "" {
+		c.Resp.Header().Set("X-Cache", string(status))
+	}
 
 	statusCode := http.StatusOK
 	for _, res := range resp.Results {
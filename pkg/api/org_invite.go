@@ -68,6 +68,7 @@ func AddOrgInvite(c *models.ReqContext, inviteDto dtos.AddInviteForm) response.R
 	// send invite email
 	if inviteDto.SendEmail && util.IsEmail(inviteDto.LoginOrEmail) {
 		emailCmd := models.SendEmailCommand{
+			OrgId:    c.OrgId,
 			To:       []string{inviteDto.LoginOrEmail},
 			Template: "new_user_invite.html",
 			Data: map[string]interface{}{
@@ -110,6 +111,7 @@ func inviteExistingUserToOrg(c *models.ReqContext, user *models.User, inviteDto
 
 	if inviteDto.SendEmail && util.IsEmail(user.Email) {
 		emailCmd := models.SendEmailCommand{
+			OrgId:    c.OrgId,
 			To:       []string{user.Email},
 			Template: "invited_to_org.html",
 			Data: map[string]interface{}{
@@ -22,6 +22,7 @@ import (
 	_ "github.com/openinsight-project/grafinsight/pkg/services/alerting/notifiers"
 	"github.com/openinsight-project/grafinsight/pkg/setting"
 	_ "github.com/openinsight-project/grafinsight/pkg/tsdb/azuremonitor"
+	_ "github.com/openinsight-project/grafinsight/pkg/tsdb/clickhouse"
 	_ "github.com/openinsight-project/grafinsight/pkg/tsdb/cloudmonitoring"
 	_ "github.com/openinsight-project/grafinsight/pkg/tsdb/cloudwatch"
 	_ "github.com/openinsight-project/grafinsight/pkg/tsdb/elasticsearch"
@@ -32,6 +33,7 @@ import (
 	_ "github.com/openinsight-project/grafinsight/pkg/tsdb/opentsdb"
 	_ "github.com/openinsight-project/grafinsight/pkg/tsdb/postgres"
 	_ "github.com/openinsight-project/grafinsight/pkg/tsdb/prometheus"
+	_ "github.com/openinsight-project/grafinsight/pkg/tsdb/sqlite"
 	_ "github.com/openinsight-project/grafinsight/pkg/tsdb/tempo"
 	_ "github.com/openinsight-project/grafinsight/pkg/tsdb/testdatasource"
 )
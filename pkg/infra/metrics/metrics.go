@@ -84,6 +84,9 @@ var (
 	// MAlertingNotificationSent is a metric counter for how many alert notifications that failed
 	MAlertingNotificationFailed *prometheus.CounterVec
 
+	// MLivePublications is a metric counter for messages published to live channels
+	MLivePublications *prometheus.CounterVec
+
 	// MAwsCloudWatchGetMetricStatistics is a metric counter for getting metric statistics from aws
 	MAwsCloudWatchGetMetricStatistics prometheus.Counter
 
@@ -333,6 +336,13 @@ func init() {
 		Namespace: ExporterName,
 	}, []string{"type"})
 
+	MLivePublications = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:      "publications_total",
+		Help:      "The total amount of messages published to live channels",
+		Namespace: ExporterName,
+		Subsystem: "live",
+	}, []string{"scope", "source"})
+
 	MAwsCloudWatchGetMetricStatistics = newCounterStartingAtZero(prometheus.CounterOpts{
 		Name:      "aws_cloudwatch_get_metric_statistics_total",
 		Help:      "counter for getting metric statistics from aws",
@@ -574,6 +584,7 @@ func initMetricVars() {
 		MAlertingResultState,
 		MAlertingNotificationSent,
 		MAlertingNotificationFailed,
+		MLivePublications,
 		MAwsCloudWatchGetMetricStatistics,
 		MAwsCloudWatchListMetrics,
 		MAwsCloudWatchGetMetricData,
@@ -18,8 +18,9 @@ type redisStorage struct {
 	c *redis.Client
 }
 
-// parseRedisConnStr parses k=v pairs in csv and builds a redis Options object
-func parseRedisConnStr(connStr string) (*redis.Options, error) {
+// ParseRedisConnStr parses k=v pairs in csv and builds a redis Options object.
+// It is shared by other services that connect to the same redis server.
+func ParseRedisConnStr(connStr string) (*redis.Options, error) {
 	keyValueCSV := strings.Split(connStr, ",")
 	options := &redis.Options{Network: "tcp"}
 	setTLSIsTrue := false
@@ -77,7 +78,7 @@ func parseRedisConnStr(connStr string) (*redis.Options, error) {
 }
 
 func newRedisStorage(opts *setting.RemoteCacheOptions) (*redisStorage, error) {
-	opt, err := parseRedisConnStr(opts.ConnStr)
+	opt, err := ParseRedisConnStr(opts.ConnStr)
 	if err != nil {
 		return nil, err
 	}
@@ -29,6 +29,11 @@ func Gziper() macaron.Handler {
 			return
 		}
 
+		// ignore live event streams, they must be flushed as they are written
+		if strings.HasPrefix(requestPath, "/live/sse") {
+			return
+		}
+
 		// ignore resources
 		if (strings.HasPrefix(requestPath, "/api/datasources/") || strings.HasPrefix(requestPath, "/api/plugins/")) && strings.Contains(requestPath, resourcesPath) {
 			return
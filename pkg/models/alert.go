@@ -81,6 +81,7 @@ type Alert struct {
 	EvalData     *simplejson.Json
 	NewStateDate time.Time
 	StateChanges int64
+	Flapping     bool
 
 	Created time.Time
 	Updated time.Time
@@ -199,6 +200,7 @@ type AlertListItemDTO struct {
 	EvalDate       time.Time        `json:"evalDate"`
 	EvalData       *simplejson.Json `json:"evalData"`
 	ExecutionError string           `json:"executionError"`
+	Flapping       bool             `json:"flapping"`
 	Url            string           `json:"url"`
 }
 
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+)
+
+// AlertNotificationDryRun is a notification that would have been sent
+// for an org that has alerting dry run enabled.
+type AlertNotificationDryRun struct {
+	Id           int64          `json:"id"`
+	OrgId        int64          `json:"orgId"`
+	AlertId      int64          `json:"alertId"`
+	AlertName    string         `json:"alertName"`
+	State        AlertStateType `json:"state"`
+	NotifierUid  string         `json:"notifierUid"`
+	NotifierType string         `json:"notifierType"`
+	Target       string         `json:"target"`
+	Payload      string         `json:"payload"`
+	Error        string         `json:"error"`
+	Created      int64          `json:"created"`
+}
+
+// ---------------------
+// COMMANDS
+
+type SaveAlertNotificationDryRunCommand struct {
+	Entry *AlertNotificationDryRun
+}
+
+type DeleteOldAlertNotificationDryRunsCommand struct {
+	OlderThan   time.Time
+	DeletedRows int64
+}
+
+// ---------------------
+// QUERIES
+
+type GetAlertNotificationDryRunsQuery struct {
+	OrgId       int64
+	AlertId     int64
+	NotifierUid string
+	From        int64
+	To          int64
+	Limit       int64
+
+	Result []*AlertNotificationDryRun
+}
@@ -16,6 +16,7 @@ var (
 	ErrAlertNotificationFailedTranslateUniqueID = errors.New("failed to translate Notification Id to Uid")
 	ErrAlertNotificationWithSameNameExists      = errors.New("alert notification with same name already exists")
 	ErrAlertNotificationWithSameUIDExists       = errors.New("alert notification with same uid already exists")
+	ErrAlertNotificationAlreadyEscalated        = errors.New("alert notification has already been escalated")
 )
 
 type AlertNotificationStateType string
@@ -144,6 +145,10 @@ type AlertNotificationState struct {
 	Version                      int64
 	UpdatedAt                    int64
 	AlertRuleStateUpdatedVersion int64
+	EscalationDueAt              int64
+	EscalatedAt                  int64
+	AcknowledgedAt               int64
+	AcknowledgedBy               int64
 }
 
 type SetAlertNotificationStateToPendingCommand struct {
@@ -159,6 +164,34 @@ type SetAlertNotificationStateToCompleteCommand struct {
 	Version int64
 }
 
+type ScheduleAlertNotificationEscalationCommand struct {
+	Id    int64
+	DueAt int64
+}
+
+type SetAlertNotificationStateToEscalatedCommand struct {
+	Id int64
+}
+
+type ResetAlertNotificationEscalationsCommand struct {
+	OrgId   int64
+	AlertId int64
+}
+
+type AcknowledgeAlertNotificationsCommand struct {
+	OrgId   int64
+	AlertId int64
+	UserId  int64
+}
+
+type GetDueAlertNotificationEscalationsQuery struct {
+	OrgId   int64
+	AlertId int64
+	Now     int64
+
+	Result []*AlertNotificationState
+}
+
 type GetOrCreateNotificationStateQuery struct {
 	OrgId      int64
 	AlertId    int64
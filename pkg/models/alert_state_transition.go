@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+)
+
+// AlertStateTransition records a single state change of an alert rule.
+// Transitions are kept for a limited time and are used for flap detection.
+type AlertStateTransition struct {
+	Id        int64          `json:"id"`
+	OrgId     int64          `json:"orgId"`
+	AlertId   int64          `json:"alertId"`
+	PrevState AlertStateType `json:"prevState"`
+	NewState  AlertStateType `json:"newState"`
+	Created   int64          `json:"created"`
+}
+
+// AlertFlappingStats summarizes how often an alert rule changed state
+// within the flap detection window.
+type AlertFlappingStats struct {
+	AlertId        int64                   `json:"alertId"`
+	Flapping       bool                    `json:"flapping"`
+	Threshold      int                     `json:"threshold"`
+	WindowSeconds  int64                   `json:"windowSeconds"`
+	Transitions    int                     `json:"transitions"`
+	FlipsPerHour   float64                 `json:"flipsPerHour"`
+	LastTransition int64                   `json:"lastTransition"`
+	History        []*AlertStateTransition `json:"history"`
+}
+
+// ---------------------
+// COMMANDS
+
+type SaveAlertStateTransitionCommand struct {
+	OrgId     int64
+	AlertId   int64
+	PrevState AlertStateType
+	NewState  AlertStateType
+}
+
+type SetAlertFlappingCommand struct {
+	OrgId    int64
+	AlertId  int64
+	Flapping bool
+}
+
+type DeleteOldAlertStateTransitionsCommand struct {
+	OlderThan   time.Time
+	DeletedRows int64
+}
+
+// ---------------------
+// QUERIES
+
+type GetAlertStateTransitionsQuery struct {
+	OrgId   int64
+	AlertId int64
+	From    int64
+
+	Result []*AlertStateTransition
+}
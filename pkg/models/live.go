@@ -1,6 +1,9 @@
 package models
 
-import "github.com/centrifugal/centrifuge"
+import (
+	"github.com/centrifugal/centrifuge"
+	"github.com/openinsight-project/grafinsight/pkg/components/simplejson"
+)
 
 // ChannelPublisher writes data into a channel. Note that pemissions are not checked.
 type ChannelPublisher func(channel string, data []byte) error
@@ -23,6 +26,34 @@ type ChannelHandlerFactory interface {
 
 // DashboardActivityChannel is a service to advertise dashboard activity
 type DashboardActivityChannel interface {
-	DashboardSaved(uid string, userID int64) error
-	DashboardDeleted(uid string, userID int64) error
+	DashboardSaved(orgID int64, uid string, userID int64) error
+	DashboardDeleted(orgID int64, uid string, userID int64) error
+}
+
+// MeasurementsChannel is a service to push measurements into live channels
+type MeasurementsChannel interface {
+	PushMeasurements(orgID int64, stream string, batch *MeasurementBatch) error
+}
+
+// ChannelUnsubscriber is implemented by channel handlers that need to know
+// when a client leaves the channel.
+type ChannelUnsubscriber interface {
+	OnUnsubscribe(c *centrifuge.Client, e centrifuge.UnsubscribeEvent)
+}
+
+// LiveQueryStreamCommand registers a query that runs server-side on an
+// interval and streams its results to the subscribers of a channel.
+type LiveQueryStreamCommand struct {
+	From     string             `json:"from"`
+	To       string             `json:"to"`
+	Interval string             `json:"interval"`
+	Queries  []*simplejson.Json `json:"queries" binding:"Required"`
+}
+
+// LiveConnectionInfo describes the user of a live connection to the other
+// users of a channel, for example in the presence of a dashboard.
+type LiveConnectionInfo struct {
+	Login     string `json:"login"`
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatarUrl"`
 }
@@ -4,6 +4,7 @@ import "errors"
 
 var ErrInvalidEmailCode = errors.New("invalid or expired email code")
 var ErrSmtpNotEnabled = errors.New("SMTP not configured, check your grafinsight.ini config file's [smtp] section")
+var ErrEmailTemplateNotFound = errors.New("email template not found")
 
 // SendEmailAttachFile is a definition of the attached files without path
 type SendEmailAttachFile struct {
@@ -13,6 +14,7 @@ type SendEmailAttachFile struct {
 
 // SendEmailCommand is command for sending emails
 type SendEmailCommand struct {
+	OrgId         int64
 	To            []string
 	SingleEmail   bool
 	Template      string
@@ -47,3 +49,31 @@ type ValidateResetPasswordCodeQuery struct {
 	Code   string
 	Result *User
 }
+
+// EmailTemplate describes a built-in email template and whether the org overrides it.
+type EmailTemplate struct {
+	Name           string `json:"name"`
+	Overridden     bool   `json:"overridden"`
+	TextOverridden bool   `json:"textOverridden"`
+}
+
+// EmailPreview is an email template rendered with sample data.
+type EmailPreview struct {
+	Subject string `json:"subject"`
+	Html    string `json:"html"`
+	Text    string `json:"text"`
+}
+
+type GetEmailTemplatesQuery struct {
+	OrgId int64
+
+	Result []*EmailTemplate
+}
+
+type RenderEmailPreviewQuery struct {
+	OrgId    int64
+	Template string
+	Data     map[string]interface{}
+
+	Result *EmailPreview
+}
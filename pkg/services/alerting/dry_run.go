@@ -0,0 +1,50 @@
+package alerting
+
+import (
+	"github.com/openinsight-project/grafinsight/pkg/bus"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/services/notifications"
+)
+
+// notifyDryRun renders the notification without delivering it and writes
+// what would have been sent to the dry run log.
+func (n *notificationService) notifyDryRun(evalContext *EvalContext, notifier Notifier) error {
+	var entries []*models.AlertNotificationDryRun
+	newEntry := func(target, payload string) *models.AlertNotificationDryRun {
+		return &models.AlertNotificationDryRun{
+			OrgId:        evalContext.Rule.OrgID,
+			AlertId:      evalContext.Rule.ID,
+			AlertName:    evalContext.Rule.Name,
+			State:        evalContext.Rule.State,
+			NotifierUid:  notifier.GetNotifierUID(),
+			NotifierType: notifier.GetType(),
+			Target:       target,
+			Payload:      payload,
+		}
+	}
+
+	ctx := evalContext.Ctx
+	evalContext.Ctx = notifications.WithDryRun(ctx, func(target, payload string) {
+		entries = append(entries, newEntry(target, payload))
+	})
+	notifyErr := notifier.Notify(evalContext)
+	evalContext.Ctx = ctx
+
+	// Record the attempt even if the notifier did not produce a payload,
+	// so that the log shows every notification that would have been sent.
+	if len(entries) == 0 {
+		entries = append(entries, newEntry("", ""))
+	}
+	if notifyErr != nil {
+		entries[len(entries)-1].Error = notifyErr.Error()
+	}
+
+	for _, entry := range entries {
+		if err := bus.DispatchCtx(ctx, &models.SaveAlertNotificationDryRunCommand{Entry: entry}); err != nil {
+			return err
+		}
+	}
+
+	n.log.Debug("Logged dry run notification", "ruleId", evalContext.Rule.ID, "uid", notifier.GetNotifierUID(), "payloads", len(entries))
+	return notifyErr
+}
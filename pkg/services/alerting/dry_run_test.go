@@ -0,0 +1,130 @@
+package alerting
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openinsight-project/grafinsight/pkg/bus"
+	"github.com/openinsight-project/grafinsight/pkg/components/simplejson"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/services/notifications"
+	"github.com/openinsight-project/grafinsight/pkg/services/validations"
+)
+
+type dryRunTestNotifier struct {
+	testNotifier
+	err error
+}
+
+func (n *dryRunTestNotifier) Notify(evalCtx *EvalContext) error {
+	if notifications.RecordDryRun(evalCtx.Ctx, "POST https://hooks.example.com", `{"state":"`+string(evalCtx.Rule.State)+`"}`) {
+		return n.err
+	}
+	return errors.New("notification should not be delivered in dry run")
+}
+
+func TestNotifyDryRun(t *testing.T) {
+	var saved []*models.AlertNotificationDryRun
+	bus.AddHandlerCtx("test", func(ctx context.Context, cmd *models.SaveAlertNotificationDryRunCommand) error {
+		saved = append(saved, cmd.Entry)
+		return nil
+	})
+
+	rule := &Rule{ID: 3, OrgID: 2, Name: "High CPU", State: models.AlertStateAlerting}
+	n := newNotificationService(nil)
+
+	t.Run("rendered payload should be logged instead of sent", func(t *testing.T) {
+		saved = nil
+		evalCtx := NewEvalContext(context.Background(), rule, &validations.OSSPluginRequestValidator{})
+		evalCtx.IsDryRun = true
+
+		notifier := &dryRunTestNotifier{testNotifier: testNotifier{UID: "ops", Type: "webhook"}}
+		err := n.notifyDryRun(evalCtx, notifier)
+		require.NoError(t, err)
+		require.Len(t, saved, 1)
+
+		assert.Equal(t, int64(2), saved[0].OrgId)
+		assert.Equal(t, int64(3), saved[0].AlertId)
+		assert.Equal(t, "High CPU", saved[0].AlertName)
+		assert.Equal(t, models.AlertStateAlerting, saved[0].State)
+		assert.Equal(t, "ops", saved[0].NotifierUid)
+		assert.Equal(t, "webhook", saved[0].NotifierType)
+		assert.Equal(t, "POST https://hooks.example.com", saved[0].Target)
+		assert.Equal(t, `{"state":"alerting"}`, saved[0].Payload)
+		assert.False(t, notifications.RecordDryRun(evalCtx.Ctx, "target", "payload"), "notifications should be delivered again after notify")
+		require.Len(t, saved, 1)
+	})
+
+	t.Run("notifier error should be logged", func(t *testing.T) {
+		saved = nil
+		evalCtx := NewEvalContext(context.Background(), rule, &validations.OSSPluginRequestValidator{})
+		evalCtx.IsDryRun = true
+
+		notifier := &dryRunTestNotifier{testNotifier: testNotifier{UID: "ops", Type: "webhook"}, err: errors.New("bad template")}
+		err := n.notifyDryRun(evalCtx, notifier)
+		require.Error(t, err)
+		require.Len(t, saved, 1)
+		assert.Equal(t, "bad template", saved[0].Error)
+	})
+}
+
+func TestSendIfNeededDryRun(t *testing.T) {
+	t.Cleanup(bus.ClearBusHandlers)
+	RegisterNotifier(&NotifierPlugin{
+		Type:    "test",
+		Name:    "Test",
+		Factory: newTestNotifier,
+	})
+
+	bus.AddHandlerCtx("test", func(ctx context.Context, query *models.GetAlertNotificationsWithUidToSendQuery) error {
+		query.Result = []*models.AlertNotification{{
+			Id:   1,
+			Uid:  "team-a",
+			Type: "test",
+			Settings: simplejson.NewFromAny(map[string]interface{}{
+				"uploadImage":   false,
+				"escalateTo":    "team-b",
+				"escalateAfter": "10m",
+			}),
+		}}
+		return nil
+	})
+	bus.AddHandlerCtx("test", func(ctx context.Context, query *models.GetOrCreateNotificationStateQuery) error {
+		query.Result = &models.AlertNotificationState{Id: 1, AlertId: 3, OrgId: 2, State: models.AlertNotificationStateUnknown}
+		return nil
+	})
+	bus.AddHandlerCtx("test", func(ctx context.Context, query *models.GetDueAlertNotificationEscalationsQuery) error {
+		return nil
+	})
+	bus.AddHandlerCtx("test", func(ctx context.Context, cmd *models.SetAlertNotificationStateToPendingCommand) error {
+		return nil
+	})
+	bus.AddHandlerCtx("test", func(ctx context.Context, cmd *models.SetAlertNotificationStateToCompleteCommand) error {
+		return nil
+	})
+	var saved []*models.AlertNotificationDryRun
+	bus.AddHandlerCtx("test", func(ctx context.Context, cmd *models.SaveAlertNotificationDryRunCommand) error {
+		saved = append(saved, cmd.Entry)
+		return nil
+	})
+	var scheduled []*models.ScheduleAlertNotificationEscalationCommand
+	bus.AddHandlerCtx("test", func(ctx context.Context, cmd *models.ScheduleAlertNotificationEscalationCommand) error {
+		scheduled = append(scheduled, cmd)
+		return nil
+	})
+
+	rule := &Rule{ID: 3, OrgID: 2, Name: "High CPU", State: models.AlertStateAlerting, Notifications: []string{"team-a"}}
+	evalCtx := NewEvalContext(context.Background(), rule, &validations.OSSPluginRequestValidator{})
+	evalCtx.IsDryRun = true
+	evalCtx.dashboardRef = &models.DashboardRef{Uid: "db-uid"}
+
+	err := newNotificationService(nil).SendIfNeeded(evalCtx)
+	require.NoError(t, err)
+	require.Len(t, saved, 1)
+	assert.Empty(t, evalCtx.AcknowledgeURL, "dry run should not send acknowledge links")
+	assert.Empty(t, scheduled, "dry run should not schedule escalations")
+}
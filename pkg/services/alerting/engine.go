@@ -167,6 +167,7 @@ func (e *AlertEngine) processJob(attemptID int, attemptChan chan int, cancelChan
 
 	evalContext := NewEvalContext(alertCtx, job.Rule, e.RequestValidator)
 	evalContext.Ctx = alertCtx
+	evalContext.IsDryRun = setting.IsAlertingDryRunOrg(job.Rule.OrgID)
 
 	go func() {
 		defer func() {
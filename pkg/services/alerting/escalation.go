@@ -0,0 +1,234 @@
+package alerting
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openinsight-project/grafinsight/pkg/bus"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/setting"
+)
+
+// acknowledgeTokenTTL is how long a signed acknowledge link stays valid.
+const acknowledgeTokenTTL = 7 * 24 * time.Hour
+
+var (
+	// ErrInvalidAcknowledgeToken is returned when an acknowledge token is malformed or has a bad signature.
+	ErrInvalidAcknowledgeToken = errors.New("invalid acknowledge token")
+	// ErrExpiredAcknowledgeToken is returned when an acknowledge token has expired.
+	ErrExpiredAcknowledgeToken = errors.New("acknowledge token has expired")
+	// ErrOutdatedAcknowledgeToken is returned when an acknowledge token was
+	// issued for an earlier incident of the alert.
+	ErrOutdatedAcknowledgeToken = errors.New("acknowledge token is for an earlier incident of the alert")
+)
+
+// AcknowledgeToken is the content of a signed acknowledge link. StateChanges
+// is the number of state changes of the alert when the link was sent, which
+// identifies the incident it acknowledges.
+type AcknowledgeToken struct {
+	OrgID        int64
+	AlertID      int64
+	StateChanges int64
+	Expires      time.Time
+}
+
+// NewAcknowledgeToken returns a token, signed with the server secret key, that
+// acknowledges the incident of the alert when presented to the acknowledge
+// API before expires.
+func NewAcknowledgeToken(orgID, alertID, stateChanges int64, expires time.Time) string {
+	payload := fmt.Sprintf("%d-%d-%d-%d", orgID, alertID, stateChanges, expires.Unix())
+	return payload + "-" + signAcknowledgePayload(payload)
+}
+
+// ParseAcknowledgeToken validates an acknowledge token and returns the
+// incident of the alert it was issued for.
+func ParseAcknowledgeToken(token string, now time.Time) (*AcknowledgeToken, error) {
+	parts := strings.SplitN(token, "-", 5)
+	if len(parts) != 5 {
+		return nil, ErrInvalidAcknowledgeToken
+	}
+
+	payload := strings.Join(parts[:4], "-")
+	if !hmac.Equal([]byte(parts[4]), []byte(signAcknowledgePayload(payload))) {
+		return nil, ErrInvalidAcknowledgeToken
+	}
+
+	values := make([]int64, 4)
+	for i, p := range parts[:4] {
+		var err error
+		if values[i], err = strconv.ParseInt(p, 10, 64); err != nil {
+			return nil, ErrInvalidAcknowledgeToken
+		}
+	}
+
+	ack := &AcknowledgeToken{
+		OrgID:        values[0],
+		AlertID:      values[1],
+		StateChanges: values[2],
+		Expires:      time.Unix(values[3], 0),
+	}
+	if now.After(ack.Expires) {
+		return nil, ErrExpiredAcknowledgeToken
+	}
+
+	return ack, nil
+}
+
+// Validate checks that the token was issued for the current incident of the
+// alert, so that links of earlier incidents do not acknowledge it.
+func (t *AcknowledgeToken) Validate(alert *models.Alert) error {
+	if alert.OrgId != t.OrgID || alert.Id != t.AlertID {
+		return ErrInvalidAcknowledgeToken
+	}
+	if alert.StateChanges != t.StateChanges {
+		return ErrOutdatedAcknowledgeToken
+	}
+	return nil
+}
+
+func signAcknowledgePayload(payload string) string {
+	mac := hmac.New(sha256.New, []byte(setting.SecretKey))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// getAcknowledgeURL returns a signed link that acknowledges the alert without signing in.
+func (c *EvalContext) getAcknowledgeURL() string {
+	token := NewAcknowledgeToken(c.Rule.OrgID, c.Rule.ID, c.Rule.StateChanges, time.Now().Add(acknowledgeTokenTTL))
+	return setting.AppUrl + "api/alerts/acknowledge/" + token
+}
+
+func isEscalatingState(state models.AlertStateType) bool {
+	return state == models.AlertStateAlerting || state == models.AlertStateNoData
+}
+
+// getEscalatedNotifiers returns the notifiers that should be notified because
+// an earlier notification for the alert was not acknowledged in time. It also
+// clears the escalation state of the alert once it stops firing.
+func (n *notificationService) getEscalatedNotifiers(evalContext *EvalContext, notified notifierStateSlice) (notifierStateSlice, error) {
+	if evalContext.IsTestRun {
+		return nil, nil
+	}
+
+	if !isEscalatingState(evalContext.Rule.State) {
+		if !evalContext.shouldUpdateAlertState() {
+			return nil, nil
+		}
+
+		cmd := &models.ResetAlertNotificationEscalationsCommand{
+			OrgId:   evalContext.Rule.OrgID,
+			AlertId: evalContext.Rule.ID,
+		}
+		return nil, bus.DispatchCtx(evalContext.Ctx, cmd)
+	}
+
+	query := &models.GetDueAlertNotificationEscalationsQuery{
+		OrgId:   evalContext.Rule.OrgID,
+		AlertId: evalContext.Rule.ID,
+		Now:     time.Now().Unix(),
+	}
+	if err := bus.DispatchCtx(evalContext.Ctx, query); err != nil {
+		return nil, err
+	}
+
+	var result notifierStateSlice
+	for _, due := range query.Result {
+		source := &models.GetAlertNotificationsQuery{Id: due.NotifierId, OrgId: due.OrgId}
+		if err := bus.Dispatch(source); err != nil || source.Result == nil {
+			n.log.Error("Could not get escalating notifier", "notifier", due.NotifierId, "error", err)
+			continue
+		}
+
+		sourceNotifier, err := InitNotifier(source.Result)
+		if err != nil {
+			n.log.Error("Could not create notifier", "notifier", source.Result.Uid, "error", err)
+			continue
+		}
+
+		targetUID := sourceNotifier.GetEscalateTo()
+		if targetUID == "" {
+			continue
+		}
+
+		// Claim the escalation so that only one server sends it.
+		markCmd := &models.SetAlertNotificationStateToEscalatedCommand{Id: due.Id}
+		if err := bus.DispatchCtx(evalContext.Ctx, markCmd); err != nil {
+			if !errors.Is(err, models.ErrAlertNotificationAlreadyEscalated) {
+				n.log.Error("Could not mark notification as escalated", "notifier", source.Result.Uid, "error", err)
+			}
+			continue
+		}
+
+		if notified.containsUID(targetUID) || result.containsUID(targetUID) {
+			continue
+		}
+
+		target := &models.GetAlertNotificationsWithUidQuery{Uid: targetUID, OrgId: due.OrgId}
+		if err := bus.Dispatch(target); err != nil || target.Result == nil {
+			n.log.Error("Could not find escalation target", "notifier", source.Result.Uid, "escalateTo", targetUID, "error", err)
+			continue
+		}
+
+		not, err := InitNotifier(target.Result)
+		if err != nil {
+			n.log.Error("Could not create notifier", "notifier", targetUID, "error", err)
+			continue
+		}
+
+		stateQuery := &models.GetOrCreateNotificationStateQuery{
+			NotifierId: target.Result.Id,
+			AlertId:    evalContext.Rule.ID,
+			OrgId:      evalContext.Rule.OrgID,
+		}
+		if err := bus.DispatchCtx(evalContext.Ctx, stateQuery); err != nil {
+			n.log.Error("Could not get notification state.", "notifier", target.Result.Id, "error", err)
+			continue
+		}
+
+		n.log.Info("Escalating alert notification", "ruleId", evalContext.Rule.ID, "from", source.Result.Uid, "to", targetUID)
+		result = append(result, &notifierState{
+			notifier:  not,
+			state:     stateQuery.Result,
+			escalated: true,
+		})
+	}
+
+	return result, nil
+}
+
+// scheduleEscalation starts the acknowledgement timer for a notifier that
+// has an escalation target configured.
+func (n *notificationService) scheduleEscalation(evalContext *EvalContext, notifierState *notifierState) error {
+	notifier := notifierState.notifier
+	state := notifierState.state
+
+	if notifier.GetEscalateTo() == "" || !isEscalatingState(evalContext.Rule.State) {
+		return nil
+	}
+
+	if state.EscalationDueAt != 0 || state.EscalatedAt != 0 || state.AcknowledgedAt != 0 {
+		return nil
+	}
+
+	cmd := &models.ScheduleAlertNotificationEscalationCommand{
+		Id:    state.Id,
+		DueAt: time.Now().Add(notifier.GetEscalateAfter()).Unix(),
+	}
+	return bus.DispatchCtx(evalContext.Ctx, cmd)
+}
+
+func (notifiers notifierStateSlice) containsUID(uid string) bool {
+	for _, ns := range notifiers {
+		if ns.notifier.GetNotifierUID() == uid {
+			return true
+		}
+	}
+
+	return false
+}
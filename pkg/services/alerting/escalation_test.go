@@ -0,0 +1,160 @@
+package alerting
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openinsight-project/grafinsight/pkg/bus"
+	"github.com/openinsight-project/grafinsight/pkg/components/simplejson"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/services/validations"
+)
+
+func TestAcknowledgeToken(t *testing.T) {
+	now := time.Now()
+
+	t.Run("valid token should be parsed", func(t *testing.T) {
+		token := NewAcknowledgeToken(2, 42, 5, now.Add(time.Hour))
+
+		ack, err := ParseAcknowledgeToken(token, now)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), ack.OrgID)
+		assert.Equal(t, int64(42), ack.AlertID)
+		assert.Equal(t, int64(5), ack.StateChanges)
+		require.NoError(t, ack.Validate(&models.Alert{Id: 42, OrgId: 2, StateChanges: 5}))
+	})
+
+	t.Run("expired token should return error", func(t *testing.T) {
+		token := NewAcknowledgeToken(2, 42, 5, now.Add(-time.Minute))
+
+		_, err := ParseAcknowledgeToken(token, now)
+		require.ErrorIs(t, err, ErrExpiredAcknowledgeToken)
+	})
+
+	t.Run("tampered token should return error", func(t *testing.T) {
+		token := NewAcknowledgeToken(2, 42, 5, now.Add(time.Hour))
+		tampered := "3" + token[1:]
+
+		_, err := ParseAcknowledgeToken(tampered, now)
+		require.ErrorIs(t, err, ErrInvalidAcknowledgeToken)
+
+		_, err = ParseAcknowledgeToken("garbage", now)
+		require.ErrorIs(t, err, ErrInvalidAcknowledgeToken)
+	})
+
+	t.Run("token of an earlier incident should not validate", func(t *testing.T) {
+		ack, err := ParseAcknowledgeToken(NewAcknowledgeToken(2, 42, 5, now.Add(time.Hour)), now)
+		require.NoError(t, err)
+
+		require.ErrorIs(t, ack.Validate(&models.Alert{Id: 42, OrgId: 2, StateChanges: 7}), ErrOutdatedAcknowledgeToken)
+		require.ErrorIs(t, ack.Validate(&models.Alert{Id: 42, OrgId: 3, StateChanges: 5}), ErrInvalidAcknowledgeToken)
+	})
+}
+
+func TestEscalation(t *testing.T) {
+	RegisterNotifier(&NotifierPlugin{
+		Type:    "test",
+		Name:    "Test",
+		Factory: newTestNotifier,
+	})
+
+	notifications := map[int64]*models.AlertNotification{
+		1: {Id: 1, Uid: "team-a", OrgId: 1, Type: "test", Settings: simplejson.NewFromAny(map[string]interface{}{
+			"escalateTo":    "team-b",
+			"escalateAfter": "10m",
+		})},
+		2: {Id: 2, Uid: "team-b", OrgId: 1, Type: "test", Settings: simplejson.New()},
+	}
+
+	t.Run("due escalation should notify the escalation target once", func(t *testing.T) {
+		escalated := map[int64]bool{}
+
+		bus.AddHandlerCtx("test", func(ctx context.Context, query *models.GetDueAlertNotificationEscalationsQuery) error {
+			query.Result = []*models.AlertNotificationState{{Id: 10, OrgId: 1, AlertId: 5, NotifierId: 1}}
+			return nil
+		})
+		bus.AddHandler("test", func(query *models.GetAlertNotificationsQuery) error {
+			query.Result = notifications[query.Id]
+			return nil
+		})
+		bus.AddHandler("test", func(query *models.GetAlertNotificationsWithUidQuery) error {
+			for _, n := range notifications {
+				if n.Uid == query.Uid {
+					query.Result = n
+				}
+			}
+			return nil
+		})
+		bus.AddHandlerCtx("test", func(ctx context.Context, cmd *models.SetAlertNotificationStateToEscalatedCommand) error {
+			if escalated[cmd.Id] {
+				return models.ErrAlertNotificationAlreadyEscalated
+			}
+			escalated[cmd.Id] = true
+			return nil
+		})
+		bus.AddHandlerCtx("test", func(ctx context.Context, query *models.GetOrCreateNotificationStateQuery) error {
+			query.Result = &models.AlertNotificationState{Id: 20, OrgId: query.OrgId, AlertId: query.AlertId, NotifierId: query.NotifierId}
+			return nil
+		})
+
+		evalCtx := NewEvalContext(context.Background(), &Rule{ID: 5, OrgID: 1, State: models.AlertStateAlerting}, &validations.OSSPluginRequestValidator{})
+		n := newNotificationService(nil)
+
+		result, err := n.getEscalatedNotifiers(evalCtx, nil)
+		require.NoError(t, err)
+		require.Len(t, result, 1)
+		assert.Equal(t, "team-b", result[0].notifier.GetNotifierUID())
+		assert.True(t, result[0].escalated)
+		assert.True(t, result.HasEscalation())
+
+		result, err = n.getEscalatedNotifiers(evalCtx, nil)
+		require.NoError(t, err)
+		require.Len(t, result, 0)
+	})
+
+	t.Run("resolved alert should reset escalations", func(t *testing.T) {
+		reset := false
+		bus.AddHandlerCtx("test", func(ctx context.Context, cmd *models.ResetAlertNotificationEscalationsCommand) error {
+			reset = cmd.AlertId == 5
+			return nil
+		})
+
+		evalCtx := NewEvalContext(context.Background(), &Rule{ID: 5, OrgID: 1, State: models.AlertStateAlerting}, &validations.OSSPluginRequestValidator{})
+		evalCtx.Rule.State = models.AlertStateOK
+		n := newNotificationService(nil)
+
+		result, err := n.getEscalatedNotifiers(evalCtx, nil)
+		require.NoError(t, err)
+		require.Len(t, result, 0)
+		require.True(t, reset)
+	})
+
+	t.Run("notifier with escalation target should schedule escalation", func(t *testing.T) {
+		var scheduled *models.ScheduleAlertNotificationEscalationCommand
+		bus.AddHandlerCtx("test", func(ctx context.Context, cmd *models.ScheduleAlertNotificationEscalationCommand) error {
+			scheduled = cmd
+			return nil
+		})
+
+		not, err := InitNotifier(notifications[1])
+		require.NoError(t, err)
+
+		evalCtx := NewEvalContext(context.Background(), &Rule{ID: 5, OrgID: 1, State: models.AlertStateAlerting}, &validations.OSSPluginRequestValidator{})
+		n := newNotificationService(nil)
+
+		err = n.scheduleEscalation(evalCtx, &notifierState{notifier: not, state: &models.AlertNotificationState{Id: 10}})
+		require.NoError(t, err)
+		require.NotNil(t, scheduled)
+		assert.Equal(t, int64(10), scheduled.Id)
+		assert.InDelta(t, time.Now().Add(10*time.Minute).Unix(), scheduled.DueAt, 5)
+
+		scheduled = nil
+		err = n.scheduleEscalation(evalCtx, &notifierState{notifier: not, state: &models.AlertNotificationState{Id: 10, AcknowledgedAt: 1}})
+		require.NoError(t, err)
+		require.Nil(t, scheduled)
+	})
+}
@@ -16,6 +16,7 @@ import (
 type EvalContext struct {
 	Firing         bool
 	IsTestRun      bool
+	IsDryRun       bool
 	IsDebug        bool
 	EvalMatches    []*EvalMatch
 	Logs           []*ResultLogEntry
@@ -32,6 +33,10 @@ type EvalContext struct {
 	ImageOnDiskPath string
 	NoDataFound     bool
 	PrevAlertState  models.AlertStateType
+	// FlappingSettled is set when the rule stops flapping in this evaluation,
+	// so that its settled state is notified even though it did not change.
+	FlappingSettled bool
+	AcknowledgeURL  string
 
 	RequestValidator models.PluginRequestValidator
 
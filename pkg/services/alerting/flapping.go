@@ -0,0 +1,96 @@
+package alerting
+
+import (
+	"context"
+	"time"
+
+	"github.com/openinsight-project/grafinsight/pkg/bus"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/setting"
+)
+
+// isFlapping decides if a rule is flapping given the number of state changes
+// in the flap detection window. A flapping rule only settles once the number
+// of state changes drops to half the threshold, so that a rule hovering
+// around the threshold does not toggle between flapping and settled.
+func isFlapping(transitions int, wasFlapping bool, threshold int) bool {
+	if threshold <= 0 {
+		return false
+	}
+
+	if wasFlapping {
+		return transitions > threshold/2
+	}
+
+	return transitions >= threshold
+}
+
+// GetFlappingStats returns the state change history of an alert rule
+// within the flap detection window.
+func GetFlappingStats(ctx context.Context, alert *models.Alert) (*models.AlertFlappingStats, error) {
+	window := setting.AlertingFlappingWindow
+	query := &models.GetAlertStateTransitionsQuery{
+		OrgId:   alert.OrgId,
+		AlertId: alert.Id,
+		From:    time.Now().Add(-window).Unix(),
+	}
+	if err := bus.DispatchCtx(ctx, query); err != nil {
+		return nil, err
+	}
+
+	stats := &models.AlertFlappingStats{
+		AlertId:       alert.Id,
+		Flapping:      alert.Flapping,
+		Threshold:     setting.AlertingFlappingThreshold,
+		WindowSeconds: int64(window / time.Second),
+		Transitions:   len(query.Result),
+		History:       query.Result,
+	}
+	if window > 0 {
+		stats.FlipsPerHour = float64(stats.Transitions) / window.Hours()
+	}
+	if len(query.Result) > 0 {
+		stats.LastTransition = query.Result[0].Created
+	}
+
+	return stats, nil
+}
+
+// updateFlapping recounts the recent state changes of the rule and
+// persists the flapping flag when it changes.
+func (handler *defaultResultHandler) updateFlapping(evalContext *EvalContext) error {
+	rule := evalContext.Rule
+
+	query := &models.GetAlertStateTransitionsQuery{
+		OrgId:   rule.OrgID,
+		AlertId: rule.ID,
+		From:    time.Now().Add(-setting.AlertingFlappingWindow).Unix(),
+	}
+	if err := bus.DispatchCtx(evalContext.Ctx, query); err != nil {
+		return err
+	}
+
+	flapping := isFlapping(len(query.Result), rule.Flapping, setting.AlertingFlappingThreshold)
+	if flapping == rule.Flapping {
+		return nil
+	}
+
+	cmd := &models.SetAlertFlappingCommand{
+		OrgId:    rule.OrgID,
+		AlertId:  rule.ID,
+		Flapping: flapping,
+	}
+	if err := bus.DispatchCtx(evalContext.Ctx, cmd); err != nil {
+		return err
+	}
+
+	if flapping {
+		handler.log.Info("Alert rule started flapping, suppressing notifications", "ruleId", rule.ID, "transitions", len(query.Result))
+	} else {
+		handler.log.Info("Alert rule stopped flapping", "ruleId", rule.ID, "transitions", len(query.Result))
+		evalContext.FlappingSettled = true
+	}
+	rule.Flapping = flapping
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package alerting
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openinsight-project/grafinsight/pkg/bus"
+	"github.com/openinsight-project/grafinsight/pkg/infra/log"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/services/validations"
+	"github.com/openinsight-project/grafinsight/pkg/setting"
+)
+
+func TestIsFlapping(t *testing.T) {
+	tcs := []struct {
+		name        string
+		transitions int
+		wasFlapping bool
+		threshold   int
+		expected    bool
+	}{
+		{name: "disabled", transitions: 100, threshold: 0, expected: false},
+		{name: "below threshold", transitions: 5, threshold: 6, expected: false},
+		{name: "reaching threshold", transitions: 6, threshold: 6, expected: true},
+		{name: "flapping rule above half threshold", transitions: 4, wasFlapping: true, threshold: 6, expected: true},
+		{name: "flapping rule settling", transitions: 3, wasFlapping: true, threshold: 6, expected: false},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, isFlapping(tc.transitions, tc.wasFlapping, tc.threshold))
+		})
+	}
+}
+
+func TestUpdateFlapping(t *testing.T) {
+	oldThreshold := setting.AlertingFlappingThreshold
+	setting.AlertingFlappingThreshold = 3
+	t.Cleanup(func() { setting.AlertingFlappingThreshold = oldThreshold })
+
+	transitions := 0
+	var flappingCmd *models.SetAlertFlappingCommand
+	bus.AddHandlerCtx("test", func(ctx context.Context, query *models.GetAlertStateTransitionsQuery) error {
+		query.Result = make([]*models.AlertStateTransition, transitions)
+		return nil
+	})
+	bus.AddHandlerCtx("test", func(ctx context.Context, cmd *models.SetAlertFlappingCommand) error {
+		flappingCmd = cmd
+		return nil
+	})
+
+	handler := &defaultResultHandler{log: log.New("test")}
+	evalContext := NewEvalContext(context.Background(), &Rule{ID: 1, OrgID: 1}, &validations.OSSPluginRequestValidator{})
+
+	t.Run("rule below threshold should not be flapping", func(t *testing.T) {
+		transitions = 2
+		require.NoError(t, handler.updateFlapping(evalContext))
+		require.Nil(t, flappingCmd)
+		require.False(t, evalContext.Rule.Flapping)
+	})
+
+	t.Run("rule reaching threshold should start flapping", func(t *testing.T) {
+		transitions = 3
+		require.NoError(t, handler.updateFlapping(evalContext))
+		require.NotNil(t, flappingCmd)
+		require.True(t, flappingCmd.Flapping)
+		require.True(t, evalContext.Rule.Flapping)
+	})
+
+	t.Run("settled rule should stop flapping", func(t *testing.T) {
+		flappingCmd = nil
+		transitions = 1
+		require.NoError(t, handler.updateFlapping(evalContext))
+		require.NotNil(t, flappingCmd)
+		require.False(t, flappingCmd.Flapping)
+		require.False(t, evalContext.Rule.Flapping)
+	})
+
+	t.Run("rule settling in alerting should notify its settled state", func(t *testing.T) {
+		flappingCmd = nil
+		transitions = 1
+		evalContext := NewEvalContext(context.Background(), &Rule{ID: 1, OrgID: 1, State: models.AlertStateAlerting, Flapping: true}, &validations.OSSPluginRequestValidator{})
+		evalContext.PrevAlertState = models.AlertStateAlerting
+
+		require.NoError(t, handler.updateFlapping(evalContext))
+		require.NotNil(t, flappingCmd)
+		require.False(t, evalContext.Rule.Flapping)
+		require.True(t, evalContext.FlappingSettled)
+	})
+
+	t.Run("flapping rule should not notify", func(t *testing.T) {
+		transitions = 3
+		evalContext := NewEvalContext(context.Background(), &Rule{ID: 1, OrgID: 1, State: models.AlertStateAlerting, Flapping: true}, &validations.OSSPluginRequestValidator{})
+
+		require.NoError(t, handler.updateFlapping(evalContext))
+		require.True(t, evalContext.Rule.Flapping)
+		require.False(t, evalContext.FlappingSettled)
+	})
+}
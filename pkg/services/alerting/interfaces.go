@@ -30,11 +30,20 @@ type Notifier interface {
 	GetSendReminder() bool
 	GetDisableResolveMessage() bool
 	GetFrequency() time.Duration
+
+	// GetEscalateTo returns the uid of the notifier to escalate to when
+	// the alert is not acknowledged within GetEscalateAfter.
+	GetEscalateTo() string
+	GetEscalateAfter() time.Duration
 }
 
 type notifierState struct {
 	notifier Notifier
 	state    *models.AlertNotificationState
+
+	// escalated is true when the notifier was reached through an
+	// escalation rather than being configured on the alert rule.
+	escalated bool
 }
 
 type notifierStateSlice []*notifierState
@@ -49,6 +58,17 @@ func (notifiers notifierStateSlice) ShouldUploadImage() bool {
 	return false
 }
 
+// HasEscalation returns true if any of the notifiers takes part in an escalation chain.
+func (notifiers notifierStateSlice) HasEscalation() bool {
+	for _, ns := range notifiers {
+		if ns.escalated || ns.notifier.GetEscalateTo() != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
 // ConditionResult is the result of a condition evaluation.
 type ConditionResult struct {
 	Firing      bool
@@ -102,11 +102,23 @@ func (n *notificationService) SendIfNeeded(evalCtx *EvalContext) error {
 		return err
 	}
 
+	escalations, err := n.getEscalatedNotifiers(evalCtx, notifierStates)
+	if err != nil {
+		n.log.Error("Failed to get escalated alert notifiers", "error", err)
+	}
+	notifierStates = append(notifierStates, escalations...)
+
 	if len(notifierStates) == 0 {
 		return nil
 	}
 
-	if notifierStates.ShouldUploadImage() {
+	// Dry run must not send acknowledge links for escalations that are not scheduled.
+	if notifierStates.HasEscalation() && !evalCtx.IsTestRun && !evalCtx.IsDryRun {
+		evalCtx.AcknowledgeURL = evalCtx.getAcknowledgeURL()
+	}
+
+	// Dry run must not have side effects such as publishing images to external stores.
+	if notifierStates.ShouldUploadImage() && !evalCtx.IsDryRun {
 		// Create a copy of EvalContext and give it a new, shorter, timeout context to upload the image
 		uploadEvalCtx := *evalCtx
 		timeout := setting.AlertingNotificationTimeout / 2
@@ -135,7 +147,14 @@ func (n *notificationService) sendAndMarkAsComplete(evalContext *EvalContext, no
 		n.log.Error("failed trying to evaluate notification template fields", "uid", notifier.GetNotifierUID(), "error", err)
 	}
 
-	if err := notifier.Notify(evalContext); err != nil {
+	notify := notifier.Notify
+	if evalContext.IsDryRun {
+		notify = func(evalContext *EvalContext) error {
+			return n.notifyDryRun(evalContext, notifier)
+		}
+	}
+
+	if err := notify(evalContext); err != nil {
 		n.log.Error("failed to send notification", "uid", notifier.GetNotifierUID(), "error", err)
 		metrics.MAlertingNotificationFailed.WithLabelValues(notifier.GetType()).Inc()
 		return err
@@ -145,6 +164,13 @@ func (n *notificationService) sendAndMarkAsComplete(evalContext *EvalContext, no
 		return nil
 	}
 
+	// Dry run notifications are not delivered, so they are not escalated.
+	if !evalContext.IsDryRun {
+		if err := n.scheduleEscalation(evalContext, notifierState); err != nil {
+			n.log.Error("failed to schedule notification escalation", "uid", notifier.GetNotifierUID(), "error", err)
+		}
+	}
+
 	cmd := &models.SetAlertNotificationStateToCompleteCommand{
 		Id:      notifierState.state.Id,
 		Version: notifierState.state.Version,
@@ -277,6 +277,8 @@ type testNotifier struct {
 	SendReminder          bool
 	DisableResolveMessage bool
 	Frequency             time.Duration
+	EscalateTo            string
+	EscalateAfter         time.Duration
 }
 
 func newTestNotifier(model *models.AlertNotification) (Notifier, error) {
@@ -286,6 +288,8 @@ func newTestNotifier(model *models.AlertNotification) (Notifier, error) {
 		uploadImage = value.MustBool()
 	}
 
+	escalateAfter, _ := time.ParseDuration(model.Settings.Get("escalateAfter").MustString())
+
 	return &testNotifier{
 		UID:                   model.Uid,
 		Name:                  model.Name,
@@ -295,6 +299,8 @@ func newTestNotifier(model *models.AlertNotification) (Notifier, error) {
 		SendReminder:          model.SendReminder,
 		DisableResolveMessage: model.DisableResolveMessage,
 		Frequency:             model.Frequency,
+		EscalateTo:            model.Settings.Get("escalateTo").MustString(),
+		EscalateAfter:         escalateAfter,
 	}, nil
 }
 
@@ -337,6 +343,14 @@ func (n *testNotifier) GetFrequency() time.Duration {
 	return n.Frequency
 }
 
+func (n *testNotifier) GetEscalateTo() string {
+	return n.EscalateTo
+}
+
+func (n *testNotifier) GetEscalateAfter() time.Duration {
+	return n.EscalateAfter
+}
+
 var _ Notifier = &testNotifier{}
 
 type testRenderService struct {
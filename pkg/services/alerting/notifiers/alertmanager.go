@@ -97,6 +97,11 @@ func (am *AlertmanagerNotifier) ShouldNotify(ctx context.Context, evalContext *a
 		return true
 	}
 
+	// Resolve the alerts sent before the rule started flapping when it settles in OK.
+	if evalContext.FlappingSettled && evalContext.Rule.State == models.AlertStateOK {
+		return true
+	}
+
 	return evalContext.Rule.State == models.AlertStateAlerting
 }
 
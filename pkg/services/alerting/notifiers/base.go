@@ -11,6 +11,8 @@ import (
 
 const (
 	triggMetrString = "Triggered metrics:\n\n"
+
+	defaultEscalateAfter = 15 * time.Minute
 )
 
 // NotifierBase is the base implementation of a notifier.
@@ -23,6 +25,8 @@ type NotifierBase struct {
 	SendReminder          bool
 	DisableResolveMessage bool
 	Frequency             time.Duration
+	EscalateTo            string
+	EscalateAfter         time.Duration
 
 	log log.Logger
 }
@@ -35,6 +39,19 @@ func NewNotifierBase(model *models.AlertNotification) NotifierBase {
 		uploadImage = value.MustBool()
 	}
 
+	logger := log.New("alerting.notifier." + model.Name)
+
+	escalateTo := model.Settings.Get("escalateTo").MustString()
+	escalateAfter := defaultEscalateAfter
+	if after := model.Settings.Get("escalateAfter").MustString(); after != "" {
+		d, err := time.ParseDuration(after)
+		if err != nil || d <= 0 {
+			logger.Warn("Invalid escalation delay, using default", "escalateAfter", after, "default", defaultEscalateAfter)
+		} else {
+			escalateAfter = d
+		}
+	}
+
 	return NotifierBase{
 		UID:                   model.Uid,
 		Name:                  model.Name,
@@ -44,7 +61,9 @@ func NewNotifierBase(model *models.AlertNotification) NotifierBase {
 		SendReminder:          model.SendReminder,
 		DisableResolveMessage: model.DisableResolveMessage,
 		Frequency:             model.Frequency,
-		log:                   log.New("alerting.notifier." + model.Name),
+		EscalateTo:            escalateTo,
+		EscalateAfter:         escalateAfter,
+		log:                   logger,
 	}
 }
 
@@ -53,6 +72,15 @@ func (n *NotifierBase) ShouldNotify(ctx context.Context, context *alerting.EvalC
 	prevState := context.PrevAlertState
 	newState := context.Rule.State
 
+	// Notifications are suppressed while the rule is flapping, so notify the
+	// state it settled in, even when it is the previous state.
+	if context.FlappingSettled {
+		if newState == models.AlertStatePending {
+			return false
+		}
+		return newState != models.AlertStateOK || !n.DisableResolveMessage
+	}
+
 	// Only notify on state change.
 	if prevState == newState && !n.SendReminder {
 		return false
@@ -146,3 +174,15 @@ func (n *NotifierBase) GetDisableResolveMessage() bool {
 func (n *NotifierBase) GetFrequency() time.Duration {
 	return n.Frequency
 }
+
+// GetEscalateTo returns the uid of the notifier that should be
+// notified when an alert is not acknowledged in time.
+func (n *NotifierBase) GetEscalateTo() string {
+	return n.EscalateTo
+}
+
+// GetEscalateAfter returns how long to wait for an acknowledgement
+// before escalating.
+func (n *NotifierBase) GetEscalateAfter() time.Duration {
+	return n.EscalateAfter
+}
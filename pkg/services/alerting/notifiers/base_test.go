@@ -19,12 +19,13 @@ func TestShouldSendAlertNotification(t *testing.T) {
 	tnow := time.Now()
 
 	tcs := []struct {
-		name         string
-		prevState    models.AlertStateType
-		newState     models.AlertStateType
-		sendReminder bool
-		frequency    time.Duration
-		state        *models.AlertNotificationState
+		name            string
+		prevState       models.AlertStateType
+		newState        models.AlertStateType
+		sendReminder    bool
+		frequency       time.Duration
+		state           *models.AlertNotificationState
+		flappingSettled bool
 
 		expect bool
 	}{
@@ -166,6 +167,30 @@ func TestShouldSendAlertNotification(t *testing.T) {
 
 			expect: true,
 		},
+		{
+			name:            "alerting -> alerting of settled flapping rule should trigger a notification",
+			newState:        models.AlertStateAlerting,
+			prevState:       models.AlertStateAlerting,
+			flappingSettled: true,
+
+			expect: true,
+		},
+		{
+			name:            "ok -> ok of settled flapping rule should trigger a notification",
+			newState:        models.AlertStateOK,
+			prevState:       models.AlertStateOK,
+			flappingSettled: true,
+
+			expect: true,
+		},
+		{
+			name:            "pending -> pending of settled flapping rule should not trigger a notification",
+			newState:        models.AlertStatePending,
+			prevState:       models.AlertStatePending,
+			flappingSettled: true,
+
+			expect: false,
+		},
 	}
 
 	for _, tc := range tcs {
@@ -178,6 +203,7 @@ func TestShouldSendAlertNotification(t *testing.T) {
 		}
 
 		evalContext.Rule.State = tc.newState
+		evalContext.FlappingSettled = tc.flappingSettled
 		nb := &NotifierBase{SendReminder: tc.sendReminder, Frequency: tc.frequency}
 
 		r := nb.ShouldNotify(evalContext.Ctx, evalContext, tc.state)
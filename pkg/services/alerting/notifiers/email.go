@@ -84,19 +84,21 @@ func (en *EmailNotifier) Notify(evalContext *alerting.EvalContext) error {
 
 	cmd := &models.SendEmailCommandSync{
 		SendEmailCommand: models.SendEmailCommand{
+			OrgId:   evalContext.Rule.OrgID,
 			Subject: evalContext.GetNotificationTitle(),
 			Data: map[string]interface{}{
-				"Title":         evalContext.GetNotificationTitle(),
-				"State":         evalContext.Rule.State,
-				"Name":          evalContext.Rule.Name,
-				"StateModel":    evalContext.GetStateModel(),
-				"Message":       evalContext.Rule.Message,
-				"Error":         error,
-				"RuleUrl":       ruleURL,
-				"ImageLink":     "",
-				"EmbeddedImage": "",
-				"AlertPageUrl":  setting.AppUrl + "alerting",
-				"EvalMatches":   evalContext.EvalMatches,
+				"Title":          evalContext.GetNotificationTitle(),
+				"State":          evalContext.Rule.State,
+				"Name":           evalContext.Rule.Name,
+				"StateModel":     evalContext.GetStateModel(),
+				"Message":        evalContext.Rule.Message,
+				"Error":          error,
+				"RuleUrl":        ruleURL,
+				"ImageLink":      "",
+				"EmbeddedImage":  "",
+				"AlertPageUrl":   setting.AppUrl + "alerting",
+				"EvalMatches":    evalContext.EvalMatches,
+				"AcknowledgeUrl": evalContext.AcknowledgeURL,
 			},
 			To:            en.Addresses,
 			SingleEmail:   en.SingleEmail,
@@ -320,7 +320,7 @@ func (sn *SlackNotifier) Notify(evalContext *alerting.EvalContext) error {
 		sn.log.Error("Failed to send slack notification", "error", err, "webhook", sn.Name)
 		return err
 	}
-	if sn.Token != "" && sn.UploadImage {
+	if sn.Token != "" && sn.UploadImage && !evalContext.IsDryRun {
 		err = sn.slackFileUpload(evalContext, sn.log, "https://slack.com/api/files.upload", sn.Recipient, sn.Token)
 		if err != nil {
 			return err
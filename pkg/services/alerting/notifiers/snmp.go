@@ -0,0 +1,300 @@
+package notifiers
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openinsight-project/grafinsight/pkg/infra/log"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/services/alerting"
+	"github.com/openinsight-project/grafinsight/pkg/services/notifications"
+)
+
+const (
+	snmpDefaultPort      = "162"
+	snmpDefaultCommunity = "public"
+	// snmpDefaultTrapOID and snmpDefaultVarbindOID live under the
+	// enterprises.32473 subtree which is reserved for documentation use.
+	snmpDefaultTrapOID    = "1.3.6.1.4.1.32473.1.0.1"
+	snmpDefaultVarbindOID = "1.3.6.1.4.1.32473.1.1"
+	snmpVersion2c         = 1
+)
+
+var (
+	snmpSysUpTimeOID   = []uint32{1, 3, 6, 1, 2, 1, 1, 3, 0}
+	snmpTrapOID        = []uint32{1, 3, 6, 1, 6, 3, 1, 1, 4, 1, 0}
+	snmpProcessStarted = time.Now()
+	snmpDialTimeout    = 10 * time.Second
+)
+
+func init() {
+	alerting.RegisterNotifier(&alerting.NotifierPlugin{
+		Type:        "snmp",
+		Name:        "SNMP trap",
+		Description: "Sends SNMPv2c traps to a trap receiver",
+		Heading:     "SNMP settings",
+		Factory:     NewSNMPNotifier,
+		Options: []alerting.NotifierOption{
+			{
+				Label:        "Address",
+				Element:      alerting.ElementTypeInput,
+				InputType:    alerting.InputTypeText,
+				Placeholder:  "snmp-receiver.local:162",
+				Description:  "Host and optional port of the trap receiver, the port defaults to 162",
+				PropertyName: "address",
+				Required:     true,
+			},
+			{
+				Label:        "Community",
+				Element:      alerting.ElementTypeInput,
+				InputType:    alerting.InputTypePassword,
+				Placeholder:  snmpDefaultCommunity,
+				PropertyName: "community",
+				Secure:       true,
+			},
+			{
+				Label:        "Trap OID",
+				Element:      alerting.ElementTypeInput,
+				InputType:    alerting.InputTypeText,
+				Placeholder:  snmpDefaultTrapOID,
+				Description:  "Value sent as snmpTrapOID.0",
+				PropertyName: "trapOid",
+			},
+			{
+				Label:        "Variable binding base OID",
+				Element:      alerting.ElementTypeInput,
+				InputType:    alerting.InputTypeText,
+				Placeholder:  snmpDefaultVarbindOID,
+				Description:  "Rule ID, rule name, state, message, eval matches and rule URL are sent as .1 to .6 below this OID",
+				PropertyName: "varbindOid",
+			},
+		},
+	})
+}
+
+// NewSNMPNotifier is the constructor for the SNMP trap notifier.
+func NewSNMPNotifier(model *models.AlertNotification) (alerting.Notifier, error) {
+	address := model.Settings.Get("address").MustString()
+	if address == "" {
+		return nil, alerting.ValidationError{Reason: "Could not find address property in settings"}
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, snmpDefaultPort)
+	}
+
+	trapOID, err := parseOID(model.Settings.Get("trapOid").MustString(snmpDefaultTrapOID))
+	if err != nil {
+		return nil, alerting.ValidationError{Reason: "Invalid trap OID", Err: err}
+	}
+	varbindOID, err := parseOID(model.Settings.Get("varbindOid").MustString(snmpDefaultVarbindOID))
+	if err != nil {
+		return nil, alerting.ValidationError{Reason: "Invalid variable binding base OID", Err: err}
+	}
+
+	community := model.DecryptedValue("community", model.Settings.Get("community").MustString())
+	if community == "" {
+		community = snmpDefaultCommunity
+	}
+
+	return &SNMPNotifier{
+		NotifierBase: NewNotifierBase(model),
+		Address:      address,
+		Community:    community,
+		TrapOID:      trapOID,
+		VarbindOID:   varbindOID,
+		log:          log.New("alerting.notifier.snmp"),
+	}, nil
+}
+
+// SNMPNotifier is responsible for sending
+// alert notifications as SNMPv2c traps.
+type SNMPNotifier struct {
+	NotifierBase
+	Address    string
+	Community  string
+	TrapOID    []uint32
+	VarbindOID []uint32
+	log        log.Logger
+}
+
+// Notify sends the alert notification as an SNMPv2c trap.
+func (sn *SNMPNotifier) Notify(evalContext *alerting.EvalContext) error {
+	sn.log.Info("Sending SNMP trap", "alert_state", evalContext.Rule.State, "address", sn.Address)
+
+	ruleURL, err := evalContext.GetRuleURL()
+	if err != nil {
+		sn.log.Error("Failed get rule link", "error", err)
+		return err
+	}
+
+	matches := make([]string, 0, len(evalContext.EvalMatches))
+	for _, evt := range evalContext.EvalMatches {
+		matches = append(matches, fmt.Sprintf("%s: %v", evt.Metric, evt.Value))
+	}
+
+	varbinds := []snmpVarbind{
+		{oid: appendOID(sn.VarbindOID, 1), value: berInteger(evalContext.Rule.ID)},
+		{oid: appendOID(sn.VarbindOID, 2), value: berOctetString(evalContext.Rule.Name)},
+		{oid: appendOID(sn.VarbindOID, 3), value: berOctetString(string(evalContext.Rule.State))},
+		{oid: appendOID(sn.VarbindOID, 4), value: berOctetString(evalContext.Rule.Message)},
+		{oid: appendOID(sn.VarbindOID, 5), value: berOctetString(strings.Join(matches, "\n"))},
+		{oid: appendOID(sn.VarbindOID, 6), value: berOctetString(ruleURL)},
+	}
+
+	packet := sn.buildTrap(time.Now().UnixNano(), varbinds)
+
+	if notifications.RecordDryRun(evalContext.Ctx, "snmp: "+sn.Address, hex.Dump(packet)) {
+		return nil
+	}
+
+	conn, err := net.DialTimeout("udp", sn.Address, snmpDialTimeout)
+	if err != nil {
+		sn.log.Error("Failed to connect to SNMP trap receiver", "error", err, "address", sn.Address)
+		return err
+	}
+	defer func() {
+		if err := conn.Close(); err != nil {
+			sn.log.Warn("Failed to close SNMP connection", "error", err)
+		}
+	}()
+
+	if deadline, ok := evalContext.Ctx.Deadline(); ok {
+		if err := conn.SetWriteDeadline(deadline); err != nil {
+			return err
+		}
+	}
+
+	if _, err := conn.Write(packet); err != nil {
+		sn.log.Error("Failed to send SNMP trap", "error", err, "address", sn.Address)
+		return err
+	}
+
+	return nil
+}
+
+// buildTrap encodes an SNMPv2c SNMPv2-Trap-PDU message as described in RFC 3416.
+func (sn *SNMPNotifier) buildTrap(requestID int64, varbinds []snmpVarbind) []byte {
+	// sysUpTime is expressed in hundredths of a second.
+	uptime := uint32(time.Since(snmpProcessStarted) / (10 * time.Millisecond))
+
+	list := [][]byte{
+		berSequence(berOID(snmpSysUpTimeOID), berTLV(0x43, berUint(uint64(uptime)))),
+		berSequence(berOID(snmpTrapOID), berOID(sn.TrapOID)),
+	}
+	for _, vb := range varbinds {
+		list = append(list, berSequence(berOID(vb.oid), vb.value))
+	}
+
+	pdu := berTLV(0xa7, bytes.Join([][]byte{
+		berInteger(requestID & 0x7fffffff),
+		berInteger(0), // error-status
+		berInteger(0), // error-index
+		berSequence(list...),
+	}, nil))
+
+	return berSequence(
+		berInteger(snmpVersion2c),
+		berOctetString(sn.Community),
+		pdu,
+	)
+}
+
+type snmpVarbind struct {
+	oid   []uint32
+	value []byte
+}
+
+func parseOID(s string) ([]uint32, error) {
+	parts := strings.Split(strings.Trim(strings.TrimSpace(s), "."), ".")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("OID %q must have at least two components", s)
+	}
+
+	oid := make([]uint32, 0, len(parts))
+	for _, p := range parts {
+		n, err := strconv.ParseUint(p, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("OID %q contains invalid component %q", s, p)
+		}
+		oid = append(oid, uint32(n))
+	}
+	if oid[0] > 2 || (oid[0] < 2 && oid[1] >= 40) {
+		return nil, fmt.Errorf("OID %q has invalid leading components", s)
+	}
+
+	return oid, nil
+}
+
+func appendOID(base []uint32, sub uint32) []uint32 {
+	oid := make([]uint32, len(base), len(base)+1)
+	copy(oid, base)
+	return append(oid, sub)
+}
+
+func berTLV(tag byte, value []byte) []byte {
+	out := []byte{tag}
+	out = append(out, berLength(len(value))...)
+	return append(out, value...)
+}
+
+func berLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+
+	var b []byte
+	for ; n > 0; n >>= 8 {
+		b = append([]byte{byte(n)}, b...)
+	}
+	return append([]byte{0x80 | byte(len(b))}, b...)
+}
+
+func berSequence(items ...[]byte) []byte {
+	return berTLV(0x30, bytes.Join(items, nil))
+}
+
+func berInteger(v int64) []byte {
+	b := []byte{byte(v)}
+	for v > 127 || v < -128 {
+		v >>= 8
+		b = append([]byte{byte(v)}, b...)
+	}
+	return berTLV(0x02, b)
+}
+
+// berUint encodes the content octets of an unsigned integer such as TimeTicks.
+func berUint(v uint64) []byte {
+	b := []byte{byte(v)}
+	for v >>= 8; v > 0; v >>= 8 {
+		b = append([]byte{byte(v)}, b...)
+	}
+	if b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return b
+}
+
+func berOctetString(s string) []byte {
+	return berTLV(0x04, []byte(s))
+}
+
+func berOID(oid []uint32) []byte {
+	b := berSubidentifier(oid[0]*40 + oid[1])
+	for _, n := range oid[2:] {
+		b = append(b, berSubidentifier(n)...)
+	}
+	return berTLV(0x06, b)
+}
+
+func berSubidentifier(n uint32) []byte {
+	b := []byte{byte(n & 0x7f)}
+	for n >>= 7; n > 0; n >>= 7 {
+		b = append([]byte{byte(n&0x7f) | 0x80}, b...)
+	}
+	return b
+}
@@ -0,0 +1,123 @@
+package notifiers
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openinsight-project/grafinsight/pkg/components/simplejson"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+)
+
+func TestSNMPNotifier(t *testing.T) {
+	t.Run("empty settings should return error", func(t *testing.T) {
+		settingsJSON, err := simplejson.NewJson([]byte(`{ }`))
+		require.NoError(t, err)
+		model := &models.AlertNotification{
+			Name:     "snmp_testing",
+			Type:     "snmp",
+			Settings: settingsJSON,
+		}
+
+		_, err = NewSNMPNotifier(model)
+		require.Error(t, err)
+	})
+
+	t.Run("invalid OID should return error", func(t *testing.T) {
+		settingsJSON, err := simplejson.NewJson([]byte(`{ "address": "localhost", "trapOid": "1.3.x" }`))
+		require.NoError(t, err)
+		model := &models.AlertNotification{
+			Name:     "snmp_testing",
+			Type:     "snmp",
+			Settings: settingsJSON,
+		}
+
+		_, err = NewSNMPNotifier(model)
+		require.Error(t, err)
+	})
+
+	t.Run("settings should be parsed with defaults", func(t *testing.T) {
+		settingsJSON, err := simplejson.NewJson([]byte(`{ "address": "snmp.local" }`))
+		require.NoError(t, err)
+		model := &models.AlertNotification{
+			Name:     "snmp_testing",
+			Type:     "snmp",
+			Settings: settingsJSON,
+		}
+
+		not, err := NewSNMPNotifier(model)
+		require.NoError(t, err)
+		snmpNotifier := not.(*SNMPNotifier)
+
+		assert.Equal(t, "snmp_testing", snmpNotifier.Name)
+		assert.Equal(t, "snmp", snmpNotifier.Type)
+		assert.Equal(t, "snmp.local:162", snmpNotifier.Address)
+		assert.Equal(t, "public", snmpNotifier.Community)
+		assert.Equal(t, []uint32{1, 3, 6, 1, 4, 1, 32473, 1, 0, 1}, snmpNotifier.TrapOID)
+		assert.Equal(t, []uint32{1, 3, 6, 1, 4, 1, 32473, 1, 1}, snmpNotifier.VarbindOID)
+	})
+
+	t.Run("settings should be parsed", func(t *testing.T) {
+		json := `
+		{
+			"address": "10.0.0.1:1162",
+			"community": "secret",
+			"trapOid": ".1.3.6.1.4.1.99999.0.1",
+			"varbindOid": "1.3.6.1.4.1.99999.1"
+		}`
+		settingsJSON, err := simplejson.NewJson([]byte(json))
+		require.NoError(t, err)
+		model := &models.AlertNotification{
+			Name:     "snmp_testing",
+			Type:     "snmp",
+			Settings: settingsJSON,
+		}
+
+		not, err := NewSNMPNotifier(model)
+		require.NoError(t, err)
+		snmpNotifier := not.(*SNMPNotifier)
+
+		assert.Equal(t, "10.0.0.1:1162", snmpNotifier.Address)
+		assert.Equal(t, "secret", snmpNotifier.Community)
+		assert.Equal(t, []uint32{1, 3, 6, 1, 4, 1, 99999, 0, 1}, snmpNotifier.TrapOID)
+		assert.Equal(t, []uint32{1, 3, 6, 1, 4, 1, 99999, 1}, snmpNotifier.VarbindOID)
+	})
+}
+
+func TestSNMPEncoding(t *testing.T) {
+	t.Run("OID encoding", func(t *testing.T) {
+		// 1.3.6.1.4.1.32473, the documentation enterprise subtree.
+		assert.Equal(t, []byte{0x06, 0x08, 0x2b, 0x06, 0x01, 0x04, 0x01, 0x81, 0xfd, 0x59}, berOID([]uint32{1, 3, 6, 1, 4, 1, 32473}))
+	})
+
+	t.Run("integer encoding", func(t *testing.T) {
+		assert.Equal(t, []byte{0x02, 0x01, 0x00}, berInteger(0))
+		assert.Equal(t, []byte{0x02, 0x01, 0x7f}, berInteger(127))
+		assert.Equal(t, []byte{0x02, 0x02, 0x00, 0x80}, berInteger(128))
+		assert.Equal(t, []byte{0x02, 0x02, 0x01, 0x00}, berInteger(256))
+		assert.Equal(t, []byte{0x02, 0x01, 0xff}, berInteger(-1))
+	})
+
+	t.Run("long length encoding", func(t *testing.T) {
+		assert.Equal(t, []byte{0x81, 0x80}, berLength(128))
+		assert.Equal(t, []byte{0x82, 0x01, 0x00}, berLength(256))
+	})
+
+	t.Run("trap message", func(t *testing.T) {
+		sn := &SNMPNotifier{
+			Community: "public",
+			TrapOID:   []uint32{1, 3, 6, 1, 4, 1, 32473, 1, 0, 1},
+		}
+		packet := sn.buildTrap(1, []snmpVarbind{
+			{oid: []uint32{1, 3, 6, 1, 4, 1, 32473, 1, 1, 2}, value: berOctetString("rule")},
+		})
+
+		require.Equal(t, byte(0x30), packet[0])
+		// version 2c followed by the community string.
+		assert.True(t, bytes.HasPrefix(packet[2:], []byte{0x02, 0x01, 0x01, 0x04, 0x06, 'p', 'u', 'b', 'l', 'i', 'c', 0xa7}))
+		assert.True(t, bytes.HasSuffix(packet, berOctetString("rule")))
+		assert.Equal(t, len(packet)-2, int(packet[1]))
+	})
+}
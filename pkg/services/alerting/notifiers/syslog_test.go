@@ -0,0 +1,104 @@
+package notifiers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openinsight-project/grafinsight/pkg/components/null"
+	"github.com/openinsight-project/grafinsight/pkg/components/simplejson"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/services/alerting"
+	"github.com/openinsight-project/grafinsight/pkg/services/validations"
+)
+
+func TestSyslogNotifier(t *testing.T) {
+	t.Run("empty settings should return error", func(t *testing.T) {
+		settingsJSON, err := simplejson.NewJson([]byte(`{ }`))
+		require.NoError(t, err)
+		model := &models.AlertNotification{
+			Name:     "syslog_testing",
+			Type:     "syslog",
+			Settings: settingsJSON,
+		}
+
+		_, err = NewSyslogNotifier(model)
+		require.Error(t, err)
+	})
+
+	t.Run("unknown transport should return error", func(t *testing.T) {
+		settingsJSON, err := simplejson.NewJson([]byte(`{ "address": "localhost:514", "network": "sctp" }`))
+		require.NoError(t, err)
+		model := &models.AlertNotification{
+			Name:     "syslog_testing",
+			Type:     "syslog",
+			Settings: settingsJSON,
+		}
+
+		_, err = NewSyslogNotifier(model)
+		require.Error(t, err)
+	})
+
+	t.Run("settings should be parsed", func(t *testing.T) {
+		json := `
+		{
+			"address": "syslog.local:6514",
+			"network": "tls",
+			"tlsSkipVerify": true,
+			"facility": "local3",
+			"appName": "noc"
+		}`
+		settingsJSON, err := simplejson.NewJson([]byte(json))
+		require.NoError(t, err)
+		model := &models.AlertNotification{
+			Name:     "syslog_testing",
+			Type:     "syslog",
+			Settings: settingsJSON,
+		}
+
+		not, err := NewSyslogNotifier(model)
+		require.NoError(t, err)
+		syslogNotifier := not.(*SyslogNotifier)
+
+		assert.Equal(t, "syslog_testing", syslogNotifier.Name)
+		assert.Equal(t, "syslog", syslogNotifier.Type)
+		assert.Equal(t, "syslog.local:6514", syslogNotifier.Address)
+		assert.Equal(t, "tls", syslogNotifier.Network)
+		assert.True(t, syslogNotifier.TLSSkipVerify)
+		assert.Equal(t, 19, syslogNotifier.Facility)
+		assert.Equal(t, "noc", syslogNotifier.AppName)
+	})
+
+	t.Run("message should be formatted as RFC5424", func(t *testing.T) {
+		settingsJSON, err := simplejson.NewJson([]byte(`{ "address": "localhost:514" }`))
+		require.NoError(t, err)
+		model := &models.AlertNotification{
+			Name:     "syslog_testing",
+			Type:     "syslog",
+			Settings: settingsJSON,
+		}
+
+		not, err := NewSyslogNotifier(model)
+		require.NoError(t, err)
+		syslogNotifier := not.(*SyslogNotifier)
+
+		evalContext := alerting.NewEvalContext(context.Background(), &alerting.Rule{
+			ID:      1,
+			Name:    `Disk "root" full`,
+			Message: "Free some space",
+			State:   models.AlertStateAlerting,
+		}, &validations.OSSPluginRequestValidator{})
+		evalContext.EvalMatches = []*alerting.EvalMatch{{Metric: "disk", Value: null.FloatFrom(95)}}
+
+		msg := syslogNotifier.buildMessage(evalContext, "http://localhost/d/abc", time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC))
+
+		assert.True(t, strings.HasPrefix(msg, "<130>1 2021-01-02T03:04:05Z "))
+		assert.Contains(t, msg, ` grafinsight `)
+		assert.Contains(t, msg, `[alert@32473 ruleId="1" ruleName="Disk \"root\" full" state="alerting" ruleUrl="http://localhost/d/abc"]`)
+		assert.True(t, strings.HasSuffix(msg, `[Alerting] Disk "root" full - Free some space - disk=95.000`))
+	})
+}
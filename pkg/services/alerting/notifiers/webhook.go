@@ -122,6 +122,10 @@ func (wn *WebhookNotifier) Notify(evalContext *alerting.EvalContext) error {
 		bodyJSON.Set("message", evalContext.Rule.Message)
 	}
 
+	if evalContext.AcknowledgeURL != "" {
+		bodyJSON.Set("acknowledgeUrl", evalContext.AcknowledgeURL)
+	}
+
 	body, _ := bodyJSON.MarshalJSON()
 
 	cmd := &models.SendWebhookSync{
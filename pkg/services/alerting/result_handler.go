@@ -10,6 +10,7 @@ import (
 	"github.com/openinsight-project/grafinsight/pkg/infra/log"
 	"github.com/openinsight-project/grafinsight/pkg/infra/metrics"
 	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/setting"
 
 	"github.com/openinsight-project/grafinsight/pkg/services/annotations"
 	"github.com/openinsight-project/grafinsight/pkg/services/rendering"
@@ -47,7 +48,8 @@ func (handler *defaultResultHandler) handle(evalContext *EvalContext) error {
 	}
 
 	metrics.MAlertingResultState.WithLabelValues(string(evalContext.Rule.State)).Inc()
-	if evalContext.shouldUpdateAlertState() {
+	stateChanged := evalContext.shouldUpdateAlertState()
+	if stateChanged {
 		handler.log.Info("New state change", "ruleId", evalContext.Rule.ID, "newState", evalContext.Rule.State, "prev state", evalContext.PrevAlertState)
 
 		cmd := &models.SetAlertStateCommand{
@@ -78,6 +80,16 @@ func (handler *defaultResultHandler) handle(evalContext *EvalContext) error {
 
 			// Update the last state change of the alert rule in memory
 			evalContext.Rule.LastStateChange = time.Now()
+
+			transitionCmd := &models.SaveAlertStateTransitionCommand{
+				OrgId:     evalContext.Rule.OrgID,
+				AlertId:   evalContext.Rule.ID,
+				PrevState: evalContext.PrevAlertState,
+				NewState:  evalContext.Rule.State,
+			}
+			if err := bus.DispatchCtx(evalContext.Ctx, transitionCmd); err != nil {
+				handler.log.Error("Failed to save alert state transition", "error", err)
+			}
 		}
 
 		// save annotation
@@ -99,6 +111,19 @@ func (handler *defaultResultHandler) handle(evalContext *EvalContext) error {
 		}
 	}
 
+	// Flapping rules are rechecked on every evaluation until they settle,
+	// other rules only when their state changes.
+	if (setting.AlertingFlappingThreshold > 0 && stateChanged) || evalContext.Rule.Flapping {
+		if err := handler.updateFlapping(evalContext); err != nil {
+			handler.log.Error("Failed to update flapping state", "error", err)
+		}
+	}
+
+	if evalContext.Rule.Flapping {
+		handler.log.Debug("Skipping notifications for flapping alert rule", "ruleId", evalContext.Rule.ID)
+		return nil
+	}
+
 	if err := handler.notifier.SendIfNeeded(evalContext); err != nil {
 		switch {
 		case errors.Is(err, context.Canceled):
@@ -50,6 +50,8 @@ func (srv *CleanUpService) Run(ctx context.Context) error {
 			srv.cleanUpOldAnnotations(ctxWithTimeout)
 			srv.expireOldUserInvites()
 			srv.deleteStaleShortURLs()
+			srv.deleteOldAlertNotificationDryRuns()
+			srv.deleteOldAlertStateTransitions()
 			err := srv.ServerLockService.LockAndExecute(ctx, "delete old login attempts",
 				time.Minute*10, func() {
 					srv.deleteOldLoginAttempts()
@@ -168,3 +170,25 @@ func (srv *CleanUpService) deleteStaleShortURLs() {
 		srv.log.Debug("Deleted short urls", "rows affected", cmd.NumDeleted)
 	}
 }
+
+func (srv *CleanUpService) deleteOldAlertNotificationDryRuns() {
+	cmd := models.DeleteOldAlertNotificationDryRunsCommand{
+		OlderThan: time.Now().Add(-setting.AlertingDryRunLogMaxAge),
+	}
+	if err := bus.Dispatch(&cmd); err != nil {
+		srv.log.Error("Problem deleting old alert notification dry runs", "error", err.Error())
+	} else {
+		srv.log.Debug("Deleted old alert notification dry runs", "rows affected", cmd.DeletedRows)
+	}
+}
+
+func (srv *CleanUpService) deleteOldAlertStateTransitions() {
+	cmd := models.DeleteOldAlertStateTransitionsCommand{
+		OlderThan: time.Now().Add(-setting.AlertingFlappingWindow),
+	}
+	if err := bus.Dispatch(&cmd); err != nil {
+		srv.log.Error("Problem deleting old alert state transitions", "error", err.Error())
+	} else {
+		srv.log.Debug("Deleted old alert state transitions", "rows affected", cmd.DeletedRows)
+	}
+}
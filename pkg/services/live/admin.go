@@ -0,0 +1,167 @@
+package live
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/centrifugal/centrifuge"
+	"github.com/openinsight-project/grafinsight/pkg/api/response"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/services/live/livecontext"
+	"github.com/openinsight-project/grafinsight/pkg/services/live/orgchannel"
+)
+
+// LiveNodeInfo describes a GrafInsight instance serving live clients.
+type LiveNodeInfo struct {
+	Name     string `json:"name"`
+	Clients  uint32 `json:"clients"`
+	Users    uint32 `json:"users"`
+	Channels uint32 `json:"channels"`
+	Uptime   uint32 `json:"uptime"`
+}
+
+// LiveChannelInfo describes a channel with subscribers on this instance.
+type LiveChannelInfo struct {
+	Channel     string `json:"channel"`
+	OrgID       int64  `json:"orgId"`
+	Scope       string `json:"scope"`
+	Subscribers int    `json:"subscribers"`
+}
+
+// LiveClientInfo describes a client connected to this instance.
+type LiveClientInfo struct {
+	ID        string   `json:"id"`
+	UserID    int64    `json:"userId"`
+	Login     string   `json:"login"`
+	OrgID     int64    `json:"orgId"`
+	Transport string   `json:"transport"`
+	Channels  []string `json:"channels"`
+}
+
+// LiveStats is returned by the live admin API.
+type LiveStats struct {
+	Nodes    []LiveNodeInfo    `json:"nodes"`
+	Channels []LiveChannelInfo `json:"channels"`
+	Clients  []LiveClientInfo  `json:"clients"`
+}
+
+// HandleAdminGetStats lists the live instances, and the channels and clients
+// of this instance.
+// GET /api/admin/live
+func (g *GrafinsightLive) HandleAdminGetStats(c *models.ReqContext) response.Response {
+	info, err := g.node.Info()
+	if err != nil {
+		return response.Error(500, "Failed to get live nodes", err)
+	}
+
+	stats := LiveStats{
+		Nodes:    make([]LiveNodeInfo, 0, len(info.Nodes)),
+		Channels: []LiveChannelInfo{},
+		Clients:  []LiveClientInfo{},
+	}
+	for _, node := range info.Nodes {
+		stats.Nodes = append(stats.Nodes, LiveNodeInfo{
+			Name:     node.Name,
+			Clients:  node.NumClients,
+			Users:    node.NumUsers,
+			Channels: node.NumChannels,
+			Uptime:   node.Uptime,
+		})
+	}
+
+	hub := g.node.Hub()
+	for _, channel := range hub.Channels() {
+		orgID, _, _ := orgchannel.StripOrgID(channel)
+		stats.Channels = append(stats.Channels, LiveChannelInfo{
+			Channel:     channel,
+			OrgID:       orgID,
+			Scope:       channelScope(channel),
+			Subscribers: hub.NumSubscribers(channel),
+		})
+	}
+	sort.Slice(stats.Channels, func(i, j int) bool {
+		return stats.Channels[i].Channel < stats.Channels[j].Channel
+	})
+
+	for _, client := range g.connectedClients() {
+		clientInfo := LiveClientInfo{
+			ID:        client.ID(),
+			Transport: client.Transport().Name(),
+			Channels:  client.Channels(),
+		}
+		if user, ok := livecontext.GetContextSignedUser(client.Context()); ok {
+			clientInfo.UserID = user.UserId
+			clientInfo.Login = user.Login
+			clientInfo.OrgID = user.OrgId
+		}
+		sort.Strings(clientInfo.Channels)
+		stats.Clients = append(stats.Clients, clientInfo)
+	}
+	sort.Slice(stats.Clients, func(i, j int) bool {
+		return stats.Clients[i].ID < stats.Clients[j].ID
+	})
+
+	return response.JSON(200, stats)
+}
+
+// clientDisconnectPublisher sends the disconnects of clients to the instances
+// they are connected to.
+type clientDisconnectPublisher interface {
+	PublishDisconnectClient(clientID string) error
+}
+
+// HandleAdminDisconnectClient disconnects a client, on the instance it is
+// connected to. The client is told not to reconnect.
+// DELETE /api/admin/live/clients/:clientId
+func (g *GrafinsightLive) HandleAdminDisconnectClient(c *models.ReqContext) response.Response {
+	clientID := c.Params(":clientId")
+
+	g.clientsMu.RLock()
+	client, ok := g.clients[clientID]
+	g.clientsMu.RUnlock()
+	if ok {
+		if err := client.Disconnect(centrifuge.DisconnectForceNoReconnect); err != nil {
+			return response.Error(500, "Failed to disconnect live client", err)
+		}
+		return response.Success("Live client disconnected")
+	}
+
+	if g.disconnects == nil {
+		return response.Error(404, "Live client not found", nil)
+	}
+	// The client may be connected to another instance
+	if err := g.disconnects.PublishDisconnectClient(clientID); err != nil {
+		return response.Error(500, "Failed to disconnect live client", err)
+	}
+	return response.Success("Live client disconnected")
+}
+
+// disconnectLocalClient disconnects a client of this instance, when another
+// instance asks to.
+func (g *GrafinsightLive) disconnectLocalClient(clientID string) {
+	g.clientsMu.RLock()
+	client, ok := g.clients[clientID]
+	g.clientsMu.RUnlock()
+	if !ok {
+		return
+	}
+
+	if err := client.Disconnect(centrifuge.DisconnectForceNoReconnect); err != nil {
+		logger.Warn("Failed to disconnect live client", "client", clientID, "error", err)
+	}
+}
+
+// HandleAdminDisconnectUser disconnects all the clients of a user, on every
+// instance.
+// DELETE /api/admin/live/users/:userId
+func (g *GrafinsightLive) HandleAdminDisconnectUser(c *models.ReqContext) response.Response {
+	userID, err := strconv.ParseInt(c.Params(":userId"), 10, 64)
+	if err != nil {
+		return response.Error(400, "Invalid user ID", err)
+	}
+
+	if err := g.node.Disconnect(strconv.FormatInt(userID, 10)); err != nil {
+		return response.Error(500, "Failed to disconnect live user", err)
+	}
+	return response.Success("Live user disconnected")
+}
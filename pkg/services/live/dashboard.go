@@ -0,0 +1,27 @@
+package live
+
+import (
+	"github.com/openinsight-project/grafinsight/pkg/api/response"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+)
+
+// HandleDashboardViewers lists the users currently viewing a dashboard and
+// whether they are editing it.
+// GET /api/live/dashboards/uid/:uid/viewers
+func (g *GrafinsightLive) HandleDashboardViewers(c *models.ReqContext) response.Response {
+	uid := c.Params(":uid")
+
+	allowed, err := canAccessDashboardChannel(c.SignedInUser, "uid/"+uid, false)
+	if err != nil {
+		return response.Error(500, "Failed to check dashboard permissions", err)
+	}
+	if !allowed {
+		return response.Error(403, "Access denied to dashboard", nil)
+	}
+
+	viewers, err := g.GrafinsightScope.DashboardViewers.Viewers(c.OrgId, uid)
+	if err != nil {
+		return response.Error(500, "Failed to get dashboard viewers", err)
+	}
+	return response.JSON(200, viewers)
+}
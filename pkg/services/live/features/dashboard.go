@@ -2,22 +2,81 @@ package features
 
 import (
 	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/centrifugal/centrifuge"
+	"github.com/openinsight-project/grafinsight/pkg/infra/remotecache"
 	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/services/live/livecontext"
+	"github.com/openinsight-project/grafinsight/pkg/services/live/orgchannel"
 )
 
+// The actions of the dashboard events
+const (
+	DashboardActionSaved            = "saved"
+	DashboardActionDeleted          = "deleted"
+	DashboardActionEditingStarted   = "editing-started"
+	DashboardActionEditingCancelled = "editing-cancelled"
+)
+
+// ErrInvalidDashboardEvent is returned when a client publishes an event that
+// is not an editing event for the dashboard of the channel.
+var ErrInvalidDashboardEvent = errors.New("invalid dashboard event")
+
 // DashboardEvent events related to dashboards
 type dashboardEvent struct {
 	UID       string `json:"uid"`
-	Action    string `json:"action"` // saved, editing
+	Action    string `json:"action"` // saved, deleted, editing-started, editing-cancelled
 	UserID    int64  `json:"userId,omitempty"`
+	Message   string `json:"message,omitempty"`
 	SessionID string `json:"sessionId,omitempty"`
+	Timestamp int64  `json:"timestamp,omitempty"`
+}
+
+// DashboardViewer is a user currently viewing a dashboard.
+type DashboardViewer struct {
+	UserID       int64      `json:"userId"`
+	Login        string     `json:"login"`
+	Name         string     `json:"name"`
+	AvatarURL    string     `json:"avatarUrl"`
+	Sessions     int        `json:"sessions"`
+	Editing      bool       `json:"editing"`
+	EditingSince *time.Time `json:"editingSince,omitempty"`
+}
+
+// dashboardEditorExpiration is how long an editor is kept in the cache. The
+// editors of clients that are no longer in the presence of the channel are
+// ignored, so this only bounds the garbage left by instances that stopped.
+const dashboardEditorExpiration = 24 * time.Hour
+
+type dashboardEditor struct {
+	UserID    int64     `json:"userId"`
+	Name      string    `json:"name"`
+	SessionID string    `json:"sessionId,omitempty"`
+	Since     time.Time `json:"since"`
 }
 
 // DashboardHandler manages all the `grafinsight/dashboard/*` channels
 type DashboardHandler struct {
 	Publisher models.ChannelPublisher
+
+	// Presence lists the clients subscribed to a channel.
+	Presence func(channel string) (centrifuge.PresenceResult, error)
+
+	// Cache stores the clients editing each dashboard, so that all the
+	// instances know about the clients connected to the others. Without a
+	// cache, the editors only live in this instance.
+	Cache remotecache.CacheStorage
+
+	// The clients editing each dashboard, by channel and client ID, when
+	// there is no cache.
+	editorsMu sync.Mutex
+	editors   map[string]map[string]dashboardEditor
 }
 
 // GetHandlerForPath called on init
@@ -25,7 +84,7 @@ func (h *DashboardHandler) GetHandlerForPath(path string) (models.ChannelHandler
 	return h, nil // all dashboards share the same handler
 }
 
-// OnSubscribe for now allows anyone to subscribe to any dashboard
+// OnSubscribe is called once the user is allowed to view the dashboard
 func (h *DashboardHandler) OnSubscribe(c *centrifuge.Client, e centrifuge.SubscribeEvent) (centrifuge.SubscribeReply, error) {
 	return centrifuge.SubscribeReply{
 		Options: centrifuge.SubscribeOptions{
@@ -35,40 +94,278 @@ func (h *DashboardHandler) OnSubscribe(c *centrifuge.Client, e centrifuge.Subscr
 	}, nil
 }
 
-// OnPublish is called when someone begins to edit a dashoard
+// OnPublish is called when someone begins or stops editing a dashboard. The
+// event is published on behalf of the user so it can not be spoofed.
 func (h *DashboardHandler) OnPublish(c *centrifuge.Client, e centrifuge.PublishEvent) (centrifuge.PublishReply, error) {
+	user, ok := livecontext.GetContextSignedUser(c.Context())
+	if !ok {
+		return centrifuge.PublishReply{}, centrifuge.ErrorUnauthorized
+	}
+
+	orgID, uid, ok := dashboardChannelUID(e.Channel)
+	if !ok {
+		return centrifuge.PublishReply{}, ErrInvalidDashboardEvent
+	}
+
+	var event dashboardEvent
+	if err := json.Unmarshal(e.Data, &event); err != nil {
+		return centrifuge.PublishReply{}, ErrInvalidDashboardEvent
+	}
+	if event.UID != uid {
+		return centrifuge.PublishReply{}, ErrInvalidDashboardEvent
+	}
+
+	switch event.Action {
+	case DashboardActionEditingStarted:
+		h.startEditing(e.Channel, c.ID(), dashboardEditor{
+			UserID:    user.UserId,
+			Name:      displayName(user),
+			SessionID: event.SessionID,
+			Since:     time.Now(),
+		})
+	case DashboardActionEditingCancelled:
+		h.stopEditing(e.Channel, c.ID())
+	default:
+		return centrifuge.PublishReply{}, ErrInvalidDashboardEvent
+	}
+
+	event.UserID = user.UserId
+	event.Message = displayName(user)
+	event.Timestamp = time.Now().UnixNano() / int64(time.Millisecond)
+	if err := h.publishToDashboard(orgID, event); err != nil {
+		return centrifuge.PublishReply{}, err
+	}
+
+	// The event is already published
 	return centrifuge.PublishReply{
-		Options: centrifuge.PublishOptions{},
+		Result: &centrifuge.PublishResult{},
 	}, nil
 }
 
+// OnUnsubscribe cancels the editing of clients leaving the dashboard.
+func (h *DashboardHandler) OnUnsubscribe(c *centrifuge.Client, e centrifuge.UnsubscribeEvent) {
+	editor, ok := h.stopEditing(e.Channel, c.ID())
+	if !ok {
+		return
+	}
+
+	orgID, uid, ok := dashboardChannelUID(e.Channel)
+	if !ok {
+		return
+	}
+
+	err := h.publishToDashboard(orgID, dashboardEvent{
+		UID:       uid,
+		Action:    DashboardActionEditingCancelled,
+		UserID:    editor.UserID,
+		Message:   editor.Name,
+		SessionID: editor.SessionID,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+	})
+	if err != nil {
+		logger.Warn("Failed to publish dashboard editing cancelled", "channel", e.Channel, "error", err)
+	}
+}
+
+func dashboardEditorCacheKey(channel string, clientID string) string {
+	return "live-dashboard-editor:" + channel + ":" + clientID
+}
+
+func (h *DashboardHandler) startEditing(channel string, clientID string, editor dashboardEditor) {
+	if h.Cache != nil {
+		if _, ok := h.getEditor(channel, clientID); ok {
+			return // keep when the editing started
+		}
+		value, err := json.Marshal(editor)
+		if err != nil {
+			logger.Warn("Failed to marshal dashboard editor", "channel", channel, "error", err)
+			return
+		}
+		if err := h.Cache.Set(dashboardEditorCacheKey(channel, clientID), value, dashboardEditorExpiration); err != nil {
+			logger.Warn("Failed to store dashboard editor", "channel", channel, "error", err)
+		}
+		return
+	}
+
+	h.editorsMu.Lock()
+	defer h.editorsMu.Unlock()
+
+	if h.editors == nil {
+		h.editors = map[string]map[string]dashboardEditor{}
+	}
+	if h.editors[channel] == nil {
+		h.editors[channel] = map[string]dashboardEditor{}
+	}
+	if _, ok := h.editors[channel][clientID]; ok {
+		return // keep when the editing started
+	}
+	h.editors[channel][clientID] = editor
+}
+
+func (h *DashboardHandler) stopEditing(channel string, clientID string) (dashboardEditor, bool) {
+	if h.Cache != nil {
+		editor, ok := h.getEditor(channel, clientID)
+		if !ok {
+			return dashboardEditor{}, false
+		}
+		if err := h.Cache.Delete(dashboardEditorCacheKey(channel, clientID)); err != nil {
+			logger.Warn("Failed to delete dashboard editor", "channel", channel, "error", err)
+		}
+		return editor, true
+	}
+
+	h.editorsMu.Lock()
+	defer h.editorsMu.Unlock()
+
+	editor, ok := h.editors[channel][clientID]
+	if !ok {
+		return dashboardEditor{}, false
+	}
+	delete(h.editors[channel], clientID)
+	if len(h.editors[channel]) == 0 {
+		delete(h.editors, channel)
+	}
+	return editor, true
+}
+
+// getEditor gets the editor of a client connected to any instance.
+func (h *DashboardHandler) getEditor(channel string, clientID string) (dashboardEditor, bool) {
+	if h.Cache == nil {
+		h.editorsMu.Lock()
+		defer h.editorsMu.Unlock()
+		editor, ok := h.editors[channel][clientID]
+		return editor, ok
+	}
+
+	value, err := h.Cache.Get(dashboardEditorCacheKey(channel, clientID))
+	if err != nil {
+		if !errors.Is(err, remotecache.ErrCacheItemNotFound) {
+			logger.Warn("Failed to read dashboard editor", "channel", channel, "error", err)
+		}
+		return dashboardEditor{}, false
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return dashboardEditor{}, false
+	}
+	var editor dashboardEditor
+	if err := json.Unmarshal(b, &editor); err != nil {
+		logger.Warn("Failed to read dashboard editor", "channel", channel, "error", err)
+		return dashboardEditor{}, false
+	}
+	return editor, true
+}
+
+// Viewers lists the users viewing a dashboard and whether they are editing it.
+func (h *DashboardHandler) Viewers(orgID int64, uid string) ([]DashboardViewer, error) {
+	channel := orgchannel.PrependOrgID(orgID, "grafinsight/dashboard/uid/"+uid)
+
+	result, err := h.Presence(channel)
+	if err != nil {
+		return nil, err
+	}
+
+	byUser := map[int64]*DashboardViewer{}
+	for clientID, info := range result.Presence {
+		userID, err := strconv.ParseInt(info.UserID, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		viewer, ok := byUser[userID]
+		if !ok {
+			viewer = &DashboardViewer{UserID: userID}
+			var conn models.LiveConnectionInfo
+			if len(info.ConnInfo) > 0 {
+				if err := json.Unmarshal(info.ConnInfo, &conn); err != nil {
+					logger.Warn("Failed to read live connection info", "client", clientID, "error", err)
+				}
+			}
+			viewer.Login = conn.Login
+			viewer.Name = conn.Name
+			viewer.AvatarURL = conn.AvatarURL
+			byUser[userID] = viewer
+		}
+		viewer.Sessions++
+
+		// The presence of the channel is shared by all the instances, and so
+		// are the editors of its clients.
+		if editor, ok := h.getEditor(channel, clientID); ok {
+			viewer.Editing = true
+			if viewer.EditingSince == nil || editor.Since.Before(*viewer.EditingSince) {
+				since := editor.Since
+				viewer.EditingSince = &since
+			}
+		}
+	}
+
+	viewers := make([]DashboardViewer, 0, len(byUser))
+	for _, viewer := range byUser {
+		viewers = append(viewers, *viewer)
+	}
+	sort.Slice(viewers, func(i, j int) bool {
+		return viewers[i].UserID < viewers[j].UserID
+	})
+	return viewers, nil
+}
+
+// publishToDashboard sends the event to the channel of the dashboard only.
+func (h *DashboardHandler) publishToDashboard(orgID int64, event dashboardEvent) error {
+	msg, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return h.Publisher(orgchannel.PrependOrgID(orgID, "grafinsight/dashboard/uid/"+event.UID), msg)
+}
+
 // DashboardSaved should broadcast to the appropriate stream
-func (h *DashboardHandler) publish(event dashboardEvent) error {
+func (h *DashboardHandler) publish(orgID int64, event dashboardEvent) error {
 	msg, err := json.Marshal(event)
 	if err != nil {
 		return err
 	}
-	err = h.Publisher("grafinsight/dashboard/uid/"+event.UID, msg)
+	err = h.Publisher(orgchannel.PrependOrgID(orgID, "grafinsight/dashboard/uid/"+event.UID), msg)
 	if err != nil {
 		return err
 	}
-	return h.Publisher("grafinsight/dashboard/changes", msg)
+	return h.Publisher(orgchannel.PrependOrgID(orgID, "grafinsight/dashboard/changes"), msg)
 }
 
 // DashboardSaved will broadcast to all connected dashboards
-func (h *DashboardHandler) DashboardSaved(uid string, userID int64) error {
-	return h.publish(dashboardEvent{
+func (h *DashboardHandler) DashboardSaved(orgID int64, uid string, userID int64) error {
+	return h.publish(orgID, dashboardEvent{
 		UID:    uid,
-		Action: "saved",
+		Action: DashboardActionSaved,
 		UserID: userID,
 	})
 }
 
 // DashboardDeleted will broadcast to all connected dashboards
-func (h *DashboardHandler) DashboardDeleted(uid string, userID int64) error {
-	return h.publish(dashboardEvent{
+func (h *DashboardHandler) DashboardDeleted(orgID int64, uid string, userID int64) error {
+	return h.publish(orgID, dashboardEvent{
 		UID:    uid,
-		Action: "deleted",
+		Action: DashboardActionDeleted,
 		UserID: userID,
 	})
 }
+
+// dashboardChannelUID returns the org and the dashboard uid of a
+// `grafinsight/dashboard/uid/${uid}` channel.
+func dashboardChannelUID(channel string) (int64, string, bool) {
+	orgID, id, err := orgchannel.StripOrgID(channel)
+	if err != nil {
+		return 0, "", false
+	}
+	uid := strings.TrimPrefix(id, "grafinsight/dashboard/uid/")
+	if uid == id || uid == "" {
+		return 0, "", false
+	}
+	return orgID, uid, true
+}
+
+func displayName(user *models.SignedInUser) string {
+	if user.Name != "" {
+		return user.Name
+	}
+	return user.Login
+}
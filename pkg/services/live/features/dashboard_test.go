@@ -0,0 +1,95 @@
+package features
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/centrifuge"
+	"github.com/openinsight-project/grafinsight/pkg/infra/remotecache"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDashboardViewers(t *testing.T) {
+	connInfo := func(login string) []byte {
+		b, err := json.Marshal(models.LiveConnectionInfo{Login: login, Name: login})
+		require.NoError(t, err)
+		return b
+	}
+
+	h := &DashboardHandler{
+		Presence: func(channel string) (centrifuge.PresenceResult, error) {
+			require.Equal(t, "1/grafinsight/dashboard/uid/abc", channel)
+			return centrifuge.PresenceResult{Presence: map[string]*centrifuge.ClientInfo{
+				"c1": {ClientID: "c1", UserID: "2", ConnInfo: connInfo("bob")},
+				"c2": {ClientID: "c2", UserID: "1", ConnInfo: connInfo("alice")},
+				"c3": {ClientID: "c3", UserID: "2", ConnInfo: connInfo("bob")},
+			}}, nil
+		},
+	}
+
+	t.Run("lists each user once", func(t *testing.T) {
+		viewers, err := h.Viewers(1, "abc")
+		require.NoError(t, err)
+		require.Len(t, viewers, 2)
+		require.Equal(t, "alice", viewers[0].Login)
+		require.Equal(t, 1, viewers[0].Sessions)
+		require.Equal(t, "bob", viewers[1].Login)
+		require.Equal(t, 2, viewers[1].Sessions)
+		require.False(t, viewers[1].Editing)
+	})
+
+	t.Run("marks the users editing the dashboard", func(t *testing.T) {
+		since := time.Now()
+		h.startEditing("1/grafinsight/dashboard/uid/abc", "c3", dashboardEditor{UserID: 2, Since: since})
+		h.startEditing("1/grafinsight/dashboard/uid/other", "c2", dashboardEditor{UserID: 1, Since: since})
+
+		viewers, err := h.Viewers(1, "abc")
+		require.NoError(t, err)
+		require.False(t, viewers[0].Editing)
+		require.True(t, viewers[1].Editing)
+		require.Equal(t, since, *viewers[1].EditingSince)
+
+		editor, ok := h.stopEditing("1/grafinsight/dashboard/uid/abc", "c3")
+		require.True(t, ok)
+		require.Equal(t, int64(2), editor.UserID)
+
+		viewers, err = h.Viewers(1, "abc")
+		require.NoError(t, err)
+		require.False(t, viewers[1].Editing)
+	})
+
+	t.Run("shares the editors through the cache", func(t *testing.T) {
+		cache := remotecache.NewFakeStore(t)
+		replica := &DashboardHandler{Presence: h.Presence, Cache: cache}
+		h.Cache = cache
+		t.Cleanup(func() { h.Cache = nil })
+
+		since := time.Now()
+		replica.startEditing("1/grafinsight/dashboard/uid/abc", "c2", dashboardEditor{UserID: 1, Since: since})
+
+		viewers, err := h.Viewers(1, "abc")
+		require.NoError(t, err)
+		require.True(t, viewers[0].Editing)
+		require.True(t, since.Equal(*viewers[0].EditingSince))
+
+		editor, ok := h.stopEditing("1/grafinsight/dashboard/uid/abc", "c2")
+		require.True(t, ok)
+		require.Equal(t, int64(1), editor.UserID)
+
+		viewers, err = replica.Viewers(1, "abc")
+		require.NoError(t, err)
+		require.False(t, viewers[0].Editing)
+	})
+}
+
+func TestDashboardChannelUID(t *testing.T) {
+	orgID, uid, ok := dashboardChannelUID("3/grafinsight/dashboard/uid/abc")
+	require.True(t, ok)
+	require.Equal(t, int64(3), orgID)
+	require.Equal(t, "abc", uid)
+
+	_, _, ok = dashboardChannelUID("3/grafinsight/dashboard/changes")
+	require.False(t, ok)
+}
@@ -1,18 +1,39 @@
 package features
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
 	"github.com/centrifugal/centrifuge"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	protocol "github.com/influxdata/line-protocol"
 	"github.com/openinsight-project/grafinsight/pkg/infra/log"
 	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/services/live/orgchannel"
 )
 
 var (
 	logger = log.New("live.features") // scoped to all features?
+
+	// ErrMeasurementsPublishNotAllowed is returned when a client tries to publish measurements over the websocket.
+	ErrMeasurementsPublishNotAllowed = errors.New("measurements must be pushed with the HTTP API")
 )
 
-// MeasurementsRunner will simply broadcast all events to `grafinsight/broadcast/*` channels.
-// This makes no assumptions about the shape of the data and will broadcast it to anyone listening
+// MeasurementsRunner manages all the `grafinsight/measurements/*` channels.
+// Measurements are pushed with the HTTP API, converted to data frames and
+// broadcast to anyone listening.
 type MeasurementsRunner struct {
+	Publisher models.ChannelPublisher
+}
+
+// measurementsMessage is the message sent to `grafinsight/measurements/*` subscribers.
+type measurementsMessage struct {
+	Action   models.MeasurementAction `json:"action,omitempty"`
+	Capacity int64                    `json:"capacity,omitempty"`
+	Frames   data.Frames              `json:"frames"`
 }
 
 // GetHandlerForPath gets the handler for a path.
@@ -26,10 +47,205 @@ func (m *MeasurementsRunner) OnSubscribe(c *centrifuge.Client, e centrifuge.Subs
 	return centrifuge.SubscribeReply{}, nil
 }
 
-// OnPublish is called when a client wants to broadcast on the websocket
-// Currently this sends measurements over websocket -- should be replaced with the HTTP interface
+// OnPublish rejects measurements sent over the websocket, they are
+// pushed with the HTTP API instead.
 func (m *MeasurementsRunner) OnPublish(c *centrifuge.Client, e centrifuge.PublishEvent) (centrifuge.PublishReply, error) {
-	return centrifuge.PublishReply{
-		Options: centrifuge.PublishOptions{},
-	}, nil
+	return centrifuge.PublishReply{}, ErrMeasurementsPublishNotAllowed
+}
+
+// PushMeasurements converts the batch to data frames and publishes
+// them to the `grafinsight/measurements/${stream}` channel of the org.
+func (m *MeasurementsRunner) PushMeasurements(orgID int64, stream string, batch *models.MeasurementBatch) error {
+	frames, err := MeasurementsToFrames(batch.Measurements)
+	if err != nil {
+		return err
+	}
+
+	msg, err := json.Marshal(&measurementsMessage{
+		Action:   batch.Action,
+		Capacity: batch.Capacity,
+		Frames:   frames,
+	})
+	if err != nil {
+		return err
+	}
+
+	return m.Publisher(orgchannel.PrependOrgID(orgID, "grafinsight/measurements/"+stream), msg)
+}
+
+// ParseInfluxLineProtocol parses measurements written in the Influx line
+// protocol. Timestamps without an explicit unit are read with precision.
+func ParseInfluxLineProtocol(body []byte, precision time.Duration) ([]models.Measurement, error) {
+	handler := protocol.NewMetricHandler()
+	handler.SetTimePrecision(precision)
+	parser := protocol.NewParser(handler)
+	parser.SetTimeFunc(time.Now)
+
+	metrics, err := parser.Parse(body)
+	if err != nil {
+		return nil, err
+	}
+
+	measurements := make([]models.Measurement, 0, len(metrics))
+	for _, metric := range metrics {
+		m := models.Measurement{
+			Name:   metric.Name(),
+			Time:   metric.Time().UnixNano() / int64(time.Millisecond),
+			Values: make(map[string]interface{}, len(metric.FieldList())),
+		}
+
+		if tags := metric.TagList(); len(tags) > 0 {
+			m.Labels = make(map[string]string, len(tags))
+			for _, tag := range tags {
+				m.Labels[tag.Key] = tag.Value
+			}
+		}
+
+		for _, field := range metric.FieldList() {
+			m.Values[field.Key] = field.Value
+		}
+
+		measurements = append(measurements, m)
+	}
+
+	return measurements, nil
+}
+
+// MeasurementsToFrames groups measurements by name and labels, and returns
+// one frame per group. Each frame has a time field followed by one field per
+// value, missing values are null.
+func MeasurementsToFrames(measurements []models.Measurement) (data.Frames, error) {
+	frames := data.Frames{}
+	byKey := map[string]*data.Frame{}
+	now := time.Now()
+
+	for _, m := range measurements {
+		if m.Name == "" {
+			return nil, errors.New("measurement name is required")
+		}
+
+		labels := data.Labels(m.Labels)
+		key := m.Name + labels.String()
+
+		frame, ok := byKey[key]
+		if !ok {
+			frame = data.NewFrame(m.Name, data.NewField("time", nil, []time.Time{}))
+			byKey[key] = frame
+			frames = append(frames, frame)
+		}
+
+		ts := now
+		if m.Time != 0 {
+			ts = time.Unix(0, m.Time*int64(time.Millisecond))
+		}
+
+		if err := appendMeasurement(frame, ts, m, labels); err != nil {
+			return nil, fmt.Errorf("measurement %q: %w", m.Name, err)
+		}
+	}
+
+	return frames, nil
+}
+
+func appendMeasurement(frame *data.Frame, ts time.Time, m models.Measurement, labels data.Labels) error {
+	rows := frame.Fields[0].Len()
+
+	// Add fields seen for the first time, back filled with nulls. The keys
+	// are sorted so that fields are added in a stable order.
+	keys := make([]string, 0, len(m.Values))
+	for key := range m.Values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fields := make(map[string]*data.Field, len(frame.Fields))
+	for _, f := range frame.Fields[1:] {
+		fields[f.Name] = f
+	}
+
+	for _, key := range keys {
+		if _, ok := fields[key]; ok || m.Values[key] == nil {
+			continue
+		}
+		fieldType, ok := measurementFieldType(m.Values[key])
+		if !ok {
+			return fmt.Errorf("unsupported value type %T for %q", m.Values[key], key)
+		}
+
+		field := data.NewFieldFromFieldType(fieldType, rows)
+		field.Name = key
+		field.Labels = labels
+		if config, ok := m.Config[key]; ok {
+			config := config
+			field.Config = &config
+		}
+		frame.Fields = append(frame.Fields, field)
+		fields[key] = field
+	}
+
+	frame.Fields[0].Append(ts)
+	for _, field := range frame.Fields[1:] {
+		value, ok := m.Values[field.Name]
+		if !ok || value == nil {
+			field.Append(nil)
+			continue
+		}
+
+		converted, err := convertMeasurementValue(field.Type(), value)
+		if err != nil {
+			return fmt.Errorf("field %q: %w", field.Name, err)
+		}
+		field.Append(converted)
+	}
+
+	return nil
+}
+
+func measurementFieldType(value interface{}) (data.FieldType, bool) {
+	switch value.(type) {
+	case float64, float32, int64, int, uint64:
+		return data.FieldTypeNullableFloat64, true
+	case string:
+		return data.FieldTypeNullableString, true
+	case bool:
+		return data.FieldTypeNullableBool, true
+	default:
+		return 0, false
+	}
+}
+
+func convertMeasurementValue(fieldType data.FieldType, value interface{}) (interface{}, error) {
+	switch fieldType {
+	case data.FieldTypeNullableFloat64:
+		var f float64
+		switch v := value.(type) {
+		case float64:
+			f = v
+		case float32:
+			f = float64(v)
+		case int64:
+			f = float64(v)
+		case int:
+			f = float64(v)
+		case uint64:
+			f = float64(v)
+		default:
+			return nil, fmt.Errorf("expected a number, got %T", value)
+		}
+		return &f, nil
+	case data.FieldTypeNullableString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string, got %T", value)
+		}
+		return &s, nil
+	case data.FieldTypeNullableBool:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("expected a boolean, got %T", value)
+		}
+		return &b, nil
+	default:
+		return nil, fmt.Errorf("unsupported field type %s", fieldType)
+	}
 }
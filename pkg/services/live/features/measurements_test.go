@@ -0,0 +1,82 @@
+package features
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openinsight-project/grafinsight/pkg/models"
+)
+
+func TestParseInfluxLineProtocol(t *testing.T) {
+	body := []byte("cpu,host=a usage=1.5,cores=4i,ok=true 1600000000000\n" +
+		"cpu,host=b usage=2.5 1600000001000\n" +
+		"cpu,host=a usage=3,state=\"busy\" 1600000002000\n")
+
+	measurements, err := ParseInfluxLineProtocol(body, time.Millisecond)
+	require.NoError(t, err)
+	require.Len(t, measurements, 3)
+	require.Equal(t, "cpu", measurements[0].Name)
+	require.Equal(t, int64(1600000000000), measurements[0].Time)
+	require.Equal(t, map[string]string{"host": "a"}, measurements[0].Labels)
+	require.Equal(t, int64(4), measurements[0].Values["cores"])
+
+	frames, err := MeasurementsToFrames(measurements)
+	require.NoError(t, err)
+	require.Len(t, frames, 2)
+
+	hostA := frames[0]
+	require.Equal(t, "cpu", hostA.Name)
+	require.Equal(t, 2, hostA.Rows())
+	require.Equal(t, []string{"time", "cores", "ok", "usage", "state"}, fieldNames(hostA.Fields))
+	require.Equal(t, "a", hostA.Fields[1].Labels["host"])
+
+	state := hostA.Fields[4]
+	require.Nil(t, state.At(0))
+	require.Equal(t, "busy", *(state.At(1).(*string)))
+
+	_, err = ParseInfluxLineProtocol([]byte("not line protocol"), time.Nanosecond)
+	require.Error(t, err)
+}
+
+func TestMeasurementsToFramesTypeMismatch(t *testing.T) {
+	_, err := MeasurementsToFrames([]models.Measurement{
+		{Name: "m", Values: map[string]interface{}{"v": 1.0}},
+		{Name: "m", Values: map[string]interface{}{"v": "one"}},
+	})
+	require.Error(t, err)
+
+	_, err = MeasurementsToFrames([]models.Measurement{{Values: map[string]interface{}{"v": 1.0}}})
+	require.Error(t, err)
+}
+
+func TestMeasurementsRunnerPush(t *testing.T) {
+	var channel string
+	var msg map[string]interface{}
+	runner := &MeasurementsRunner{
+		Publisher: func(c string, data []byte) error {
+			channel = c
+			return json.Unmarshal(data, &msg)
+		},
+	}
+
+	err := runner.PushMeasurements(2, "ci", &models.MeasurementBatch{
+		Measurements: []models.Measurement{
+			{Name: "build", Time: 1600000000000, Values: map[string]interface{}{"duration": 42.0}},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "2/grafinsight/measurements/ci", channel)
+	require.Len(t, msg["frames"], 1)
+}
+
+func fieldNames(fields []*data.Field) []string {
+	names := make([]string, 0, len(fields))
+	for _, f := range fields {
+		names = append(names, f.Name)
+	}
+	return names
+}
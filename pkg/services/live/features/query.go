@@ -0,0 +1,424 @@
+package features
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/centrifugal/centrifuge"
+	"github.com/openinsight-project/grafinsight/pkg/bus"
+	"github.com/openinsight-project/grafinsight/pkg/components/simplejson"
+	"github.com/openinsight-project/grafinsight/pkg/infra/remotecache"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/services/live/livecontext"
+	"github.com/openinsight-project/grafinsight/pkg/services/live/orgchannel"
+	"github.com/openinsight-project/grafinsight/pkg/tsdb"
+)
+
+// queryStreamIdleTimeout is how long a query stream without subscribers is kept
+// so that clients can subscribe again, for example after a reconnect.
+const queryStreamIdleTimeout = time.Hour
+
+var (
+	// ErrQueryStreamNotFound is returned when subscribing to a query that was not registered.
+	ErrQueryStreamNotFound = errors.New("query stream not found")
+	// ErrQueryStreamPublishNotAllowed is returned when a client publishes to a query channel.
+	ErrQueryStreamPublishNotAllowed = errors.New("query results are published by the server")
+)
+
+// QueryStream is a query run server-side on an interval.
+type QueryStream struct {
+	OrgID        int64
+	DatasourceID int64
+	From         string
+	To           string
+	Interval     time.Duration
+	Queries      []*simplejson.Json
+}
+
+type queryStream struct {
+	QueryStream
+
+	channel     string
+	subscribers []querySubscriber
+	stop        chan struct{}
+	lastUsed    time.Time
+}
+
+type querySubscriber struct {
+	clientID string
+	user     *models.SignedInUser
+}
+
+// QueryLocker elects the instance running a query stream when the instances
+// share their channels.
+type QueryLocker interface {
+	// Lock takes the lock of the key for the TTL, or extends it when this
+	// instance holds it, and reports whether this instance holds it.
+	Lock(key string, ttl time.Duration) (bool, error)
+	// Unlock releases the lock of the key when this instance holds it.
+	Unlock(key string) error
+}
+
+// QueryRunner manages all the `grafinsight/query/*` channels. Queries are
+// registered with the HTTP API and run once per interval for all the
+// subscribers of the channel. A query stops running when its last
+// subscriber leaves.
+//
+// The definitions of the queries are shared with the other instances through
+// the remote cache, so that clients can subscribe on any instance and after a
+// restart. When the instances share their channels, only the instance holding
+// the lock of a query runs it for the subscribers of all the instances.
+type QueryRunner struct {
+	Publisher models.ChannelPublisher
+
+	// Cache stores the query definitions. Without a cache, they only live
+	// in this instance.
+	Cache remotecache.CacheStorage
+	// Locker elects the instance running each query. Without a locker, every
+	// instance runs the query for its own subscribers.
+	Locker QueryLocker
+
+	// Query runs the query against the datasource.
+	Query func(ctx context.Context, ds *models.DataSource, query *tsdb.TsdbQuery) (*tsdb.Response, error)
+	// CanQuery checks that a subscriber can query the datasource.
+	CanQuery func(user *models.SignedInUser, datasourceID int64) (bool, error)
+	// Validator validates the datasource URL before each run.
+	Validator models.PluginRequestValidator
+
+	mu      sync.Mutex
+	streams map[string]*queryStream
+}
+
+// Register adds the query stream and returns the key of its channel. The
+// same query, datasource and time range always share the same key.
+func (r *QueryRunner) Register(stream QueryStream) (string, error) {
+	b, err := json.Marshal(stream)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	key := hex.EncodeToString(sum[:16])
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.streams == nil {
+		r.streams = map[string]*queryStream{}
+	}
+
+	now := time.Now()
+	for k, s := range r.streams {
+		if len(s.subscribers) == 0 && now.Sub(s.lastUsed) > queryStreamIdleTimeout {
+			delete(r.streams, k)
+		}
+	}
+
+	if err := r.store(key, b); err != nil {
+		return "", err
+	}
+
+	if s, ok := r.streams[key]; ok {
+		s.lastUsed = now
+		return key, nil
+	}
+
+	r.addStream(key, stream, now)
+	return key, nil
+}
+
+func (r *QueryRunner) addStream(key string, stream QueryStream, now time.Time) *queryStream {
+	s := &queryStream{
+		QueryStream: stream,
+		channel:     orgchannel.PrependOrgID(stream.OrgID, "grafinsight/query/"+key),
+		lastUsed:    now,
+	}
+	r.streams[key] = s
+	return s
+}
+
+func queryStreamCacheKey(key string) string {
+	return "live-query-stream:" + key
+}
+
+// store saves the query definition in the cache, which also extends its
+// expiry.
+func (r *QueryRunner) store(key string, definition []byte) error {
+	if r.Cache == nil {
+		return nil
+	}
+	return r.Cache.Set(queryStreamCacheKey(key), definition, queryStreamIdleTimeout)
+}
+
+// load gets a query registered on another instance, or before a restart,
+// from the cache.
+func (r *QueryRunner) load(key string) (*QueryStream, error) {
+	if r.Cache == nil || key == "" {
+		return nil, nil
+	}
+
+	value, err := r.Cache.Get(queryStreamCacheKey(key))
+	if err != nil {
+		if errors.Is(err, remotecache.ErrCacheItemNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	definition, ok := value.([]byte)
+	if !ok {
+		return nil, nil
+	}
+	var stream QueryStream
+	if err := json.Unmarshal(definition, &stream); err != nil {
+		return nil, err
+	}
+	return &stream, nil
+}
+
+// getStream gets the query stream of a channel, loading it from the cache
+// when it was registered on another instance.
+func (r *QueryRunner) getStream(channel string) (*queryStream, error) {
+	key := queryStreamKey(channel)
+
+	r.mu.Lock()
+	s, ok := r.streams[key]
+	r.mu.Unlock()
+	if ok {
+		return s, nil
+	}
+
+	stream, err := r.load(key)
+	if err != nil || stream == nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.streams == nil {
+		r.streams = map[string]*queryStream{}
+	}
+	if s, ok := r.streams[key]; ok {
+		return s, nil
+	}
+	return r.addStream(key, *stream, time.Now()), nil
+}
+
+// GetHandlerForPath gets the handler for a path.
+// It's called on init.
+func (r *QueryRunner) GetHandlerForPath(path string) (models.ChannelHandler, error) {
+	return r, nil // all queries share the same handler
+}
+
+// OnSubscribe starts running the query for the first subscriber.
+func (r *QueryRunner) OnSubscribe(c *centrifuge.Client, e centrifuge.SubscribeEvent) (centrifuge.SubscribeReply, error) {
+	user, ok := livecontext.GetContextSignedUser(c.Context())
+	if !ok {
+		return centrifuge.SubscribeReply{}, centrifuge.ErrorUnauthorized
+	}
+	return centrifuge.SubscribeReply{}, r.subscribe(c.ID(), user, e.Channel)
+}
+
+// OnUnsubscribe stops running the query when the last subscriber leaves.
+func (r *QueryRunner) OnUnsubscribe(c *centrifuge.Client, e centrifuge.UnsubscribeEvent) {
+	r.unsubscribe(c.ID(), e.Channel)
+}
+
+func (r *QueryRunner) subscribe(clientID string, user *models.SignedInUser, channel string) error {
+	s, err := r.getStream(channel)
+	if err != nil {
+		return err
+	}
+	if s == nil || s.channel != channel {
+		return ErrQueryStreamNotFound
+	}
+
+	allowed, err := r.CanQuery(user, s.DatasourceID)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return centrifuge.ErrorPermissionDenied
+	}
+
+	if definition, err := json.Marshal(s.QueryStream); err == nil {
+		if err := r.store(queryStreamKey(channel), definition); err != nil {
+			logger.Warn("Failed to store query stream", "channel", channel, "error", err)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s.subscribers = append(s.subscribers, querySubscriber{clientID: clientID, user: user})
+	s.lastUsed = time.Now()
+	if len(s.subscribers) == 1 {
+		s.stop = make(chan struct{})
+		go r.run(s, s.stop)
+	}
+	return nil
+}
+
+func (r *QueryRunner) unsubscribe(clientID string, channel string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.streams[queryStreamKey(channel)]
+	if !ok {
+		return
+	}
+
+	for i, subscriber := range s.subscribers {
+		if subscriber.clientID != clientID {
+			continue
+		}
+		s.subscribers = append(s.subscribers[:i], s.subscribers[i+1:]...)
+		s.lastUsed = time.Now()
+		if len(s.subscribers) == 0 {
+			close(s.stop)
+		}
+		return
+	}
+}
+
+// runAs returns the user the query runs as: its oldest subscriber on this
+// instance, who was allowed to query the datasource when subscribing.
+func (r *QueryRunner) runAs(s *queryStream) (*models.SignedInUser, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(s.subscribers) == 0 {
+		return nil, false
+	}
+	return s.subscribers[0].user, true
+}
+
+// OnPublish rejects messages sent over the websocket.
+func (r *QueryRunner) OnPublish(c *centrifuge.Client, e centrifuge.PublishEvent) (centrifuge.PublishReply, error) {
+	return centrifuge.PublishReply{}, ErrQueryStreamPublishNotAllowed
+}
+
+func queryStreamKey(channel string) string {
+	_, id, err := orgchannel.StripOrgID(channel)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(id, "grafinsight/query/")
+}
+
+// run runs the query on the stream interval until stop is closed. When the
+// query runs on another instance, this instance takes over if that instance
+// stops refreshing the lock.
+func (r *QueryRunner) run(s *queryStream, stop <-chan struct{}) {
+	logger.Debug("Starting query stream", "channel", s.channel, "interval", s.Interval)
+
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	lockKey := queryStreamLockKey(queryStreamKey(s.channel))
+	defer func() {
+		if r.Locker == nil {
+			return
+		}
+		if err := r.Locker.Unlock(lockKey); err != nil {
+			logger.Warn("Failed to release query stream lock", "channel", s.channel, "error", err)
+		}
+	}()
+
+	for {
+		if r.lock(s, lockKey) {
+			if user, ok := r.runAs(s); ok {
+				r.runOnce(s, user)
+			}
+		}
+
+		select {
+		case <-stop:
+			logger.Debug("Stopping query stream", "channel", s.channel)
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func queryStreamLockKey(key string) string {
+	return "live-query-stream-lock:" + key
+}
+
+// lock reports whether this instance runs the query. The lock outlives a
+// few intervals so that a slow run does not hand the query over.
+func (r *QueryRunner) lock(s *queryStream, lockKey string) bool {
+	if r.Locker == nil {
+		return true
+	}
+	held, err := r.Locker.Lock(lockKey, 3*s.Interval)
+	if err != nil {
+		logger.Warn("Failed to lock query stream", "channel", s.channel, "error", err)
+		return false
+	}
+	return held
+}
+
+func (r *QueryRunner) runOnce(s *queryStream, user *models.SignedInUser) {
+	// Results that take longer than the interval are not worth waiting for.
+	ctx, cancel := context.WithTimeout(context.Background(), s.Interval)
+	defer cancel()
+
+	resp, err := r.query(ctx, s, user)
+	if err != nil {
+		logger.Warn("Failed to run streamed query", "channel", s.channel, "error", err)
+		resp = &tsdb.Response{Message: err.Error()}
+	}
+
+	for _, res := range resp.Results {
+		if res.Error != nil {
+			res.ErrorString = res.Error.Error()
+			resp.Message = res.ErrorString
+		}
+	}
+
+	msg, err := json.Marshal(resp)
+	if err != nil {
+		logger.Warn("Failed to marshal streamed query results", "channel", s.channel, "error", err)
+		return
+	}
+
+	if err := r.Publisher(s.channel, msg); err != nil {
+		logger.Warn("Failed to publish streamed query results", "channel", s.channel, "error", err)
+	}
+}
+
+func (r *QueryRunner) query(ctx context.Context, s *queryStream, user *models.SignedInUser) (*tsdb.Response, error) {
+	dsQuery := models.GetDataSourceQuery{Id: s.DatasourceID, OrgId: s.OrgID}
+	if err := bus.Dispatch(&dsQuery); err != nil {
+		return nil, err
+	}
+	ds := dsQuery.Result
+
+	if r.Validator != nil {
+		if err := r.Validator.Validate(ds.Url, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	request := &tsdb.TsdbQuery{
+		TimeRange: tsdb.NewTimeRange(s.From, s.To),
+		User:      user,
+		Queries:   make([]*tsdb.Query, 0, len(s.Queries)),
+	}
+	for _, query := range s.Queries {
+		request.Queries = append(request.Queries, &tsdb.Query{
+			RefId:         query.Get("refId").MustString("A"),
+			MaxDataPoints: query.Get("maxDataPoints").MustInt64(100),
+			IntervalMs:    query.Get("intervalMs").MustInt64(1000),
+			QueryType:     query.Get("queryType").MustString(""),
+			Model:         query,
+			DataSource:    ds,
+		})
+	}
+
+	return r.Query(ctx, ds, request)
+}
@@ -0,0 +1,211 @@
+package features
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/centrifuge"
+	"github.com/openinsight-project/grafinsight/pkg/bus"
+	"github.com/openinsight-project/grafinsight/pkg/components/simplejson"
+	"github.com/openinsight-project/grafinsight/pkg/infra/remotecache"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/tsdb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryRunner(t *testing.T) {
+	t.Cleanup(bus.ClearBusHandlers)
+	bus.AddHandler("test", func(query *models.GetDataSourceQuery) error {
+		query.Result = &models.DataSource{Id: query.Id, OrgId: query.OrgId, Type: "test"}
+		return nil
+	})
+
+	var runs int32
+	published := make(chan string, 10)
+	runner := &QueryRunner{
+		Publisher: func(channel string, data []byte) error {
+			published <- channel
+			return nil
+		},
+		Query: func(ctx context.Context, ds *models.DataSource, query *tsdb.TsdbQuery) (*tsdb.Response, error) {
+			atomic.AddInt32(&runs, 1)
+			return &tsdb.Response{Results: map[string]*tsdb.QueryResult{"A": {RefId: "A"}}}, nil
+		},
+		CanQuery: func(user *models.SignedInUser, datasourceID int64) (bool, error) {
+			return user.UserId != 3, nil
+		},
+	}
+
+	stream := QueryStream{
+		OrgID:        1,
+		DatasourceID: 5,
+		From:         "now-1h",
+		To:           "now",
+		Interval:     time.Hour,
+		Queries:      []*simplejson.Json{simplejson.NewFromAny(map[string]interface{}{"refId": "A", "expr": "up"})},
+	}
+
+	key, err := runner.Register(stream)
+	require.NoError(t, err)
+
+	t.Run("the same query shares the same key", func(t *testing.T) {
+		same, err := runner.Register(stream)
+		require.NoError(t, err)
+		require.Equal(t, key, same)
+
+		other := stream
+		other.From = "now-6h"
+		otherKey, err := runner.Register(other)
+		require.NoError(t, err)
+		require.NotEqual(t, key, otherKey)
+	})
+
+	channel := "1/grafinsight/query/" + key
+
+	t.Run("subscribers share one running query", func(t *testing.T) {
+		require.NoError(t, runner.subscribe("client-1", &models.SignedInUser{UserId: 1, OrgId: 1}, channel))
+		require.Equal(t, channel, <-published)
+
+		require.NoError(t, runner.subscribe("client-2", &models.SignedInUser{UserId: 2, OrgId: 1}, channel))
+		require.Equal(t, int32(1), atomic.LoadInt32(&runs))
+
+		runner.unsubscribe("client-1", channel)
+		require.Len(t, runner.streams[key].subscribers, 1)
+		runner.unsubscribe("client-2", channel)
+		require.Empty(t, runner.streams[key].subscribers)
+
+		// the query runs again for a new subscriber
+		require.NoError(t, runner.subscribe("client-1", &models.SignedInUser{UserId: 1, OrgId: 1}, channel))
+		require.Equal(t, channel, <-published)
+		require.Equal(t, int32(2), atomic.LoadInt32(&runs))
+		runner.unsubscribe("client-1", channel)
+	})
+
+	t.Run("the query runs as a current subscriber", func(t *testing.T) {
+		require.NoError(t, runner.subscribe("client-1", &models.SignedInUser{UserId: 1, OrgId: 1}, channel))
+		<-published
+		require.NoError(t, runner.subscribe("client-2", &models.SignedInUser{UserId: 2, OrgId: 1}, channel))
+
+		user, ok := runner.runAs(runner.streams[key])
+		require.True(t, ok)
+		require.Equal(t, int64(1), user.UserId)
+
+		// the first subscriber left
+		runner.unsubscribe("client-1", channel)
+		user, ok = runner.runAs(runner.streams[key])
+		require.True(t, ok)
+		require.Equal(t, int64(2), user.UserId)
+		runner.unsubscribe("client-2", channel)
+	})
+
+	t.Run("subscribers need access to the datasource", func(t *testing.T) {
+		err := runner.subscribe("client-3", &models.SignedInUser{UserId: 3, OrgId: 1}, channel)
+		require.Equal(t, centrifuge.ErrorPermissionDenied, err)
+	})
+
+	t.Run("query streams are scoped to the org", func(t *testing.T) {
+		err := runner.subscribe("client-1", &models.SignedInUser{UserId: 1, OrgId: 2}, "2/grafinsight/query/"+key)
+		require.Equal(t, ErrQueryStreamNotFound, err)
+	})
+	t.Run("query streams are shared through the cache", func(t *testing.T) {
+		cache := remotecache.NewFakeStore(t)
+		runner.Cache = cache
+		t.Cleanup(func() { runner.Cache = nil })
+
+		other := stream
+		other.To = "now-5m"
+		otherKey, err := runner.Register(other)
+		require.NoError(t, err)
+
+		// another instance, or this one after a restart
+		replica := &QueryRunner{
+			Publisher: runner.Publisher,
+			Cache:     cache,
+			Query:     runner.Query,
+			CanQuery:  runner.CanQuery,
+		}
+		otherChannel := "1/grafinsight/query/" + otherKey
+		require.NoError(t, replica.subscribe("client-1", &models.SignedInUser{UserId: 1, OrgId: 1}, otherChannel))
+		require.Equal(t, otherChannel, <-published)
+		replica.unsubscribe("client-1", otherChannel)
+
+		err = replica.subscribe("client-1", &models.SignedInUser{UserId: 1, OrgId: 2}, "2/grafinsight/query/"+otherKey)
+		require.Equal(t, ErrQueryStreamNotFound, err)
+	})
+
+	t.Run("one instance runs the query for all the instances", func(t *testing.T) {
+		locks := &fakeLocks{owners: map[string]string{}}
+		newInstance := func(id string) *QueryRunner {
+			instance := &QueryRunner{
+				Publisher: runner.Publisher,
+				Locker:    &fakeQueryLocker{locks: locks, id: id},
+				Query:     runner.Query,
+				CanQuery:  runner.CanQuery,
+			}
+			_, err := instance.Register(stream)
+			require.NoError(t, err)
+			return instance
+		}
+		primary, replica := newInstance("a"), newInstance("b")
+		before := atomic.LoadInt32(&runs)
+
+		require.NoError(t, primary.subscribe("client-1", &models.SignedInUser{UserId: 1, OrgId: 1}, channel))
+		require.Equal(t, channel, <-published)
+		require.NoError(t, replica.subscribe("client-2", &models.SignedInUser{UserId: 2, OrgId: 1}, channel))
+		require.Eventually(t, func() bool { return locks.attempts() == 2 }, time.Second, 10*time.Millisecond)
+		require.Equal(t, before+1, atomic.LoadInt32(&runs))
+
+		// the lock is released when the last subscriber of the instance leaves
+		primary.unsubscribe("client-1", channel)
+		require.Eventually(t, func() bool { return locks.owner(queryStreamLockKey(key)) == "" }, time.Second, 10*time.Millisecond)
+		replica.unsubscribe("client-2", channel)
+	})
+}
+
+// fakeLocks are the locks shared by the instances of a test.
+type fakeLocks struct {
+	mu      sync.Mutex
+	owners  map[string]string
+	attempt int
+}
+
+func (l *fakeLocks) attempts() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.attempt
+}
+
+func (l *fakeLocks) owner(key string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.owners[key]
+}
+
+// fakeQueryLocker is the QueryLocker of one instance.
+type fakeQueryLocker struct {
+	locks *fakeLocks
+	id    string
+}
+
+func (l *fakeQueryLocker) Lock(key string, ttl time.Duration) (bool, error) {
+	l.locks.mu.Lock()
+	defer l.locks.mu.Unlock()
+	l.locks.attempt++
+	if owner, ok := l.locks.owners[key]; ok && owner != l.id {
+		return false, nil
+	}
+	l.locks.owners[key] = l.id
+	return true, nil
+}
+
+func (l *fakeQueryLocker) Unlock(key string) error {
+	l.locks.mu.Lock()
+	defer l.locks.mu.Unlock()
+	if l.locks.owners[key] == l.id {
+		delete(l.locks.owners, key)
+	}
+	return nil
+}
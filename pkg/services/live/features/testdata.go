@@ -28,15 +28,12 @@ type TestDataSupplier struct {
 // GetHandlerForPath gets the channel handler for a path.
 // Called on init.
 func (s *TestDataSupplier) GetHandlerForPath(path string) (models.ChannelHandler, error) {
-	channel := "grafinsight/testdata/" + path
-
 	if path == "random-2s-stream" {
 		return &testDataRunner{
 			publisher:   s.Publisher,
 			running:     false,
 			speedMillis: 2000,
 			dropPercent: 0,
-			channel:     channel,
 			name:        path,
 		}, nil
 	}
@@ -47,17 +44,18 @@ func (s *TestDataSupplier) GetHandlerForPath(path string) (models.ChannelHandler
 			running:     false,
 			speedMillis: 400,
 			dropPercent: .6,
-			channel:     channel,
 		}, nil
 	}
 
 	return nil, fmt.Errorf("unknown channel")
 }
 
-// OnSubscribe will let anyone connect to the path
+// OnSubscribe will let anyone connect to the path. Each org has its own
+// runner, which publishes to the channel it was first subscribed with.
 func (r *testDataRunner) OnSubscribe(c *centrifuge.Client, e centrifuge.SubscribeEvent) (centrifuge.SubscribeReply, error) {
 	if !r.running {
 		r.running = true
+		r.channel = e.Channel
 
 		// Run in the background
 		go r.runRandomCSV()
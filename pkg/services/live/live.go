@@ -1,18 +1,29 @@
 package live
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/centrifugal/centrifuge"
+	"github.com/openinsight-project/grafinsight/pkg/api/dtos"
 	"github.com/openinsight-project/grafinsight/pkg/api/routing"
 	"github.com/openinsight-project/grafinsight/pkg/infra/log"
+	"github.com/openinsight-project/grafinsight/pkg/infra/metrics"
+	"github.com/openinsight-project/grafinsight/pkg/infra/remotecache"
 	"github.com/openinsight-project/grafinsight/pkg/models"
 	"github.com/openinsight-project/grafinsight/pkg/plugins"
 	"github.com/openinsight-project/grafinsight/pkg/registry"
 	"github.com/openinsight-project/grafinsight/pkg/services/live/features"
+	"github.com/openinsight-project/grafinsight/pkg/services/live/livecontext"
+	"github.com/openinsight-project/grafinsight/pkg/services/live/orgchannel"
 	"github.com/openinsight-project/grafinsight/pkg/setting"
+	"github.com/openinsight-project/grafinsight/pkg/tsdb"
 	"github.com/openinsight-project/grafinsight/pkg/tsdb/cloudwatch"
+	"github.com/prometheus/client_golang/prometheus"
+	redis "gopkg.in/redis.v5"
 )
 
 var (
@@ -24,6 +35,7 @@ func init() {
 	registry.RegisterService(&GrafinsightLive{
 		channels:   make(map[string]models.ChannelHandler),
 		channelsMu: sync.RWMutex{},
+		clients:    make(map[string]*centrifuge.Client),
 		GrafinsightScope: CoreGrafinsightScope{
 			Features: make(map[string]models.ChannelHandlerFactory),
 		},
@@ -36,22 +48,44 @@ type CoreGrafinsightScope struct {
 
 	// The generic service to advertise dashboard changes
 	Dashboards models.DashboardActivityChannel
+
+	// The service that knows who is viewing and editing dashboards
+	DashboardViewers *features.DashboardHandler
+
+	// The service that publishes measurements pushed with the HTTP API
+	Measurements models.MeasurementsChannel
+
+	// The service that runs queries server-side for the `grafinsight/query/*` channels
+	Query *features.QueryRunner
 }
 
 // GrafinsightLive pretends to be the server
 type GrafinsightLive struct {
-	Cfg           *setting.Cfg            `inject:""`
-	RouteRegister routing.RouteRegister   `inject:""`
-	LogsService   *cloudwatch.LogsService `inject:""`
-	node          *centrifuge.Node
+	Cfg                    *setting.Cfg                  `inject:""`
+	RouteRegister          routing.RouteRegister         `inject:""`
+	LogsService            *cloudwatch.LogsService       `inject:""`
+	PluginRequestValidator models.PluginRequestValidator `inject:""`
+	RemoteCache            *remotecache.RemoteCache      `inject:""`
+	node                   *centrifuge.Node
 
 	// The websocket handler
 	WebsocketHandler interface{}
 
+	// The Server-Sent Events handlers
+	sse *sseHandler
+
+	// Sends the disconnects of clients to the other instances, when they
+	// share channels
+	disconnects clientDisconnectPublisher
+
 	// Full channel handler
 	channels   map[string]models.ChannelHandler
 	channelsMu sync.RWMutex
 
+	// The clients connected to this instance
+	clients   map[string]*centrifuge.Client
+	clientsMu sync.RWMutex
+
 	// The core internal features
 	GrafinsightScope CoreGrafinsightScope
 }
@@ -83,18 +117,55 @@ func (g *GrafinsightLive) Init() error {
 	}
 	g.node = node
 
+	// Share channels between instances when running several replicas.
+	var relay sseRelay
+	var locker features.QueryLocker
+	if g.Cfg.Live.HAEngine == setting.LiveHAEngineRedis {
+		opts, err := remotecache.ParseRedisConnStr(g.Cfg.Live.HAEngineConnStr)
+		if err != nil {
+			return err
+		}
+		broker := newRedisBroker(redis.NewClient(opts), redisKeyPrefix)
+		node.SetBroker(broker)
+		node.SetPresenceManager(broker)
+		relay = broker
+		broker.OnDisconnectClient(g.disconnectLocalClient)
+		g.disconnects = broker
+		locker = broker
+		logger.Info("Live is using redis to share channels between instances")
+	}
+
 	// Initialize the main features
 	dash := &features.DashboardHandler{
 		Publisher: g.Publish,
+		Presence:  node.Presence,
+		Cache:     g.RemoteCache,
 	}
 
 	g.GrafinsightScope.Dashboards = dash
+	g.GrafinsightScope.DashboardViewers = dash
 	g.GrafinsightScope.Features["dashboard"] = dash
 	g.GrafinsightScope.Features["testdata"] = &features.TestDataSupplier{
 		Publisher: g.Publish,
 	}
 	g.GrafinsightScope.Features["broadcast"] = &features.BroadcastRunner{}
-	g.GrafinsightScope.Features["measurements"] = &features.MeasurementsRunner{}
+
+	measurements := &features.MeasurementsRunner{
+		Publisher: g.Publish,
+	}
+	g.GrafinsightScope.Measurements = measurements
+	g.GrafinsightScope.Features["measurements"] = measurements
+
+	query := &features.QueryRunner{
+		Publisher: g.Publish,
+		Cache:     g.RemoteCache,
+		Locker:    locker,
+		Query:     tsdb.HandleRequest,
+		CanQuery:  canQueryDatasource,
+		Validator: g.PluginRequestValidator,
+	}
+	g.GrafinsightScope.Query = query
+	g.GrafinsightScope.Features["query"] = query
 
 	// Set ConnectHandler called when client successfully connected to Node. Your code
 	// inside handler must be synchronized since it will be called concurrently from
@@ -103,25 +174,97 @@ func (g *GrafinsightLive) Init() error {
 	node.OnConnect(func(client *centrifuge.Client) {
 		logger.Debug("Client connected", "user", client.UserID())
 
+		user, ok := livecontext.GetContextSignedUser(client.Context())
+		if !ok {
+			logger.Error("No signed in user for live client", "client", client.ID())
+			return
+		}
+
+		g.clientsMu.Lock()
+		g.clients[client.ID()] = client
+		g.clientsMu.Unlock()
+
+		client.OnDisconnect(func(e centrifuge.DisconnectEvent) {
+			g.clientsMu.Lock()
+			delete(g.clients, client.ID())
+			g.clientsMu.Unlock()
+		})
+
 		client.OnSubscribe(func(e centrifuge.SubscribeEvent, cb centrifuge.SubscribeCallback) {
-			handler, err := g.GetChannelHandler(e.Channel)
+			handler, addr, err := g.GetChannelHandler(user, e.Channel)
 			if err != nil {
 				cb(centrifuge.SubscribeReply{}, err)
-			} else {
-				cb(handler.OnSubscribe(client, e))
+				return
 			}
+
+			allowed, err := canSubscribe(user, addr)
+			if err != nil {
+				logger.Error("Failed to check live channel permissions", "channel", e.Channel, "error", err)
+				cb(centrifuge.SubscribeReply{}, centrifuge.ErrorInternal)
+				return
+			}
+			if !allowed {
+				cb(centrifuge.SubscribeReply{}, centrifuge.ErrorPermissionDenied)
+				return
+			}
+
+			reply, err := handler.OnSubscribe(client, e)
+			if err == nil && g.hasHistory(e.Channel) {
+				// Clients recover the messages they missed when reconnecting
+				reply.Options.Recover = true
+			}
+			cb(reply, err)
 		})
 
 		// Called when a client publishes to the websocket channel.
 		// In general, we should prefer writing to the HTTP API, but this
 		// allows some simple prototypes to work quickly.
 		client.OnPublish(func(e centrifuge.PublishEvent, cb centrifuge.PublishCallback) {
-			handler, err := g.GetChannelHandler(e.Channel)
+			handler, addr, err := g.GetChannelHandler(user, e.Channel)
 			if err != nil {
 				cb(centrifuge.PublishReply{}, err)
-			} else {
-				cb(handler.OnPublish(client, e))
+				return
+			}
+
+			allowed, err := canPublish(user, addr)
+			if err != nil {
+				logger.Error("Failed to check live channel permissions", "channel", e.Channel, "error", err)
+				cb(centrifuge.PublishReply{}, centrifuge.ErrorInternal)
+				return
+			}
+			if !allowed {
+				cb(centrifuge.PublishReply{}, centrifuge.ErrorPermissionDenied)
+				return
+			}
+
+			reply, err := handler.OnPublish(client, e)
+			if err == nil && reply.Options.HistorySize == 0 && g.hasHistory(e.Channel) {
+				reply.Options.HistorySize, reply.Options.HistoryTTL = g.historyFor(e.Channel)
 			}
+			if err == nil && reply.Result == nil {
+				metrics.MLivePublications.WithLabelValues(addr.Scope, "client").Inc()
+			}
+			cb(reply, err)
+		})
+
+		client.OnUnsubscribe(func(e centrifuge.UnsubscribeEvent) {
+			g.channelsMu.RLock()
+			handler, ok := g.channels[e.Channel]
+			g.channelsMu.RUnlock()
+			if u, isUnsubscriber := handler.(models.ChannelUnsubscriber); ok && isUnsubscriber {
+				u.OnUnsubscribe(client, e)
+			}
+		})
+
+		// Called when a client asks for the history of a channel, which
+		// lets late subscribers start with the latest messages.
+		client.OnHistory(func(e centrifuge.HistoryEvent, cb centrifuge.HistoryCallback) {
+			cb(centrifuge.HistoryReply{}, g.checkSubscribe(user, e.Channel))
+		})
+
+		// Called when a client asks who else is subscribed to a channel.
+		client.OnPresence(func(e centrifuge.PresenceEvent, cb centrifuge.PresenceCallback) {
+			cb(centrifuge.PresenceReply{}, g.checkSubscribe(user, e.Channel))
 		})
 	})
 
@@ -130,6 +273,10 @@ func (g *GrafinsightLive) Init() error {
 		return err
 	}
 
+	if err := prometheus.Register(newLiveCollector(g)); err != nil {
+		logger.Warn("Failed to register live metrics", "error", err)
+	}
+
 	// Use a pure websocket transport.
 	wsHandler := centrifuge.NewWebsocketHandler(node, centrifuge.WebsocketConfig{
 		ReadBufferSize:  1024,
@@ -143,11 +290,11 @@ func (g *GrafinsightLive) Init() error {
 			return
 		}
 
-		// Centrifuge expects Credentials in context with a current user ID.
-		cred := &centrifuge.Credentials{
-			UserID: fmt.Sprintf("%d", user.UserId),
+		newCtx, err := clientContext(ctx)
+		if err != nil {
+			ctx.Resp.WriteHeader(500)
+			return
 		}
-		newCtx := centrifuge.SetCredentials(ctx.Req.Context(), cred)
 
 		r := ctx.Req.Request
 		r = r.WithContext(newCtx) // Set a user ID.
@@ -157,22 +304,78 @@ func (g *GrafinsightLive) Init() error {
 
 	g.RouteRegister.Get("/live/ws", g.WebsocketHandler)
 
+	// Server-Sent Events work through proxies that block websocket upgrades.
+	g.sse = newSSEHandler(node, relay)
+	g.RouteRegister.Get("/live/sse", g.sse.Stream)
+	g.RouteRegister.Post("/live/sse/:sessionId", g.sse.Send)
+
 	return nil
 }
 
-// GetChannelHandler gives threadsafe access to the channel
-func (g *GrafinsightLive) GetChannelHandler(channel string) (models.ChannelHandler, error) {
-	g.channelsMu.RLock()
-	c, ok := g.channels[channel]
-	g.channelsMu.RUnlock() // defer? but then you can't lock further down
-	if ok {
-		return c, nil
+// clientContext returns the context of a new live client for the signed in
+// user of the request.
+func clientContext(ctx *models.ReqContext) (context.Context, error) {
+	user := ctx.SignedInUser
+
+	// Centrifuge expects Credentials in context with a current user ID.
+	// The connection info is shared with the other users of a channel.
+	info, err := json.Marshal(models.LiveConnectionInfo{
+		Login:     user.Login,
+		Name:      user.Name,
+		AvatarURL: dtos.GetGravatarUrl(user.Email),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cred := &centrifuge.Credentials{
+		UserID: fmt.Sprintf("%d", user.UserId),
+		Info:   info,
+	}
+	newCtx := centrifuge.SetCredentials(ctx.Req.Context(), cred)
+	return livecontext.SetContextSignedUser(newCtx, user), nil
+}
+
+// checkSubscribe returns an error when the user can not read the channel.
+func (g *GrafinsightLive) checkSubscribe(user *models.SignedInUser, channel string) error {
+	_, addr, err := g.GetChannelHandler(user, channel)
+	if err != nil {
+		return err
+	}
+
+	allowed, err := canSubscribe(user, addr)
+	if err != nil {
+		logger.Error("Failed to check live channel permissions", "channel", channel, "error", err)
+		return centrifuge.ErrorInternal
+	}
+	if !allowed {
+		return centrifuge.ErrorPermissionDenied
+	}
+	return nil
+}
+
+// GetChannelHandler gives threadsafe access to the channel. The channel ID
+// is prefixed with the org ID, which must match the org of the user.
+func (g *GrafinsightLive) GetChannelHandler(user *models.SignedInUser, channel string) (models.ChannelHandler, ChannelAddress, error) {
+	orgID, id, err := orgchannel.StripOrgID(channel)
+	if err != nil {
+		return nil, ChannelAddress{}, err
+	}
+	if orgID != user.OrgId {
+		return nil, ChannelAddress{}, centrifuge.ErrorPermissionDenied
 	}
 
 	// Parse the identifier ${scope}/${namespace}/${path}
-	addr := ParseChannelAddress(channel)
+	addr := ParseChannelAddress(id)
 	if !addr.IsValid() {
-		return nil, fmt.Errorf("invalid channel: %q", channel)
+		return nil, ChannelAddress{}, fmt.Errorf("invalid channel: %q", channel)
+	}
+
+	g.channelsMu.RLock()
+	c, ok := g.channels[channel]
+	g.channelsMu.RUnlock() // defer? but then you can't lock further down
+	if ok {
+		return c, addr, nil
 	}
 	logger.Info("initChannel", "channel", channel, "address", addr)
 
@@ -180,22 +383,22 @@ func (g *GrafinsightLive) GetChannelHandler(channel string) (models.ChannelHandl
 	defer g.channelsMu.Unlock()
 	c, ok = g.channels[channel] // may have filled in while locked
 	if ok {
-		return c, nil
+		return c, addr, nil
 	}
 
 	getter, err := g.GetChannelHandlerFactory(addr.Scope, addr.Namespace)
 	if err != nil {
-		return nil, err
+		return nil, ChannelAddress{}, err
 	}
 
 	// First access will initialize
 	c, err = getter.GetHandlerForPath(addr.Path)
 	if err != nil {
-		return nil, err
+		return nil, ChannelAddress{}, err
 	}
 
 	g.channels[channel] = c
-	return c, nil
+	return c, addr, nil
 }
 
 // GetChannelHandlerFactory gets a ChannelHandlerFactory for a namespace.
@@ -235,12 +438,34 @@ func (g *GrafinsightLive) GetChannelHandlerFactory(scope string, name string) (m
 	return nil, fmt.Errorf("invalid scope: %q", scope)
 }
 
-// Publish sends the data to the channel without checking permissions etc
+// Publish sends the data to the channel without checking permissions etc.
+// The channel must already be prefixed with the org ID.
 func (g *GrafinsightLive) Publish(channel string, data []byte) error {
-	_, err := g.node.Publish(channel, data)
+	var opts []centrifuge.PublishOption
+	if size, ttl := g.historyFor(channel); size > 0 && ttl > 0 {
+		opts = append(opts, centrifuge.WithHistory(size, ttl))
+	}
+	_, err := g.node.Publish(channel, data, opts...)
+	if err == nil {
+		metrics.MLivePublications.WithLabelValues(channelScope(channel), "server").Inc()
+	}
 	return err
 }
 
+// historyFor returns the configured history size and TTL of a channel.
+func (g *GrafinsightLive) historyFor(channel string) (int, time.Duration) {
+	_, id, err := orgchannel.StripOrgID(channel)
+	if err != nil {
+		return 0, 0
+	}
+	return g.Cfg.Live.HistoryFor(id)
+}
+
+func (g *GrafinsightLive) hasHistory(channel string) bool {
+	size, ttl := g.historyFor(channel)
+	return size > 0 && ttl > 0
+}
+
 // IsEnabled returns true if the Grafinsight Live feature is enabled.
 func (g *GrafinsightLive) IsEnabled() bool {
 	return g.Cfg.IsLiveEnabled()
@@ -0,0 +1,43 @@
+package live
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/centrifuge"
+	"github.com/openinsight-project/grafinsight/pkg/setting"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishKeepsHistory(t *testing.T) {
+	node, err := centrifuge.New(centrifuge.DefaultConfig)
+	require.NoError(t, err)
+	require.NoError(t, node.Run())
+	t.Cleanup(func() { _ = node.Shutdown(context.Background()) })
+
+	g := &GrafinsightLive{
+		node: node,
+		Cfg: &setting.Cfg{Live: setting.LiveSettings{
+			HistorySize: 2,
+			HistoryTTL:  time.Minute,
+			ChannelHistory: []setting.LiveChannelHistory{
+				{Prefix: "grafinsight/dashboard", Size: 0, TTL: time.Minute},
+			},
+		}},
+	}
+
+	for _, msg := range []string{`{"a":1}`, `{"a":2}`, `{"a":3}`} {
+		require.NoError(t, g.Publish("1/grafinsight/broadcast/test", []byte(msg)))
+		require.NoError(t, g.Publish("1/grafinsight/dashboard/changes", []byte(msg)))
+	}
+
+	history, err := node.History("1/grafinsight/broadcast/test", centrifuge.WithLimit(centrifuge.NoLimit))
+	require.NoError(t, err)
+	require.Len(t, history.Publications, 2)
+	require.Equal(t, []byte(`{"a":3}`), history.Publications[1].Data)
+
+	history, err = node.History("1/grafinsight/dashboard/changes", centrifuge.WithLimit(centrifuge.NoLimit))
+	require.NoError(t, err)
+	require.Empty(t, history.Publications)
+}
@@ -0,0 +1,23 @@
+package livecontext
+
+import (
+	"context"
+
+	"github.com/openinsight-project/grafinsight/pkg/models"
+)
+
+type signedUserContextKeyType int
+
+var signedUserContextKey signedUserContextKeyType
+
+// SetContextSignedUser stores the user in the connection context so that
+// channel handlers know who subscribes or publishes.
+func SetContextSignedUser(ctx context.Context, user *models.SignedInUser) context.Context {
+	return context.WithValue(ctx, signedUserContextKey, user)
+}
+
+// GetContextSignedUser returns the user of the connection.
+func GetContextSignedUser(ctx context.Context) (*models.SignedInUser, bool) {
+	user, ok := ctx.Value(signedUserContextKey).(*models.SignedInUser)
+	return user, ok && user != nil
+}
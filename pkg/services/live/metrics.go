@@ -0,0 +1,102 @@
+package live
+
+import (
+	"github.com/centrifugal/centrifuge"
+	"github.com/openinsight-project/grafinsight/pkg/infra/metrics"
+	"github.com/openinsight-project/grafinsight/pkg/services/live/orgchannel"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// channelScope returns the scope of a channel for the metric labels.
+func channelScope(channel string) string {
+	_, id, err := orgchannel.StripOrgID(channel)
+	if err != nil {
+		return "invalid"
+	}
+	addr := ParseChannelAddress(id)
+	if !addr.IsValid() {
+		return "invalid"
+	}
+	return addr.Scope
+}
+
+// liveCollector reads the connected clients and the subscriptions of this
+// instance when the metrics are scraped.
+type liveCollector struct {
+	g *GrafinsightLive
+
+	clients       *prometheus.Desc
+	users         *prometheus.Desc
+	channels      *prometheus.Desc
+	subscriptions *prometheus.Desc
+}
+
+func newLiveCollector(g *GrafinsightLive) *liveCollector {
+	return &liveCollector{
+		g: g,
+		clients: prometheus.NewDesc(
+			prometheus.BuildFQName(metrics.ExporterName, "live", "clients"),
+			"Number of clients connected to live on this instance",
+			[]string{"transport"}, nil),
+		users: prometheus.NewDesc(
+			prometheus.BuildFQName(metrics.ExporterName, "live", "users"),
+			"Number of unique users connected to live on this instance",
+			nil, nil),
+		channels: prometheus.NewDesc(
+			prometheus.BuildFQName(metrics.ExporterName, "live", "channels"),
+			"Number of live channels with subscribers on this instance",
+			[]string{"scope"}, nil),
+		subscriptions: prometheus.NewDesc(
+			prometheus.BuildFQName(metrics.ExporterName, "live", "subscriptions"),
+			"Number of live channel subscriptions on this instance",
+			[]string{"scope"}, nil),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *liveCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.clients
+	ch <- c.users
+	ch <- c.channels
+	ch <- c.subscriptions
+}
+
+// Collect implements prometheus.Collector.
+func (c *liveCollector) Collect(ch chan<- prometheus.Metric) {
+	byTransport := map[string]int{}
+	for _, client := range c.g.connectedClients() {
+		byTransport[client.Transport().Name()]++
+	}
+	for transport, n := range byTransport {
+		ch <- prometheus.MustNewConstMetric(c.clients, prometheus.GaugeValue, float64(n), transport)
+	}
+
+	hub := c.g.node.Hub()
+	ch <- prometheus.MustNewConstMetric(c.users, prometheus.GaugeValue, float64(hub.NumUsers()))
+
+	channels := map[string]int{}
+	subscriptions := map[string]int{}
+	for _, channel := range hub.Channels() {
+		scope := channelScope(channel)
+		channels[scope]++
+		subscriptions[scope] += hub.NumSubscribers(channel)
+	}
+	for scope, n := range channels {
+		ch <- prometheus.MustNewConstMetric(c.channels, prometheus.GaugeValue, float64(n), scope)
+		ch <- prometheus.MustNewConstMetric(c.subscriptions, prometheus.GaugeValue, float64(subscriptions[scope]), scope)
+	}
+}
+
+var _ prometheus.Collector = &liveCollector{}
+
+// connectedClients returns the clients connected to this instance.
+func (g *GrafinsightLive) connectedClients() []*centrifuge.Client {
+	g.clientsMu.RLock()
+	defer g.clientsMu.RUnlock()
+
+	clients := make([]*centrifuge.Client, 0, len(g.clients))
+	for _, client := range g.clients {
+		clients = append(clients, client)
+	}
+	return clients
+}
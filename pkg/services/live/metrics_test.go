@@ -0,0 +1,61 @@
+package live
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/centrifugal/centrifuge"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiveCollector(t *testing.T) {
+	node, err := centrifuge.New(centrifuge.DefaultConfig)
+	require.NoError(t, err)
+	node.OnConnect(func(client *centrifuge.Client) {
+		client.OnSubscribe(func(e centrifuge.SubscribeEvent, cb centrifuge.SubscribeCallback) {
+			cb(centrifuge.SubscribeReply{}, nil)
+		})
+	})
+	require.NoError(t, node.Run())
+	t.Cleanup(func() { _ = node.Shutdown(context.Background()) })
+
+	g := &GrafinsightLive{node: node, clients: map[string]*centrifuge.Client{}}
+
+	for _, user := range []string{"1", "2"} {
+		rec := newFlushRecorder()
+		ctx := centrifuge.SetCredentials(context.Background(), &centrifuge.Credentials{UserID: user})
+		client, closeFn, err := centrifuge.NewClient(ctx, node, newSSETransport(rec, rec))
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = closeFn() })
+
+		require.True(t, client.Handle([]byte(`{"id":1}`)))
+		<-rec.flushed
+		require.True(t, client.Handle([]byte(`{"id":2,"method":1,"params":{"channel":"1/grafinsight/broadcast/test"}}`)))
+		<-rec.flushed
+		g.clients[client.ID()] = client
+	}
+
+	expected := `
+# HELP grafinsight_live_channels Number of live channels with subscribers on this instance
+# TYPE grafinsight_live_channels gauge
+grafinsight_live_channels{scope="grafinsight"} 1
+# HELP grafinsight_live_clients Number of clients connected to live on this instance
+# TYPE grafinsight_live_clients gauge
+grafinsight_live_clients{transport="sse"} 2
+# HELP grafinsight_live_subscriptions Number of live channel subscriptions on this instance
+# TYPE grafinsight_live_subscriptions gauge
+grafinsight_live_subscriptions{scope="grafinsight"} 2
+# HELP grafinsight_live_users Number of unique users connected to live on this instance
+# TYPE grafinsight_live_users gauge
+grafinsight_live_users 2
+`
+	require.NoError(t, testutil.CollectAndCompare(newLiveCollector(g), strings.NewReader(expected)))
+}
+
+func TestChannelScope(t *testing.T) {
+	require.Equal(t, "grafinsight", channelScope("1/grafinsight/dashboard/changes"))
+	require.Equal(t, "ds", channelScope("1/ds/5/stream"))
+	require.Equal(t, "invalid", channelScope("grafinsight/dashboard/changes"))
+}
@@ -0,0 +1,27 @@
+package orgchannel
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PrependOrgID returns the centrifuge channel ID of a channel in an org,
+// ${orgId}/${scope}/${namespace}/${path}. Every live channel is scoped to an org so that messages never leak
+// between orgs.
+func PrependOrgID(orgID int64, channel string) string {
+	return strconv.FormatInt(orgID, 10) + "/" + channel
+}
+
+// StripOrgID splits a centrifuge channel ID into the org ID and the channel.
+func StripOrgID(channel string) (int64, string, error) {
+	parts := strings.SplitN(channel, "/", 2)
+	if len(parts) != 2 {
+		return 0, "", fmt.Errorf("channel %q has no org id", channel)
+	}
+	orgID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || orgID <= 0 {
+		return 0, "", fmt.Errorf("channel %q has an invalid org id", channel)
+	}
+	return orgID, parts[1], nil
+}
@@ -0,0 +1,110 @@
+package live
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/openinsight-project/grafinsight/pkg/bus"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/services/guardian"
+)
+
+// canSubscribe checks if the user can read the channel. The org of the
+// channel is already checked when the channel handler is looked up.
+func canSubscribe(user *models.SignedInUser, addr ChannelAddress) (bool, error) {
+	switch addr.Scope {
+	case "grafinsight":
+		if addr.Namespace == "dashboard" {
+			return canAccessDashboardChannel(user, addr.Path, false)
+		}
+		return true, nil
+	case "ds":
+		return canAccessDatasource(user, addr.Namespace)
+	default:
+		return true, nil
+	}
+}
+
+// canPublish checks if the user can write to the channel. Viewers can never
+// publish, the channel handler may restrict publishing further.
+func canPublish(user *models.SignedInUser, addr ChannelAddress) (bool, error) {
+	if !user.HasRole(models.ROLE_EDITOR) {
+		return false, nil
+	}
+
+	switch addr.Scope {
+	case "grafinsight":
+		if addr.Namespace == "dashboard" {
+			return canAccessDashboardChannel(user, addr.Path, true)
+		}
+		return true, nil
+	case "ds":
+		return canAccessDatasource(user, addr.Namespace)
+	default:
+		return true, nil
+	}
+}
+
+// canAccessDashboardChannel checks the dashboard permissions for the
+// `grafinsight/dashboard/uid/${uid}` channels. The `changes` channel only
+// carries dashboard uids and can be read by anyone in the org.
+func canAccessDashboardChannel(user *models.SignedInUser, path string, edit bool) (bool, error) {
+	if path == "changes" {
+		return !edit, nil
+	}
+
+	uid := strings.TrimPrefix(path, "uid/")
+	if uid == path || uid == "" {
+		return false, nil
+	}
+
+	query := models.GetDashboardQuery{Uid: uid, OrgId: user.OrgId}
+	if err := bus.Dispatch(&query); err != nil {
+		if errors.Is(err, models.ErrDashboardNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	g := guardian.New(query.Result.Id, user.OrgId, user)
+	if edit {
+		return g.CanEdit()
+	}
+	return g.CanView()
+}
+
+// canAccessDatasource checks the datasource permissions for the
+// `ds/${datasourceId}/${path}` channels.
+func canAccessDatasource(user *models.SignedInUser, namespace string) (bool, error) {
+	id, err := strconv.ParseInt(namespace, 10, 64)
+	if err != nil {
+		return false, nil
+	}
+	return canQueryDatasource(user, id)
+}
+
+// canQueryDatasource checks that the datasource exists in the org of the
+// user and that the user is allowed to query it.
+func canQueryDatasource(user *models.SignedInUser, id int64) (bool, error) {
+	query := models.GetDataSourceQuery{Id: id, OrgId: user.OrgId}
+	if err := bus.Dispatch(&query); err != nil {
+		if errors.Is(err, models.ErrDataSourceNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	filter := models.DatasourcesPermissionFilterQuery{
+		User:        user,
+		Datasources: []*models.DataSource{query.Result},
+	}
+	if err := bus.Dispatch(&filter); err != nil {
+		if errors.Is(err, bus.ErrHandlerNotFound) {
+			return true, nil
+		}
+		return false, err
+	}
+
+	return len(filter.Result) > 0, nil
+}
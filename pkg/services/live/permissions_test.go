@@ -0,0 +1,122 @@
+package live
+
+import (
+	"testing"
+
+	"github.com/centrifugal/centrifuge"
+	"github.com/openinsight-project/grafinsight/pkg/bus"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/services/guardian"
+	"github.com/openinsight-project/grafinsight/pkg/services/live/features"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetChannelHandler_OrgIsolation(t *testing.T) {
+	g := &GrafinsightLive{
+		channels: make(map[string]models.ChannelHandler),
+		GrafinsightScope: CoreGrafinsightScope{
+			Features: map[string]models.ChannelHandlerFactory{
+				"broadcast": &features.BroadcastRunner{},
+			},
+		},
+	}
+	user := &models.SignedInUser{UserId: 1, OrgId: 2, OrgRole: models.ROLE_VIEWER}
+
+	t.Run("channel in the org of the user", func(t *testing.T) {
+		handler, addr, err := g.GetChannelHandler(user, "2/grafinsight/broadcast/test")
+		require.NoError(t, err)
+		require.NotNil(t, handler)
+		require.Equal(t, ChannelAddress{Scope: "grafinsight", Namespace: "broadcast", Path: "test"}, addr)
+	})
+
+	t.Run("channel in another org", func(t *testing.T) {
+		_, _, err := g.GetChannelHandler(user, "3/grafinsight/broadcast/test")
+		require.Equal(t, centrifuge.ErrorPermissionDenied, err)
+	})
+
+	t.Run("channel without org", func(t *testing.T) {
+		_, _, err := g.GetChannelHandler(user, "grafinsight/broadcast/test")
+		require.Error(t, err)
+	})
+}
+
+func TestChannelPermissions(t *testing.T) {
+	viewer := &models.SignedInUser{UserId: 1, OrgId: 1, OrgRole: models.ROLE_VIEWER}
+	editor := &models.SignedInUser{UserId: 2, OrgId: 1, OrgRole: models.ROLE_EDITOR}
+
+	t.Run("viewers can subscribe to broadcast but not publish", func(t *testing.T) {
+		addr := ChannelAddress{Scope: "grafinsight", Namespace: "broadcast", Path: "test"}
+
+		ok, err := canSubscribe(viewer, addr)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		ok, err = canPublish(viewer, addr)
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		ok, err = canPublish(editor, addr)
+		require.NoError(t, err)
+		require.True(t, ok)
+	})
+
+	t.Run("dashboard channels use the dashboard permissions", func(t *testing.T) {
+		t.Cleanup(bus.ClearBusHandlers)
+		bus.AddHandler("test", func(query *models.GetDashboardQuery) error {
+			if query.Uid != "abc" || query.OrgId != 1 {
+				return models.ErrDashboardNotFound
+			}
+			query.Result = &models.Dashboard{Id: 10, Uid: "abc", OrgId: 1}
+			return nil
+		})
+
+		origNew := guardian.New
+		t.Cleanup(func() { guardian.New = origNew })
+		guardian.MockDashboardGuardian(&guardian.FakeDashboardGuardian{CanViewValue: true, CanEditValue: false})
+
+		addr := ChannelAddress{Scope: "grafinsight", Namespace: "dashboard", Path: "uid/abc"}
+		ok, err := canSubscribe(viewer, addr)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		ok, err = canPublish(editor, addr)
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		ok, err = canSubscribe(viewer, ChannelAddress{Scope: "grafinsight", Namespace: "dashboard", Path: "uid/other"})
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		ok, err = canPublish(editor, ChannelAddress{Scope: "grafinsight", Namespace: "dashboard", Path: "changes"})
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+
+	t.Run("datasource channels require access to the datasource", func(t *testing.T) {
+		t.Cleanup(bus.ClearBusHandlers)
+		bus.AddHandler("test", func(query *models.GetDataSourceQuery) error {
+			if query.Id != 5 || query.OrgId != 1 {
+				return models.ErrDataSourceNotFound
+			}
+			query.Result = &models.DataSource{Id: 5, OrgId: 1}
+			return nil
+		})
+
+		ok, err := canSubscribe(viewer, ChannelAddress{Scope: "ds", Namespace: "5", Path: "test"})
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		ok, err = canSubscribe(viewer, ChannelAddress{Scope: "ds", Namespace: "6", Path: "test"})
+		require.NoError(t, err)
+		require.False(t, ok)
+
+		bus.AddHandler("test", func(query *models.DatasourcesPermissionFilterQuery) error {
+			query.Result = []*models.DataSource{}
+			return nil
+		})
+
+		ok, err = canSubscribe(viewer, ChannelAddress{Scope: "ds", Namespace: "5", Path: "test"})
+		require.NoError(t, err)
+		require.False(t, ok)
+	})
+}
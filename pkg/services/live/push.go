@@ -0,0 +1,89 @@
+package live
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"regexp"
+	"time"
+
+	"github.com/openinsight-project/grafinsight/pkg/api/response"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/services/live/features"
+)
+
+// maxPushBodySize limits the size of a single HTTP push request.
+const maxPushBodySize = 10 * 1024 * 1024
+
+var (
+	streamIDPattern = regexp.MustCompile(`^[A-Za-z0-9_\-.]+$`)
+
+	influxPrecisions = map[string]time.Duration{
+		"":   time.Nanosecond,
+		"ns": time.Nanosecond,
+		"us": time.Microsecond,
+		"ms": time.Millisecond,
+		"s":  time.Second,
+	}
+)
+
+// HandleHTTPPush accepts a batch of measurements, as Influx line protocol or
+// as a JSON measurement batch, and publishes it to the
+// `grafinsight/measurements/${streamId}` channel.
+// POST /api/live/push/:streamId
+func (g *GrafinsightLive) HandleHTTPPush(c *models.ReqContext) response.Response {
+	streamID := c.Params(":streamId")
+	if !streamIDPattern.MatchString(streamID) {
+		return response.Error(400, "Invalid stream id", nil)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(c.Req.Request.Body, maxPushBodySize+1))
+	if err != nil {
+		return response.Error(500, "Failed to read request body", err)
+	}
+	if len(body) > maxPushBodySize {
+		return response.Error(413, fmt.Sprintf("Request body exceeds %d bytes", maxPushBodySize), nil)
+	}
+
+	batch, err := parsePushBody(c.Req.Header.Get("Content-Type"), c.Query("precision"), body)
+	if err != nil {
+		return response.Error(400, "Failed to parse measurements", err)
+	}
+
+	if err := g.GrafinsightScope.Measurements.PushMeasurements(c.OrgId, streamID, batch); err != nil {
+		return response.Error(400, "Failed to push measurements", err)
+	}
+
+	return response.JSON(200, map[string]interface{}{
+		"message":      "Measurements pushed",
+		"channel":      "grafinsight/measurements/" + streamID,
+		"measurements": len(batch.Measurements),
+	})
+}
+
+// parsePushBody reads a JSON measurement batch when the content type is
+// JSON and Influx line protocol otherwise.
+func parsePushBody(contentType string, precision string, body []byte) (*models.MeasurementBatch, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "application/json" {
+		batch := &models.MeasurementBatch{}
+		if err := json.Unmarshal(body, batch); err != nil {
+			return nil, err
+		}
+		return batch, nil
+	}
+
+	p, ok := influxPrecisions[precision]
+	if !ok {
+		return nil, fmt.Errorf("unsupported precision %q", precision)
+	}
+
+	measurements, err := features.ParseInfluxLineProtocol(body, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.MeasurementBatch{Measurements: measurements}, nil
+}
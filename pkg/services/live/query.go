@@ -0,0 +1,81 @@
+package live
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/openinsight-project/grafinsight/pkg/api/response"
+	"github.com/openinsight-project/grafinsight/pkg/components/gtime"
+	"github.com/openinsight-project/grafinsight/pkg/expr"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/services/live/features"
+)
+
+const (
+	defaultQueryStreamInterval = 10 * time.Second
+	minQueryStreamInterval     = time.Second
+)
+
+// HandleQueryStream registers a query that runs server-side on an interval,
+// and returns the `grafinsight/query/${key}` channel streaming its results.
+// Everyone registering the same query, datasource and time range shares the
+// same channel, so the query runs once for all of them.
+// POST /api/live/query
+func (g *GrafinsightLive) HandleQueryStream(c *models.ReqContext, cmd models.LiveQueryStreamCommand) response.Response {
+	if len(cmd.Queries) == 0 {
+		return response.Error(400, "No queries found in query", nil)
+	}
+
+	interval := defaultQueryStreamInterval
+	if cmd.Interval != "" {
+		var err error
+		interval, err = gtime.ParseDuration(cmd.Interval)
+		if err != nil {
+			return response.Error(400, "Invalid interval", err)
+		}
+	}
+	if interval < minQueryStreamInterval {
+		return response.Error(400, fmt.Sprintf("Interval must be at least %s", minQueryStreamInterval), nil)
+	}
+
+	var datasourceID int64
+	for i, query := range cmd.Queries {
+		if query.Get("datasource").MustString("") == expr.DatasourceName {
+			return response.Error(400, "Expressions can not be streamed", nil)
+		}
+
+		id, err := query.Get("datasourceId").Int64()
+		if err != nil {
+			return response.Error(400, "Query missing data source ID", nil)
+		}
+		if i == 0 {
+			datasourceID = id
+		} else if id != datasourceID {
+			return response.Error(400, "All queries must use the same data source", nil)
+		}
+	}
+
+	allowed, err := canQueryDatasource(c.SignedInUser, datasourceID)
+	if err != nil {
+		return response.Error(500, "Failed to check data source permissions", err)
+	}
+	if !allowed {
+		return response.Error(403, "Access denied to data source", nil)
+	}
+
+	key, err := g.GrafinsightScope.Query.Register(features.QueryStream{
+		OrgID:        c.OrgId,
+		DatasourceID: datasourceID,
+		From:         cmd.From,
+		To:           cmd.To,
+		Interval:     interval,
+		Queries:      cmd.Queries,
+	})
+	if err != nil {
+		return response.Error(500, "Failed to register query stream", err)
+	}
+
+	return response.JSON(200, map[string]interface{}{
+		"channel": "grafinsight/query/" + key,
+	})
+}
@@ -0,0 +1,543 @@
+package live
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/centrifugal/centrifuge"
+	"github.com/google/uuid"
+	"github.com/openinsight-project/grafinsight/pkg/util"
+	redis "gopkg.in/redis.v5"
+)
+
+const redisKeyPrefix = "grafinsight.live"
+
+const (
+	// extendLockSource extends a lock when it is held by the given owner.
+	extendLockSource = `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0
+`
+	// unlockSource releases a lock when it is held by the given owner.
+	unlockSource = `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`
+	// publishSource adds a publication to the history of a channel and
+	// publishes it, with the next offset of the channel added to the message.
+	// It returns the offset and the epoch of the history.
+	publishSource = `
+redis.call("SET", KEYS[3], ARGV[1], "PX", ARGV[3], "NX")
+local offset = redis.call("INCR", KEYS[2])
+local payload = '{"offset":' .. offset .. ',' .. string.sub(ARGV[4], 2)
+redis.call("LPUSH", KEYS[1], payload)
+redis.call("LTRIM", KEYS[1], 0, ARGV[2] - 1)
+redis.call("PEXPIRE", KEYS[1], ARGV[3])
+redis.call("PEXPIRE", KEYS[2], ARGV[3])
+redis.call("PEXPIRE", KEYS[3], ARGV[3])
+redis.call("PUBLISH", ARGV[5], payload)
+return {offset, redis.call("GET", KEYS[3])}
+`
+)
+
+var (
+	extendLockScript = redis.NewScript(extendLockSource)
+	unlockScript     = redis.NewScript(unlockSource)
+	publishScript    = redis.NewScript(publishSource)
+)
+
+// redisBroker shares channels between GrafInsight instances through redis
+// pub/sub, so that clients connected to different instances behind a load
+// balancer receive the same messages. It also keeps the publication history
+// and the channel presence in redis.
+type redisBroker struct {
+	client *redis.Client
+	prefix string
+	// id identifies this instance as the owner of the locks
+	id string
+
+	mu     sync.Mutex
+	pubSub *redis.PubSub
+	closed chan struct{}
+
+	// The handlers of the commands sent to the SSE sessions of this instance
+	sseMu       sync.RWMutex
+	sseHandlers map[string]func(cmd sseCommand)
+
+	// disconnectClient disconnects a client of this instance
+	disconnectClient func(clientID string)
+}
+
+// redisMessage is the envelope of the messages sent through redis pub/sub
+// and stored in the history lists.
+type redisMessage struct {
+	Type   string                 `json:"type"`
+	Offset uint64                 `json:"offset,omitempty"`
+	Data   []byte                 `json:"data,omitempty"`
+	Info   *centrifuge.ClientInfo `json:"info,omitempty"`
+}
+
+const (
+	redisMessagePublication = "pub"
+	redisMessageJoin        = "join"
+	redisMessageLeave       = "leave"
+)
+
+func newRedisBroker(client *redis.Client, prefix string) *redisBroker {
+	return &redisBroker{
+		client:      client,
+		prefix:      prefix,
+		id:          uuid.New().String(),
+		closed:      make(chan struct{}),
+		sseHandlers: make(map[string]func(cmd sseCommand)),
+	}
+}
+
+func (b *redisBroker) controlChannel() string {
+	return b.prefix + ".control"
+}
+
+func (b *redisBroker) disconnectChannel() string {
+	return b.prefix + ".disconnect"
+}
+
+func (b *redisBroker) clientChannel(ch string) string {
+	return b.prefix + ".client." + ch
+}
+
+func (b *redisBroker) sseChannel(session string) string {
+	return b.prefix + ".sse." + session
+}
+
+func (b *redisBroker) historyListKey(ch string) string {
+	return b.prefix + ".history.list." + ch
+}
+
+func (b *redisBroker) historyOffsetKey(ch string) string {
+	return b.prefix + ".history.offset." + ch
+}
+
+func (b *redisBroker) historyEpochKey(ch string) string {
+	return b.prefix + ".history.epoch." + ch
+}
+
+func (b *redisBroker) lockKey(key string) string {
+	return b.prefix + ".lock." + key
+}
+
+func (b *redisBroker) presenceDataKey(ch string) string {
+	return b.prefix + ".presence.data." + ch
+}
+
+func (b *redisBroker) presenceExpireKey(ch string) string {
+	return b.prefix + ".presence.expire." + ch
+}
+
+// Run subscribes to the control channel and starts delivering the messages
+// received from redis to the node.
+func (b *redisBroker) Run(h centrifuge.BrokerEventHandler) error {
+	pubSub, err := b.client.Subscribe(b.controlChannel(), b.disconnectChannel())
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to live control channel: %w", err)
+	}
+
+	b.mu.Lock()
+	b.pubSub = pubSub
+	b.mu.Unlock()
+
+	go b.receive(pubSub, h)
+	return nil
+}
+
+func (b *redisBroker) receive(pubSub *redis.PubSub, h centrifuge.BrokerEventHandler) {
+	for {
+		msg, err := pubSub.ReceiveMessage()
+		if err != nil {
+			select {
+			case <-b.closed:
+				return
+			default:
+			}
+			logger.Error("Failed to receive live message from redis", "error", err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		if err := b.handleMessage(msg, h); err != nil {
+			logger.Error("Failed to handle live message from redis", "channel", msg.Channel, "error", err)
+		}
+	}
+}
+
+func (b *redisBroker) handleMessage(msg *redis.Message, h centrifuge.BrokerEventHandler) error {
+	if msg.Channel == b.controlChannel() {
+		return h.HandleControl([]byte(msg.Payload))
+	}
+	if msg.Channel == b.disconnectChannel() {
+		b.mu.Lock()
+		disconnect := b.disconnectClient
+		b.mu.Unlock()
+		if disconnect != nil {
+			disconnect(msg.Payload)
+		}
+		return nil
+	}
+	if session := strings.TrimPrefix(msg.Channel, b.prefix+".sse."); session != msg.Channel {
+		return b.handleSSECommand(session, msg.Payload)
+	}
+
+	ch := strings.TrimPrefix(msg.Channel, b.prefix+".client.")
+	if ch == msg.Channel {
+		return fmt.Errorf("unexpected redis channel")
+	}
+
+	var m redisMessage
+	if err := json.Unmarshal([]byte(msg.Payload), &m); err != nil {
+		return err
+	}
+
+	switch m.Type {
+	case redisMessagePublication:
+		return h.HandlePublication(ch, &centrifuge.Publication{Offset: m.Offset, Data: m.Data, Info: m.Info})
+	case redisMessageJoin:
+		return h.HandleJoin(ch, m.Info)
+	case redisMessageLeave:
+		return h.HandleLeave(ch, m.Info)
+	default:
+		return fmt.Errorf("unknown message type %q", m.Type)
+	}
+}
+
+// Close stops receiving messages from redis.
+func (b *redisBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	select {
+	case <-b.closed:
+		return nil
+	default:
+		close(b.closed)
+	}
+
+	if b.pubSub != nil {
+		return b.pubSub.Close()
+	}
+	return nil
+}
+
+func (b *redisBroker) Subscribe(ch string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.pubSub.Subscribe(b.clientChannel(ch))
+}
+
+func (b *redisBroker) Unsubscribe(ch string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.pubSub.Unsubscribe(b.clientChannel(ch))
+}
+
+// OnDisconnectClient sets the function disconnecting the clients of this
+// instance when an instance asks to disconnect them.
+func (b *redisBroker) OnDisconnectClient(disconnect func(clientID string)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.disconnectClient = disconnect
+}
+
+// PublishDisconnectClient asks every instance to disconnect the client.
+func (b *redisBroker) PublishDisconnectClient(clientID string) error {
+	return b.client.Publish(b.disconnectChannel(), clientID).Err()
+}
+
+// Lock takes the lock of the key for the TTL, or extends it when this
+// instance holds it, and reports whether this instance holds it.
+func (b *redisBroker) Lock(key string, ttl time.Duration) (bool, error) {
+	locked, err := b.client.SetNX(b.lockKey(key), b.id, ttl).Result()
+	if err != nil || locked {
+		return locked, err
+	}
+
+	extended, err := extendLockScript.Run(b.client, []string{b.lockKey(key)}, b.id, int64(ttl/time.Millisecond)).Result()
+	if err != nil {
+		return false, err
+	}
+	return extended == int64(1), nil
+}
+
+// Unlock releases the lock of the key when this instance holds it.
+func (b *redisBroker) Unlock(key string) error {
+	return unlockScript.Run(b.client, []string{b.lockKey(key)}, b.id).Err()
+}
+
+// SubscribeSSE receives the commands sent to the session on the other
+// instances.
+func (b *redisBroker) SubscribeSSE(session string, handle func(cmd sseCommand)) error {
+	b.sseMu.Lock()
+	b.sseHandlers[session] = handle
+	b.sseMu.Unlock()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.pubSub.Subscribe(b.sseChannel(session))
+}
+
+// UnsubscribeSSE stops receiving the commands of the session.
+func (b *redisBroker) UnsubscribeSSE(session string) error {
+	b.sseMu.Lock()
+	delete(b.sseHandlers, session)
+	b.sseMu.Unlock()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.pubSub.Unsubscribe(b.sseChannel(session))
+}
+
+// PublishSSE sends the command to the instance subscribed to the session.
+func (b *redisBroker) PublishSSE(session string, cmd sseCommand) (bool, error) {
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return false, err
+	}
+	receivers, err := b.client.Publish(b.sseChannel(session), string(data)).Result()
+	if err != nil {
+		return false, err
+	}
+	return receivers > 0, nil
+}
+
+func (b *redisBroker) handleSSECommand(session string, payload string) error {
+	b.sseMu.RLock()
+	handle, ok := b.sseHandlers[session]
+	b.sseMu.RUnlock()
+	if !ok {
+		return nil // the session just ended
+	}
+
+	var cmd sseCommand
+	if err := json.Unmarshal([]byte(payload), &cmd); err != nil {
+		return err
+	}
+	handle(cmd)
+	return nil
+}
+
+// Publish sends the data to every instance subscribed to the channel. When
+// history is enabled the publication is also added to the channel history.
+func (b *redisBroker) Publish(ch string, data []byte, opts centrifuge.PublishOptions) (centrifuge.StreamPosition, error) {
+	m := redisMessage{Type: redisMessagePublication, Data: data, Info: opts.ClientInfo}
+
+	if opts.HistorySize <= 0 || opts.HistoryTTL <= 0 {
+		return centrifuge.StreamPosition{}, b.publish(ch, &m)
+	}
+
+	newEpoch, err := util.GetRandomString(8)
+	if err != nil {
+		return centrifuge.StreamPosition{}, err
+	}
+	// The offset is added by the script, so that publications get their
+	// offsets in the order they are added to the history.
+	payload, err := json.Marshal(&m)
+	if err != nil {
+		return centrifuge.StreamPosition{}, err
+	}
+
+	keys := []string{b.historyListKey(ch), b.historyOffsetKey(ch), b.historyEpochKey(ch)}
+	result, err := publishScript.Run(b.client, keys, newEpoch, opts.HistorySize,
+		int64(opts.HistoryTTL/time.Millisecond), string(payload), b.clientChannel(ch)).Result()
+	if err != nil {
+		return centrifuge.StreamPosition{}, err
+	}
+
+	values, ok := result.([]interface{})
+	if !ok || len(values) != 2 {
+		return centrifuge.StreamPosition{}, fmt.Errorf("unexpected publish result %v", result)
+	}
+	offset, ok := values[0].(int64)
+	if !ok {
+		return centrifuge.StreamPosition{}, fmt.Errorf("unexpected publication offset %v", values[0])
+	}
+	epoch, ok := values[1].(string)
+	if !ok {
+		return centrifuge.StreamPosition{}, fmt.Errorf("unexpected history epoch %v", values[1])
+	}
+
+	return centrifuge.StreamPosition{Offset: uint64(offset), Epoch: epoch}, nil
+}
+
+func (b *redisBroker) PublishJoin(ch string, info *centrifuge.ClientInfo) error {
+	return b.publish(ch, &redisMessage{Type: redisMessageJoin, Info: info})
+}
+
+func (b *redisBroker) PublishLeave(ch string, info *centrifuge.ClientInfo) error {
+	return b.publish(ch, &redisMessage{Type: redisMessageLeave, Info: info})
+}
+
+func (b *redisBroker) PublishControl(data []byte) error {
+	return b.client.Publish(b.controlChannel(), string(data)).Err()
+}
+
+func (b *redisBroker) publish(ch string, m *redisMessage) error {
+	payload, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(b.clientChannel(ch), string(payload)).Err()
+}
+
+func (b *redisBroker) History(ch string, filter centrifuge.HistoryFilter) ([]*centrifuge.Publication, centrifuge.StreamPosition, error) {
+	offset, err := b.client.Get(b.historyOffsetKey(ch)).Result()
+	if err != nil && err != redis.Nil {
+		return nil, centrifuge.StreamPosition{}, err
+	}
+	epoch, err := b.client.Get(b.historyEpochKey(ch)).Result()
+	if err != nil && err != redis.Nil {
+		return nil, centrifuge.StreamPosition{}, err
+	}
+
+	position := centrifuge.StreamPosition{Epoch: epoch}
+	if offset != "" {
+		position.Offset, err = strconv.ParseUint(offset, 10, 64)
+		if err != nil {
+			return nil, centrifuge.StreamPosition{}, err
+		}
+	}
+
+	if filter.Since == nil && filter.Limit == 0 {
+		return nil, position, nil
+	}
+	if filter.Since != nil && filter.Since.Offset == position.Offset && filter.Since.Epoch == position.Epoch {
+		return nil, position, nil
+	}
+
+	items, err := b.client.LRange(b.historyListKey(ch), 0, -1).Result()
+	if err != nil {
+		return nil, centrifuge.StreamPosition{}, err
+	}
+
+	// The list is ordered from the newest to the oldest publication.
+	pubs := make([]*centrifuge.Publication, 0, len(items))
+	for i := len(items) - 1; i >= 0; i-- {
+		var m redisMessage
+		if err := json.Unmarshal([]byte(items[i]), &m); err != nil {
+			return nil, centrifuge.StreamPosition{}, err
+		}
+		if filter.Since != nil && m.Offset <= filter.Since.Offset {
+			continue
+		}
+		pubs = append(pubs, &centrifuge.Publication{Offset: m.Offset, Data: m.Data, Info: m.Info})
+		if filter.Limit > 0 && len(pubs) >= filter.Limit {
+			break
+		}
+	}
+
+	return pubs, position, nil
+}
+
+// RemoveHistory removes the publications of the channel, and its offset and
+// epoch so that a new history starts with a new epoch.
+func (b *redisBroker) RemoveHistory(ch string) error {
+	return b.client.Del(b.historyListKey(ch), b.historyOffsetKey(ch), b.historyEpochKey(ch)).Err()
+}
+
+func (b *redisBroker) Channels() ([]string, error) {
+	channels, err := b.client.PubSubChannels(b.clientChannel("*")).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]string, 0, len(channels))
+	for _, ch := range channels {
+		result = append(result, strings.TrimPrefix(ch, b.prefix+".client."))
+	}
+	return result, nil
+}
+
+// Presence returns the clients subscribed to the channel on every instance.
+// Clients that were not refreshed before they expired are removed.
+func (b *redisBroker) Presence(ch string) (map[string]*centrifuge.ClientInfo, error) {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	expired, err := b.client.ZRangeByScore(b.presenceExpireKey(ch), redis.ZRangeBy{Min: "-inf", Max: now}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(expired) > 0 {
+		members := make([]interface{}, len(expired))
+		for i, id := range expired {
+			members[i] = id
+		}
+		_, err = b.client.TxPipelined(func(pipe *redis.Pipeline) error {
+			pipe.ZRem(b.presenceExpireKey(ch), members...)
+			pipe.HDel(b.presenceDataKey(ch), expired...)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := b.client.HGetAll(b.presenceDataKey(ch)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	presence := make(map[string]*centrifuge.ClientInfo, len(data))
+	for clientID, value := range data {
+		var info centrifuge.ClientInfo
+		if err := json.Unmarshal([]byte(value), &info); err != nil {
+			return nil, err
+		}
+		presence[clientID] = &info
+	}
+	return presence, nil
+}
+
+func (b *redisBroker) PresenceStats(ch string) (centrifuge.PresenceStats, error) {
+	presence, err := b.Presence(ch)
+	if err != nil {
+		return centrifuge.PresenceStats{}, err
+	}
+
+	users := map[string]struct{}{}
+	for _, info := range presence {
+		users[info.UserID] = struct{}{}
+	}
+
+	return centrifuge.PresenceStats{NumClients: len(presence), NumUsers: len(users)}, nil
+}
+
+func (b *redisBroker) AddPresence(ch string, clientID string, info *centrifuge.ClientInfo, expire time.Duration) error {
+	value, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	expireAt := float64(time.Now().Add(expire).Unix())
+	_, err = b.client.TxPipelined(func(pipe *redis.Pipeline) error {
+		pipe.ZAdd(b.presenceExpireKey(ch), redis.Z{Score: expireAt, Member: clientID})
+		pipe.HSet(b.presenceDataKey(ch), clientID, string(value))
+		pipe.Expire(b.presenceExpireKey(ch), expire)
+		pipe.Expire(b.presenceDataKey(ch), expire)
+		return nil
+	})
+	return err
+}
+
+func (b *redisBroker) RemovePresence(ch string, clientID string) error {
+	_, err := b.client.TxPipelined(func(pipe *redis.Pipeline) error {
+		pipe.ZRem(b.presenceExpireKey(ch), clientID)
+		pipe.HDel(b.presenceDataKey(ch), clientID)
+		return nil
+	})
+	return err
+}
@@ -0,0 +1,554 @@
+package live
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/centrifugal/centrifuge"
+	"github.com/stretchr/testify/require"
+	redis "gopkg.in/redis.v5"
+)
+
+func TestRedisBroker(t *testing.T) {
+	addr := startFakeRedis(t)
+
+	newBroker := func() (*redisBroker, *recordingEventHandler) {
+		b := newRedisBroker(redis.NewClient(&redis.Options{Addr: addr}), "test")
+		h := &recordingEventHandler{events: make(chan string, 10)}
+		require.NoError(t, b.Run(h))
+		t.Cleanup(func() { _ = b.Close() })
+		return b, h
+	}
+
+	nodeA, handlerA := newBroker()
+	nodeB, handlerB := newBroker()
+
+	t.Run("publications are delivered to the other instances", func(t *testing.T) {
+		require.NoError(t, nodeB.Subscribe("grafinsight/broadcast/test"))
+		require.Eventually(t, func() bool {
+			channels, err := nodeA.Channels()
+			return err == nil && len(channels) == 1 && channels[0] == "grafinsight/broadcast/test"
+		}, time.Second, 10*time.Millisecond)
+
+		info := &centrifuge.ClientInfo{ClientID: "client-1", UserID: "1"}
+		_, err := nodeA.Publish("grafinsight/broadcast/test", []byte(`{"hello":"world"}`), centrifuge.PublishOptions{ClientInfo: info})
+		require.NoError(t, err)
+		require.NoError(t, nodeA.PublishJoin("grafinsight/broadcast/test", info))
+		require.NoError(t, nodeA.PublishLeave("grafinsight/broadcast/test", info))
+
+		require.Equal(t, `pub grafinsight/broadcast/test 0 {"hello":"world"} client-1`, handlerB.next(t))
+		require.Equal(t, "join grafinsight/broadcast/test client-1", handlerB.next(t))
+		require.Equal(t, "leave grafinsight/broadcast/test client-1", handlerB.next(t))
+	})
+
+	t.Run("control messages are delivered to every instance", func(t *testing.T) {
+		require.NoError(t, nodeB.PublishControl([]byte("node")))
+
+		require.Equal(t, "control node", handlerA.next(t))
+		require.Equal(t, "control node", handlerB.next(t))
+	})
+
+	t.Run("history is shared and trimmed to the history size", func(t *testing.T) {
+		opts := centrifuge.PublishOptions{HistorySize: 2, HistoryTTL: time.Minute}
+		var pos centrifuge.StreamPosition
+		for i := 1; i <= 3; i++ {
+			var err error
+			pos, err = nodeA.Publish("grafinsight/testdata/random", []byte(strconv.Itoa(i)), opts)
+			require.NoError(t, err)
+			require.Equal(t, uint64(i), pos.Offset)
+		}
+		require.NotEmpty(t, pos.Epoch)
+
+		pubs, top, err := nodeB.History("grafinsight/testdata/random", centrifuge.HistoryFilter{})
+		require.NoError(t, err)
+		require.Empty(t, pubs)
+		require.Equal(t, pos, top)
+
+		pubs, _, err = nodeB.History("grafinsight/testdata/random", centrifuge.HistoryFilter{Limit: -1})
+		require.NoError(t, err)
+		require.Len(t, pubs, 2)
+		require.Equal(t, uint64(2), pubs[0].Offset)
+		require.Equal(t, []byte("3"), pubs[1].Data)
+
+		pubs, _, err = nodeB.History("grafinsight/testdata/random", centrifuge.HistoryFilter{
+			Since: &centrifuge.StreamPosition{Offset: 2, Epoch: pos.Epoch},
+			Limit: -1,
+		})
+		require.NoError(t, err)
+		require.Len(t, pubs, 1)
+		require.Equal(t, uint64(3), pubs[0].Offset)
+
+		require.NoError(t, nodeB.RemoveHistory("grafinsight/testdata/random"))
+		pubs, top, err = nodeA.History("grafinsight/testdata/random", centrifuge.HistoryFilter{Limit: -1})
+		require.NoError(t, err)
+		require.Empty(t, pubs)
+		require.Equal(t, centrifuge.StreamPosition{}, top)
+
+		// a new history starts from the first offset with a new epoch
+		restarted, err := nodeA.Publish("grafinsight/testdata/random", []byte("4"), opts)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), restarted.Offset)
+		require.NotEqual(t, pos.Epoch, restarted.Epoch)
+	})
+
+	t.Run("presence is shared between instances", func(t *testing.T) {
+		ch := "grafinsight/dashboard/abc"
+		require.NoError(t, nodeA.AddPresence(ch, "client-1", &centrifuge.ClientInfo{ClientID: "client-1", UserID: "1"}, time.Minute))
+		require.NoError(t, nodeB.AddPresence(ch, "client-2", &centrifuge.ClientInfo{ClientID: "client-2", UserID: "1"}, time.Minute))
+		require.NoError(t, nodeB.AddPresence(ch, "client-3", &centrifuge.ClientInfo{ClientID: "client-3", UserID: "2"}, -time.Minute))
+
+		presence, err := nodeA.Presence(ch)
+		require.NoError(t, err)
+		require.Len(t, presence, 2)
+		require.Equal(t, "1", presence["client-2"].UserID)
+
+		stats, err := nodeB.PresenceStats(ch)
+		require.NoError(t, err)
+		require.Equal(t, centrifuge.PresenceStats{NumClients: 2, NumUsers: 1}, stats)
+
+		require.NoError(t, nodeB.RemovePresence(ch, "client-1"))
+		presence, err = nodeA.Presence(ch)
+		require.NoError(t, err)
+		require.Len(t, presence, 1)
+	})
+
+	t.Run("client disconnects are sent to every instance", func(t *testing.T) {
+		disconnected := make(chan string, 1)
+		nodeB.OnDisconnectClient(func(clientID string) {
+			disconnected <- clientID
+		})
+
+		require.NoError(t, nodeA.PublishDisconnectClient("client-1"))
+		select {
+		case clientID := <-disconnected:
+			require.Equal(t, "client-1", clientID)
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for disconnect")
+		}
+	})
+
+	t.Run("locks are held by one instance at a time", func(t *testing.T) {
+		locked, err := nodeA.Lock("query", time.Minute)
+		require.NoError(t, err)
+		require.True(t, locked)
+
+		// the instance holding the lock extends it
+		locked, err = nodeA.Lock("query", time.Minute)
+		require.NoError(t, err)
+		require.True(t, locked)
+
+		locked, err = nodeB.Lock("query", time.Minute)
+		require.NoError(t, err)
+		require.False(t, locked)
+
+		// only the instance holding the lock releases it
+		require.NoError(t, nodeB.Unlock("query"))
+		locked, err = nodeB.Lock("query", time.Minute)
+		require.NoError(t, err)
+		require.False(t, locked)
+
+		require.NoError(t, nodeA.Unlock("query"))
+		locked, err = nodeB.Lock("query", time.Minute)
+		require.NoError(t, err)
+		require.True(t, locked)
+	})
+
+	t.Run("sse commands are forwarded to the instance of the session", func(t *testing.T) {
+		commands := make(chan sseCommand, 1)
+		require.NoError(t, nodeB.SubscribeSSE("session-1", func(cmd sseCommand) {
+			commands <- cmd
+		}))
+
+		require.Eventually(t, func() bool {
+			found, err := nodeA.PublishSSE("session-1", sseCommand{UserID: 1, OrgID: 2, Data: []byte(`{"id":1}`)})
+			return err == nil && found
+		}, time.Second, 10*time.Millisecond)
+		require.Equal(t, sseCommand{UserID: 1, OrgID: 2, Data: []byte(`{"id":1}`)}, <-commands)
+
+		require.NoError(t, nodeB.UnsubscribeSSE("session-1"))
+		require.Eventually(t, func() bool {
+			found, err := nodeA.PublishSSE("session-1", sseCommand{UserID: 1, OrgID: 2})
+			return err == nil && !found
+		}, time.Second, 10*time.Millisecond)
+	})
+}
+
+type recordingEventHandler struct {
+	events chan string
+}
+
+func (h *recordingEventHandler) next(t *testing.T) string {
+	t.Helper()
+	select {
+	case e := <-h.events:
+		return e
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for event")
+		return ""
+	}
+}
+
+func (h *recordingEventHandler) HandlePublication(ch string, pub *centrifuge.Publication) error {
+	h.events <- fmt.Sprintf("pub %s %d %s %s", ch, pub.Offset, pub.Data, pub.Info.ClientID)
+	return nil
+}
+
+func (h *recordingEventHandler) HandleJoin(ch string, info *centrifuge.ClientInfo) error {
+	h.events <- fmt.Sprintf("join %s %s", ch, info.ClientID)
+	return nil
+}
+
+func (h *recordingEventHandler) HandleLeave(ch string, info *centrifuge.ClientInfo) error {
+	h.events <- fmt.Sprintf("leave %s %s", ch, info.ClientID)
+	return nil
+}
+
+func (h *recordingEventHandler) HandleControl(data []byte) error {
+	h.events <- fmt.Sprintf("control %s", data)
+	return nil
+}
+
+// fakeRedis is an in-process stand-in for a redis server that implements the
+// commands used by the live redis broker. Keys never expire, PUBSUB CHANNELS
+// only supports trailing wildcards and EVAL only runs the scripts of the
+// broker, which are implemented in fakeScripts.
+type fakeRedis struct {
+	mu      sync.Mutex
+	strings map[string]string
+	lists   map[string][]string
+	hashes  map[string]map[string]string
+	zsets   map[string]map[string]float64
+	subs    map[*fakeRedisConn]map[string]bool
+}
+
+type fakeRedisConn struct {
+	conn    net.Conn
+	writeMu sync.Mutex
+}
+
+func (c *fakeRedisConn) write(reply string) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, _ = io.WriteString(c.conn, reply)
+}
+
+func startFakeRedis(t *testing.T) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	s := &fakeRedis{
+		strings: map[string]string{},
+		lists:   map[string][]string{},
+		hashes:  map[string]map[string]string{},
+		zsets:   map[string]map[string]float64{},
+		subs:    map[*fakeRedisConn]map[string]bool{},
+	}
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(&fakeRedisConn{conn: conn})
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+func (s *fakeRedis) serve(c *fakeRedisConn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.subs, c)
+		s.mu.Unlock()
+		_ = c.conn.Close()
+	}()
+
+	r := bufio.NewReader(c.conn)
+	var queued [][]string
+	inMulti := false
+
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+
+		switch cmd := strings.ToUpper(args[0]); {
+		case cmd == "MULTI":
+			inMulti = true
+			queued = nil
+			c.write("+OK\r\n")
+		case cmd == "EXEC":
+			replies := make([]string, 0, len(queued))
+			for _, q := range queued {
+				replies = append(replies, s.exec(c, q))
+			}
+			inMulti = false
+			c.write(fmt.Sprintf("*%d\r\n%s", len(replies), strings.Join(replies, "")))
+		case inMulti:
+			queued = append(queued, args)
+			c.write("+QUEUED\r\n")
+		default:
+			if reply := s.exec(c, args); reply != "" {
+				c.write(reply)
+			}
+		}
+	}
+}
+
+func (s *fakeRedis) exec(c *fakeRedisConn, args []string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "SUBSCRIBE", "UNSUBSCRIBE":
+		if s.subs[c] == nil {
+			s.subs[c] = map[string]bool{}
+		}
+		kind := strings.ToLower(args[0])
+		for _, ch := range args[1:] {
+			s.subs[c][ch] = kind == "subscribe"
+			if kind == "unsubscribe" {
+				delete(s.subs[c], ch)
+			}
+			c.write(respArray(bulk(kind), bulk(ch), fmt.Sprintf(":%d\r\n", len(s.subs[c]))))
+		}
+		return ""
+	case "PUBLISH":
+		return fmt.Sprintf(":%d\r\n", s.publish(args[1], args[2]))
+	case "PUBSUB":
+		var channels []string
+		seen := map[string]bool{}
+		for _, subscribed := range s.subs {
+			for ch := range subscribed {
+				if strings.HasPrefix(ch, strings.TrimSuffix(args[2], "*")) && !seen[ch] {
+					seen[ch] = true
+					channels = append(channels, ch)
+				}
+			}
+		}
+		sort.Strings(channels)
+		return bulkArray(channels)
+	case "GET":
+		v, ok := s.strings[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return bulk(v)
+	case "SET":
+		if _, ok := s.strings[args[1]]; ok && strings.EqualFold(args[len(args)-1], "nx") {
+			return "$-1\r\n"
+		}
+		s.strings[args[1]] = args[2]
+		return "+OK\r\n"
+	case "DEL":
+		n := 0
+		for _, key := range args[1:] {
+			if _, ok := s.lists[key]; ok {
+				n++
+			}
+			delete(s.strings, key)
+			delete(s.lists, key)
+			delete(s.hashes, key)
+			delete(s.zsets, key)
+		}
+		return fmt.Sprintf(":%d\r\n", n)
+	case "EXPIRE":
+		return ":1\r\n"
+	case "EVAL", "EVALSHA":
+		hash := args[1]
+		if strings.EqualFold(args[0], "eval") {
+			hash = scriptHash(args[1])
+		}
+		script, ok := fakeScripts[hash]
+		if !ok {
+			return "-NOSCRIPT No matching script\r\n"
+		}
+		numKeys, _ := strconv.Atoi(args[2])
+		return script(s, args[3:3+numKeys], args[3+numKeys:])
+	case "LPUSH":
+		for _, v := range args[2:] {
+			s.lists[args[1]] = append([]string{v}, s.lists[args[1]]...)
+		}
+		return fmt.Sprintf(":%d\r\n", len(s.lists[args[1]]))
+	case "LTRIM":
+		list := s.lists[args[1]]
+		start, stop := listRange(args[2], args[3], len(list))
+		s.lists[args[1]] = append([]string{}, list[start:stop]...)
+		return "+OK\r\n"
+	case "LRANGE":
+		list := s.lists[args[1]]
+		start, stop := listRange(args[2], args[3], len(list))
+		return bulkArray(list[start:stop])
+	case "HSET":
+		if s.hashes[args[1]] == nil {
+			s.hashes[args[1]] = map[string]string{}
+		}
+		s.hashes[args[1]][args[2]] = args[3]
+		return ":1\r\n"
+	case "HDEL":
+		for _, field := range args[2:] {
+			delete(s.hashes[args[1]], field)
+		}
+		return ":1\r\n"
+	case "HGETALL":
+		var values []string
+		for k, v := range s.hashes[args[1]] {
+			values = append(values, k, v)
+		}
+		return bulkArray(values)
+	case "ZADD":
+		if s.zsets[args[1]] == nil {
+			s.zsets[args[1]] = map[string]float64{}
+		}
+		score, _ := strconv.ParseFloat(args[2], 64)
+		s.zsets[args[1]][args[3]] = score
+		return ":1\r\n"
+	case "ZREM":
+		for _, member := range args[2:] {
+			delete(s.zsets[args[1]], member)
+		}
+		return ":1\r\n"
+	case "ZRANGEBYSCORE":
+		max, _ := strconv.ParseFloat(args[3], 64)
+		var members []string
+		for member, score := range s.zsets[args[1]] {
+			if score <= max {
+				members = append(members, member)
+			}
+		}
+		return bulkArray(members)
+	default:
+		return fmt.Sprintf("-ERR unknown command '%s'\r\n", args[0])
+	}
+}
+
+func scriptHash(source string) string {
+	sum := sha1.Sum([]byte(source))
+	return hex.EncodeToString(sum[:])
+}
+
+// publish sends the message to the subscribers of the channel and returns
+// their number. s.mu must be held.
+func (s *fakeRedis) publish(ch, message string) int {
+	n := 0
+	for sub, channels := range s.subs {
+		if channels[ch] {
+			sub.write(respArray(bulk("message"), bulk(ch), bulk(message)))
+			n++
+		}
+	}
+	return n
+}
+
+// fakeScripts implements the scripts of the broker by their SHA1 digest.
+var fakeScripts = map[string]func(s *fakeRedis, keys, args []string) string{
+	scriptHash(extendLockSource): func(s *fakeRedis, keys, args []string) string {
+		if s.strings[keys[0]] != args[0] {
+			return ":0\r\n"
+		}
+		return ":1\r\n"
+	},
+	scriptHash(unlockSource): func(s *fakeRedis, keys, args []string) string {
+		if s.strings[keys[0]] != args[0] {
+			return ":0\r\n"
+		}
+		delete(s.strings, keys[0])
+		return ":1\r\n"
+	},
+	scriptHash(publishSource): func(s *fakeRedis, keys, args []string) string {
+		if _, ok := s.strings[keys[2]]; !ok {
+			s.strings[keys[2]] = args[0]
+		}
+		offset, _ := strconv.ParseInt(s.strings[keys[1]], 10, 64)
+		offset++
+		s.strings[keys[1]] = strconv.FormatInt(offset, 10)
+
+		payload := fmt.Sprintf(`{"offset":%d,%s`, offset, args[3][1:])
+		size, _ := strconv.Atoi(args[1])
+		s.lists[keys[0]] = append([]string{payload}, s.lists[keys[0]]...)
+		if len(s.lists[keys[0]]) > size {
+			s.lists[keys[0]] = s.lists[keys[0]][:size]
+		}
+		s.publish(args[4], payload)
+		return respArray(fmt.Sprintf(":%d\r\n", offset), bulk(s.strings[keys[2]]))
+	},
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+func listRange(startArg, stopArg string, length int) (int, int) {
+	start, _ := strconv.Atoi(startArg)
+	stop, _ := strconv.Atoi(stopArg)
+	if stop < 0 {
+		stop = length + stop
+	}
+	stop++
+	if stop > length {
+		stop = length
+	}
+	if start > stop {
+		start = stop
+	}
+	return start, stop
+}
+
+func bulk(s string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s)
+}
+
+func bulkArray(values []string) string {
+	items := make([]string, len(values))
+	for i, v := range values {
+		items[i] = bulk(v)
+	}
+	return respArray(items...)
+}
+
+func respArray(items ...string) string {
+	return fmt.Sprintf("*%d\r\n%s", len(items), strings.Join(items, ""))
+}
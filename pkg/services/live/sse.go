@@ -0,0 +1,339 @@
+package live
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/centrifugal/centrifuge"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+)
+
+const (
+	// sseTransportName is reported as the transport of the clients.
+	sseTransportName = "sse"
+	// ssePingInterval keeps idle streams open through proxies.
+	ssePingInterval = 25 * time.Second
+	// sseMaxCommandSize limits the commands sent in a single request.
+	sseMaxCommandSize = 1 << 20
+)
+
+var errSSEClosed = errors.New("sse transport closed")
+
+// sseTransport writes the replies of a client as Server-Sent Events. Each
+// message event carries one JSON encoded reply.
+type sseTransport struct {
+	mu      sync.Mutex
+	w       http.ResponseWriter
+	flusher http.Flusher
+	closed  bool
+	closeCh chan struct{}
+}
+
+func newSSETransport(w http.ResponseWriter, flusher http.Flusher) *sseTransport {
+	return &sseTransport{
+		w:       w,
+		flusher: flusher,
+		closeCh: make(chan struct{}),
+	}
+}
+
+// Name returns the name of the transport.
+func (t *sseTransport) Name() string {
+	return sseTransportName
+}
+
+// Protocol returns the protocol used by the transport.
+func (t *sseTransport) Protocol() centrifuge.ProtocolType {
+	return centrifuge.ProtocolTypeJSON
+}
+
+// Encoding returns the payload encoding used by the transport.
+func (t *sseTransport) Encoding() centrifuge.EncodingType {
+	return centrifuge.EncodingTypeJSON
+}
+
+// Write sends the replies to the client.
+func (t *sseTransport) Write(data []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return errSSEClosed
+	}
+	for _, line := range bytes.Split(bytes.TrimSpace(data), []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if err := t.writeEvent("", line); err != nil {
+			return err
+		}
+	}
+	t.flusher.Flush()
+	return nil
+}
+
+// Close tells the client why it was disconnected and ends the stream.
+func (t *sseTransport) Close(disconnect *centrifuge.Disconnect) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return nil
+	}
+	t.closed = true
+	close(t.closeCh)
+
+	if disconnect == nil {
+		return nil
+	}
+	data, err := json.Marshal(disconnect)
+	if err != nil {
+		return err
+	}
+	if err := t.writeEvent("disconnect", data); err != nil {
+		return err
+	}
+	t.flusher.Flush()
+	return nil
+}
+
+// send writes an event outside of the centrifuge protocol.
+func (t *sseTransport) send(event string, data []byte) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return errSSEClosed
+	}
+	if err := t.writeEvent(event, data); err != nil {
+		return err
+	}
+	t.flusher.Flush()
+	return nil
+}
+
+func (t *sseTransport) ping() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return errSSEClosed
+	}
+	if _, err := t.w.Write([]byte(": ping\n\n")); err != nil {
+		return err
+	}
+	t.flusher.Flush()
+	return nil
+}
+
+func (t *sseTransport) writeEvent(event string, data []byte) error {
+	var buf bytes.Buffer
+	if event != "" {
+		fmt.Fprintf(&buf, "event: %s\n", event)
+	}
+	buf.WriteString("data: ")
+	buf.Write(data)
+	buf.WriteString("\n\n")
+	_, err := t.w.Write(buf.Bytes())
+	return err
+}
+
+type sseSession struct {
+	client *centrifuge.Client
+	userID int64
+	orgID  int64
+}
+
+// sseCommand is a command sent to a session connected to another instance.
+type sseCommand struct {
+	UserID int64  `json:"userId"`
+	OrgID  int64  `json:"orgId"`
+	Data   []byte `json:"data"`
+}
+
+// sseRelay forwards the commands of the sessions between instances, as the
+// commands of a client can reach any instance behind a load balancer.
+type sseRelay interface {
+	// SubscribeSSE calls handle with the commands sent to the session on
+	// the other instances.
+	SubscribeSSE(session string, handle func(cmd sseCommand)) error
+	// UnsubscribeSSE stops receiving the commands of the session.
+	UnsubscribeSSE(session string) error
+	// PublishSSE sends the command to the instance of the session, and
+	// reports whether an instance has the session.
+	PublishSSE(session string, cmd sseCommand) (bool, error)
+}
+
+// sseHandler serves Live over Server-Sent Events for browsers behind proxies
+// that block websocket upgrades. The client opens an event stream with
+// `GET /live/sse`, which starts with a `session` event, and sends its
+// commands with `POST /live/sse/${session}`. The replies are written to the
+// event stream.
+//
+// When several instances share channels, the commands received by an
+// instance without the session are forwarded through the relay.
+type sseHandler struct {
+	node  *centrifuge.Node
+	relay sseRelay
+
+	mu       sync.RWMutex
+	sessions map[string]*sseSession
+}
+
+func newSSEHandler(node *centrifuge.Node, relay sseRelay) *sseHandler {
+	return &sseHandler{
+		node:     node,
+		relay:    relay,
+		sessions: make(map[string]*sseSession),
+	}
+}
+
+// Stream opens the event stream of a new client.
+// GET /live/sse
+func (h *sseHandler) Stream(ctx *models.ReqContext) {
+	user := ctx.SignedInUser
+	if user == nil {
+		ctx.Resp.WriteHeader(401)
+		return
+	}
+
+	flusher, ok := ctx.Resp.(http.Flusher)
+	if !ok {
+		ctx.Resp.WriteHeader(500)
+		return
+	}
+
+	newCtx, err := clientContext(ctx)
+	if err != nil {
+		ctx.Resp.WriteHeader(500)
+		return
+	}
+
+	header := ctx.Resp.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// Stop nginx from buffering the stream
+	header.Set("X-Accel-Buffering", "no")
+	ctx.Resp.WriteHeader(200)
+
+	transport := newSSETransport(ctx.Resp, flusher)
+	client, closeFn, err := centrifuge.NewClient(newCtx, h.node, transport)
+	if err != nil {
+		logger.Error("Failed to create live client", "error", err)
+		return
+	}
+	defer func() {
+		h.mu.Lock()
+		delete(h.sessions, client.ID())
+		h.mu.Unlock()
+
+		if err := closeFn(); err != nil {
+			logger.Debug("Failed to close live client", "client", client.ID(), "error", err)
+		}
+	}()
+
+	h.mu.Lock()
+	h.sessions[client.ID()] = &sseSession{client: client, userID: user.UserId, orgID: user.OrgId}
+	h.mu.Unlock()
+
+	if h.relay != nil {
+		err := h.relay.SubscribeSSE(client.ID(), func(cmd sseCommand) {
+			if cmd.UserID != user.UserId || cmd.OrgID != user.OrgId {
+				logger.Debug("Dropping live command of another user", "client", client.ID())
+				return
+			}
+			client.Handle(cmd.Data)
+		})
+		if err != nil {
+			logger.Error("Failed to subscribe to live commands", "client", client.ID(), "error", err)
+			return
+		}
+		defer func() {
+			if err := h.relay.UnsubscribeSSE(client.ID()); err != nil {
+				logger.Debug("Failed to unsubscribe from live commands", "client", client.ID(), "error", err)
+			}
+		}()
+	}
+
+	session, err := json.Marshal(map[string]string{"session": client.ID()})
+	if err != nil {
+		return
+	}
+	if err := transport.send("session", session); err != nil {
+		return
+	}
+
+	ticker := time.NewTicker(ssePingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Req.Context().Done():
+			return
+		case <-transport.closeCh:
+			return
+		case <-ticker.C:
+			if err := transport.ping(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Send handles the commands of a client connected with Stream.
+// POST /live/sse/:sessionId
+func (h *sseHandler) Send(ctx *models.ReqContext) {
+	user := ctx.SignedInUser
+	if user == nil {
+		ctx.Resp.WriteHeader(401)
+		return
+	}
+
+	sessionID := ctx.Params(":sessionId")
+	h.mu.RLock()
+	session, ok := h.sessions[sessionID]
+	h.mu.RUnlock()
+	if ok && (session.userID != user.UserId || session.orgID != user.OrgId) {
+		ctx.Resp.WriteHeader(404)
+		return
+	}
+	if !ok && h.relay == nil {
+		ctx.Resp.WriteHeader(404)
+		return
+	}
+
+	data, err := ioutil.ReadAll(http.MaxBytesReader(ctx.Resp, ctx.Req.Request.Body, sseMaxCommandSize))
+	if err != nil {
+		ctx.Resp.WriteHeader(400)
+		return
+	}
+
+	if !ok {
+		// The event stream of the session is open on another instance
+		found, err := h.relay.PublishSSE(sessionID, sseCommand{UserID: user.UserId, OrgID: user.OrgId, Data: data})
+		if err != nil {
+			logger.Error("Failed to forward live command", "session", sessionID, "error", err)
+			ctx.Resp.WriteHeader(500)
+			return
+		}
+		if !found {
+			ctx.Resp.WriteHeader(404)
+			return
+		}
+		ctx.Resp.WriteHeader(204)
+		return
+	}
+
+	if !session.client.Handle(data) {
+		ctx.Resp.WriteHeader(410)
+		return
+	}
+	ctx.Resp.WriteHeader(204)
+}
@@ -0,0 +1,74 @@
+package live
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/centrifugal/centrifuge"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSETransport(t *testing.T) {
+	node, err := centrifuge.New(centrifuge.DefaultConfig)
+	require.NoError(t, err)
+	require.NoError(t, node.Run())
+	t.Cleanup(func() { _ = node.Shutdown(context.Background()) })
+
+	rec := newFlushRecorder()
+	transport := newSSETransport(rec, rec)
+
+	ctx := centrifuge.SetCredentials(context.Background(), &centrifuge.Credentials{UserID: "1"})
+	client, closeFn, err := centrifuge.NewClient(ctx, node, transport)
+	require.NoError(t, err)
+
+	t.Run("replies are written as events", func(t *testing.T) {
+		require.True(t, client.Handle([]byte(`{"id":1}`)))
+
+		body := <-rec.flushed
+		require.True(t, strings.HasPrefix(body, `data: {"id":1,"result":{"client":"`+client.ID()+`"`), body)
+		require.True(t, strings.HasSuffix(body, "\n\n"), body)
+	})
+
+	t.Run("each reply is a separate event", func(t *testing.T) {
+		require.NoError(t, transport.Write([]byte("{\"id\":2}\n{\"id\":3}")))
+		require.Equal(t, "data: {\"id\":2}\n\ndata: {\"id\":3}\n\n", <-rec.flushed)
+	})
+
+	t.Run("closing tells the client why", func(t *testing.T) {
+		require.NoError(t, transport.Close(centrifuge.DisconnectForceNoReconnect))
+		require.Equal(t, "event: disconnect\ndata: {\"code\":3012,\"reason\":\"force disconnect\",\"reconnect\":false}\n\n", <-rec.flushed)
+
+		select {
+		case <-transport.closeCh:
+		default:
+			t.Fatal("the stream should end once the transport is closed")
+		}
+
+		require.Equal(t, errSSEClosed, transport.Write([]byte(`{"id":4}`)))
+		require.NoError(t, closeFn())
+	})
+}
+
+// flushRecorder sends everything written since the last flush to flushed,
+// as the replies of the client are written from another goroutine.
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushed chan string
+}
+
+func newFlushRecorder() *flushRecorder {
+	return &flushRecorder{
+		ResponseRecorder: httptest.NewRecorder(),
+		flushed:          make(chan string, 10),
+	}
+}
+
+func (r *flushRecorder) Flush() {
+	r.flushed <- r.Body.String()
+	r.Body.Reset()
+}
+
+var _ http.Flusher = &flushRecorder{}
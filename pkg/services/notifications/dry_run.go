@@ -0,0 +1,29 @@
+package notifications
+
+import (
+	"context"
+)
+
+// DryRunRecorder receives the rendered payload of a notification that would
+// have been delivered to target if dry run was not enabled.
+type DryRunRecorder func(target string, payload string)
+
+type dryRunContextKey struct{}
+
+// WithDryRun returns a context that makes notification senders hand their
+// rendered payload to recorder instead of delivering it.
+func WithDryRun(ctx context.Context, recorder DryRunRecorder) context.Context {
+	return context.WithValue(ctx, dryRunContextKey{}, recorder)
+}
+
+// RecordDryRun hands the payload to the dry run recorder of ctx, if any, and
+// returns true if the notification should not be delivered.
+func RecordDryRun(ctx context.Context, target string, payload string) bool {
+	recorder, ok := ctx.Value(dryRunContextKey{}).(DryRunRecorder)
+	if !ok {
+		return false
+	}
+
+	recorder(target, payload)
+	return true
+}
@@ -18,6 +18,7 @@ type Message struct {
 	From          string
 	Subject       string
 	Body          string
+	PlainBody     string
 	Info          string
 	ReplyTo       []string
 	EmbeddedFiles []string
@@ -56,7 +56,12 @@ func (ns *NotificationService) dialAndSend(messages ...*Message) (num int, err e
 			m.SetAddressHeader("Reply-To", replyTo, "")
 		}
 
-		m.SetBody("text/html", msg.Body)
+		if msg.PlainBody != "" {
+			m.SetBody("text/plain", msg.PlainBody)
+			m.AddAlternative("text/html", msg.Body)
+		} else {
+			m.SetBody("text/html", msg.Body)
+		}
 
 		if e := dialer.DialAndSend(m); e != nil {
 			err = errutil.Wrapf(e, "Failed to send notification to email addresses: %s", strings.Join(msg.To, ";"))
@@ -138,42 +143,23 @@ func (ns *NotificationService) buildEmailMessage(cmd *models.SendEmailCommand) (
 		return nil, models.ErrSmtpNotEnabled
 	}
 
-	var buffer bytes.Buffer
-	var err error
-
 	data := cmd.Data
 	if data == nil {
 		data = make(map[string]interface{}, 10)
 	}
 
 	setDefaultTemplateData(data, nil)
-	err = mailTemplates.ExecuteTemplate(&buffer, cmd.Template, data)
+	body, plainBody, err := ns.renderTemplate(cmd.OrgId, cmd.Template, data)
 	if err != nil {
 		return nil, err
 	}
 
 	subject := cmd.Subject
 	if cmd.Subject == "" {
-		var subjectText interface{}
-		subjectData := data["Subject"].(map[string]interface{})
-		subjectText, hasSubject := subjectData["value"]
-
-		if !hasSubject {
-			return nil, fmt.Errorf("missing subject in template %s", cmd.Template)
-		}
-
-		subjectTmpl, err := template.New("subject").Parse(subjectText.(string))
+		subject, err = renderSubject(cmd.Template, data)
 		if err != nil {
 			return nil, err
 		}
-
-		var subjectBuffer bytes.Buffer
-		err = subjectTmpl.ExecuteTemplate(&subjectBuffer, "subject", data)
-		if err != nil {
-			return nil, err
-		}
-
-		subject = subjectBuffer.String()
 	}
 
 	addr := mail.Address{Name: ns.Cfg.Smtp.FromName, Address: ns.Cfg.Smtp.FromAddress}
@@ -182,7 +168,8 @@ func (ns *NotificationService) buildEmailMessage(cmd *models.SendEmailCommand) (
 		SingleEmail:   cmd.SingleEmail,
 		From:          addr.String(),
 		Subject:       subject,
-		Body:          buffer.String(),
+		Body:          body,
+		PlainBody:     plainBody,
 		EmbeddedFiles: cmd.EmbeddedFiles,
 		AttachedFiles: buildAttachedFiles(cmd.AttachedFiles),
 		ReplyTo:       cmd.ReplyTo,
@@ -204,3 +191,26 @@ func buildAttachedFiles(
 
 	return result
 }
+
+// renderSubject renders the subject that the template set with the Subject function.
+func renderSubject(name string, data map[string]interface{}) (string, error) {
+	subjectData := data["Subject"].(map[string]interface{})
+	subjectText, hasSubject := subjectData["value"]
+
+	if !hasSubject {
+		return "", fmt.Errorf("missing subject in template %s", name)
+	}
+
+	subjectTmpl, err := template.New("subject").Parse(subjectText.(string))
+	if err != nil {
+		return "", err
+	}
+
+	var subjectBuffer bytes.Buffer
+	err = subjectTmpl.ExecuteTemplate(&subjectBuffer, "subject", data)
+	if err != nil {
+		return "", err
+	}
+
+	return subjectBuffer.String(), nil
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"html/template"
+	"net/http"
 	"net/url"
 	"path/filepath"
 	"strings"
@@ -31,9 +32,10 @@ type NotificationService struct {
 	Bus bus.Bus      `inject:""`
 	Cfg *setting.Cfg `inject:""`
 
-	mailQueue    chan *Message
-	webhookQueue chan *Webhook
-	log          log.Logger
+	mailQueue         chan *Message
+	webhookQueue      chan *Webhook
+	templateOverrides *templateOverrides
+	log               log.Logger
 }
 
 func (ns *NotificationService) Init() error {
@@ -44,6 +46,8 @@ func (ns *NotificationService) Init() error {
 	ns.Bus.AddHandler(ns.sendResetPasswordEmail)
 	ns.Bus.AddHandler(ns.validateResetPasswordCode)
 	ns.Bus.AddHandler(ns.sendEmailCommandHandler)
+	ns.Bus.AddHandler(ns.getEmailTemplates)
+	ns.Bus.AddHandler(ns.renderEmailPreview)
 
 	ns.Bus.AddHandlerCtx(ns.sendEmailCommandHandlerSync)
 	ns.Bus.AddHandlerCtx(ns.SendWebhookSync)
@@ -62,6 +66,8 @@ func (ns *NotificationService) Init() error {
 		return err
 	}
 
+	ns.templateOverrides = newTemplateOverrides(ns.Cfg.Smtp.TemplatesOverridePath)
+
 	if !util.IsEmail(ns.Cfg.Smtp.FromAddress) {
 		return errors.New("invalid email address for SMTP from_address config")
 	}
@@ -101,6 +107,10 @@ func (ns *NotificationService) Run(ctx context.Context) error {
 }
 
 func (ns *NotificationService) SendWebhookSync(ctx context.Context, cmd *models.SendWebhookSync) error {
+	if RecordDryRun(ctx, dryRunWebhookTarget(cmd), cmd.Body) {
+		return nil
+	}
+
 	return ns.sendWebRequestSync(ctx, &Webhook{
 		Url:         cmd.Url,
 		User:        cmd.User,
@@ -112,6 +122,22 @@ func (ns *NotificationService) SendWebhookSync(ctx context.Context, cmd *models.
 	})
 }
 
+// dryRunWebhookTarget describes the webhook destination without its path
+// and query, since many services embed credentials in those.
+func dryRunWebhookTarget(cmd *models.SendWebhookSync) string {
+	method := cmd.HttpMethod
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	u, err := url.Parse(cmd.Url)
+	if err != nil {
+		return method
+	}
+
+	return method + " " + u.Scheme + "://" + u.Host
+}
+
 func subjectTemplateFunc(obj map[string]interface{}, value string) string {
 	obj["value"] = value
 	return ""
@@ -119,6 +145,7 @@ func subjectTemplateFunc(obj map[string]interface{}, value string) string {
 
 func (ns *NotificationService) sendEmailCommandHandlerSync(ctx context.Context, cmd *models.SendEmailCommandSync) error {
 	message, err := ns.buildEmailMessage(&models.SendEmailCommand{
+		OrgId:         cmd.OrgId,
 		Data:          cmd.Data,
 		Info:          cmd.Info,
 		Template:      cmd.Template,
@@ -133,6 +160,10 @@ func (ns *NotificationService) sendEmailCommandHandlerSync(ctx context.Context,
 		return err
 	}
 
+	if RecordDryRun(ctx, "email: "+strings.Join(message.To, ", "), "Subject: "+message.Subject+"\n\n"+message.Body) {
+		return nil
+	}
+
 	_, err = ns.send(message)
 	return err
 }
@@ -154,6 +185,7 @@ func (ns *NotificationService) sendResetPasswordEmail(cmd *models.SendResetPassw
 		return err
 	}
 	return ns.sendEmailCommandHandler(&models.SendEmailCommand{
+		OrgId:    cmd.User.OrgId,
 		To:       []string{cmd.User.Email},
 		Template: tmplResetPassword,
 		Data: map[string]interface{}{
@@ -1,6 +1,7 @@
 package notifications
 
 import (
+	"context"
 	"testing"
 
 	"github.com/openinsight-project/grafinsight/pkg/bus"
@@ -33,5 +34,39 @@ func TestNotifications(t *testing.T) {
 			So(sentMsg.Subject, ShouldEqual, "Reset your Grafinsight password - asd@asd.com")
 			So(sentMsg.Body, ShouldNotContainSubstring, "Subject")
 		})
+
+		Convey("When sending a webhook in dry run", func() {
+			var target, payload string
+			ctx := WithDryRun(context.Background(), func(t, p string) {
+				target, payload = t, p
+			})
+
+			err := ns.SendWebhookSync(ctx, &models.SendWebhookSync{
+				Url:  "https://hooks.example.com/services/secret-token?key=secret",
+				Body: `{"text":"alert"}`,
+			})
+			So(err, ShouldBeNil)
+			So(target, ShouldEqual, "POST https://hooks.example.com")
+			So(payload, ShouldEqual, `{"text":"alert"}`)
+		})
+
+		Convey("When sending an alert email in dry run", func() {
+			var target, payload string
+			ctx := WithDryRun(context.Background(), func(t, p string) {
+				target, payload = t, p
+			})
+
+			err := ns.sendEmailCommandHandlerSync(ctx, &models.SendEmailCommandSync{
+				SendEmailCommand: models.SendEmailCommand{
+					To:       []string{"ops@example.com"},
+					Template: tmplResetPassword,
+					Data:     map[string]interface{}{"Code": "code", "Name": "ops"},
+				},
+			})
+			So(err, ShouldBeNil)
+			So(target, ShouldEqual, "email: ops@example.com")
+			So(payload, ShouldStartWith, "Subject: Reset your GrafInsight password")
+			So(ns.mailQueue, ShouldHaveLength, 0)
+		})
 	})
 }
@@ -0,0 +1,119 @@
+package notifications
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// blockElements start on a new line in the plain text version of an email.
+var blockElements = map[string]bool{
+	"p": true, "div": true, "table": true, "tr": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "ul": true, "ol": true,
+	"li": true, "blockquote": true, "hr": true,
+}
+
+// htmlToText derives the plain text alternative of an HTML email. Links keep
+// their target in parentheses so that they can still be followed.
+func htmlToText(s string) string {
+	var sb strings.Builder
+	z := html.NewTokenizer(strings.NewReader(s))
+	skip := 0
+	var hrefs []string
+
+	newline := func() {
+		str := sb.String()
+		if len(str) > 0 && !strings.HasSuffix(str, "\n") {
+			sb.WriteString("\n")
+		}
+	}
+
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return tidyText(sb.String())
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			tag := string(name)
+			switch {
+			case tag == "style" || tag == "script" || tag == "head" || tag == "title":
+				if tt == html.StartTagToken {
+					skip++
+				}
+			case tag == "br":
+				sb.WriteString("\n")
+			case tag == "a":
+				href := ""
+				for hasAttr {
+					var key, val []byte
+					key, val, hasAttr = z.TagAttr()
+					if string(key) == "href" {
+						href = string(val)
+					}
+				}
+				hrefs = append(hrefs, href)
+			case blockElements[tag]:
+				newline()
+				if tag == "li" {
+					sb.WriteString("- ")
+				}
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			tag := string(name)
+			switch {
+			case tag == "style" || tag == "script" || tag == "head" || tag == "title":
+				if skip > 0 {
+					skip--
+				}
+			case tag == "a" && len(hrefs) > 0:
+				href := hrefs[len(hrefs)-1]
+				hrefs = hrefs[:len(hrefs)-1]
+				if href != "" && !strings.HasPrefix(href, "#") && !strings.Contains(sb.String()[lastLineStart(sb.String()):], href) {
+					sb.WriteString(" (" + href + ")")
+				}
+			case blockElements[tag] || tag == "td":
+				newline()
+			}
+		case html.TextToken:
+			if skip > 0 {
+				continue
+			}
+			text := strings.Join(strings.Fields(string(z.Text())), " ")
+			if text == "" {
+				continue
+			}
+			str := sb.String()
+			if len(str) > 0 && !strings.HasSuffix(str, "\n") && !strings.HasSuffix(str, " ") {
+				sb.WriteString(" ")
+			}
+			sb.WriteString(text)
+		}
+	}
+}
+
+func lastLineStart(s string) int {
+	return strings.LastIndex(s, "\n") + 1
+}
+
+// tidyText trims every line and collapses runs of empty lines.
+func tidyText(s string) string {
+	lines := strings.Split(s, "\n")
+	out := make([]string, 0, len(lines))
+	blank := false
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if !blank && len(out) > 0 {
+				out = append(out, "")
+			}
+			blank = true
+			continue
+		}
+		blank = false
+		out = append(out, line)
+	}
+
+	return strings.TrimSpace(strings.Join(out, "\n")) + "\n"
+}
@@ -0,0 +1,109 @@
+package notifications
+
+import (
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/setting"
+)
+
+// sampleTemplateData returns the data used to preview a template. It mirrors
+// the data the template is rendered with when the email is sent.
+func sampleTemplateData(name string) map[string]interface{} {
+	switch name {
+	case "alert_notification.html":
+		return map[string]interface{}{
+			"Title":          "[Alerting] Sample alert",
+			"State":          models.AlertStateAlerting,
+			"Name":           "Sample alert",
+			"Message":        "CPU usage is above 90% on one or more servers.",
+			"Error":          "",
+			"RuleUrl":        setting.AppUrl + "d/sample?viewPanel=1",
+			"ImageLink":      "",
+			"EmbeddedImage":  "",
+			"AlertPageUrl":   setting.AppUrl + "alerting",
+			"AcknowledgeUrl": setting.AppUrl + "api/alerts/acknowledge/sample",
+			"EvalMatches": []map[string]interface{}{
+				{"Metric": "server-1", "Value": 95.3},
+				{"Metric": "server-2", "Value": 91.7},
+			},
+		}
+	case "new_user_invite.html":
+		return map[string]interface{}{
+			"Name":      "Jane Doe",
+			"OrgName":   "Sample org",
+			"Email":     "admin@example.com",
+			"LinkUrl":   setting.AppUrl + "invite/sample",
+			"InvitedBy": "Admin",
+		}
+	case "invited_to_org.html":
+		return map[string]interface{}{
+			"Name":      "Jane Doe",
+			"OrgName":   "Sample org",
+			"InvitedBy": "Admin",
+		}
+	case "reset_password.html":
+		return map[string]interface{}{
+			"Name": "Jane Doe",
+			"Code": "sample-code",
+		}
+	case "signup_started.html":
+		return map[string]interface{}{
+			"Email":     "jane@example.com",
+			"Code":      "sample-code",
+			"SignUpUrl": setting.AppUrl + "signup/?email=jane%40example.com&code=sample-code",
+		}
+	case "welcome_on_signup.html":
+		return map[string]interface{}{
+			"Name": "Jane Doe",
+		}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+func (ns *NotificationService) getEmailTemplates(query *models.GetEmailTemplatesQuery) error {
+	result := make([]*models.EmailTemplate, 0)
+	for _, name := range templateNames() {
+		htmlOverride, err := ns.templateOverrides.lookup(query.OrgId, name)
+		if err != nil {
+			ns.log.Warn("Failed to load email template override", "template", name, "error", err)
+		}
+		textOverride, err := ns.templateOverrides.lookup(query.OrgId, textTemplateName(name))
+		if err != nil {
+			ns.log.Warn("Failed to load email template override", "template", textTemplateName(name), "error", err)
+		}
+
+		result = append(result, &models.EmailTemplate{
+			Name:           name,
+			Overridden:     htmlOverride != nil,
+			TextOverridden: textOverride != nil,
+		})
+	}
+
+	query.Result = result
+	return nil
+}
+
+func (ns *NotificationService) renderEmailPreview(query *models.RenderEmailPreviewQuery) error {
+	data := sampleTemplateData(query.Template)
+	for k, v := range query.Data {
+		data[k] = v
+	}
+	setDefaultTemplateData(data, nil)
+
+	html, text, err := ns.renderTemplate(query.OrgId, query.Template, data)
+	if err != nil {
+		return err
+	}
+
+	subject, err := renderSubject(query.Template, data)
+	if err != nil {
+		return err
+	}
+
+	query.Result = &models.EmailPreview{
+		Subject: subject,
+		Html:    html,
+		Text:    text,
+	}
+	return nil
+}
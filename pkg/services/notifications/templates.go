@@ -0,0 +1,178 @@
+package notifications
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	ttemplate "text/template"
+	"time"
+
+	"github.com/openinsight-project/grafinsight/pkg/models"
+)
+
+// templateOverrides loads email templates from the override directory. Org
+// specific templates live in a sub directory named after the org id and take
+// precedence over templates in the directory itself. Files are parsed on first
+// use and parsed again whenever they change on disk.
+type templateOverrides struct {
+	dir string
+
+	mu    sync.Mutex
+	cache map[string]*overrideTemplate
+}
+
+type overrideTemplate struct {
+	modTime time.Time
+	size    int64
+	tmpl    executor
+}
+
+// executor is implemented by both html/template and text/template.
+type executor interface {
+	Execute(wr io.Writer, data interface{}) error
+}
+
+func newTemplateOverrides(dir string) *templateOverrides {
+	return &templateOverrides{
+		dir:   dir,
+		cache: map[string]*overrideTemplate{},
+	}
+}
+
+// lookup returns the override for the template, or nil if there is none.
+func (o *templateOverrides) lookup(orgID int64, name string) (executor, error) {
+	if o == nil || o.dir == "" {
+		return nil, nil
+	}
+
+	paths := []string{filepath.Join(o.dir, name)}
+	if orgID != 0 {
+		paths = append([]string{filepath.Join(o.dir, strconv.FormatInt(orgID, 10), name)}, paths...)
+	}
+
+	for _, path := range paths {
+		tmpl, err := o.load(path)
+		if err != nil || tmpl != nil {
+			return tmpl, err
+		}
+	}
+
+	return nil, nil
+}
+
+func (o *templateOverrides) load(path string) (executor, error) {
+	fi, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if cached, ok := o.cache[path]; ok && cached.modTime.Equal(fi.ModTime()) && cached.size == fi.Size() {
+		return cached.tmpl, nil
+	}
+
+	// We can ignore the gosec G304 warning on this one because `path` is built
+	// from the configured override directory and a known template name.
+	// nolint:gosec
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tmpl executor
+	if filepath.Ext(path) == ".txt" {
+		t, err := ttemplate.New(filepath.Base(path)).Funcs(ttemplate.FuncMap{"Subject": noopSubjectTemplateFunc}).Parse(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse email template %s: %w", path, err)
+		}
+		tmpl = t
+	} else {
+		t, err := template.New(filepath.Base(path)).Funcs(template.FuncMap{"Subject": subjectTemplateFunc}).Parse(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse email template %s: %w", path, err)
+		}
+		tmpl = t
+	}
+
+	o.cache[path] = &overrideTemplate{modTime: fi.ModTime(), size: fi.Size(), tmpl: tmpl}
+	return tmpl, nil
+}
+
+// noopSubjectTemplateFunc lets plain text templates call Subject while
+// the subject is always taken from the HTML template.
+func noopSubjectTemplateFunc(obj map[string]interface{}, value string) string {
+	return ""
+}
+
+// textTemplateName returns the name of the plain text alternative of an HTML template.
+func textTemplateName(name string) string {
+	return strings.TrimSuffix(name, filepath.Ext(name)) + ".txt"
+}
+
+// isValidTemplateName rejects template names that would escape the template directories.
+func isValidTemplateName(name string) bool {
+	return name != "" && filepath.Base(name) == name && !strings.HasPrefix(name, ".")
+}
+
+// templateNames returns the names of the built-in HTML email templates.
+func templateNames() []string {
+	names := []string{}
+	for _, t := range mailTemplates.Templates() {
+		if filepath.Ext(t.Name()) == ".html" {
+			names = append(names, t.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// renderTemplate renders the HTML template and its plain text alternative
+// for the org. The plain text part is rendered from a .txt override when one
+// exists and is otherwise derived from the HTML part.
+func (ns *NotificationService) renderTemplate(orgID int64, name string, data map[string]interface{}) (string, string, error) {
+	if !isValidTemplateName(name) || mailTemplates.Lookup(name) == nil {
+		return "", "", models.ErrEmailTemplateNotFound
+	}
+
+	var htmlBuf bytes.Buffer
+	override, err := ns.templateOverrides.lookup(orgID, name)
+	if err != nil {
+		ns.log.Warn("Failed to load email template override, using built-in template", "template", name, "error", err)
+	}
+	if override != nil {
+		err = override.Execute(&htmlBuf, data)
+	} else {
+		err = mailTemplates.ExecuteTemplate(&htmlBuf, name, data)
+	}
+	if err != nil {
+		return "", "", err
+	}
+
+	textOverride, err := ns.templateOverrides.lookup(orgID, textTemplateName(name))
+	if err != nil {
+		ns.log.Warn("Failed to load email template override, using generated plain text", "template", textTemplateName(name), "error", err)
+	}
+	if textOverride == nil {
+		return htmlBuf.String(), htmlToText(htmlBuf.String()), nil
+	}
+
+	var textBuf bytes.Buffer
+	if err := textOverride.Execute(&textBuf, data); err != nil {
+		return "", "", err
+	}
+
+	return htmlBuf.String(), textBuf.String(), nil
+}
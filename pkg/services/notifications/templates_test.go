@@ -0,0 +1,138 @@
+package notifications
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/openinsight-project/grafinsight/pkg/bus"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/setting"
+)
+
+func newTestNotificationService(t *testing.T, overridePath string) *NotificationService {
+	t.Helper()
+	setting.StaticRootPath = "../../../public/"
+
+	ns := &NotificationService{}
+	ns.Bus = bus.New()
+	ns.Cfg = setting.NewCfg()
+	ns.Cfg.Smtp.Enabled = true
+	ns.Cfg.Smtp.TemplatesPattern = "emails/*.html"
+	ns.Cfg.Smtp.FromAddress = "from@address.com"
+	ns.Cfg.Smtp.TemplatesOverridePath = overridePath
+
+	require.NoError(t, ns.Init())
+	return ns
+}
+
+func writeTemplate(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestEmailTemplateOverrides(t *testing.T) {
+	dir := t.TempDir()
+	ns := newTestNotificationService(t, dir)
+	now := time.Now()
+
+	writeTemplate(t, filepath.Join(dir, "welcome_on_signup.html"), `{{Subject .Subject "Welcome"}}<p>Hello from everyone, {{.Name}}</p>`, now)
+	writeTemplate(t, filepath.Join(dir, "2", "welcome_on_signup.html"), `{{Subject .Subject "Welcome to org 2"}}<p>Hello from org 2, {{.Name}}</p>`, now)
+
+	t.Run("built-in template should be used without override", func(t *testing.T) {
+		msg, err := ns.buildEmailMessage(&models.SendEmailCommand{
+			OrgId:    1,
+			To:       []string{"asd@asd.com"},
+			Template: "reset_password.html",
+			Data:     map[string]interface{}{"Name": "Jane", "Code": "code"},
+		})
+		require.NoError(t, err)
+		assert.Contains(t, msg.Body, "<html")
+		assert.NotContains(t, msg.PlainBody, "<")
+		assert.Contains(t, msg.PlainBody, "Jane")
+	})
+
+	t.Run("org override should take precedence over global override", func(t *testing.T) {
+		msg, err := ns.buildEmailMessage(&models.SendEmailCommand{
+			OrgId:    2,
+			To:       []string{"asd@asd.com"},
+			Template: "welcome_on_signup.html",
+			Data:     map[string]interface{}{"Name": "Jane"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "Welcome to org 2", msg.Subject)
+		assert.Equal(t, "<p>Hello from org 2, Jane</p>", msg.Body)
+		assert.Equal(t, "Hello from org 2, Jane\n", msg.PlainBody)
+
+		msg, err = ns.buildEmailMessage(&models.SendEmailCommand{
+			OrgId:    3,
+			To:       []string{"asd@asd.com"},
+			Template: "welcome_on_signup.html",
+			Data:     map[string]interface{}{"Name": "Jane"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "Welcome", msg.Subject)
+	})
+
+	t.Run("changed override should be reloaded", func(t *testing.T) {
+		writeTemplate(t, filepath.Join(dir, "2", "welcome_on_signup.html"), `{{Subject .Subject "Reloaded"}}<p>Reloaded</p>`, now.Add(time.Minute))
+		writeTemplate(t, filepath.Join(dir, "2", "welcome_on_signup.txt"), `{{Subject .Subject "Ignored"}}Plain {{.Name}}`, now)
+
+		msg, err := ns.buildEmailMessage(&models.SendEmailCommand{
+			OrgId:    2,
+			To:       []string{"asd@asd.com"},
+			Template: "welcome_on_signup.html",
+			Data:     map[string]interface{}{"Name": "<Jane>"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "Reloaded", msg.Subject)
+		assert.Equal(t, "<p>Reloaded</p>", msg.Body)
+		assert.Equal(t, "Plain <Jane>", msg.PlainBody)
+	})
+
+	t.Run("preview should render template with sample data", func(t *testing.T) {
+		query := &models.RenderEmailPreviewQuery{OrgId: 1, Template: "alert_notification.html"}
+		require.NoError(t, ns.renderEmailPreview(query))
+		assert.Contains(t, query.Result.Html, "server-1")
+		assert.Contains(t, query.Result.Text, "Sample alert")
+
+		query = &models.RenderEmailPreviewQuery{OrgId: 2, Template: "welcome_on_signup.html", Data: map[string]interface{}{"Name": "Joe"}}
+		require.NoError(t, ns.renderEmailPreview(query))
+		assert.Equal(t, "Plain Joe", query.Result.Text)
+
+		query = &models.RenderEmailPreviewQuery{OrgId: 1, Template: "../secret.html"}
+		require.ErrorIs(t, ns.renderEmailPreview(query), models.ErrEmailTemplateNotFound)
+	})
+
+	t.Run("template list should flag overrides", func(t *testing.T) {
+		query := &models.GetEmailTemplatesQuery{OrgId: 2}
+		require.NoError(t, ns.getEmailTemplates(query))
+
+		byName := map[string]*models.EmailTemplate{}
+		for _, tmpl := range query.Result {
+			byName[tmpl.Name] = tmpl
+		}
+		require.Contains(t, byName, "welcome_on_signup.html")
+		assert.True(t, byName["welcome_on_signup.html"].Overridden)
+		assert.True(t, byName["welcome_on_signup.html"].TextOverridden)
+		assert.False(t, byName["reset_password.html"].Overridden)
+	})
+}
+
+func TestHTMLToText(t *testing.T) {
+	html := `<html><head><style>p { color: red; }</style></head><body>
+		<h1>Title</h1>
+		<p>First   line<br>second line</p>
+		<a href="https://example.com/reset">Reset password</a>
+		<ul><li>one</li><li>two &amp; three</li></ul>
+	</body></html>`
+
+	assert.Equal(t, "Title\nFirst line\nsecond line\nReset password (https://example.com/reset)\n- one\n- two & three\n", htmlToText(html))
+}
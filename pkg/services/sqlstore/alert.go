@@ -84,6 +84,7 @@ func HandleAlertsQuery(query *models.GetAlertsQuery) error {
 		alert.eval_data,
 		alert.eval_date,
 		alert.execution_error,
+		alert.flapping,
 		dashboard.uid as dashboard_uid,
 		dashboard.slug as dashboard_slug
 		FROM alert
@@ -23,6 +23,11 @@ func init() {
 	bus.AddHandlerCtx("sql", GetOrCreateAlertNotificationState)
 	bus.AddHandlerCtx("sql", SetAlertNotificationStateToCompleteCommand)
 	bus.AddHandlerCtx("sql", SetAlertNotificationStateToPendingCommand)
+	bus.AddHandlerCtx("sql", ScheduleAlertNotificationEscalation)
+	bus.AddHandlerCtx("sql", SetAlertNotificationStateToEscalated)
+	bus.AddHandlerCtx("sql", ResetAlertNotificationEscalations)
+	bus.AddHandlerCtx("sql", AcknowledgeAlertNotifications)
+	bus.AddHandlerCtx("sql", GetDueAlertNotificationEscalations)
 
 	bus.AddHandler("sql", GetAlertNotificationsWithUid)
 	bus.AddHandler("sql", UpdateAlertNotificationWithUid)
@@ -545,6 +550,99 @@ func SetAlertNotificationStateToPendingCommand(ctx context.Context, cmd *models.
 	})
 }
 
+func ScheduleAlertNotificationEscalation(ctx context.Context, cmd *models.ScheduleAlertNotificationEscalationCommand) error {
+	return withDbSession(ctx, x, func(sess *DBSession) error {
+		// Only schedule an escalation once per incident, and never for an
+		// incident that has already been acknowledged.
+		sql := `UPDATE alert_notification_state SET
+			escalation_due_at = ?
+		WHERE
+			id = ? AND
+			escalation_due_at = 0 AND
+			escalated_at = 0 AND
+			acknowledged_at = 0`
+
+		_, err := sess.Exec(sql, cmd.DueAt, cmd.Id)
+		return err
+	})
+}
+
+func SetAlertNotificationStateToEscalated(ctx context.Context, cmd *models.SetAlertNotificationStateToEscalatedCommand) error {
+	return withDbSession(ctx, x, func(sess *DBSession) error {
+		sql := `UPDATE alert_notification_state SET
+			escalated_at = ?
+		WHERE
+			id = ? AND
+			escalated_at = 0`
+
+		res, err := sess.Exec(sql, timeNow().Unix(), cmd.Id)
+		if err != nil {
+			return err
+		}
+
+		affected, _ := res.RowsAffected()
+		if affected == 0 {
+			return models.ErrAlertNotificationAlreadyEscalated
+		}
+
+		return nil
+	})
+}
+
+func ResetAlertNotificationEscalations(ctx context.Context, cmd *models.ResetAlertNotificationEscalationsCommand) error {
+	return withDbSession(ctx, x, func(sess *DBSession) error {
+		sql := `UPDATE alert_notification_state SET
+			escalation_due_at = 0,
+			escalated_at = 0,
+			acknowledged_at = 0,
+			acknowledged_by = 0
+		WHERE
+			org_id = ? AND
+			alert_id = ?`
+
+		_, err := sess.Exec(sql, cmd.OrgId, cmd.AlertId)
+		return err
+	})
+}
+
+func AcknowledgeAlertNotifications(ctx context.Context, cmd *models.AcknowledgeAlertNotificationsCommand) error {
+	return withDbSession(ctx, x, func(sess *DBSession) error {
+		sql := `UPDATE alert_notification_state SET
+			acknowledged_at = ?,
+			acknowledged_by = ?
+		WHERE
+			org_id = ? AND
+			alert_id = ? AND
+			acknowledged_at = 0`
+
+		_, err := sess.Exec(sql, timeNow().Unix(), cmd.UserId, cmd.OrgId, cmd.AlertId)
+		return err
+	})
+}
+
+func GetDueAlertNotificationEscalations(ctx context.Context, query *models.GetDueAlertNotificationEscalationsQuery) error {
+	return withDbSession(ctx, x, func(sess *DBSession) error {
+		// An acknowledgement on any of the alert's notification states
+		// stops the whole escalation chain.
+		acknowledged, err := sess.
+			Where("org_id = ? AND alert_id = ? AND acknowledged_at > 0", query.OrgId, query.AlertId).
+			Count(&models.AlertNotificationState{})
+		if err != nil {
+			return err
+		}
+
+		query.Result = make([]*models.AlertNotificationState, 0)
+		if acknowledged > 0 {
+			return nil
+		}
+
+		return sess.
+			Where("org_id = ? AND alert_id = ?", query.OrgId, query.AlertId).
+			Where("escalation_due_at > 0 AND escalation_due_at <= ? AND escalated_at = 0", query.Now).
+			Find(&query.Result)
+	})
+}
+
 func GetOrCreateAlertNotificationState(ctx context.Context, cmd *models.GetOrCreateNotificationStateQuery) error {
 	return inTransactionCtx(ctx, func(sess *DBSession) error {
 		nj := &models.AlertNotificationState{}
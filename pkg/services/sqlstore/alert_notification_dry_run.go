@@ -0,0 +1,66 @@
+package sqlstore
+
+import (
+	"context"
+
+	"github.com/openinsight-project/grafinsight/pkg/bus"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+)
+
+const defaultAlertNotificationDryRunLimit = 100
+
+func init() {
+	bus.AddHandlerCtx("sql", SaveAlertNotificationDryRun)
+	bus.AddHandlerCtx("sql", GetAlertNotificationDryRuns)
+	bus.AddHandler("sql", DeleteOldAlertNotificationDryRuns)
+}
+
+func SaveAlertNotificationDryRun(ctx context.Context, cmd *models.SaveAlertNotificationDryRunCommand) error {
+	return withDbSession(ctx, x, func(sess *DBSession) error {
+		if cmd.Entry.Created == 0 {
+			cmd.Entry.Created = timeNow().Unix()
+		}
+
+		_, err := sess.Insert(cmd.Entry)
+		return err
+	})
+}
+
+func GetAlertNotificationDryRuns(ctx context.Context, query *models.GetAlertNotificationDryRunsQuery) error {
+	return withDbSession(ctx, x, func(sess *DBSession) error {
+		sess.Where("org_id = ?", query.OrgId)
+
+		if query.AlertId != 0 {
+			sess.And("alert_id = ?", query.AlertId)
+		}
+		if query.NotifierUid != "" {
+			sess.And("notifier_uid = ?", query.NotifierUid)
+		}
+		if query.From > 0 {
+			sess.And("created >= ?", query.From)
+		}
+		if query.To > 0 {
+			sess.And("created <= ?", query.To)
+		}
+
+		limit := query.Limit
+		if limit <= 0 {
+			limit = defaultAlertNotificationDryRunLimit
+		}
+
+		query.Result = make([]*models.AlertNotificationDryRun, 0)
+		return sess.Desc("created").Desc("id").Limit(int(limit)).Find(&query.Result)
+	})
+}
+
+func DeleteOldAlertNotificationDryRuns(cmd *models.DeleteOldAlertNotificationDryRunsCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		result, err := sess.Exec("DELETE FROM alert_notification_dry_run WHERE created < ?", cmd.OlderThan.Unix())
+		if err != nil {
+			return err
+		}
+
+		cmd.DeletedRows, err = result.RowsAffected()
+		return err
+	})
+}
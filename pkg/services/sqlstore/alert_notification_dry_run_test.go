@@ -0,0 +1,63 @@
+// +build integration
+
+package sqlstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/openinsight-project/grafinsight/pkg/models"
+)
+
+func TestAlertNotificationDryRunSQLAccess(t *testing.T) {
+	InitTestDB(t)
+
+	for i, alertID := range []int64{1, 1, 2} {
+		err := SaveAlertNotificationDryRun(context.Background(), &models.SaveAlertNotificationDryRunCommand{
+			Entry: &models.AlertNotificationDryRun{
+				OrgId:        1,
+				AlertId:      alertID,
+				AlertName:    "alert",
+				State:        models.AlertStateAlerting,
+				NotifierUid:  "ops",
+				NotifierType: "webhook",
+				Payload:      "{}",
+				Created:      int64(1000 + i),
+			},
+		})
+		require.NoError(t, err)
+	}
+
+	t.Run("should return newest entries for org", func(t *testing.T) {
+		query := &models.GetAlertNotificationDryRunsQuery{OrgId: 1}
+		err := GetAlertNotificationDryRuns(context.Background(), query)
+		require.NoError(t, err)
+		require.Len(t, query.Result, 3)
+		require.Equal(t, int64(1002), query.Result[0].Created)
+	})
+
+	t.Run("should filter by alert and limit", func(t *testing.T) {
+		query := &models.GetAlertNotificationDryRunsQuery{OrgId: 1, AlertId: 1, Limit: 1}
+		err := GetAlertNotificationDryRuns(context.Background(), query)
+		require.NoError(t, err)
+		require.Len(t, query.Result, 1)
+		require.Equal(t, int64(1001), query.Result[0].Created)
+	})
+
+	t.Run("should not return entries of other orgs", func(t *testing.T) {
+		query := &models.GetAlertNotificationDryRunsQuery{OrgId: 2}
+		err := GetAlertNotificationDryRuns(context.Background(), query)
+		require.NoError(t, err)
+		require.Len(t, query.Result, 0)
+	})
+
+	t.Run("should delete old entries", func(t *testing.T) {
+		cmd := &models.DeleteOldAlertNotificationDryRunsCommand{OlderThan: time.Unix(1002, 0)}
+		err := DeleteOldAlertNotificationDryRuns(cmd)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), cmd.DeletedRows)
+	})
+}
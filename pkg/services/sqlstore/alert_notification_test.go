@@ -136,6 +136,57 @@ func TestAlertNotificationSQLAccess(t *testing.T) {
 					err := SetAlertNotificationStateToPendingCommand(context.Background(), &cmd)
 					So(err, ShouldNotBeNil)
 				})
+
+				Convey("Scheduled escalation should be returned once due", func() {
+					s := *query.Result
+					dueAt := now.Add(10 * time.Minute).Unix()
+					err := ScheduleAlertNotificationEscalation(context.Background(), &models.ScheduleAlertNotificationEscalationCommand{Id: s.Id, DueAt: dueAt})
+					So(err, ShouldBeNil)
+
+					due := &models.GetDueAlertNotificationEscalationsQuery{OrgId: orgID, AlertId: alertID, Now: now.Unix()}
+					err = GetDueAlertNotificationEscalations(context.Background(), due)
+					So(err, ShouldBeNil)
+					So(due.Result, ShouldHaveLength, 0)
+
+					due.Now = dueAt
+					err = GetDueAlertNotificationEscalations(context.Background(), due)
+					So(err, ShouldBeNil)
+					So(due.Result, ShouldHaveLength, 1)
+					So(due.Result[0].Id, ShouldEqual, s.Id)
+
+					Convey("Escalating twice should return already escalated error", func() {
+						err := SetAlertNotificationStateToEscalated(context.Background(), &models.SetAlertNotificationStateToEscalatedCommand{Id: s.Id})
+						So(err, ShouldBeNil)
+
+						err = SetAlertNotificationStateToEscalated(context.Background(), &models.SetAlertNotificationStateToEscalatedCommand{Id: s.Id})
+						So(err, ShouldEqual, models.ErrAlertNotificationAlreadyEscalated)
+
+						err = GetDueAlertNotificationEscalations(context.Background(), due)
+						So(err, ShouldBeNil)
+						So(due.Result, ShouldHaveLength, 0)
+					})
+
+					Convey("Acknowledged alert should not be escalated", func() {
+						err := AcknowledgeAlertNotifications(context.Background(), &models.AcknowledgeAlertNotificationsCommand{OrgId: orgID, AlertId: alertID, UserId: 3})
+						So(err, ShouldBeNil)
+
+						err = GetDueAlertNotificationEscalations(context.Background(), due)
+						So(err, ShouldBeNil)
+						So(due.Result, ShouldHaveLength, 0)
+
+						Convey("Reset should clear the acknowledgement", func() {
+							err := ResetAlertNotificationEscalations(context.Background(), &models.ResetAlertNotificationEscalationsCommand{OrgId: orgID, AlertId: alertID})
+							So(err, ShouldBeNil)
+
+							query2 := &models.GetOrCreateNotificationStateQuery{AlertId: alertID, OrgId: orgID, NotifierId: notifierID}
+							err = GetOrCreateAlertNotificationState(context.Background(), query2)
+							So(err, ShouldBeNil)
+							So(query2.Result.EscalationDueAt, ShouldEqual, 0)
+							So(query2.Result.AcknowledgedAt, ShouldEqual, 0)
+							So(query2.Result.AcknowledgedBy, ShouldEqual, 0)
+						})
+					})
+				})
 			})
 
 			Reset(func() {
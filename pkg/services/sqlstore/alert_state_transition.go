@@ -0,0 +1,56 @@
+package sqlstore
+
+import (
+	"context"
+
+	"github.com/openinsight-project/grafinsight/pkg/bus"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+)
+
+func init() {
+	bus.AddHandlerCtx("sql", SaveAlertStateTransition)
+	bus.AddHandlerCtx("sql", GetAlertStateTransitions)
+	bus.AddHandlerCtx("sql", SetAlertFlapping)
+	bus.AddHandler("sql", DeleteOldAlertStateTransitions)
+}
+
+func SaveAlertStateTransition(ctx context.Context, cmd *models.SaveAlertStateTransitionCommand) error {
+	return withDbSession(ctx, x, func(sess *DBSession) error {
+		_, err := sess.Insert(&models.AlertStateTransition{
+			OrgId:     cmd.OrgId,
+			AlertId:   cmd.AlertId,
+			PrevState: cmd.PrevState,
+			NewState:  cmd.NewState,
+			Created:   timeNow().Unix(),
+		})
+		return err
+	})
+}
+
+func GetAlertStateTransitions(ctx context.Context, query *models.GetAlertStateTransitionsQuery) error {
+	return withDbSession(ctx, x, func(sess *DBSession) error {
+		query.Result = make([]*models.AlertStateTransition, 0)
+		return sess.Where("org_id = ? AND alert_id = ? AND created >= ?", query.OrgId, query.AlertId, query.From).
+			Desc("created").Desc("id").
+			Find(&query.Result)
+	})
+}
+
+func SetAlertFlapping(ctx context.Context, cmd *models.SetAlertFlappingCommand) error {
+	return withDbSession(ctx, x, func(sess *DBSession) error {
+		_, err := sess.Exec("UPDATE alert SET flapping = ? WHERE id = ? AND org_id = ?", cmd.Flapping, cmd.AlertId, cmd.OrgId)
+		return err
+	})
+}
+
+func DeleteOldAlertStateTransitions(cmd *models.DeleteOldAlertStateTransitionsCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		result, err := sess.Exec("DELETE FROM alert_state_transition WHERE created < ?", cmd.OlderThan.Unix())
+		if err != nil {
+			return err
+		}
+
+		cmd.DeletedRows, err = result.RowsAffected()
+		return err
+	})
+}
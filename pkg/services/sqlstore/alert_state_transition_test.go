@@ -0,0 +1,46 @@
+// +build integration
+
+package sqlstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/openinsight-project/grafinsight/pkg/models"
+)
+
+func TestAlertStateTransitionSQLAccess(t *testing.T) {
+	InitTestDB(t)
+
+	now := time.Unix(10000, 0)
+	t.Cleanup(func() { timeNow = time.Now })
+
+	for i, alertID := range []int64{1, 1, 1, 2} {
+		timeNow = func() time.Time { return now.Add(time.Duration(i) * time.Minute) }
+		err := SaveAlertStateTransition(context.Background(), &models.SaveAlertStateTransitionCommand{
+			OrgId:     1,
+			AlertId:   alertID,
+			PrevState: models.AlertStateOK,
+			NewState:  models.AlertStateAlerting,
+		})
+		require.NoError(t, err)
+	}
+
+	t.Run("should return transitions of the alert within the window, newest first", func(t *testing.T) {
+		query := &models.GetAlertStateTransitionsQuery{OrgId: 1, AlertId: 1, From: now.Add(time.Minute).Unix()}
+		err := GetAlertStateTransitions(context.Background(), query)
+		require.NoError(t, err)
+		require.Len(t, query.Result, 2)
+		require.Equal(t, now.Add(2*time.Minute).Unix(), query.Result[0].Created)
+	})
+
+	t.Run("should delete old transitions", func(t *testing.T) {
+		cmd := &models.DeleteOldAlertStateTransitionsCommand{OlderThan: now.Add(2 * time.Minute)}
+		err := DeleteOldAlertStateTransitions(cmd)
+		require.NoError(t, err)
+		require.Equal(t, int64(2), cmd.DeletedRows)
+	})
+}
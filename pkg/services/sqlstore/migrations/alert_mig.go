@@ -212,4 +212,69 @@ func addAlertMigrations(mg *Migrator) {
 	mg.AddMigration("Add non-unique index alert_rule_tag_alert_id", NewAddIndexMigration(alertRuleTagTable, &Index{
 		Cols: []string{"alert_id"}, Type: IndexType,
 	}))
+
+	mg.AddMigration("Add column escalation_due_at in alert_notification_state", NewAddColumnMigration(alert_notification_state, &Column{
+		Name: "escalation_due_at", Type: DB_BigInt, Nullable: false, Default: "0",
+	}))
+
+	mg.AddMigration("Add column escalated_at in alert_notification_state", NewAddColumnMigration(alert_notification_state, &Column{
+		Name: "escalated_at", Type: DB_BigInt, Nullable: false, Default: "0",
+	}))
+
+	mg.AddMigration("Add column acknowledged_at in alert_notification_state", NewAddColumnMigration(alert_notification_state, &Column{
+		Name: "acknowledged_at", Type: DB_BigInt, Nullable: false, Default: "0",
+	}))
+
+	mg.AddMigration("Add column acknowledged_by in alert_notification_state", NewAddColumnMigration(alert_notification_state, &Column{
+		Name: "acknowledged_by", Type: DB_BigInt, Nullable: false, Default: "0",
+	}))
+
+	alertNotificationDryRun := Table{
+		Name: "alert_notification_dry_run",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "alert_id", Type: DB_BigInt, Nullable: false},
+			{Name: "alert_name", Type: DB_NVarchar, Length: 255, Nullable: false},
+			{Name: "state", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "notifier_uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "notifier_type", Type: DB_NVarchar, Length: 255, Nullable: false},
+			{Name: "target", Type: DB_NVarchar, Length: 255, Nullable: false},
+			{Name: "payload", Type: DB_MediumText, Nullable: false},
+			{Name: "error", Type: DB_Text, Nullable: false},
+			{Name: "created", Type: DB_BigInt, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "created"}},
+			{Cols: []string{"org_id", "alert_id"}},
+		},
+	}
+
+	mg.AddMigration("create alert_notification_dry_run table v1", NewAddTableMigration(alertNotificationDryRun))
+	mg.AddMigration("add index alert_notification_dry_run org_id & created", NewAddIndexMigration(alertNotificationDryRun, alertNotificationDryRun.Indices[0]))
+	mg.AddMigration("add index alert_notification_dry_run org_id & alert_id", NewAddIndexMigration(alertNotificationDryRun, alertNotificationDryRun.Indices[1]))
+
+	mg.AddMigration("Add flapping to alert table", NewAddColumnMigration(alertV1, &Column{
+		Name: "flapping", Type: DB_Bool, Nullable: true, Default: "0",
+	}))
+
+	alertStateTransition := Table{
+		Name: "alert_state_transition",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "alert_id", Type: DB_BigInt, Nullable: false},
+			{Name: "prev_state", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "new_state", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "created", Type: DB_BigInt, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "alert_id", "created"}},
+			{Cols: []string{"created"}},
+		},
+	}
+
+	mg.AddMigration("create alert_state_transition table v1", NewAddTableMigration(alertStateTransition))
+	mg.AddMigration("add index alert_state_transition org_id & alert_id & created", NewAddIndexMigration(alertStateTransition, alertStateTransition.Indices[0]))
+	mg.AddMigration("add index alert_state_transition created", NewAddIndexMigration(alertStateTransition, alertStateTransition.Indices[1]))
 }
@@ -85,6 +85,7 @@ var (
 	DataProxyMaxIdleConns          int
 	DataProxyKeepAlive             int
 	DataProxyIdleConnTimeout       int
+	SQLiteDatasourcesPath          string
 	StaticRootPath                 string
 	EnableGzip                     bool
 	EnforceDomain                  bool
@@ -174,6 +175,10 @@ var (
 	AlertingNotificationTimeout time.Duration
 	AlertingMaxAttempts         int
 	AlertingMinInterval         int64
+	AlertingDryRunOrgs          []int64
+	AlertingDryRunLogMaxAge     time.Duration
+	AlertingFlappingWindow      time.Duration
+	AlertingFlappingThreshold   int
 
 	// Explore UI
 	ExploreEnabled bool
@@ -229,6 +234,12 @@ type Cfg struct {
 	// SMTP email settings
 	Smtp SmtpSettings
 
+	// Grafinsight Live
+	Live LiveSettings
+
+	// Data source query caching
+	QueryCaching QueryCachingSettings
+
 	// Rendering
 	ImagesDir                      string
 	RendererUrl                    string
@@ -925,6 +936,14 @@ func (cfg *Cfg) Load(args *CommandLineArgs) error {
 		ConnStr: connStr,
 	}
 
+	if err := cfg.readLiveSettings(); err != nil {
+		return err
+	}
+
+	if err := cfg.readQueryCachingSettings(); err != nil {
+		return err
+	}
+
 	cfg.readDateFormats()
 	cfg.readSentryConfig()
 
@@ -1296,9 +1315,42 @@ func readAlertingSettings(iniFile *ini.File) error {
 	AlertingMaxAttempts = alerting.Key("max_attempts").MustInt(3)
 	AlertingMinInterval = alerting.Key("min_interval_seconds").MustInt64(1)
 
+	AlertingDryRunOrgs = make([]int64, 0)
+	for _, value := range util.SplitString(alerting.Key("dry_run_orgs").String()) {
+		orgID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid org id %q in alerting dry_run_orgs: %w", value, err)
+		}
+		AlertingDryRunOrgs = append(AlertingDryRunOrgs, orgID)
+	}
+
+	dryRunLogMaxAge, err := gtime.ParseDuration(valueAsString(alerting, "dry_run_log_max_age", "7d"))
+	if err != nil {
+		return err
+	}
+	AlertingDryRunLogMaxAge = dryRunLogMaxAge
+
+	flappingWindow, err := gtime.ParseDuration(valueAsString(alerting, "flapping_window", "1h"))
+	if err != nil {
+		return err
+	}
+	AlertingFlappingWindow = flappingWindow
+	AlertingFlappingThreshold = alerting.Key("flapping_threshold").MustInt(0)
+
 	return nil
 }
 
+// IsAlertingDryRunOrg returns true if alert notifications for the org
+// should be logged instead of sent.
+func IsAlertingDryRunOrg(orgID int64) bool {
+	for _, id := range AlertingDryRunOrgs {
+		if id == orgID {
+			return true
+		}
+	}
+	return false
+}
+
 func readSnapshotsSettings(cfg *Cfg, iniFile *ini.File) error {
 	snapshots := iniFile.Section("snapshots")
 
@@ -1391,4 +1443,11 @@ func (cfg *Cfg) GetContentDeliveryURL(prefix string) string {
 func (cfg *Cfg) readDataSourcesSettings() {
 	datasources := cfg.Raw.Section("datasources")
 	cfg.DataSourceLimit = datasources.Key("datasource_limit").MustInt(5000)
+
+	// SQLite data sources can only read the files in this directory, and are
+	// disabled when it's not set
+	SQLiteDatasourcesPath = ""
+	if sqlitePath := valueAsString(datasources, "sqlite_path", ""); sqlitePath != "" {
+		SQLiteDatasourcesPath = makeAbsolute(sqlitePath, HomePath)
+	}
 }
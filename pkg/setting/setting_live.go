@@ -0,0 +1,124 @@
+package setting
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openinsight-project/grafinsight/pkg/components/gtime"
+)
+
+const (
+	LiveHAEngineMemory = "memory"
+	LiveHAEngineRedis  = "redis"
+
+	LiveTransportWebsocket = "websocket"
+	LiveTransportSSE       = "sse"
+)
+
+type LiveSettings struct {
+	// HAEngine is the engine used to share channels between GrafInsight
+	// instances, either "memory" (a single instance) or "redis".
+	HAEngine string
+	// HAEngineConnStr is the redis connection string, it defaults to the
+	// remote cache connection string when the remote cache uses redis.
+	HAEngineConnStr string
+
+	// Transport is used by browsers to connect to Live, either "websocket"
+	// or "sse" when proxies block websocket upgrades.
+	Transport string
+
+	// HistorySize and HistoryTTL are the default history of a channel.
+	HistorySize int
+	HistoryTTL  time.Duration
+	// ChannelHistory overrides the history for channels matching a prefix,
+	// ordered from the longest to the shortest prefix.
+	ChannelHistory []LiveChannelHistory
+}
+
+// LiveChannelHistory is the history kept for the channels starting with Prefix.
+type LiveChannelHistory struct {
+	Prefix string
+	Size   int
+	TTL    time.Duration
+}
+
+// HistoryFor returns the history size and TTL of a channel. The channel
+// does not include the org ID. A zero size means no history is kept.
+func (s LiveSettings) HistoryFor(channel string) (int, time.Duration) {
+	for _, h := range s.ChannelHistory {
+		if channel == h.Prefix || strings.HasPrefix(channel, strings.TrimSuffix(h.Prefix, "/")+"/") {
+			return h.Size, h.TTL
+		}
+	}
+	return s.HistorySize, s.HistoryTTL
+}
+
+func (cfg *Cfg) readLiveSettings() error {
+	sec := cfg.Raw.Section("live")
+	cfg.Live.HAEngine = valueAsString(sec, "ha_engine", LiveHAEngineMemory)
+	cfg.Live.HAEngineConnStr = valueAsString(sec, "ha_engine_connstr", "")
+
+	switch cfg.Live.HAEngine {
+	case LiveHAEngineMemory:
+	case LiveHAEngineRedis:
+		if cfg.Live.HAEngineConnStr == "" && cfg.RemoteCacheOptions.Name == LiveHAEngineRedis {
+			cfg.Live.HAEngineConnStr = cfg.RemoteCacheOptions.ConnStr
+		}
+		if cfg.Live.HAEngineConnStr == "" {
+			return fmt.Errorf("live ha_engine redis requires ha_engine_connstr or a redis remote_cache")
+		}
+	default:
+		return fmt.Errorf("unsupported live ha_engine %q", cfg.Live.HAEngine)
+	}
+
+	cfg.Live.Transport = valueAsString(sec, "transport", LiveTransportWebsocket)
+	if cfg.Live.Transport != LiveTransportWebsocket && cfg.Live.Transport != LiveTransportSSE {
+		return fmt.Errorf("unsupported live transport %q", cfg.Live.Transport)
+	}
+
+	cfg.Live.HistorySize = sec.Key("history_size").MustInt(10)
+	historyTTL, err := gtime.ParseDuration(valueAsString(sec, "history_ttl", "10m"))
+	if err != nil {
+		return fmt.Errorf("invalid live history_ttl: %w", err)
+	}
+	cfg.Live.HistoryTTL = historyTTL
+
+	cfg.Live.ChannelHistory = nil
+	for _, key := range cfg.Raw.Section("live.history").Keys() {
+		h, err := parseLiveChannelHistory(key.Name(), key.String(), historyTTL)
+		if err != nil {
+			return err
+		}
+		cfg.Live.ChannelHistory = append(cfg.Live.ChannelHistory, h)
+	}
+	sort.SliceStable(cfg.Live.ChannelHistory, func(i, j int) bool {
+		return len(cfg.Live.ChannelHistory[i].Prefix) > len(cfg.Live.ChannelHistory[j].Prefix)
+	})
+
+	return nil
+}
+
+// parseLiveChannelHistory parses a `<size>` or `<size>,<ttl>` history value.
+func parseLiveChannelHistory(prefix string, value string, defaultTTL time.Duration) (LiveChannelHistory, error) {
+	h := LiveChannelHistory{Prefix: prefix, TTL: defaultTTL}
+	parts := strings.SplitN(value, ",", 2)
+
+	size, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || size < 0 {
+		return h, fmt.Errorf("invalid live history size for %q: %q", prefix, value)
+	}
+	h.Size = size
+
+	if len(parts) == 2 {
+		ttl, err := gtime.ParseDuration(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return h, fmt.Errorf("invalid live history ttl for %q: %w", prefix, err)
+		}
+		h.TTL = ttl
+	}
+
+	return h, nil
+}
@@ -0,0 +1,70 @@
+package setting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiveHistorySettings(t *testing.T) {
+	cfg := NewCfg()
+	sec, err := cfg.Raw.NewSection("live")
+	require.NoError(t, err)
+	_, err = sec.NewKey("history_size", "5")
+	require.NoError(t, err)
+	_, err = sec.NewKey("history_ttl", "2m")
+	require.NoError(t, err)
+
+	sec, err = cfg.Raw.NewSection("live.history")
+	require.NoError(t, err)
+	_, err = sec.NewKey("grafinsight/dashboard", "0")
+	require.NoError(t, err)
+	_, err = sec.NewKey("grafinsight/measurements", "100,1h")
+	require.NoError(t, err)
+	_, err = sec.NewKey("grafinsight/measurements/ci", "1")
+	require.NoError(t, err)
+
+	require.NoError(t, cfg.readLiveSettings())
+
+	size, ttl := cfg.Live.HistoryFor("grafinsight/broadcast/test")
+	require.Equal(t, 5, size)
+	require.Equal(t, 2*time.Minute, ttl)
+
+	size, _ = cfg.Live.HistoryFor("grafinsight/dashboard/uid/abc")
+	require.Equal(t, 0, size)
+
+	size, ttl = cfg.Live.HistoryFor("grafinsight/measurements/build")
+	require.Equal(t, 100, size)
+	require.Equal(t, time.Hour, ttl)
+
+	size, ttl = cfg.Live.HistoryFor("grafinsight/measurements/ci")
+	require.Equal(t, 1, size)
+	require.Equal(t, 2*time.Minute, ttl)
+
+	// a prefix only matches whole path segments
+	size, _ = cfg.Live.HistoryFor("grafinsight/measurementsx/build")
+	require.Equal(t, 5, size)
+
+	t.Run("invalid history size", func(t *testing.T) {
+		_, err := sec.NewKey("grafinsight/testdata", "many")
+		require.NoError(t, err)
+		require.Error(t, cfg.readLiveSettings())
+	})
+}
+
+func TestLiveTransportSettings(t *testing.T) {
+	cfg := NewCfg()
+	require.NoError(t, cfg.readLiveSettings())
+	require.Equal(t, LiveTransportWebsocket, cfg.Live.Transport)
+
+	sec, err := cfg.Raw.NewSection("live")
+	require.NoError(t, err)
+	key, err := sec.NewKey("transport", "sse")
+	require.NoError(t, err)
+	require.NoError(t, cfg.readLiveSettings())
+	require.Equal(t, LiveTransportSSE, cfg.Live.Transport)
+
+	key.SetValue("longpoll")
+	require.Error(t, cfg.readLiveSettings())
+}
@@ -0,0 +1,40 @@
+package setting
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/openinsight-project/grafinsight/pkg/components/gtime"
+)
+
+type QueryCachingSettings struct {
+	// Enabled caches the results of data source queries in the remote cache.
+	Enabled bool
+	// TTL is how long results are cached, unless the data source sets its
+	// own TTL.
+	TTL time.Duration
+	// MinInterval is the smallest step the time ranges are aligned to.
+	MinInterval time.Duration
+}
+
+func (cfg *Cfg) readQueryCachingSettings() error {
+	sec := cfg.Raw.Section("query_caching")
+	cfg.QueryCaching.Enabled = sec.Key("enabled").MustBool(false)
+
+	ttl, err := gtime.ParseDuration(valueAsString(sec, "ttl", "1m"))
+	if err != nil {
+		return fmt.Errorf("invalid query_caching ttl: %w", err)
+	}
+	cfg.QueryCaching.TTL = ttl
+
+	minInterval, err := gtime.ParseDuration(valueAsString(sec, "min_interval", "10s"))
+	if err != nil {
+		return fmt.Errorf("invalid query_caching min_interval: %w", err)
+	}
+	if minInterval <= 0 {
+		return fmt.Errorf("query_caching min_interval must be positive")
+	}
+	cfg.QueryCaching.MinInterval = minInterval
+
+	return nil
+}
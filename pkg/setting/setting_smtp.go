@@ -15,6 +15,7 @@ type SmtpSettings struct {
 
 	SendWelcomeEmailOnSignUp bool
 	TemplatesPattern         string
+	TemplatesOverridePath    string
 }
 
 func (cfg *Cfg) readSmtpSettings() {
@@ -34,4 +35,7 @@ func (cfg *Cfg) readSmtpSettings() {
 	emails := cfg.Raw.Section("emails")
 	cfg.Smtp.SendWelcomeEmailOnSignUp = emails.Key("welcome_email_on_sign_up").MustBool(false)
 	cfg.Smtp.TemplatesPattern = emails.Key("templates_pattern").MustString("emails/*.html")
+	if overridePath := emails.Key("templates_override_path").String(); overridePath != "" {
+		cfg.Smtp.TemplatesOverridePath = makeAbsolute(overridePath, HomePath)
+	}
 }
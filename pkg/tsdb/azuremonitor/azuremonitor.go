@@ -41,6 +41,12 @@ func init() {
 	legendKeyFormat = regexp.MustCompile(`\{\{\s*(.+?)\s*\}\}`)
 }
 
+// HasFixedInterval reports that the time grains and the $__interval macro
+// follow the IntervalMs of the queries.
+func (e *AzureMonitorExecutor) HasFixedInterval() bool {
+	return true
+}
+
 // Query takes in the frontend queries, parses them into the query format
 // expected by chosen Azure Monitor service (Azure Monitor, App Insights etc.)
 // executes the queries against the API and parses the response into
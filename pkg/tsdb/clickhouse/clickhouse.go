@@ -0,0 +1,137 @@
+package clickhouse
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/openinsight-project/grafinsight/pkg/infra/log"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/setting"
+	"github.com/openinsight-project/grafinsight/pkg/tsdb"
+	"github.com/openinsight-project/grafinsight/pkg/tsdb/sqleng"
+	"xorm.io/core"
+)
+
+func init() {
+	tsdb.RegisterTsdbQueryEndpoint("clickhouse", newClickHouseQueryEndpoint)
+}
+
+func newClickHouseQueryEndpoint(datasource *models.DataSource) (tsdb.TsdbQueryEndpoint, error) {
+	logger := log.New("tsdb.clickhouse")
+
+	cnnstr, err := generateConnectionString(datasource)
+	if err != nil {
+		return nil, err
+	}
+
+	if setting.Env == setting.Dev {
+		logger.Debug("getEngine", "connection", cnnstr)
+	}
+
+	config := sqleng.SqlQueryEndpointConfiguration{
+		DriverName:        driverName,
+		ConnectionString:  cnnstr,
+		Datasource:        datasource,
+		MetricColumnTypes: []string{"String", "FixedString", "LowCardinality(String)", "Nullable(String)"},
+		// ClickHouse intervals are numbers of a unit, like INTERVAL 60 second
+		IntervalAsSeconds: true,
+	}
+
+	rowTransformer := clickHouseQueryResultTransformer{
+		log: logger,
+	}
+
+	return sqleng.NewSqlQueryEndpoint(&config, &rowTransformer, newClickHouseMacroEngine(), logger)
+}
+
+// generateConnectionString returns the connection string of the HTTP
+// interface of the server. The URL of the datasource defaults to HTTP on the
+// default port, and the TLS settings of the datasource are used for HTTPS.
+func generateConnectionString(datasource *models.DataSource) (string, error) {
+	rawURL := datasource.Url
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "http://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid datasource URL %q: %w", datasource.Url, err)
+	}
+	if u.Port() == "" {
+		if u.Scheme == "https" {
+			u.Host += ":8443"
+		} else {
+			u.Host += ":8123"
+		}
+	}
+
+	if datasource.User != "" {
+		u.User = url.UserPassword(datasource.User, datasource.DecryptedPassword())
+	}
+	u.Path = "/" + datasource.Database
+
+	params := url.Values{}
+	// Let the server stop queries running longer than the timeout
+	if timeout := sqleng.ConfiguredQueryTimeout(datasource); timeout > 0 {
+		params.Set("max_execution_time", fmt.Sprintf("%d", int64(timeout.Seconds())))
+	}
+
+	if u.Scheme == "https" {
+		tlsConfig, err := datasource.GetTLSConfig()
+		if err != nil {
+			return "", err
+		}
+		tlsConfigName := fmt.Sprintf("ds%d", datasource.Id)
+		RegisterTLSConfig(tlsConfigName, tlsConfig)
+		params.Set("tls", tlsConfigName)
+	}
+
+	u.RawQuery = params.Encode()
+	return u.String(), nil
+}
+
+type clickHouseQueryResultTransformer struct {
+	log log.Logger
+}
+
+// TransformQueryResult returns the values of a row, which the driver already
+// converted to the Go types of their columns.
+func (t *clickHouseQueryResultTransformer) TransformQueryResult(columnTypes []*sql.ColumnType, rows *core.Rows) (tsdb.RowValues, error) {
+	values := make([]interface{}, len(columnTypes))
+	valuePtrs := make([]interface{}, len(columnTypes))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+
+	if err := rows.Scan(valuePtrs...); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// Error codes of the errors in the query itself, which are returned to the user
+const (
+	errCodeUnknownIdentifier = 47
+	errCodeUnknownTable      = 60
+	errCodeSyntaxError       = 62
+	errCodeUnknownDatabase   = 81
+)
+
+func (t *clickHouseQueryResultTransformer) TransformQueryError(err error) error {
+	var driverErr *Error
+	if errors.As(err, &driverErr) {
+		switch driverErr.Code {
+		case errCodeUnknownIdentifier, errCodeUnknownTable, errCodeSyntaxError, errCodeUnknownDatabase:
+			return err
+		}
+		t.log.Error("query error", "err", err)
+		return errQueryFailed
+	}
+
+	return err
+}
+
+var errQueryFailed = errors.New("query failed - please inspect Grafinsight server log for details")
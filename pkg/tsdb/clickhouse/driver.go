@@ -0,0 +1,471 @@
+package clickhouse
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"xorm.io/core"
+)
+
+// The driver runs queries with the HTTP interface of ClickHouse, which every
+// server has, and streams their results in the JSONCompact format.
+const driverName = "clickhouse"
+
+func init() {
+	sql.Register(driverName, &chDriver{})
+	core.RegisterDriver(driverName, &chDriver{})
+}
+
+var (
+	tlsConfigsLock sync.RWMutex
+	tlsConfigs     = map[string]*tls.Config{}
+)
+
+// RegisterTLSConfig registers a TLS configuration that connection strings can
+// use with the tls parameter.
+func RegisterTLSConfig(name string, config *tls.Config) {
+	tlsConfigsLock.Lock()
+	defer tlsConfigsLock.Unlock()
+	tlsConfigs[name] = config
+}
+
+func getTLSConfig(name string) (*tls.Config, error) {
+	tlsConfigsLock.RLock()
+	defer tlsConfigsLock.RUnlock()
+	config, ok := tlsConfigs[name]
+	if !ok {
+		return nil, fmt.Errorf("invalid value / unknown config name: %s", name)
+	}
+	return config, nil
+}
+
+// Error is an error returned by the ClickHouse server.
+type Error struct {
+	Code    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+var errorCodeRegex = regexp.MustCompile(`^Code: (\d+)`)
+
+func newError(message string) *Error {
+	err := &Error{Message: strings.TrimSpace(message)}
+	if match := errorCodeRegex.FindStringSubmatch(err.Message); match != nil {
+		err.Code, _ = strconv.Atoi(match[1])
+	}
+	return err
+}
+
+type chDriver struct{}
+
+// Parse returns the connection URI for xorm. ClickHouse quotes identifiers
+// with backticks like MySQL, and queries are only run as raw SQL, so the MySQL
+// dialect is used.
+func (d *chDriver) Parse(driverName, dataSourceName string) (*core.Uri, error) {
+	u, err := url.Parse(dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	return &core.Uri{DbType: core.MYSQL, DbName: strings.TrimPrefix(u.Path, "/")}, nil
+}
+
+// Open returns a connection to the server of a connection string with the
+// form http[s]://user:password@host:port/database?tls=name&setting=value.
+// Parameters other than tls are sent as settings with every query.
+func (d *chDriver) Open(dataSourceName string) (driver.Conn, error) {
+	u, err := url.Parse(dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid scheme %q, must be http or https", u.Scheme)
+	}
+
+	transport := &http.Transport{Proxy: http.ProxyFromEnvironment}
+	params := u.Query()
+	if name := params.Get("tls"); name != "" {
+		config, err := getTLSConfig(name)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = config
+		params.Del("tls")
+	}
+
+	c := &conn{client: &http.Client{Transport: transport}}
+	if u.User != nil {
+		c.user = u.User.Username()
+		c.password, _ = u.User.Password()
+	}
+	if database := strings.TrimPrefix(u.Path, "/"); database != "" {
+		params.Set("database", database)
+	}
+
+	c.endpoint = &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/", RawQuery: params.Encode()}
+	return c, nil
+}
+
+type conn struct {
+	client   *http.Client
+	endpoint *url.URL
+	user     string
+	password string
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) Close() error {
+	c.client.CloseIdleConnections()
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	res, err := c.do(ctx, "SELECT 1", nil)
+	if err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if len(args) > 0 {
+		return nil, errors.New("query arguments are not supported")
+	}
+	res, err := c.do(ctx, query, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := res.Body.Close(); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(0), nil
+}
+
+// QueryContext runs the query, and streams its result in the JSONCompact
+// format, so that the rows past the row limit of the caller are not read.
+// The format is the default format of the request, as the query may set its
+// own or end with a comment.
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if len(args) > 0 {
+		return nil, errors.New("query arguments are not supported")
+	}
+	res, err := c.do(ctx, query, url.Values{"default_format": []string{"JSONCompact"}})
+	if err != nil {
+		return nil, err
+	}
+
+	// Times without a time zone in their type are in the time zone of the
+	// server
+	location := time.UTC
+	if name := res.Header.Get("X-ClickHouse-Timezone"); name != "" {
+		if l, err := time.LoadLocation(name); err == nil {
+			location = l
+		}
+	}
+
+	decoder := json.NewDecoder(res.Body)
+	decoder.UseNumber()
+	r := &rows{body: res.Body, decoder: decoder, location: location}
+	if err := r.readHeader(); err != nil {
+		_ = res.Body.Close()
+		return nil, fmt.Errorf("failed to read the query result: %w", err)
+	}
+	return r, nil
+}
+
+// do sends a query to the server, and returns the response if the query
+// succeeded. The params are added to the settings of the connection.
+func (c *conn) do(ctx context.Context, query string, params url.Values) (*http.Response, error) {
+	endpoint := *c.endpoint
+	if len(params) > 0 {
+		values := endpoint.Query()
+		for name, value := range params {
+			values[name] = value
+		}
+		endpoint.RawQuery = values.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String(), strings.NewReader(query))
+	if err != nil {
+		return nil, err
+	}
+	if c.user != "" {
+		req.Header.Set("X-ClickHouse-User", c.user)
+	}
+	if c.password != "" {
+		req.Header.Set("X-ClickHouse-Key", c.password)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		defer func() {
+			_ = res.Body.Close()
+		}()
+		body, err := ioutil.ReadAll(io.LimitReader(res.Body, 64*1024))
+		if err != nil {
+			return nil, err
+		}
+		return nil, newError(string(body))
+	}
+	return res, nil
+}
+
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	if len(args) > 0 {
+		return nil, errors.New("query arguments are not supported")
+	}
+	return s.conn.ExecContext(context.Background(), s.query, nil)
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	if len(args) > 0 {
+		return nil, errors.New("query arguments are not supported")
+	}
+	return s.conn.QueryContext(context.Background(), s.query, nil)
+}
+
+// rows decodes the rows of a JSONCompact result as they are read.
+type rows struct {
+	columns  []string
+	types    []string
+	body     io.ReadCloser
+	decoder  *json.Decoder
+	location *time.Location
+	done     bool
+}
+
+// readHeader reads the result up to its first row. The columns are in the
+// meta object, which comes before the data array.
+func (r *rows) readHeader() error {
+	if err := r.expectDelim('{'); err != nil {
+		return err
+	}
+	for r.decoder.More() {
+		token, err := r.decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch token {
+		case "meta":
+			var meta []struct {
+				Name string `json:"name"`
+				Type string `json:"type"`
+			}
+			if err := r.decoder.Decode(&meta); err != nil {
+				return err
+			}
+			for _, column := range meta {
+				r.columns = append(r.columns, column.Name)
+				r.types = append(r.types, column.Type)
+			}
+		case "data":
+			return r.expectDelim('[')
+		default:
+			var skipped json.RawMessage
+			if err := r.decoder.Decode(&skipped); err != nil {
+				return err
+			}
+		}
+	}
+	r.done = true
+	return nil
+}
+
+func (r *rows) expectDelim(delim json.Delim) error {
+	token, err := r.decoder.Token()
+	if err != nil {
+		return err
+	}
+	if token != delim {
+		return fmt.Errorf("unexpected %v, expected %v", token, delim)
+	}
+	return nil
+}
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+// Close stops reading the result.
+func (r *rows) Close() error {
+	return r.body.Close()
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	if !r.decoder.More() {
+		r.done = true
+		if err := r.expectDelim(']'); err != nil {
+			return fmt.Errorf("failed to read the query result: %w", err)
+		}
+		return io.EOF
+	}
+
+	var row []interface{}
+	if err := r.decoder.Decode(&row); err != nil {
+		// Errors after the first rows are written in the body
+		return fmt.Errorf("failed to read the query result: %w", err)
+	}
+
+	for i := range dest {
+		if i >= len(row) {
+			dest[i] = nil
+			continue
+		}
+		value, err := convertValue(r.types[i], row[i], r.location)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", r.columns[i], err)
+		}
+		dest[i] = value
+	}
+	return nil
+}
+
+func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
+	return r.types[index]
+}
+
+func (r *rows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	return strings.HasPrefix(r.types[index], "Nullable("), true
+}
+
+var (
+	scanTypeInt64   = reflect.TypeOf(int64(0))
+	scanTypeFloat64 = reflect.TypeOf(float64(0))
+	scanTypeBool    = reflect.TypeOf(false)
+	scanTypeTime    = reflect.TypeOf(time.Time{})
+	scanTypeString  = reflect.TypeOf("")
+)
+
+func (r *rows) ColumnTypeScanType(index int) reflect.Type {
+	name, _ := baseType(r.types[index])
+	switch {
+	case strings.HasPrefix(name, "Int"), strings.HasPrefix(name, "UInt"):
+		return scanTypeInt64
+	case strings.HasPrefix(name, "Float"), strings.HasPrefix(name, "Decimal"):
+		return scanTypeFloat64
+	case name == "Bool":
+		return scanTypeBool
+	case strings.HasPrefix(name, "Date"):
+		return scanTypeTime
+	default:
+		return scanTypeString
+	}
+}
+
+// baseType returns the name of a type without the Nullable and LowCardinality
+// wrappers, and the arguments of the type.
+func baseType(typ string) (string, string) {
+	for {
+		unwrapped := false
+		for _, wrapper := range []string{"Nullable(", "LowCardinality("} {
+			if strings.HasPrefix(typ, wrapper) && strings.HasSuffix(typ, ")") {
+				typ = typ[len(wrapper) : len(typ)-1]
+				unwrapped = true
+			}
+		}
+		if !unwrapped {
+			break
+		}
+	}
+
+	if i := strings.Index(typ, "("); i >= 0 && strings.HasSuffix(typ, ")") {
+		return typ[:i], typ[i+1 : len(typ)-1]
+	}
+	return typ, ""
+}
+
+// timeLocation returns the time zone of a DateTime or DateTime64 type, which
+// is its last quoted argument.
+func timeLocation(args string, location *time.Location) *time.Location {
+	parts := strings.Split(args, ",")
+	last := strings.TrimSpace(parts[len(parts)-1])
+	if strings.HasPrefix(last, "'") && strings.HasSuffix(last, "'") && len(last) > 1 {
+		if l, err := time.LoadLocation(strings.Trim(last, "'")); err == nil {
+			return l
+		}
+	}
+	return location
+}
+
+// convertValue converts a value of the JSON result of a query to the Go type
+// of its column.
+func convertValue(typ string, value interface{}, location *time.Location) (driver.Value, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	name, args := baseType(typ)
+	switch {
+	case strings.HasPrefix(name, "Int"), strings.HasPrefix(name, "UInt"):
+		s := fmt.Sprint(value)
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i, nil
+		}
+		// Numbers that do not fit an int64 lose precision
+		return strconv.ParseFloat(s, 64)
+	case strings.HasPrefix(name, "Float"), strings.HasPrefix(name, "Decimal"):
+		return strconv.ParseFloat(fmt.Sprint(value), 64)
+	case name == "Bool":
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+		return strconv.ParseBool(fmt.Sprint(value))
+	case name == "Date", name == "Date32":
+		return time.ParseInLocation("2006-01-02", fmt.Sprint(value), time.UTC)
+	case name == "DateTime", name == "DateTime64":
+		return time.ParseInLocation("2006-01-02 15:04:05.999999999", fmt.Sprint(value), timeLocation(args, location))
+	}
+
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	// Arrays, tuples and maps are returned as JSON
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
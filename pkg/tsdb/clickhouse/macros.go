@@ -0,0 +1,151 @@
+package clickhouse
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/openinsight-project/grafinsight/pkg/components/gtime"
+	"github.com/openinsight-project/grafinsight/pkg/tsdb"
+	"github.com/openinsight-project/grafinsight/pkg/tsdb/sqleng"
+)
+
+const rsIdentifier = `([_a-zA-Z0-9]+)`
+const sExpr = `\$` + rsIdentifier + `\(([^\)]*)\)`
+
+type clickHouseMacroEngine struct {
+	*sqleng.SqlMacroEngineBase
+	timeRange *tsdb.TimeRange
+	query     *tsdb.Query
+}
+
+func newClickHouseMacroEngine() sqleng.SqlMacroEngine {
+	return &clickHouseMacroEngine{SqlMacroEngineBase: sqleng.NewSqlMacroEngineBase()}
+}
+
+func (m *clickHouseMacroEngine) Interpolate(query *tsdb.Query, timeRange *tsdb.TimeRange, sql string) (string, error) {
+	m.timeRange = timeRange
+	m.query = query
+	rExp, _ := regexp.Compile(sExpr)
+	var macroError error
+
+	sql = m.ReplaceAllStringSubmatchFunc(rExp, sql, func(groups []string) string {
+		args := strings.Split(groups[2], ",")
+		for i, arg := range args {
+			args[i] = strings.Trim(arg, " ")
+		}
+		res, err := m.evaluateMacro(groups[1], args)
+		if err != nil && macroError == nil {
+			macroError = err
+			return "macro_error()"
+		}
+		return res
+	})
+
+	if macroError != nil {
+		return "", macroError
+	}
+
+	return sql, nil
+}
+
+// intervalSeconds returns the interval argument of a macro in seconds, as
+// ClickHouse intervals are whole numbers. Numbers are already in seconds,
+// like $__interval.
+func intervalSeconds(arg string) (int64, error) {
+	arg = strings.Trim(arg, `'"`)
+	if seconds, err := strconv.ParseInt(arg, 10, 64); err == nil {
+		return int64(math.Max(1, float64(seconds))), nil
+	}
+	interval, err := gtime.ParseInterval(arg)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing interval %v", arg)
+	}
+	return int64(math.Max(1, math.Round(interval.Seconds()))), nil
+}
+
+func (m *clickHouseMacroEngine) evaluateMacro(name string, args []string) (string, error) {
+	switch name {
+	case "__time":
+		if len(args) == 0 {
+			return "", fmt.Errorf("missing time column argument for macro %v", name)
+		}
+		return fmt.Sprintf("%s AS time", args[0]), nil
+	case "__timeEpoch":
+		if len(args) == 0 {
+			return "", fmt.Errorf("missing time column argument for macro %v", name)
+		}
+		return fmt.Sprintf("toUnixTimestamp(%s) AS time", args[0]), nil
+	case "__timeFilter":
+		if len(args) == 0 {
+			return "", fmt.Errorf("missing time column argument for macro %v", name)
+		}
+
+		return fmt.Sprintf("%s BETWEEN toDateTime(%d) AND toDateTime(%d)", args[0], m.timeRange.GetFromAsSecondsEpoch(), m.timeRange.GetToAsSecondsEpoch()), nil
+	case "__timeFrom":
+		return fmt.Sprintf("toDateTime(%d)", m.timeRange.GetFromAsSecondsEpoch()), nil
+	case "__timeTo":
+		return fmt.Sprintf("toDateTime(%d)", m.timeRange.GetToAsSecondsEpoch()), nil
+	case "__timeGroup":
+		if len(args) < 2 {
+			return "", fmt.Errorf("macro %v needs time column and interval", name)
+		}
+		seconds, err := intervalSeconds(args[1])
+		if err != nil {
+			return "", err
+		}
+		if len(args) == 3 {
+			err := sqleng.SetupFillmode(m.query, time.Duration(seconds)*time.Second, args[2])
+			if err != nil {
+				return "", err
+			}
+		}
+		return fmt.Sprintf("toStartOfInterval(%s, INTERVAL %d second)", args[0], seconds), nil
+	case "__timeGroupAlias":
+		tg, err := m.evaluateMacro("__timeGroup", args)
+		if err == nil {
+			return tg + " AS time", nil
+		}
+		return "", err
+	case "__unixEpochFilter":
+		if len(args) == 0 {
+			return "", fmt.Errorf("missing time column argument for macro %v", name)
+		}
+		return fmt.Sprintf("%s >= %d AND %s <= %d", args[0], m.timeRange.GetFromAsSecondsEpoch(), args[0], m.timeRange.GetToAsSecondsEpoch()), nil
+	case "__unixEpochNanoFilter":
+		if len(args) == 0 {
+			return "", fmt.Errorf("missing time column argument for macro %v", name)
+		}
+		return fmt.Sprintf("%s >= %d AND %s <= %d", args[0], m.timeRange.GetFromAsTimeUTC().UnixNano(), args[0], m.timeRange.GetToAsTimeUTC().UnixNano()), nil
+	case "__unixEpochNanoFrom":
+		return fmt.Sprintf("%d", m.timeRange.GetFromAsTimeUTC().UnixNano()), nil
+	case "__unixEpochNanoTo":
+		return fmt.Sprintf("%d", m.timeRange.GetToAsTimeUTC().UnixNano()), nil
+	case "__unixEpochGroup":
+		if len(args) < 2 {
+			return "", fmt.Errorf("macro %v needs time column and interval and optional fill value", name)
+		}
+		seconds, err := intervalSeconds(args[1])
+		if err != nil {
+			return "", err
+		}
+		if len(args) == 3 {
+			err := sqleng.SetupFillmode(m.query, time.Duration(seconds)*time.Second, args[2])
+			if err != nil {
+				return "", err
+			}
+		}
+		return fmt.Sprintf("intDiv(%s, %d) * %d", args[0], seconds, seconds), nil
+	case "__unixEpochGroupAlias":
+		tg, err := m.evaluateMacro("__unixEpochGroup", args)
+		if err == nil {
+			return tg + " AS time", nil
+		}
+		return "", err
+	default:
+		return "", fmt.Errorf("unknown macro %v", name)
+	}
+}
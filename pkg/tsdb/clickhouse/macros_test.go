@@ -0,0 +1,104 @@
+package clickhouse
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openinsight-project/grafinsight/pkg/components/simplejson"
+	"github.com/openinsight-project/grafinsight/pkg/tsdb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMacroEngine(t *testing.T) {
+	engine := newClickHouseMacroEngine()
+	query := &tsdb.Query{Model: simplejson.New()}
+
+	from := time.Date(2018, 4, 12, 18, 0, 0, 0, time.UTC)
+	to := from.Add(5 * time.Minute)
+	timeRange := tsdb.NewFakeTimeRange("5m", "now", to)
+
+	t.Run("interpolate __time function", func(t *testing.T) {
+		sql, err := engine.Interpolate(query, timeRange, "select $__time(time_column)")
+		require.NoError(t, err)
+		require.Equal(t, "select time_column AS time", sql)
+	})
+
+	t.Run("interpolate __timeEpoch function", func(t *testing.T) {
+		sql, err := engine.Interpolate(query, timeRange, "select $__timeEpoch(time_column)")
+		require.NoError(t, err)
+		require.Equal(t, "select toUnixTimestamp(time_column) AS time", sql)
+	})
+
+	t.Run("interpolate __timeFilter function", func(t *testing.T) {
+		sql, err := engine.Interpolate(query, timeRange, "WHERE $__timeFilter(time_column)")
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("WHERE time_column BETWEEN toDateTime(%d) AND toDateTime(%d)", from.Unix(), to.Unix()), sql)
+	})
+
+	t.Run("interpolate __timeFrom and __timeTo functions", func(t *testing.T) {
+		sql, err := engine.Interpolate(query, timeRange, "select $__timeFrom(), $__timeTo()")
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("select toDateTime(%d), toDateTime(%d)", from.Unix(), to.Unix()), sql)
+	})
+
+	t.Run("interpolate __timeGroup function", func(t *testing.T) {
+		sql, err := engine.Interpolate(query, timeRange, "GROUP BY $__timeGroup(time_column, '5m')")
+		require.NoError(t, err)
+		require.Equal(t, "GROUP BY toStartOfInterval(time_column, INTERVAL 300 second)", sql)
+
+		sql, err = engine.Interpolate(query, timeRange, "SELECT $__timeGroupAlias(time_column, 5m)")
+		require.NoError(t, err)
+		require.Equal(t, "SELECT toStartOfInterval(time_column, INTERVAL 300 second) AS time", sql)
+	})
+
+	t.Run("interpolate __timeGroup function with an interval in seconds", func(t *testing.T) {
+		sql, err := engine.Interpolate(query, timeRange, "GROUP BY $__timeGroup(time_column, 60)")
+		require.NoError(t, err)
+		require.Equal(t, "GROUP BY toStartOfInterval(time_column, INTERVAL 60 second)", sql)
+	})
+
+	t.Run("interpolate __timeGroup function with an interval below a second", func(t *testing.T) {
+		sql, err := engine.Interpolate(query, timeRange, "GROUP BY $__timeGroup(time_column, 100ms)")
+		require.NoError(t, err)
+		require.Equal(t, "GROUP BY toStartOfInterval(time_column, INTERVAL 1 second)", sql)
+	})
+
+	t.Run("interpolate __timeGroup function with fill", func(t *testing.T) {
+		query := &tsdb.Query{Model: simplejson.New()}
+		_, err := engine.Interpolate(query, timeRange, "GROUP BY $__timeGroup(time_column, 5m, NULL)")
+		require.NoError(t, err)
+		require.True(t, query.Model.Get("fill").MustBool())
+		require.Equal(t, float64(300), query.Model.Get("fillInterval").MustFloat64())
+		require.Equal(t, "null", query.Model.Get("fillMode").MustString())
+	})
+
+	t.Run("interpolate __unixEpochFilter function", func(t *testing.T) {
+		sql, err := engine.Interpolate(query, timeRange, "select $__unixEpochFilter(time)")
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("select time >= %d AND time <= %d", from.Unix(), to.Unix()), sql)
+	})
+
+	t.Run("interpolate __unixEpochNanoFilter function", func(t *testing.T) {
+		sql, err := engine.Interpolate(query, timeRange, "select $__unixEpochNanoFilter(time)")
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("select time >= %d AND time <= %d", from.UnixNano(), to.UnixNano()), sql)
+	})
+
+	t.Run("interpolate __unixEpochGroup function", func(t *testing.T) {
+		sql, err := engine.Interpolate(query, timeRange, "SELECT $__unixEpochGroupAlias(time_column, '5m')")
+		require.NoError(t, err)
+		require.Equal(t, "SELECT intDiv(time_column, 300) * 300 AS time", sql)
+	})
+
+	t.Run("return an error for unknown macros and invalid arguments", func(t *testing.T) {
+		_, err := engine.Interpolate(query, timeRange, "select $__unknown(time)")
+		require.EqualError(t, err, "unknown macro __unknown")
+
+		_, err = engine.Interpolate(query, timeRange, "select $__timeGroup(time)")
+		require.Error(t, err)
+
+		_, err = engine.Interpolate(query, timeRange, "select $__timeGroup(time, abc)")
+		require.Error(t, err)
+	})
+}
@@ -35,9 +35,13 @@ var newDatasourceHttpClient = func(ds *models.DataSource) (*http.Client, error)
 type Client interface {
 	GetVersion() int
 	GetTimeField() string
+	GetLogMessageField() string
+	GetLogLevelField() string
 	GetMinInterval(queryInterval string) (time.Duration, error)
 	ExecuteMultisearch(r *MultiSearchRequest) (*MultiSearchResponse, error)
 	MultiSearch() *MultiSearchRequestBuilder
+	ExecuteSQL(r *SQLRequest) (*SQLResponse, error)
+	CloseSQLCursor(cursor string) error
 	EnableDebug()
 }
 
@@ -99,6 +103,14 @@ func (c *baseClientImpl) GetTimeField() string {
 	return c.timeField
 }
 
+func (c *baseClientImpl) GetLogMessageField() string {
+	return c.ds.JsonData.Get("logMessageField").MustString()
+}
+
+func (c *baseClientImpl) GetLogLevelField() string {
+	return c.ds.JsonData.Get("logLevelField").MustString()
+}
+
 func (c *baseClientImpl) GetMinInterval(queryInterval string) (time.Duration, error) {
 	return tsdb.GetIntervalFrom(c.ds, simplejson.NewFromAny(map[string]interface{}{
 		"interval": queryInterval,
@@ -320,6 +332,70 @@ func (c *baseClientImpl) getMultiSearchQueryParameters() string {
 	return ""
 }
 
+// getSQLPath returns the path of the SQL API, which is part of X-Pack before
+// Elasticsearch 7.0.
+func (c *baseClientImpl) getSQLPath() (string, error) {
+	switch {
+	case c.version >= 70:
+		return "_sql", nil
+	case c.version >= 60:
+		return "_xpack/sql", nil
+	}
+	return "", fmt.Errorf("SQL queries are not supported by elasticsearch version=%d", c.version)
+}
+
+func (c *baseClientImpl) ExecuteSQL(r *SQLRequest) (*SQLResponse, error) {
+	uriPath, err := c.getSQLPath()
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+
+	clientLog.Debug("Executing SQL query")
+
+	clientRes, err := c.executeRequest(http.MethodPost, uriPath, "format=json", body)
+	if err != nil {
+		return nil, err
+	}
+	res := clientRes.httpResponse
+	defer func() {
+		if err := res.Body.Close(); err != nil {
+			clientLog.Warn("Failed to close response body", "err", err)
+		}
+	}()
+
+	clientLog.Debug("Received SQL response", "code", res.StatusCode, "status", res.Status, "content-length", res.ContentLength)
+
+	var sr SQLResponse
+	if err := json.NewDecoder(res.Body).Decode(&sr); err != nil {
+		return nil, err
+	}
+
+	return &sr, nil
+}
+
+func (c *baseClientImpl) CloseSQLCursor(cursor string) error {
+	uriPath, err := c.getSQLPath()
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(&SQLRequest{Cursor: cursor})
+	if err != nil {
+		return err
+	}
+
+	clientRes, err := c.executeRequest(http.MethodPost, path.Join(uriPath, "close"), "", body)
+	if err != nil {
+		return err
+	}
+	return clientRes.httpResponse.Body.Close()
+}
+
 func (c *baseClientImpl) MultiSearch() *MultiSearchRequestBuilder {
 	return NewMultiSearchRequestBuilder(c.GetVersion())
 }
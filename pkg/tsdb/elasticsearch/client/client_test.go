@@ -325,6 +325,80 @@ func TestClient_ExecuteMultisearch(t *testing.T) {
 	})
 }
 
+func TestClient_ExecuteSQL(t *testing.T) {
+	httpClientScenario(t, "Given a fake http client and a v7.0 client with a SQL response", &models.DataSource{
+		Database: "[metrics-]YYYY.MM.DD",
+		JsonData: simplejson.NewFromAny(map[string]interface{}{
+			"esVersion": 70,
+			"timeField": "@timestamp",
+			"interval":  "Daily",
+		}),
+	}, func(sc *scenarioContext) {
+		sc.responseBody = `{
+				"columns": [{ "name": "host", "type": "keyword" }, { "name": "c", "type": "long" }],
+				"rows": [["a", 1], ["b", 2]],
+				"cursor": "next"
+			}`
+
+		res, err := sc.client.ExecuteSQL(&SQLRequest{
+			Query:     "SELECT host, COUNT(*) AS c FROM metrics GROUP BY host",
+			FetchSize: 2,
+			Filter:    &RangeFilter{Key: "@timestamp", Gte: "1", Lte: "2", Format: DateFormatEpochMS},
+		})
+		require.NoError(t, err)
+
+		require.NotNil(t, sc.request)
+		assert.Equal(t, http.MethodPost, sc.request.Method)
+		assert.Equal(t, "/_sql", sc.request.URL.Path)
+		assert.Equal(t, "json", sc.request.URL.Query().Get("format"))
+
+		jBody, err := simplejson.NewJson(sc.requestBody.Bytes())
+		require.NoError(t, err)
+		assert.Equal(t, "SELECT host, COUNT(*) AS c FROM metrics GROUP BY host", jBody.Get("query").MustString())
+		assert.Equal(t, 2, jBody.Get("fetch_size").MustInt())
+		assert.Equal(t, "1", jBody.GetPath("filter", "range", "@timestamp", "gte").MustString())
+
+		require.Len(t, res.Columns, 2)
+		assert.Equal(t, "long", res.Columns[1].Type)
+		require.Len(t, res.Rows, 2)
+		assert.Equal(t, "next", res.Cursor)
+
+		require.NoError(t, sc.client.CloseSQLCursor("next"))
+		assert.Equal(t, "/_sql/close", sc.request.URL.Path)
+		jBody, err = simplejson.NewJson(sc.requestBody.Bytes())
+		require.NoError(t, err)
+		assert.Equal(t, "next", jBody.Get("cursor").MustString())
+	})
+
+	httpClientScenario(t, "Given a fake http client and a v6.0 client", &models.DataSource{
+		Database: "[metrics-]YYYY.MM.DD",
+		JsonData: simplejson.NewFromAny(map[string]interface{}{
+			"esVersion": 60,
+			"timeField": "@timestamp",
+			"interval":  "Daily",
+		}),
+	}, func(sc *scenarioContext) {
+		sc.responseBody = `{ "columns": [], "rows": [] }`
+
+		_, err := sc.client.ExecuteSQL(&SQLRequest{Query: "SELECT 1"})
+		require.NoError(t, err)
+		assert.Equal(t, "/_xpack/sql", sc.request.URL.Path)
+	})
+
+	httpClientScenario(t, "Given a fake http client and a v5.6 client", &models.DataSource{
+		Database: "[metrics-]YYYY.MM.DD",
+		JsonData: simplejson.NewFromAny(map[string]interface{}{
+			"esVersion": 56,
+			"timeField": "@timestamp",
+			"interval":  "Daily",
+		}),
+	}, func(sc *scenarioContext) {
+		_, err := sc.client.ExecuteSQL(&SQLRequest{Query: "SELECT 1"})
+		require.Error(t, err)
+		assert.Nil(t, sc.request)
+	})
+}
+
 func createMultisearchForTest(t *testing.T, c Client) (*MultiSearchRequest, error) {
 	t.Helper()
 
@@ -30,6 +30,12 @@ type SearchDebugInfo struct {
 	Response *SearchResponseInfo `json:"response"`
 }
 
+// Tags around the highlighted matches of the query in the hits
+const (
+	HighlightPreTag  = "@HIGHLIGHT@"
+	HighlightPostTag = "@/HIGHLIGHT@"
+)
+
 // SearchRequest represents a search request
 type SearchRequest struct {
 	Index       string
@@ -261,6 +267,15 @@ type TermsAggregation struct {
 	Missing     *string                `json:"missing,omitempty"`
 }
 
+// CompositeAggregation represents a composite aggregation. The buckets of
+// all the combinations of the values of the sources are paged, the next page
+// starting after the after key of the previous one.
+type CompositeAggregation struct {
+	Size    int                      `json:"size"`
+	Sources []map[string]interface{} `json:"sources"`
+	After   map[string]interface{}   `json:"after,omitempty"`
+}
+
 // ExtendedBounds represents extended bounds
 type ExtendedBounds struct {
 	Min string `json:"min"`
@@ -314,3 +329,48 @@ func (a *PipelineAggregation) MarshalJSON() ([]byte, error) {
 
 	return json.Marshal(root)
 }
+
+// SQLRequest represents a SQL query request, or a request for the next page
+// of the results of a SQL query if Cursor is set.
+type SQLRequest struct {
+	Query     string
+	FetchSize int
+	Filter    Filter
+	Cursor    string
+}
+
+// MarshalJSON returns the JSON encoding of the SQL request.
+func (r *SQLRequest) MarshalJSON() ([]byte, error) {
+	if r.Cursor != "" {
+		return json.Marshal(map[string]interface{}{
+			"cursor": r.Cursor,
+		})
+	}
+
+	root := map[string]interface{}{
+		"query":     r.Query,
+		"time_zone": "Z",
+	}
+	if r.FetchSize > 0 {
+		root["fetch_size"] = r.FetchSize
+	}
+	if r.Filter != nil {
+		root["filter"] = r.Filter
+	}
+
+	return json.Marshal(root)
+}
+
+// SQLColumn represents a column of a SQL response
+type SQLColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// SQLResponse represents a page of the results of a SQL query
+type SQLResponse struct {
+	Error   map[string]interface{} `json:"error"`
+	Columns []SQLColumn            `json:"columns"`
+	Rows    [][]interface{}        `json:"rows"`
+	Cursor  string                 `json:"cursor"`
+}
@@ -71,8 +71,13 @@ func (b *SearchRequestBuilder) Size(size int) *SearchRequestBuilder {
 
 // SortDesc adds a sort to the search request
 func (b *SearchRequestBuilder) SortDesc(field, unmappedType string) *SearchRequestBuilder {
+	return b.Sort("desc", field, unmappedType)
+}
+
+// Sort adds a sort in the given order, asc or desc, to the search request
+func (b *SearchRequestBuilder) Sort(order, field, unmappedType string) *SearchRequestBuilder {
 	props := map[string]string{
-		"order": "desc",
+		"order": order,
 	}
 
 	if unmappedType != "" {
@@ -102,6 +107,21 @@ func (b *SearchRequestBuilder) AddDocValueField(field string) *SearchRequestBuil
 	return b
 }
 
+// AddHighlight highlights the matches of the query in all the fields of the
+// hits, with the tags the frontend looks for
+func (b *SearchRequestBuilder) AddHighlight() *SearchRequestBuilder {
+	b.customProps["highlight"] = map[string]interface{}{
+		"fields": map[string]interface{}{
+			"*": map[string]interface{}{},
+		},
+		"pre_tags":      []string{HighlightPreTag},
+		"post_tags":     []string{HighlightPostTag},
+		"fragment_size": 2147483647,
+	}
+
+	return b
+}
+
 // Query creates and return a query builder
 func (b *SearchRequestBuilder) Query() *QueryBuilder {
 	if b.queryBuilder == nil {
@@ -267,6 +287,7 @@ type AggBuilder interface {
 	Terms(key, field string, fn func(a *TermsAggregation, b AggBuilder)) AggBuilder
 	Filters(key string, fn func(a *FiltersAggregation, b AggBuilder)) AggBuilder
 	GeoHashGrid(key, field string, fn func(a *GeoHashGridAggregation, b AggBuilder)) AggBuilder
+	Composite(key string, fn func(a *CompositeAggregation, b AggBuilder)) AggBuilder
 	Metric(key, metricType, field string, fn func(a *MetricAggregation)) AggBuilder
 	Pipeline(key, pipelineType string, bucketPath interface{}, fn func(a *PipelineAggregation)) AggBuilder
 	Build() (AggArray, error)
@@ -419,6 +440,26 @@ func (b *aggBuilderImpl) GeoHashGrid(key, field string, fn func(a *GeoHashGridAg
 	return b
 }
 
+func (b *aggBuilderImpl) Composite(key string, fn func(a *CompositeAggregation, b AggBuilder)) AggBuilder {
+	innerAgg := &CompositeAggregation{
+		Sources: make([]map[string]interface{}, 0),
+	}
+	aggDef := newAggDef(key, &aggContainer{
+		Type:        "composite",
+		Aggregation: innerAgg,
+	})
+
+	if fn != nil {
+		builder := newAggBuilder(b.version)
+		aggDef.builders = append(aggDef.builders, builder)
+		fn(innerAgg, builder)
+	}
+
+	b.aggDefs = append(b.aggDefs, aggDef)
+
+	return b
+}
+
 func (b *aggBuilderImpl) Metric(key, metricType, field string, fn func(a *MetricAggregation)) AggBuilder {
 	innerAgg := &MetricAggregation{
 		Field:    field,
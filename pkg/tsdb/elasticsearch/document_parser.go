@@ -0,0 +1,260 @@
+package elasticsearch
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/openinsight-project/grafinsight/pkg/tsdb"
+	es "github.com/openinsight-project/grafinsight/pkg/tsdb/elasticsearch/client"
+)
+
+var highlightRegex = regexp.MustCompile(regexp.QuoteMeta(es.HighlightPreTag) + "(.*?)" + regexp.QuoteMeta(es.HighlightPostTag))
+
+// processDocuments returns the hits of a raw data or logs query as a data
+// frame, followed by the series of its aggregations.
+func (rp *responseParser) processDocuments(res *es.SearchResponse, target *Query, series tsdb.TimeSeriesSlice) (data.Frames, error) {
+	isLogs := target.Metrics[0].Type == logsType
+	frames := data.Frames{}
+
+	if res.Hits != nil && len(res.Hits.Hits) > 0 {
+		frames = append(frames, rp.hitsToFrame(res.Hits.Hits, target, isLogs))
+	}
+
+	for _, s := range series {
+		frame, err := tsdb.SeriesToFrame(s)
+		if err != nil {
+			return nil, err
+		}
+		frame.RefID = target.RefID
+		// The logs are shown next to the graph of their count
+		if isLogs {
+			frame.Meta = &data.FrameMeta{PreferredVisualization: data.VisTypeGraph}
+		}
+		frames = append(frames, frame)
+	}
+
+	return frames, nil
+}
+
+// hitsToFrame returns a frame with a row for each hit. Like in the frontend,
+// the nested fields of the source of the hits are flattened to a field for
+// each path, and the hits do not need to have the same fields.
+func (rp *responseParser) hitsToFrame(hits []map[string]interface{}, target *Query, isLogs bool) *data.Frame {
+	docs := make([]map[string]interface{}, 0, len(hits))
+	propNames := map[string]bool{}
+	searchWords := []string{}
+	seenWords := map[string]bool{}
+
+	for _, hit := range hits {
+		doc := map[string]interface{}{
+			"_id":    hit["_id"],
+			"_type":  hit["_type"],
+			"_index": hit["_index"],
+		}
+		if sortValues, ok := hit["sort"]; ok {
+			doc["sort"] = sortValues
+		}
+		if highlight, ok := hit["highlight"].(map[string]interface{}); ok {
+			doc["highlight"] = highlight
+			for _, word := range highlightedWords(highlight) {
+				if !seenWords[word] {
+					seenWords[word] = true
+					searchWords = append(searchWords, word)
+				}
+			}
+		}
+
+		if source, ok := hit["_source"].(map[string]interface{}); ok {
+			flattened := flatten(source)
+			for k, v := range flattened {
+				doc[k] = v
+			}
+			if isLogs {
+				doc["_source"] = flattened
+			}
+		}
+
+		// The time field may only be a doc value field
+		if _, ok := doc[target.TimeField]; !ok {
+			if fields, ok := hit["fields"].(map[string]interface{}); ok {
+				if values, ok := fields[target.TimeField].([]interface{}); ok && len(values) > 0 {
+					doc[target.TimeField] = values[0]
+				}
+			}
+		}
+
+		if isLogs && rp.LogLevelField != "" {
+			doc["level"] = doc[rp.LogLevelField]
+		}
+
+		for k := range doc {
+			propNames[k] = true
+		}
+		docs = append(docs, doc)
+	}
+
+	names := []string{target.TimeField}
+	if isLogs && rp.LogMessageField != "" {
+		names = append(names, rp.LogMessageField)
+	}
+	if isLogs && rp.LogLevelField != "" {
+		names = append(names, "level")
+	}
+	for _, name := range names {
+		delete(propNames, name)
+	}
+	sortedNames := make([]string, 0, len(propNames))
+	for name := range propNames {
+		sortedNames = append(sortedNames, name)
+	}
+	sort.Strings(sortedNames)
+	names = append(names, sortedNames...)
+
+	fields := make([]*data.Field, 0, len(names))
+	fields = append(fields, documentTimeField(target.TimeField, docs))
+	for _, name := range names[1:] {
+		fields = append(fields, documentField(name, docs))
+	}
+
+	frame := data.NewFrame("", fields...)
+	frame.RefID = target.RefID
+	if isLogs {
+		frame.Meta = &data.FrameMeta{PreferredVisualization: data.VisTypeLogs}
+		if len(searchWords) > 0 {
+			frame.Meta.Custom = map[string]interface{}{"searchWords": searchWords}
+		}
+	}
+
+	return frame
+}
+
+// flatten returns the nested fields of a document with their paths joined by
+// dots as keys. Arrays are not flattened.
+func flatten(doc map[string]interface{}) map[string]interface{} {
+	flattened := make(map[string]interface{})
+	var step func(object map[string]interface{}, prefix string)
+	step = func(object map[string]interface{}, prefix string) {
+		for k, v := range object {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			if nested, ok := v.(map[string]interface{}); ok && len(nested) > 0 {
+				step(nested, key)
+				continue
+			}
+			flattened[key] = v
+		}
+	}
+	step(doc, "")
+	return flattened
+}
+
+// highlightedWords returns the words between the highlight tags in the
+// highlighted fragments of a hit.
+func highlightedWords(highlight map[string]interface{}) []string {
+	keys := make([]string, 0, len(highlight))
+	for k := range highlight {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	words := []string{}
+	for _, k := range keys {
+		fragments, _ := highlight[k].([]interface{})
+		for _, fragment := range fragments {
+			s, ok := fragment.(string)
+			if !ok {
+				continue
+			}
+			for _, match := range highlightRegex.FindAllStringSubmatch(s, -1) {
+				if match[1] != "" {
+					words = append(words, match[1])
+				}
+			}
+		}
+	}
+	return words
+}
+
+func documentTimeField(name string, docs []map[string]interface{}) *data.Field {
+	values := make([]*time.Time, len(docs))
+	for i, doc := range docs {
+		if t, ok := parseDocumentTime(doc[name]); ok {
+			values[i] = &t
+		}
+	}
+	return data.NewField(name, nil, values)
+}
+
+// parseDocumentTime parses dates in the default formats of Elasticsearch,
+// which are either ISO 8601 strings or milliseconds since the epoch.
+func parseDocumentTime(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case float64:
+		return time.Unix(0, int64(v)*int64(time.Millisecond)).UTC(), true
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02 15:04:05", "2006-01-02"} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t.UTC(), true
+			}
+		}
+		if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Unix(0, ms*int64(time.Millisecond)).UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// documentField returns a field with the values of a property of the
+// documents. Properties with only numbers or only booleans are typed as such,
+// anything else is a string.
+func documentField(name string, docs []map[string]interface{}) *data.Field {
+	fieldType := data.FieldTypeNullableString
+	found := false
+	for _, doc := range docs {
+		var t data.FieldType
+		switch doc[name].(type) {
+		case nil:
+			continue
+		case float64:
+			t = data.FieldTypeNullableFloat64
+		case bool:
+			t = data.FieldTypeNullableBool
+		default:
+			t = data.FieldTypeNullableString
+		}
+		if !found {
+			fieldType = t
+			found = true
+		} else if t != fieldType {
+			fieldType = data.FieldTypeNullableString
+			break
+		}
+	}
+
+	var field *data.Field
+	switch fieldType {
+	case data.FieldTypeNullableFloat64:
+		field = data.NewField(name, nil, make([]*float64, len(docs)))
+	case data.FieldTypeNullableBool:
+		field = data.NewField(name, nil, make([]*bool, len(docs)))
+	default:
+		field = data.NewField(name, nil, make([]*string, len(docs)))
+	}
+
+	for i, doc := range docs {
+		if doc[name] == nil {
+			continue
+		}
+		// The values have the type of the field, or are encoded as strings
+		if value, err := sqlValue(fieldType, doc[name]); err == nil {
+			field.Set(i, value)
+		}
+	}
+
+	return field
+}
@@ -41,6 +41,46 @@ func (e *ElasticsearchExecutor) Query(ctx context.Context, dsInfo *models.DataSo
 		client.EnableDebug()
 	}
 
-	query := newTimeSeriesQuery(client, tsdbQuery, intervalCalculator)
-	return query.execute()
+	var sqlQueries, timeSeriesQueries []*tsdb.Query
+	for _, q := range tsdbQuery.Queries {
+		if isSQLQuery(q) {
+			sqlQueries = append(sqlQueries, q)
+		} else {
+			timeSeriesQueries = append(timeSeriesQueries, q)
+		}
+	}
+
+	result := &tsdb.Response{
+		Results: make(map[string]*tsdb.QueryResult),
+	}
+
+	if len(timeSeriesQueries) > 0 {
+		query := newTimeSeriesQuery(client, withQueries(tsdbQuery, timeSeriesQueries), intervalCalculator)
+		res, err := query.execute()
+		if err != nil {
+			return nil, err
+		}
+		for refID, queryRes := range res.Results {
+			result.Results[refID] = queryRes
+		}
+	}
+
+	if len(sqlQueries) > 0 {
+		res, err := newSQLQuery(client, withQueries(tsdbQuery, sqlQueries)).execute()
+		if err != nil {
+			return nil, err
+		}
+		for refID, queryRes := range res.Results {
+			result.Results[refID] = queryRes
+		}
+	}
+
+	return result, nil
+}
+
+// withQueries returns a copy of the request with only some of its queries.
+func withQueries(tsdbQuery *tsdb.TsdbQuery, queries []*tsdb.Query) *tsdb.TsdbQuery {
+	copied := *tsdbQuery
+	copied.Queries = queries
+	return &copied
 }
@@ -51,6 +51,8 @@ var metricAggType = map[string]string{
 	"serial_diff":    "Serial Difference",
 	"bucket_script":  "Bucket Script",
 	"raw_document":   "Raw Document",
+	"raw_data":       "Raw Data",
+	"logs":           "Logs",
 }
 
 var extendedStats = map[string]string{
@@ -91,6 +93,17 @@ func isPipelineAggWithMultipleBucketPaths(metricType string) bool {
 	return false
 }
 
+// isDocumentMetric returns whether the metric type returns the hits instead
+// of an aggregation.
+func isDocumentMetric(metricType string) bool {
+	return metricType == rawDataType || metricType == logsType
+}
+
+// isDocumentQuery returns whether the query returns the hits as data frames.
+func isDocumentQuery(q *Query) bool {
+	return len(q.Metrics) > 0 && isDocumentMetric(q.Metrics[0].Type)
+}
+
 func describeMetric(metricType, field string) string {
 	text := metricAggType[metricType]
 	if metricType == countType {
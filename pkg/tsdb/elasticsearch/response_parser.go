@@ -18,18 +18,25 @@ const (
 	countType         = "count"
 	percentilesType   = "percentiles"
 	extendedStatsType = "extended_stats"
+	rawDataType       = "raw_data"
+	logsType          = "logs"
 	// Bucket types
 	dateHistType    = "date_histogram"
 	histogramType   = "histogram"
 	filtersType     = "filters"
 	termsType       = "terms"
 	geohashGridType = "geohash_grid"
+	compositeType   = "composite"
 )
 
 type responseParser struct {
 	Responses []*es.SearchResponse
 	Targets   []*Query
 	DebugInfo *es.SearchDebugInfo
+	// LogMessageField and LogLevelField are the fields of the messages and
+	// levels of logs, configured on the datasource
+	LogMessageField string
+	LogLevelField   string
 }
 
 var newResponseParser = func(responses []*es.SearchResponse, targets []*Query, debugInfo *es.SearchDebugInfo) *responseParser {
@@ -80,6 +87,15 @@ func (rp *responseParser) getTimeSeries() (*tsdb.Response, error) {
 			queryRes.Tables = append(queryRes.Tables, &table)
 		}
 
+		if isDocumentQuery(target) {
+			frames, err := rp.processDocuments(res, target, queryRes.Series)
+			if err != nil {
+				return nil, err
+			}
+			queryRes.Series = nil
+			queryRes.Dataframes = tsdb.NewDecodedDataFrames(frames)
+		}
+
 		result.Results[target.RefID] = queryRes
 	}
 	return result, nil
@@ -120,9 +136,9 @@ func (rp *responseParser) processBuckets(aggs map[string]interface{}, target *Qu
 					newProps[k] = v
 				}
 
-				if key, err := bucket.Get("key").String(); err == nil {
+				if key, err := bucketKey(bucket, aggDef).String(); err == nil {
 					newProps[aggDef.Field] = key
-				} else if key, err := bucket.Get("key").Int64(); err == nil {
+				} else if key, err := bucketKey(bucket, aggDef).Int64(); err == nil {
 					newProps[aggDef.Field] = strconv.FormatInt(key, 10)
 				}
 
@@ -169,7 +185,8 @@ func (rp *responseParser) processMetrics(esAgg *simplejson.Json, target *Query,
 		}
 
 		switch metric.Type {
-		case countType:
+		// Logs queries count the documents of the buckets
+		case countType, logsType:
 			newSeries := tsdb.TimeSeries{
 				Tags: make(map[string]string),
 			}
@@ -326,10 +343,10 @@ func (rp *responseParser) processAggregationDocs(esAgg *simplejson.Json, aggDef
 			values = append(values, props[propKey])
 		}
 
-		if key, err := bucket.Get("key").String(); err == nil {
+		if key, err := bucketKey(bucket, aggDef).String(); err == nil {
 			values = append(values, key)
 		} else {
-			values = append(values, castToNullFloat(bucket.Get("key")))
+			values = append(values, castToNullFloat(bucketKey(bucket, aggDef)))
 		}
 
 		for _, metric := range target.Metrics {
@@ -555,6 +572,15 @@ func castToNullFloat(j *simplejson.Json) null.Float {
 	return null.NewFloat(0, false)
 }
 
+// bucketKey returns the key of a bucket. The key of a composite aggregation
+// bucket has a value for each source, which is named after its field.
+func bucketKey(bucket *simplejson.Json, aggDef *BucketAgg) *simplejson.Json {
+	if aggDef.Type == compositeType {
+		return bucket.GetPath("key", aggDef.Field)
+	}
+	return bucket.Get("key")
+}
+
 func findAgg(target *Query, aggID string) (*BucketAgg, error) {
 	for _, v := range target.BucketAggs {
 		if aggID == v.ID {
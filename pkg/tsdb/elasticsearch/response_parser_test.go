@@ -6,12 +6,14 @@ import (
 	"testing"
 	"time"
 
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/openinsight-project/grafinsight/pkg/components/null"
 	"github.com/openinsight-project/grafinsight/pkg/components/simplejson"
 	es "github.com/openinsight-project/grafinsight/pkg/tsdb/elasticsearch/client"
 
 	"github.com/openinsight-project/grafinsight/pkg/tsdb"
 	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/require"
 )
 
 func TestResponseParser(t *testing.T) {
@@ -994,6 +996,122 @@ func TestResponseParser(t *testing.T) {
 	})
 }
 
+func TestResponseParserDocuments(t *testing.T) {
+	t.Run("Raw data query", func(t *testing.T) {
+		targets := map[string]string{
+			"A": `{
+				"timeField": "@timestamp",
+				"metrics": [{ "type": "raw_data", "id": "1" }]
+			}`,
+		}
+		response := `{
+			"responses": [{
+				"hits": {
+					"hits": [
+						{
+							"_id": "1",
+							"_index": "logs",
+							"_source": {
+								"@timestamp": "2018-05-15T17:51:00.000Z",
+								"host": { "name": "a", "ip": "10.0.0.1" },
+								"value": 1,
+								"tags": ["x", "y"]
+							}
+						},
+						{
+							"_id": "2",
+							"_index": "logs",
+							"_source": { "value": "n/a" },
+							"fields": { "@timestamp": ["2018-05-15T17:50:00.000Z"] }
+						}
+					]
+				}
+			}]
+		}`
+		rp, err := newResponseParserForTest(targets, response)
+		require.NoError(t, err)
+		result, err := rp.getTimeSeries()
+		require.NoError(t, err)
+
+		queryRes := result.Results["A"]
+		require.Empty(t, queryRes.Series)
+		frames, err := queryRes.Dataframes.Decoded()
+		require.NoError(t, err)
+		require.Len(t, frames, 1)
+
+		frame := frames[0]
+		require.Equal(t, "A", frame.RefID)
+		require.Equal(t, 2, frame.Rows())
+
+		names := make([]string, len(frame.Fields))
+		for i, f := range frame.Fields {
+			names[i] = f.Name
+		}
+		require.Equal(t, []string{"@timestamp", "_id", "_index", "_type", "host.ip", "host.name", "tags", "value"}, names)
+
+		require.Equal(t, time.Date(2018, 5, 15, 17, 51, 0, 0, time.UTC), *frame.Fields[0].At(0).(*time.Time))
+		require.Equal(t, time.Date(2018, 5, 15, 17, 50, 0, 0, time.UTC), *frame.Fields[0].At(1).(*time.Time))
+		require.Equal(t, "a", *frame.Fields[5].At(0).(*string))
+		require.Nil(t, frame.Fields[5].At(1))
+		require.Equal(t, `["x","y"]`, *frame.Fields[6].At(0).(*string))
+		// Numbers and strings in the same field are strings
+		require.Equal(t, "1", *frame.Fields[7].At(0).(*string))
+		require.Equal(t, "n/a", *frame.Fields[7].At(1).(*string))
+	})
+
+	t.Run("Logs query", func(t *testing.T) {
+		targets := map[string]string{
+			"A": `{
+				"timeField": "@timestamp",
+				"metrics": [{ "type": "logs", "id": "1" }],
+				"bucketAggs": [{ "type": "date_histogram", "field": "@timestamp", "id": "2" }]
+			}`,
+		}
+		response := `{
+			"responses": [{
+				"hits": {
+					"hits": [{
+						"_id": "1",
+						"_source": { "@timestamp": 1526406660000, "msg": "connection refused", "lvl": "error" },
+						"highlight": { "msg": ["connection @HIGHLIGHT@refused@/HIGHLIGHT@"] }
+					}]
+				},
+				"aggregations": {
+					"2": { "buckets": [{ "doc_count": 1, "key": 1526406600000 }] }
+				}
+			}]
+		}`
+		rp, err := newResponseParserForTest(targets, response)
+		require.NoError(t, err)
+		rp.LogMessageField = "msg"
+		rp.LogLevelField = "lvl"
+		result, err := rp.getTimeSeries()
+		require.NoError(t, err)
+
+		frames, err := result.Results["A"].Dataframes.Decoded()
+		require.NoError(t, err)
+		require.Len(t, frames, 2)
+
+		logs := frames[0]
+		require.Equal(t, data.VisType(data.VisTypeLogs), logs.Meta.PreferredVisualization)
+		require.Equal(t, map[string]interface{}{"searchWords": []string{"refused"}}, logs.Meta.Custom)
+		require.Equal(t, "@timestamp", logs.Fields[0].Name)
+		require.Equal(t, time.Date(2018, 5, 15, 17, 51, 0, 0, time.UTC), *logs.Fields[0].At(0).(*time.Time))
+		require.Equal(t, "msg", logs.Fields[1].Name)
+		require.Equal(t, "level", logs.Fields[2].Name)
+		require.Equal(t, "error", *logs.Fields[2].At(0).(*string))
+		names := make([]string, len(logs.Fields))
+		for i, f := range logs.Fields {
+			names[i] = f.Name
+		}
+		require.Equal(t, []string{"@timestamp", "msg", "level", "_id", "_index", "_source", "_type", "highlight", "lvl"}, names)
+
+		counts := frames[1]
+		require.Equal(t, data.VisTypeGraph, counts.Meta.PreferredVisualization)
+		require.Equal(t, 1.0, *counts.Fields[1].At(0).(*float64))
+	})
+}
+
 func newResponseParserForTest(tsdbQueries map[string]string, responseBody string) (*responseParser, error) {
 	from := time.Date(2018, 5, 15, 17, 50, 0, 0, time.UTC)
 	to := time.Date(2018, 5, 15, 17, 55, 0, 0, time.UTC)
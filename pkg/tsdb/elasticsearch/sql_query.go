@@ -0,0 +1,218 @@
+package elasticsearch
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/openinsight-project/grafinsight/pkg/tsdb"
+	es "github.com/openinsight-project/grafinsight/pkg/tsdb/elasticsearch/client"
+)
+
+const (
+	sqlQueryType = "sql"
+	// defaultSQLLimit is the default maximum number of rows of a SQL query.
+	defaultSQLLimit = 10000
+	// sqlFetchSize is the number of rows of a page of a SQL query.
+	sqlFetchSize = 1000
+)
+
+type sqlQuery struct {
+	client    es.Client
+	tsdbQuery *tsdb.TsdbQuery
+}
+
+var newSQLQuery = func(client es.Client, tsdbQuery *tsdb.TsdbQuery) *sqlQuery {
+	return &sqlQuery{
+		client:    client,
+		tsdbQuery: tsdbQuery,
+	}
+}
+
+// isSQLQuery returns whether the query is a SQL query instead of a query
+// with aggregations.
+func isSQLQuery(q *tsdb.Query) bool {
+	return q.QueryType == sqlQueryType || q.Model.Get("queryType").MustString() == sqlQueryType
+}
+
+func (e *sqlQuery) execute() (*tsdb.Response, error) {
+	from := fmt.Sprintf("%d", e.tsdbQuery.TimeRange.GetFromAsMsEpoch())
+	to := fmt.Sprintf("%d", e.tsdbQuery.TimeRange.GetToAsMsEpoch())
+	result := &tsdb.Response{
+		Results: make(map[string]*tsdb.QueryResult),
+	}
+
+	for _, q := range e.tsdbQuery.Queries {
+		queryRes, err := e.executeQuery(q, from, to)
+		if err != nil {
+			return nil, err
+		}
+		queryRes.RefId = q.RefId
+		result.Results[q.RefId] = queryRes
+	}
+
+	return result, nil
+}
+
+// executeQuery runs the SQL query over the time range, fetching the pages of
+// results until the limit of rows is reached.
+func (e *sqlQuery) executeQuery(q *tsdb.Query, from, to string) (*tsdb.QueryResult, error) {
+	query := strings.TrimSpace(q.Model.Get("query").MustString())
+	if query == "" {
+		return &tsdb.QueryResult{
+			Error:       fmt.Errorf("invalid query, missing SQL query"),
+			ErrorString: "invalid query, missing SQL query",
+		}, nil
+	}
+
+	limit := q.Model.Get("limit").MustInt(defaultSQLLimit)
+	if limit <= 0 {
+		limit = defaultSQLLimit
+	}
+	fetchSize := sqlFetchSize
+	if limit < fetchSize {
+		fetchSize = limit
+	}
+
+	req := &es.SQLRequest{
+		Query:     query,
+		FetchSize: fetchSize,
+		Filter: &es.RangeFilter{
+			Key:    e.client.GetTimeField(),
+			Gte:    from,
+			Lte:    to,
+			Format: es.DateFormatEpochMS,
+		},
+	}
+
+	var columns []es.SQLColumn
+	var rows [][]interface{}
+	for {
+		res, err := e.client.ExecuteSQL(req)
+		if err != nil {
+			return nil, err
+		}
+		if res.Error != nil {
+			return getErrorFromElasticResponse(&es.SearchResponse{Error: res.Error}), nil
+		}
+
+		// Only the first page has the columns
+		if columns == nil {
+			columns = res.Columns
+		}
+		rows = append(rows, res.Rows...)
+
+		if res.Cursor == "" {
+			break
+		}
+		if len(rows) >= limit {
+			// The cursor expires anyway if it can not be closed
+			_ = e.client.CloseSQLCursor(res.Cursor)
+			break
+		}
+		req = &es.SQLRequest{Cursor: res.Cursor}
+	}
+
+	if len(rows) > limit {
+		rows = rows[:limit]
+	}
+
+	frame, err := sqlResponseToFrame(columns, rows)
+	if err != nil {
+		return nil, err
+	}
+	frame.RefID = q.RefId
+	frame.Meta = &data.FrameMeta{
+		ExecutedQueryString: query,
+	}
+
+	queryRes := tsdb.NewQueryResult()
+	queryRes.Dataframes = tsdb.NewDecodedDataFrames(data.Frames{frame})
+	return queryRes, nil
+}
+
+// sqlResponseToFrame returns a frame with a field for each column, typed
+// after the Elasticsearch type of the column.
+func sqlResponseToFrame(columns []es.SQLColumn, rows [][]interface{}) (*data.Frame, error) {
+	fields := make([]*data.Field, len(columns))
+	for i, column := range columns {
+		switch sqlFieldType(column.Type) {
+		case data.FieldTypeNullableTime:
+			fields[i] = data.NewField(column.Name, nil, make([]*time.Time, len(rows)))
+		case data.FieldTypeNullableFloat64:
+			fields[i] = data.NewField(column.Name, nil, make([]*float64, len(rows)))
+		case data.FieldTypeNullableBool:
+			fields[i] = data.NewField(column.Name, nil, make([]*bool, len(rows)))
+		default:
+			fields[i] = data.NewField(column.Name, nil, make([]*string, len(rows)))
+		}
+	}
+
+	for rowIdx, row := range rows {
+		for colIdx, field := range fields {
+			if colIdx >= len(row) || row[colIdx] == nil {
+				continue
+			}
+
+			value, err := sqlValue(field.Type(), row[colIdx])
+			if err != nil {
+				return nil, fmt.Errorf("failed to read column %q: %w", columns[colIdx].Name, err)
+			}
+			field.Set(rowIdx, value)
+		}
+	}
+
+	return data.NewFrame("", fields...), nil
+}
+
+func sqlFieldType(esType string) data.FieldType {
+	switch strings.ToLower(esType) {
+	case "date", "datetime":
+		return data.FieldTypeNullableTime
+	case "byte", "short", "integer", "long", "unsigned_long", "double", "float", "half_float", "scaled_float":
+		return data.FieldTypeNullableFloat64
+	case "boolean":
+		return data.FieldTypeNullableBool
+	}
+	return data.FieldTypeNullableString
+}
+
+func sqlValue(fieldType data.FieldType, value interface{}) (interface{}, error) {
+	switch fieldType {
+	case data.FieldTypeNullableTime:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("unexpected time value %v", value)
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return nil, err
+		}
+		return &t, nil
+	case data.FieldTypeNullableFloat64:
+		f, ok := value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("unexpected numeric value %v", value)
+		}
+		return &f, nil
+	case data.FieldTypeNullableBool:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("unexpected boolean value %v", value)
+		}
+		return &b, nil
+	}
+
+	if s, ok := value.(string); ok {
+		return &s, nil
+	}
+	// Objects and arrays are kept as JSON
+	b, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	s := string(b)
+	return &s, nil
+}
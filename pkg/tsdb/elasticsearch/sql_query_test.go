@@ -0,0 +1,123 @@
+package elasticsearch
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/openinsight-project/grafinsight/pkg/components/simplejson"
+	"github.com/openinsight-project/grafinsight/pkg/tsdb"
+	es "github.com/openinsight-project/grafinsight/pkg/tsdb/elasticsearch/client"
+	"github.com/stretchr/testify/require"
+)
+
+func executeSQLQuery(t *testing.T, c es.Client, body string) *tsdb.Response {
+	t.Helper()
+
+	model, err := simplejson.NewJson([]byte(body))
+	require.NoError(t, err)
+
+	from := time.Date(2018, 5, 15, 17, 50, 0, 0, time.UTC)
+	to := time.Date(2018, 5, 15, 17, 55, 0, 0, time.UTC)
+	tsdbQuery := &tsdb.TsdbQuery{
+		Queries: []*tsdb.Query{{RefId: "A", Model: model}},
+		TimeRange: tsdb.NewTimeRange(
+			fmt.Sprintf("%d", from.UnixNano()/int64(time.Millisecond)),
+			fmt.Sprintf("%d", to.UnixNano()/int64(time.Millisecond))),
+	}
+
+	res, err := newSQLQuery(c, tsdbQuery).execute()
+	require.NoError(t, err)
+	return res
+}
+
+func TestSQLQuery(t *testing.T) {
+	t.Run("Should page through the results", func(t *testing.T) {
+		c := newFakeClient(70)
+		c.sqlResponses = []*es.SQLResponse{
+			{
+				Columns: []es.SQLColumn{
+					{Name: "@timestamp", Type: "datetime"},
+					{Name: "host", Type: "keyword"},
+					{Name: "value", Type: "long"},
+					{Name: "up", Type: "boolean"},
+				},
+				Rows: [][]interface{}{
+					{"2018-05-15T17:50:00.000Z", "a", float64(1), true},
+				},
+				Cursor: "page2",
+			},
+			{
+				Rows: [][]interface{}{
+					{"2018-05-15T17:51:00.000Z", nil, float64(2), false},
+				},
+			},
+		}
+
+		res := executeSQLQuery(t, c, `{"queryType": "sql", "query": "SELECT * FROM metrics"}`)
+
+		require.Len(t, c.sqlRequests, 2)
+		require.Equal(t, "SELECT * FROM metrics", c.sqlRequests[0].Query)
+		require.Equal(t, sqlFetchSize, c.sqlRequests[0].FetchSize)
+		rangeFilter := c.sqlRequests[0].Filter.(*es.RangeFilter)
+		require.Equal(t, "@timestamp", rangeFilter.Key)
+		require.Equal(t, "1526406600000", rangeFilter.Gte)
+		require.Equal(t, "page2", c.sqlRequests[1].Cursor)
+
+		frames, err := res.Results["A"].Dataframes.Decoded()
+		require.NoError(t, err)
+		require.Len(t, frames, 1)
+
+		frame := frames[0]
+		require.Equal(t, "A", frame.RefID)
+		require.Equal(t, 2, frame.Rows())
+		require.Equal(t, time.Date(2018, 5, 15, 17, 51, 0, 0, time.UTC), *frame.Fields[0].At(1).(*time.Time))
+		require.Equal(t, "a", *frame.Fields[1].At(0).(*string))
+		require.Nil(t, frame.Fields[1].At(1))
+		require.Equal(t, 2.0, *frame.Fields[2].At(1).(*float64))
+		require.Equal(t, false, *frame.Fields[3].At(1).(*bool))
+	})
+
+	t.Run("Should stop at the limit and close the cursor", func(t *testing.T) {
+		c := newFakeClient(70)
+		c.sqlResponses = []*es.SQLResponse{
+			{
+				Columns: []es.SQLColumn{{Name: "host", Type: "keyword"}},
+				Rows:    [][]interface{}{{"a"}, {"b"}, {"c"}},
+				Cursor:  "page2",
+			},
+		}
+
+		res := executeSQLQuery(t, c, `{"queryType": "sql", "query": "SELECT host FROM metrics", "limit": 2}`)
+
+		require.Len(t, c.sqlRequests, 1)
+		require.Equal(t, 2, c.sqlRequests[0].FetchSize)
+		require.Equal(t, []string{"page2"}, c.closedCursors)
+
+		frames, err := res.Results["A"].Dataframes.Decoded()
+		require.NoError(t, err)
+		require.Equal(t, 2, frames[0].Rows())
+	})
+
+	t.Run("Should return the error of the query", func(t *testing.T) {
+		c := newFakeClient(70)
+		c.sqlResponses = []*es.SQLResponse{
+			{
+				Error: map[string]interface{}{
+					"root_cause": []interface{}{map[string]interface{}{"reason": "Unknown index [metrics]"}},
+				},
+			},
+		}
+
+		res := executeSQLQuery(t, c, `{"queryType": "sql", "query": "SELECT * FROM metrics"}`)
+		require.Equal(t, "Unknown index [metrics]", res.Results["A"].ErrorString)
+	})
+
+	t.Run("Should return an error without query", func(t *testing.T) {
+		c := newFakeClient(70)
+
+		res := executeSQLQuery(t, c, `{"queryType": "sql", "query": " "}`)
+		require.Empty(t, c.sqlRequests)
+		require.Error(t, res.Results["A"].Error)
+	})
+}
@@ -10,6 +10,18 @@ import (
 	es "github.com/openinsight-project/grafinsight/pkg/tsdb/elasticsearch/client"
 )
 
+const (
+	// defaultCompositeSize is the default number of buckets of a page of a
+	// composite aggregation.
+	defaultCompositeSize = 500
+	// maxCompositePages is the maximum number of pages of a composite
+	// aggregation.
+	maxCompositePages = 100
+	// defaultDocumentSize is the default number of hits of raw data and logs
+	// queries.
+	defaultDocumentSize = 500
+)
+
 type timeSeriesQuery struct {
 	client             es.Client
 	tsdbQuery          *tsdb.TsdbQuery
@@ -54,7 +66,13 @@ func (e *timeSeriesQuery) execute() (*tsdb.Response, error) {
 		return nil, err
 	}
 
+	if err := e.pageCompositeAggs(queries, req, res); err != nil {
+		return nil, err
+	}
+
 	rp := newResponseParser(res.Responses, queries, res.DebugInfo)
+	rp.LogMessageField = e.client.GetLogMessageField()
+	rp.LogLevelField = e.client.GetLogLevelField()
 	return rp.getTimeSeries()
 }
 
@@ -75,6 +93,14 @@ func (e *timeSeriesQuery) processQuery(q *Query, ms *es.MultiSearchRequestBuilde
 		filters.AddQueryStringFilter(q.RawQuery, true)
 	}
 
+	if isDocumentQuery(q) {
+		e.processDocumentQuery(q, b)
+		// Logs queries may also count the documents over time
+		if q.Metrics[0].Type == rawDataType || len(q.BucketAggs) == 0 {
+			return nil
+		}
+	}
+
 	if len(q.BucketAggs) == 0 {
 		if len(q.Metrics) == 0 || q.Metrics[0].Type != "raw_document" {
 			result.Results[q.RefID] = &tsdb.QueryResult{
@@ -91,6 +117,20 @@ func (e *timeSeriesQuery) processQuery(q *Query, ms *es.MultiSearchRequestBuilde
 		return nil
 	}
 
+	for i, bucketAgg := range q.BucketAggs {
+		if bucketAgg.Type != compositeType {
+			continue
+		}
+		// The buckets of the composite aggregation are paged, which is only
+		// possible at the top level
+		if i > 0 {
+			return fmt.Errorf("composite aggregation must be the first bucket aggregation")
+		}
+		if e.client.GetVersion() < 60 {
+			return fmt.Errorf("composite aggregations are not supported by elasticsearch version=%d", e.client.GetVersion())
+		}
+	}
+
 	aggBuilder := b.Agg()
 
 	// iterate backwards to create aggregations bottom-down
@@ -106,12 +146,14 @@ func (e *timeSeriesQuery) processQuery(q *Query, ms *es.MultiSearchRequestBuilde
 			aggBuilder = addTermsAgg(aggBuilder, bucketAgg, q.Metrics)
 		case geohashGridType:
 			aggBuilder = addGeoHashGridAgg(aggBuilder, bucketAgg)
+		case compositeType:
+			aggBuilder = addCompositeAgg(aggBuilder, bucketAgg)
 		}
 	}
 
 	for _, m := range q.Metrics {
 		m := m
-		if m.Type == countType {
+		if m.Type == countType || isDocumentMetric(m.Type) {
 			continue
 		}
 
@@ -177,6 +219,39 @@ func (e *timeSeriesQuery) processQuery(q *Query, ms *es.MultiSearchRequestBuilde
 	return nil
 }
 
+// processDocumentQuery requests the hits of raw data and logs queries, sorted
+// by time.
+func (e *timeSeriesQuery) processDocumentQuery(q *Query, b *es.SearchRequestBuilder) {
+	metric := q.Metrics[0]
+
+	size := defaultDocumentSize
+	if s, err := metric.Settings.Get("size").Int(); err == nil {
+		size = s
+	} else if s, err := metric.Settings.Get("size").String(); err == nil {
+		size, _ = strconv.Atoi(s)
+	}
+	if size <= 0 {
+		size = defaultDocumentSize
+	}
+
+	order := "desc"
+	if metric.Settings.Get("sortDirection").MustString() == "asc" {
+		order = "asc"
+	}
+
+	timeField := q.TimeField
+	if timeField == "" {
+		timeField = e.client.GetTimeField()
+	}
+
+	b.Size(size)
+	b.Sort(order, timeField, "boolean")
+	b.AddDocValueField(timeField)
+	if metric.Type == logsType {
+		b.AddHighlight()
+	}
+}
+
 func addDateHistogramAgg(aggBuilder es.AggBuilder, bucketAgg *BucketAgg, timeFrom, timeTo string) es.AggBuilder {
 	aggBuilder.DateHistogram(bucketAgg.ID, bucketAgg.Field, func(a *es.DateHistogramAgg, b es.AggBuilder) {
 		a.Interval = bucketAgg.Settings.Get("interval").MustString("auto")
@@ -302,6 +377,109 @@ func addGeoHashGridAgg(aggBuilder es.AggBuilder, bucketAgg *BucketAgg) es.AggBui
 	return aggBuilder
 }
 
+func addCompositeAgg(aggBuilder es.AggBuilder, bucketAgg *BucketAgg) es.AggBuilder {
+	aggBuilder.Composite(bucketAgg.ID, func(a *es.CompositeAggregation, b es.AggBuilder) {
+		if size, err := bucketAgg.Settings.Get("size").Int(); err == nil {
+			a.Size = size
+		} else if size, err := bucketAgg.Settings.Get("size").String(); err == nil {
+			a.Size, _ = strconv.Atoi(size)
+		}
+		if a.Size <= 0 {
+			a.Size = defaultCompositeSize
+		}
+
+		terms := map[string]interface{}{
+			"field": bucketAgg.Field,
+			"order": bucketAgg.Settings.Get("order").MustString("asc"),
+		}
+		if bucketAgg.Settings.Get("missing_bucket").MustBool(false) {
+			terms["missing_bucket"] = true
+		}
+		a.Sources = append(a.Sources, map[string]interface{}{
+			bucketAgg.Field: map[string]interface{}{"terms": terms},
+		})
+
+		aggBuilder = b
+	})
+
+	return aggBuilder
+}
+
+// pageCompositeAggs queries the next pages of the composite aggregations,
+// and appends their buckets to the first page. Queries with more pages than
+// allowed fail instead of returning only some of the buckets.
+func (e *timeSeriesQuery) pageCompositeAggs(queries []*Query, req *es.MultiSearchRequest, res *es.MultiSearchResponse) error {
+	for i, q := range queries {
+		if i >= len(res.Responses) || i >= len(req.Requests) || len(q.BucketAggs) == 0 || q.BucketAggs[0].Type != compositeType {
+			continue
+		}
+
+		firstPage := res.Responses[i]
+		aggID := q.BucketAggs[0].ID
+		if firstPage.Error != nil {
+			continue
+		}
+		agg, ok := firstPage.Aggregations[aggID].(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		searchReq := req.Requests[i]
+		var composite *es.CompositeAggregation
+		for _, a := range searchReq.Aggs {
+			if a.Key == aggID {
+				composite, _ = a.Aggregation.Aggregation.(*es.CompositeAggregation)
+			}
+		}
+		if composite == nil {
+			continue
+		}
+
+		buckets, _ := agg["buckets"].([]interface{})
+		page := agg
+		for pages := 1; ; pages++ {
+			afterKey, ok := page["after_key"].(map[string]interface{})
+			pageBuckets, _ := page["buckets"].([]interface{})
+			if !ok || len(pageBuckets) < composite.Size {
+				break
+			}
+			if pages >= maxCompositePages {
+				firstPage.Error = map[string]interface{}{
+					"reason": fmt.Sprintf("composite aggregation has more than %d buckets", maxCompositePages*composite.Size),
+				}
+				break
+			}
+
+			composite.After = afterKey
+			pageRes, err := e.client.ExecuteMultisearch(&es.MultiSearchRequest{
+				Requests: []*es.SearchRequest{searchReq},
+			})
+			if err != nil {
+				return err
+			}
+			if len(pageRes.Responses) == 0 {
+				break
+			}
+			if pageRes.Responses[0].Error != nil {
+				firstPage.Error = pageRes.Responses[0].Error
+				break
+			}
+
+			page, ok = pageRes.Responses[0].Aggregations[aggID].(map[string]interface{})
+			if !ok {
+				break
+			}
+			pageBuckets, _ = page["buckets"].([]interface{})
+			buckets = append(buckets, pageBuckets...)
+		}
+
+		agg["buckets"] = buckets
+		delete(agg, "after_key")
+	}
+
+	return nil
+}
+
 type timeSeriesQueryParser struct{}
 
 func newTimeSeriesQueryParser() *timeSeriesQueryParser {
@@ -10,6 +10,7 @@ import (
 	"github.com/openinsight-project/grafinsight/pkg/components/simplejson"
 	"github.com/openinsight-project/grafinsight/pkg/tsdb"
 	. "github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/require"
 )
 
 func TestExecuteTimeSeriesQuery(t *testing.T) {
@@ -855,13 +856,151 @@ func TestExecuteTimeSeriesQuery(t *testing.T) {
 	})
 }
 
+func TestExecuteCompositeAggregation(t *testing.T) {
+	from := time.Date(2018, 5, 15, 17, 50, 0, 0, time.UTC)
+	to := time.Date(2018, 5, 15, 17, 55, 0, 0, time.UTC)
+	body := `{
+		"timeField": "@timestamp",
+		"bucketAggs": [
+			{ "type": "composite", "id": "2", "field": "@host", "settings": { "size": "1" } },
+			{ "type": "date_histogram", "field": "@timestamp", "id": "3" }
+		],
+		"metrics": [{"type": "count", "id": "1" }]
+	}`
+
+	page := func(host string, afterKey bool) *es.MultiSearchResponse {
+		agg := map[string]interface{}{
+			"buckets": []interface{}{
+				map[string]interface{}{
+					"key": map[string]interface{}{"@host": host},
+					"3": map[string]interface{}{
+						"buckets": []interface{}{
+							map[string]interface{}{"key": float64(1000), "doc_count": float64(1)},
+						},
+					},
+				},
+			},
+		}
+		if afterKey {
+			agg["after_key"] = map[string]interface{}{"@host": host}
+		}
+		return &es.MultiSearchResponse{
+			Responses: []*es.SearchResponse{
+				{Aggregations: map[string]interface{}{"2": agg}},
+			},
+		}
+	}
+
+	t.Run("Should page through the buckets", func(t *testing.T) {
+		c := newFakeClient(70)
+		c.multiSearchPages = []*es.MultiSearchResponse{page("a", true), page("b", true), page("c", false)}
+
+		res, err := executeTsdbQuery(c, body, from, to, 15*time.Second)
+		require.NoError(t, err)
+
+		require.Len(t, c.multisearchRequests, 3)
+		composite := c.multisearchRequests[2].Requests[0].Aggs[0].Aggregation.Aggregation.(*es.CompositeAggregation)
+		require.Equal(t, 1, composite.Size)
+		require.Equal(t, map[string]interface{}{"@host": "b"}, composite.After)
+
+		series := res.Results[""].Series
+		require.Len(t, series, 3)
+		require.Equal(t, "a", series[0].Tags["@host"])
+		require.Equal(t, "b", series[1].Tags["@host"])
+		require.Equal(t, "c", series[2].Tags["@host"])
+	})
+
+	t.Run("Should fail with too many pages", func(t *testing.T) {
+		c := newFakeClient(70)
+		c.multiSearchResponse = page("a", true)
+
+		res, err := executeTsdbQuery(c, body, from, to, 15*time.Second)
+		require.NoError(t, err)
+
+		require.Len(t, c.multisearchRequests, maxCompositePages)
+		require.Equal(t, "composite aggregation has more than 100 buckets", res.Results[""].ErrorString)
+	})
+
+	t.Run("Should fail when it is not the first bucket aggregation", func(t *testing.T) {
+		c := newFakeClient(70)
+		_, err := executeTsdbQuery(c, `{
+			"timeField": "@timestamp",
+			"bucketAggs": [
+				{ "type": "date_histogram", "field": "@timestamp", "id": "3" },
+				{ "type": "composite", "id": "2", "field": "@host" }
+			],
+			"metrics": [{"type": "count", "id": "1" }]
+		}`, from, to, 15*time.Second)
+		require.Error(t, err)
+	})
+
+	t.Run("Should fail with elasticsearch version 5", func(t *testing.T) {
+		c := newFakeClient(56)
+		_, err := executeTsdbQuery(c, body, from, to, 15*time.Second)
+		require.Error(t, err)
+	})
+}
+
+func TestExecuteDocumentQuery(t *testing.T) {
+	from := time.Date(2018, 5, 15, 17, 50, 0, 0, time.UTC)
+	to := time.Date(2018, 5, 15, 17, 55, 0, 0, time.UTC)
+
+	t.Run("Raw data query", func(t *testing.T) {
+		c := newFakeClient(70)
+		_, err := executeTsdbQuery(c, `{
+			"timeField": "time",
+			"bucketAggs": [],
+			"metrics": [{ "id": "1", "type": "raw_data", "settings": { "size": "1337", "sortDirection": "asc" } }]
+		}`, from, to, 15*time.Second)
+		require.NoError(t, err)
+
+		sr := c.multisearchRequests[0].Requests[0]
+		require.Equal(t, 1337, sr.Size)
+		require.Equal(t, map[string]interface{}{
+			"time": map[string]string{"order": "asc", "unmapped_type": "boolean"},
+		}, sr.Sort)
+		require.Equal(t, []string{"time"}, sr.CustomProps["docvalue_fields"])
+		require.Empty(t, sr.Aggs)
+		require.NotContains(t, sr.CustomProps, "highlight")
+	})
+
+	t.Run("Logs query", func(t *testing.T) {
+		c := newFakeClient(70)
+		_, err := executeTsdbQuery(c, `{
+			"timeField": "@timestamp",
+			"bucketAggs": [{ "type": "date_histogram", "field": "@timestamp", "id": "2" }],
+			"metrics": [{ "id": "1", "type": "logs" }]
+		}`, from, to, 15*time.Second)
+		require.NoError(t, err)
+
+		sr := c.multisearchRequests[0].Requests[0]
+		require.Equal(t, defaultDocumentSize, sr.Size)
+		require.Equal(t, map[string]interface{}{
+			"@timestamp": map[string]string{"order": "desc", "unmapped_type": "boolean"},
+		}, sr.Sort)
+		require.Contains(t, sr.CustomProps, "highlight")
+
+		require.Len(t, sr.Aggs, 1)
+		require.Equal(t, "date_histogram", sr.Aggs[0].Aggregation.Type)
+		// The documents are only counted
+		require.Empty(t, sr.Aggs[0].Aggregation.Aggs)
+	})
+}
+
 type fakeClient struct {
 	version             int
 	timeField           string
+	logMessageField     string
+	logLevelField       string
 	multiSearchResponse *es.MultiSearchResponse
 	multiSearchError    error
 	builder             *es.MultiSearchRequestBuilder
 	multisearchRequests []*es.MultiSearchRequest
+	// multiSearchPages are returned in turn instead of multiSearchResponse
+	multiSearchPages []*es.MultiSearchResponse
+	sqlRequests      []*es.SQLRequest
+	sqlResponses     []*es.SQLResponse
+	closedCursors    []string
 }
 
 func newFakeClient(version int) *fakeClient {
@@ -883,15 +1022,40 @@ func (c *fakeClient) GetTimeField() string {
 	return c.timeField
 }
 
+func (c *fakeClient) GetLogMessageField() string {
+	return c.logMessageField
+}
+
+func (c *fakeClient) GetLogLevelField() string {
+	return c.logLevelField
+}
+
 func (c *fakeClient) GetMinInterval(queryInterval string) (time.Duration, error) {
 	return 15 * time.Second, nil
 }
 
 func (c *fakeClient) ExecuteMultisearch(r *es.MultiSearchRequest) (*es.MultiSearchResponse, error) {
 	c.multisearchRequests = append(c.multisearchRequests, r)
+	if len(c.multiSearchPages) > 0 {
+		page := c.multiSearchPages[0]
+		c.multiSearchPages = c.multiSearchPages[1:]
+		return page, c.multiSearchError
+	}
 	return c.multiSearchResponse, c.multiSearchError
 }
 
+func (c *fakeClient) ExecuteSQL(r *es.SQLRequest) (*es.SQLResponse, error) {
+	c.sqlRequests = append(c.sqlRequests, r)
+	res := c.sqlResponses[0]
+	c.sqlResponses = c.sqlResponses[1:]
+	return res, nil
+}
+
+func (c *fakeClient) CloseSQLCursor(cursor string) error {
+	c.closedCursors = append(c.closedCursors, cursor)
+	return nil
+}
+
 func (c *fakeClient) MultiSearch() *es.MultiSearchRequestBuilder {
 	c.builder = es.NewMultiSearchRequestBuilder(c.version)
 	return c.builder
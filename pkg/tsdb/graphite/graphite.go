@@ -131,6 +131,7 @@ func (e *GraphiteExecutor) Query(ctx context.Context, dsInfo *models.DataSource,
 	for _, series := range data {
 		queryRes.Series = append(queryRes.Series, &tsdb.TimeSeries{
 			Name:   series.Target,
+			Tags:   parseSeriesTags(series),
 			Points: series.DataPoints,
 		})
 
@@ -175,6 +176,35 @@ func (e *GraphiteExecutor) parseResponse(res *http.Response) ([]TargetResponseDT
 	return data, nil
 }
 
+// parseSeriesTags returns the tags of a series, so that they are the labels of
+// its frame. The tags are in the response of Graphite 1.1 and later, or in the
+// name of tagged series, which has the form name;tag1=value1;tag2=value2.
+func parseSeriesTags(series TargetResponseDTO) map[string]string {
+	if len(series.Tags) > 0 {
+		tags := make(map[string]string, len(series.Tags))
+		for name, value := range series.Tags {
+			tags[name] = fmt.Sprint(value)
+		}
+		return tags
+	}
+
+	// Names of series processed by functions can contain semicolons in
+	// their arguments
+	if !strings.Contains(series.Target, ";") || strings.ContainsAny(series.Target, "()") {
+		return nil
+	}
+	parts := strings.Split(series.Target, ";")
+	tags := map[string]string{"name": parts[0]}
+	for _, part := range parts[1:] {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil
+		}
+		tags[kv[0]] = kv[1]
+	}
+	return tags
+}
+
 func (e *GraphiteExecutor) createRequest(dsInfo *models.DataSource, data url.Values) (*http.Request, error) {
 	u, err := url.Parse(dsInfo.Url)
 	if err != nil {
@@ -59,3 +59,44 @@ func TestFixIntervalFormat(t *testing.T) {
 		})
 	}
 }
+
+func TestParseSeriesTags(t *testing.T) {
+	testCases := []struct {
+		name     string
+		series   TargetResponseDTO
+		expected map[string]string
+	}{
+		{
+			name: "should use the tags of the response",
+			series: TargetResponseDTO{
+				Target: "sumSeries(seriesByTag('name=cpu'))",
+				Tags:   map[string]interface{}{"name": "cpu", "host": "a", "aggregatedBy": "sum"},
+			},
+			expected: map[string]string{"name": "cpu", "host": "a", "aggregatedBy": "sum"},
+		},
+		{
+			name:     "should parse the name of tagged series",
+			series:   TargetResponseDTO{Target: "disk.used;datacenter=dc1;rack=a1;server=web01"},
+			expected: map[string]string{"name": "disk.used", "datacenter": "dc1", "rack": "a1", "server": "web01"},
+		},
+		{
+			name:     "should keep equal signs in tag values",
+			series:   TargetResponseDTO{Target: "query.count;query=a=b"},
+			expected: map[string]string{"name": "query.count", "query": "a=b"},
+		},
+		{
+			name:   "should not parse the name of series without tags",
+			series: TargetResponseDTO{Target: "app.grafinsight.dashboards.views.count"},
+		},
+		{
+			name:   "should not parse the name of series processed by functions",
+			series: TargetResponseDTO{Target: "alias(cpu;host=a, 'x;y=z')"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, parseSeriesTags(tc.series))
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package graphite
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/resource/httpadapter"
+	"golang.org/x/net/context/ctxhttp"
+
+	"github.com/openinsight-project/grafinsight/pkg/bus"
+	"github.com/openinsight-project/grafinsight/pkg/infra/log"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/plugins/backendplugin"
+	"github.com/openinsight-project/grafinsight/pkg/plugins/backendplugin/coreplugin"
+	"github.com/openinsight-project/grafinsight/pkg/registry"
+)
+
+func init() {
+	registry.RegisterService(&graphiteResources{})
+}
+
+// graphiteResources serves the resources of Graphite data sources, which are
+// the autocompletion of tags for seriesByTag queries and the descriptions of
+// the functions of the server.
+type graphiteResources struct {
+	BackendPluginManager backendplugin.Manager `inject:""`
+	logger               log.Logger
+}
+
+func (r *graphiteResources) Init() error {
+	r.logger = log.New("tsdb.graphite")
+	resourceMux := http.NewServeMux()
+	r.registerRoutes(resourceMux)
+	factory := coreplugin.New(backend.ServeOpts{
+		CallResourceHandler: httpadapter.New(resourceMux),
+	})
+	if err := r.BackendPluginManager.Register("graphite", factory); err != nil {
+		r.logger.Error("Failed to register plugin", "error", err)
+	}
+	return nil
+}
+
+func (r *graphiteResources) registerRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("/tags", r.handleTags)
+	mux.HandleFunc("/tags/values", r.handleTagValues)
+	mux.HandleFunc("/functions", r.handleFunctions)
+}
+
+// handleTags returns the tags of the series matching the expr parameters, with
+// the tagPrefix, limit, from and until parameters of the Graphite API.
+func (r *graphiteResources) handleTags(rw http.ResponseWriter, req *http.Request) {
+	params := forwardParams(req.URL.Query(), "expr", "tagPrefix", "limit", "from", "until")
+	r.handleAutoComplete(rw, req, "tags/autoComplete/tags", params)
+}
+
+// handleTagValues returns the values of the tag parameter of the series
+// matching the expr parameters, with the valuePrefix, limit, from and until
+// parameters of the Graphite API.
+func (r *graphiteResources) handleTagValues(rw http.ResponseWriter, req *http.Request) {
+	if req.URL.Query().Get("tag") == "" {
+		writeError(rw, http.StatusBadRequest, "missing tag parameter")
+		return
+	}
+	params := forwardParams(req.URL.Query(), "expr", "tag", "valuePrefix", "limit", "from", "until")
+	r.handleAutoComplete(rw, req, "tags/autoComplete/values", params)
+}
+
+func (r *graphiteResources) handleAutoComplete(rw http.ResponseWriter, req *http.Request, apiPath string, params url.Values) {
+	body, status, err := r.get(req.Context(), httpadapter.PluginConfigFromContext(req.Context()), apiPath, params)
+	if err != nil {
+		r.writeRequestError(rw, status, err)
+		return
+	}
+
+	var values []string
+	if err := json.Unmarshal(body, &values); err != nil {
+		r.logger.Info("Failed to unmarshal graphite response", "error", err, "body", string(body))
+		writeError(rw, http.StatusBadGateway, "invalid response from Graphite")
+		return
+	}
+	writeJSON(rw, values)
+}
+
+// Graphite writes infinite defaults of function parameters as Infinity, which
+// is not valid JSON.
+var infinityRegex = regexp.MustCompile(`:\s*(-?)Infinity\b`)
+
+// handleFunctions returns the descriptions of the functions of the server.
+func (r *graphiteResources) handleFunctions(rw http.ResponseWriter, req *http.Request) {
+	body, status, err := r.get(req.Context(), httpadapter.PluginConfigFromContext(req.Context()), "functions", nil)
+	if err != nil {
+		r.writeRequestError(rw, status, err)
+		return
+	}
+
+	body = infinityRegex.ReplaceAll(body, []byte(`: "${1}Infinity"`))
+	var functions map[string]json.RawMessage
+	if err := json.Unmarshal(body, &functions); err != nil {
+		r.logger.Info("Failed to unmarshal graphite functions", "error", err)
+		writeError(rw, http.StatusBadGateway, "invalid response from Graphite")
+		return
+	}
+	writeJSON(rw, functions)
+}
+
+// get sends a GET request to the API of the data source of a plugin context,
+// and returns the body of the response. The status is the one to respond with
+// when there is an error.
+func (r *graphiteResources) get(ctx context.Context, pCtx backend.PluginContext, apiPath string, params url.Values) ([]byte, int, error) {
+	if pCtx.DataSourceInstanceSettings == nil {
+		return nil, http.StatusBadRequest, fmt.Errorf("missing data source")
+	}
+
+	query := &models.GetDataSourceQuery{Id: pCtx.DataSourceInstanceSettings.ID, OrgId: pCtx.OrgID}
+	if err := bus.Dispatch(query); err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to load data source: %w", err)
+	}
+	dsInfo := query.Result
+
+	u, err := url.Parse(dsInfo.Url)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	u.Path = path.Join(u.Path, apiPath)
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to create request: %w", err)
+	}
+	if dsInfo.BasicAuth {
+		req.SetBasicAuth(dsInfo.BasicAuthUser, dsInfo.DecryptedBasicAuthPassword())
+	}
+
+	httpClient, err := dsInfo.GetHttpClient()
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+
+	res, err := ctxhttp.Do(ctx, httpClient, req)
+	if err != nil {
+		return nil, http.StatusBadGateway, err
+	}
+	defer func() {
+		if err := res.Body.Close(); err != nil {
+			r.logger.Warn("Failed to close response body", "err", err)
+		}
+	}()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, http.StatusBadGateway, err
+	}
+	if res.StatusCode/100 != 2 {
+		r.logger.Info("Request failed", "status", res.Status, "body", string(body))
+		return nil, res.StatusCode, fmt.Errorf("request failed, status: %s", res.Status)
+	}
+	return body, http.StatusOK, nil
+}
+
+func (r *graphiteResources) writeRequestError(rw http.ResponseWriter, status int, err error) {
+	if status >= http.StatusInternalServerError {
+		r.logger.Error("Graphite resource request failed", "error", err)
+	}
+	writeError(rw, status, err.Error())
+}
+
+// forwardParams returns the parameters with the given names.
+func forwardParams(query url.Values, names ...string) url.Values {
+	params := url.Values{}
+	for _, name := range names {
+		if values, ok := query[name]; ok {
+			params[name] = values
+		}
+	}
+	return params
+}
+
+func writeJSON(rw http.ResponseWriter, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		writeError(rw, http.StatusInternalServerError, err.Error())
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	if _, err := rw.Write(body); err != nil {
+		glog.Error("Failed to write response", "error", err)
+	}
+}
+
+func writeError(rw http.ResponseWriter, status int, message string) {
+	body, _ := json.Marshal(map[string]string{"message": message})
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	if _, err := rw.Write(body); err != nil {
+		glog.Error("Failed to write response", "error", err)
+	}
+}
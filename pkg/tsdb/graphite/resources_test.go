@@ -0,0 +1,152 @@
+package graphite
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/resource/httpadapter"
+	"github.com/openinsight-project/grafinsight/pkg/bus"
+	"github.com/openinsight-project/grafinsight/pkg/components/simplejson"
+	"github.com/openinsight-project/grafinsight/pkg/infra/log"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type resourceResponseSender struct {
+	response *backend.CallResourceResponse
+}
+
+func (s *resourceResponseSender) Send(res *backend.CallResourceResponse) error {
+	s.response = res
+	return nil
+}
+
+func TestResources(t *testing.T) {
+	var graphiteRequest *http.Request
+	graphiteResponse := ""
+	graphite := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		graphiteRequest = req
+		if graphiteResponse == "" {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, err := rw.Write([]byte(graphiteResponse))
+		require.NoError(t, err)
+	}))
+	t.Cleanup(graphite.Close)
+
+	bus.AddHandler("test", func(query *models.GetDataSourceQuery) error {
+		query.Result = &models.DataSource{
+			Id:            query.Id,
+			OrgId:         query.OrgId,
+			Url:           graphite.URL + "/graphite",
+			BasicAuth:     true,
+			BasicAuthUser: "user",
+			JsonData:      simplejson.New(),
+		}
+		return nil
+	})
+	t.Cleanup(bus.ClearBusHandlers)
+
+	resources := &graphiteResources{logger: log.New("test")}
+	mux := http.NewServeMux()
+	resources.registerRoutes(mux)
+	handler := httpadapter.New(mux)
+
+	callResource := func(t *testing.T, rawURL string) *backend.CallResourceResponse {
+		t.Helper()
+
+		u, err := url.Parse(rawURL)
+		require.NoError(t, err)
+		sender := &resourceResponseSender{}
+		err = handler.CallResource(context.Background(), &backend.CallResourceRequest{
+			PluginContext: backend.PluginContext{
+				OrgID:                      1,
+				DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{ID: 2},
+			},
+			Path:   u.Path,
+			Method: http.MethodGet,
+			URL:    rawURL,
+		}, sender)
+		require.NoError(t, err)
+		require.NotNil(t, sender.response)
+		return sender.response
+	}
+
+	t.Run("Should return the tags of series", func(t *testing.T) {
+		graphiteResponse = `["datacenter", "name", "server"]`
+
+		res := callResource(t, "tags?expr=name%3Ddisk.used&expr=server%3D~web.*&limit=10&unknown=1")
+		assert.Equal(t, http.StatusOK, res.Status)
+		assert.JSONEq(t, `["datacenter", "name", "server"]`, string(res.Body))
+
+		assert.Equal(t, "/graphite/tags/autoComplete/tags", graphiteRequest.URL.Path)
+		assert.Equal(t, []string{"name=disk.used", "server=~web.*"}, graphiteRequest.URL.Query()["expr"])
+		assert.Equal(t, "10", graphiteRequest.URL.Query().Get("limit"))
+		assert.NotContains(t, graphiteRequest.URL.Query(), "unknown")
+		user, _, ok := graphiteRequest.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "user", user)
+	})
+
+	t.Run("Should return the values of tags", func(t *testing.T) {
+		graphiteResponse = `["web01", "web02"]`
+
+		res := callResource(t, "tags/values?expr=name%3Ddisk.used&tag=server&valuePrefix=web")
+		assert.Equal(t, http.StatusOK, res.Status)
+		assert.JSONEq(t, `["web01", "web02"]`, string(res.Body))
+
+		assert.Equal(t, "/graphite/tags/autoComplete/values", graphiteRequest.URL.Path)
+		assert.Equal(t, "server", graphiteRequest.URL.Query().Get("tag"))
+		assert.Equal(t, "web", graphiteRequest.URL.Query().Get("valuePrefix"))
+	})
+
+	t.Run("Should require a tag for the values of tags", func(t *testing.T) {
+		res := callResource(t, "tags/values?expr=name%3Ddisk.used")
+		assert.Equal(t, http.StatusBadRequest, res.Status)
+	})
+
+	t.Run("Should return the descriptions of functions", func(t *testing.T) {
+		graphiteResponse = `{
+			"removeAboveValue": {
+				"name": "removeAboveValue",
+				"group": "Filter Data",
+				"params": [{"name": "n", "type": "float", "default": Infinity, "required": true}]
+			},
+			"removeBelowValue": {
+				"name": "removeBelowValue",
+				"group": "Filter Data",
+				"params": [{"name": "n", "type": "float", "default":-Infinity}]
+			}
+		}`
+
+		res := callResource(t, "functions")
+		assert.Equal(t, http.StatusOK, res.Status)
+		assert.JSONEq(t, `{
+			"removeAboveValue": {
+				"name": "removeAboveValue",
+				"group": "Filter Data",
+				"params": [{"name": "n", "type": "float", "default": "Infinity", "required": true}]
+			},
+			"removeBelowValue": {
+				"name": "removeBelowValue",
+				"group": "Filter Data",
+				"params": [{"name": "n", "type": "float", "default": "-Infinity"}]
+			}
+		}`, string(res.Body))
+		assert.Equal(t, "/graphite/functions", graphiteRequest.URL.Path)
+	})
+
+	t.Run("Should return errors of Graphite", func(t *testing.T) {
+		graphiteResponse = ""
+
+		res := callResource(t, "functions")
+		assert.Equal(t, http.StatusNotFound, res.Status)
+		assert.JSONEq(t, `{"message": "request failed, status: 404 Not Found"}`, string(res.Body))
+	})
+}
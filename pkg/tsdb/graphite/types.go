@@ -5,4 +5,6 @@ import "github.com/openinsight-project/grafinsight/pkg/tsdb"
 type TargetResponseDTO struct {
 	Target     string                `json:"target"`
 	DataPoints tsdb.TimeSeriesPoints `json:"datapoints"`
+	// Tags are returned by Graphite 1.1 and later
+	Tags map[string]interface{} `json:"tags"`
 }
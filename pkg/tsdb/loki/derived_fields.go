@@ -0,0 +1,83 @@
+package loki
+
+import (
+	"regexp"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+)
+
+// derivedField is a field extracted from log lines with a regular
+// expression, for example a trace ID. It is configured on the data source.
+type derivedField struct {
+	Name    string
+	Matcher *regexp.Regexp
+	Links   []data.DataLink
+}
+
+// parseDerivedFields reads the derived fields of the data source. Like in the
+// frontend, configurations with the same name are a single field, extracted
+// with the first regular expression, with the links of all of them.
+func parseDerivedFields(dsInfo *models.DataSource) []*derivedField {
+	if dsInfo.JsonData == nil {
+		return nil
+	}
+
+	fields := []*derivedField{}
+	byName := map[string]*derivedField{}
+	for _, config := range dsInfo.JsonData.Get("derivedFields").MustArray() {
+		c, ok := config.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _ := c["name"].(string)
+		matcherRegex, _ := c["matcherRegex"].(string)
+		url, _ := c["url"].(string)
+		datasourceUID, _ := c["datasourceUid"].(string)
+		if name == "" {
+			continue
+		}
+
+		field, ok := byName[name]
+		if !ok {
+			matcher, err := regexp.Compile(matcherRegex)
+			if err != nil {
+				plog.Warn("Invalid derived field regular expression", "datasource", dsInfo.Id, "field", name, "error", err)
+				continue
+			}
+			field = &derivedField{Name: name, Matcher: matcher}
+			byName[name] = field
+			fields = append(fields, field)
+		}
+
+		// Internal links to other data sources are only supported by the
+		// frontend
+		if url != "" && datasourceUID == "" {
+			field.Links = append(field.Links, data.DataLink{URL: url})
+		}
+	}
+
+	return fields
+}
+
+// derivedFieldValues returns a field for each derived field, with the first
+// submatch of its regular expression in each line.
+func derivedFieldValues(derivedFields []*derivedField, lines []string) []*data.Field {
+	fields := make([]*data.Field, 0, len(derivedFields))
+	for _, derived := range derivedFields {
+		values := make([]*string, len(lines))
+		for i, line := range lines {
+			if match := derived.Matcher.FindStringSubmatch(line); len(match) > 1 {
+				value := match[1]
+				values[i] = &value
+			}
+		}
+
+		field := data.NewField(derived.Name, nil, values)
+		if len(derived.Links) > 0 {
+			field.Config = &data.FieldConfig{Links: derived.Links}
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
@@ -4,13 +4,13 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/grafana/loki/pkg/logcli/client"
 	"github.com/grafana/loki/pkg/loghttp"
 	"github.com/grafana/loki/pkg/logproto"
-	"github.com/openinsight-project/grafinsight/pkg/components/null"
 	"github.com/openinsight-project/grafinsight/pkg/infra/log"
 	"github.com/openinsight-project/grafinsight/pkg/models"
 	"github.com/openinsight-project/grafinsight/pkg/tsdb"
@@ -18,6 +18,10 @@ import (
 	"github.com/prometheus/common/model"
 )
 
+// defaultMaxLines is the default maximum number of log lines returned by log
+// queries.
+const defaultMaxLines = 1000
+
 type LokiExecutor struct{}
 
 func NewLokiExecutor(dsInfo *models.DataSource) (tsdb.TsdbQueryEndpoint, error) {
@@ -53,35 +57,48 @@ func (e *LokiExecutor) Query(ctx context.Context, dsInfo *models.DataSource, tsd
 		return nil, err
 	}
 
-	for _, query := range queries {
-		plog.Debug("Sending query", "start", query.Start, "end", query.End, "step", query.Step, "query", query.Expr)
-		span, _ := opentracing.StartSpanFromContext(ctx, "alerting.loki")
-		span.SetTag("expr", query.Expr)
-		span.SetTag("start_unixnano", query.Start.UnixNano())
-		span.SetTag("stop_unixnano", query.End.UnixNano())
-		defer span.Finish()
-
-		//Currently hard coded as not used - applies to log queries
-		limit := 1000
-		//Currently hard coded as not used - applies to queries which produce a stream response
-		interval := time.Second * 1
-
-		value, err := client.QueryRange(query.Expr, limit, query.Start, query.End, logproto.BACKWARD, query.Step, interval, false)
+	derivedFields := parseDerivedFields(dsInfo)
 
+	for _, query := range queries {
+		value, err := runQuery(ctx, client, query)
 		if err != nil {
 			return nil, err
 		}
 
-		queryResult, err := parseResponse(value, query)
+		frames, err := parseResponse(value, query, derivedFields)
 		if err != nil {
 			return nil, err
 		}
+
+		queryResult := tsdb.NewQueryResult()
+		queryResult.RefId = query.RefId
+		queryResult.Dataframes = tsdb.NewDecodedDataFrames(frames)
 		result.Results[query.RefId] = queryResult
 	}
 
 	return result, nil
 }
 
+func runQuery(ctx context.Context, client *client.DefaultClient, query *LokiQuery) (*loghttp.QueryResponse, error) {
+	span, _ := opentracing.StartSpanFromContext(ctx, "alerting.loki")
+	span.SetTag("expr", query.Expr)
+	span.SetTag("start_unixnano", query.Start.UnixNano())
+	span.SetTag("stop_unixnano", query.End.UnixNano())
+	defer span.Finish()
+
+	if query.InstantQuery {
+		plog.Debug("Sending instant query", "time", query.End, "query", query.Expr)
+		return client.Query(query.Expr, query.MaxLines, query.End, logproto.BACKWARD, true)
+	}
+
+	plog.Debug("Sending query", "start", query.Start, "end", query.End, "step", query.Step, "query", query.Expr)
+
+	//Currently hard coded as not used - applies to queries which produce a stream response
+	interval := time.Second * 1
+
+	return client.QueryRange(query.Expr, query.MaxLines, query.Start, query.End, logproto.BACKWARD, query.Step, interval, true)
+}
+
 //If legend (using of name or pattern instead of time series name) is used, use that name/pattern for formatting
 func formatLegend(metric model.Metric, query *LokiQuery) string {
 	if query.LegendFormat == "" {
@@ -126,6 +143,11 @@ func parseQuery(dsInfo *models.DataSource, queries []*tsdb.Query, queryContext *
 			return nil, fmt.Errorf("failed to parse Interval: %v", err)
 		}
 
+		maxLines := queryModel.Model.Get("maxLines").MustInt(0)
+		if maxLines <= 0 {
+			maxLines = dsMaxLines(dsInfo)
+		}
+
 		interval := intervalCalculator.Calculate(queryContext.TimeRange, dsInterval)
 		step := time.Duration(int64(interval.Value))
 
@@ -136,38 +158,22 @@ func parseQuery(dsInfo *models.DataSource, queries []*tsdb.Query, queryContext *
 			Start:        start,
 			End:          end,
 			RefId:        queryModel.RefId,
+			MaxLines:     maxLines,
+			InstantQuery: queryModel.Model.Get("instant").MustBool(false),
 		})
 	}
 
 	return qs, nil
 }
 
-func parseResponse(value *loghttp.QueryResponse, query *LokiQuery) (*tsdb.QueryResult, error) {
-	queryRes := tsdb.NewQueryResult()
-
-	//We are currently processing only matrix results (for alerting)
-	data, ok := value.Data.Result.(loghttp.Matrix)
-	if !ok {
-		return queryRes, fmt.Errorf("unsupported result format: %q", value.Data.ResultType)
-	}
-
-	for _, v := range data {
-		series := tsdb.TimeSeries{
-			Name:   formatLegend(v.Metric, query),
-			Tags:   make(map[string]string, len(v.Metric)),
-			Points: make([]tsdb.TimePoint, 0, len(v.Values)),
+// dsMaxLines returns the maximum number of log lines set on the data source.
+func dsMaxLines(dsInfo *models.DataSource) int {
+	if dsInfo.JsonData != nil {
+		// The frontend stores the setting as a string
+		value := dsInfo.JsonData.Get("maxLines").MustString("")
+		if maxLines, err := strconv.Atoi(value); err == nil && maxLines > 0 {
+			return maxLines
 		}
-
-		for k, v := range v.Metric {
-			series.Tags[string(k)] = string(v)
-		}
-
-		for _, k := range v.Values {
-			series.Points = append(series.Points, tsdb.NewTimePoint(null.FloatFrom(float64(k.Value)), float64(k.Timestamp.Unix()*1000)))
-		}
-
-		queryRes.Series = append(queryRes.Series, &series)
 	}
-
-	return queryRes, nil
+	return defaultMaxLines
 }
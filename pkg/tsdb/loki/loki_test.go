@@ -1,9 +1,15 @@
 package loki
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
 
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/loki/pkg/loghttp"
 	"github.com/openinsight-project/grafinsight/pkg/components/simplejson"
 	"github.com/openinsight-project/grafinsight/pkg/models"
 	"github.com/openinsight-project/grafinsight/pkg/tsdb"
@@ -85,3 +91,126 @@ func TestLoki(t *testing.T) {
 		require.Equal(t, time.Second*2, models[0].Step)
 	})
 }
+
+func TestParseResponse(t *testing.T) {
+	query := &LokiQuery{
+		Expr:         `{job="api"}`,
+		LegendFormat: "{{job}}",
+		RefId:        "A",
+		MaxLines:     100,
+	}
+
+	t.Run("log streams", func(t *testing.T) {
+		dsInfo := &models.DataSource{JsonData: simplejson.NewFromAny(map[string]interface{}{
+			"derivedFields": []interface{}{
+				map[string]interface{}{"name": "traceID", "matcherRegex": `traceID=(\w+)`, "url": "http://tracing/${__value.raw}"},
+				map[string]interface{}{"name": "traceID", "matcherRegex": `ignored`, "datasourceUid": "jaeger"},
+				map[string]interface{}{"name": "invalid", "matcherRegex": `(`},
+			},
+		})}
+		derivedFields := parseDerivedFields(dsInfo)
+		require.Len(t, derivedFields, 1)
+
+		ts := time.Unix(1600000000, 123)
+		value := &loghttp.QueryResponse{Data: loghttp.QueryResponseData{
+			ResultType: loghttp.ResultTypeStream,
+			Result: loghttp.Streams{{
+				Labels: loghttp.LabelSet{"job": "api"},
+				Entries: []loghttp.Entry{
+					{Timestamp: ts, Line: "level=info traceID=abc123"},
+					{Timestamp: ts, Line: "level=info traceID=abc123"},
+					{Timestamp: ts, Line: "level=error"},
+				},
+			}},
+		}}
+
+		frames, err := parseResponse(value, query, derivedFields)
+		require.NoError(t, err)
+		require.Len(t, frames, 1)
+
+		frame := frames[0]
+		require.Equal(t, "A", frame.RefID)
+		require.Equal(t, data.VisTypeLogs, string(frame.Meta.PreferredVisualization))
+
+		names := []string{}
+		for _, field := range frame.Fields {
+			names = append(names, field.Name)
+		}
+		require.Equal(t, []string{"ts", "line", "id", "tsNs", "traceID"}, names)
+		require.Equal(t, 3, frame.Rows())
+
+		require.Equal(t, ts.UTC(), frame.Fields[0].At(0))
+		require.Equal(t, data.Labels{"job": "api"}, frame.Fields[1].Labels)
+		require.Equal(t, "level=error", frame.Fields[1].At(2))
+		require.NotEqual(t, frame.Fields[2].At(0), frame.Fields[2].At(1))
+		require.Equal(t, "1600000000000000123", frame.Fields[3].At(0))
+
+		traceID := frame.Fields[4]
+		require.Equal(t, "abc123", *traceID.At(0).(*string))
+		require.Nil(t, traceID.At(2))
+		require.Equal(t, []data.DataLink{{URL: "http://tracing/${__value.raw}"}}, traceID.Config.Links)
+	})
+
+	t.Run("instant metric query", func(t *testing.T) {
+		value := &loghttp.QueryResponse{Data: loghttp.QueryResponseData{
+			ResultType: loghttp.ResultTypeVector,
+			Result: loghttp.Vector{
+				{Metric: p.Metric{"job": "api"}, Timestamp: 1000, Value: 5},
+			},
+		}}
+
+		frames, err := parseResponse(value, query, nil)
+		require.NoError(t, err)
+		require.Len(t, frames, 1)
+		require.Equal(t, "api", frames[0].Name)
+		require.Equal(t, data.Labels{"job": "api"}, frames[0].Fields[1].Labels)
+		require.Equal(t, 5.0, frames[0].Fields[1].At(0))
+	})
+
+	t.Run("range metric query", func(t *testing.T) {
+		value := &loghttp.QueryResponse{Data: loghttp.QueryResponseData{
+			ResultType: loghttp.ResultTypeMatrix,
+			Result: loghttp.Matrix{
+				{Metric: p.Metric{"job": "api"}, Values: []p.SamplePair{{Timestamp: 1000, Value: 1}, {Timestamp: 2000, Value: 2}}},
+			},
+		}}
+
+		frames, err := parseResponse(value, query, nil)
+		require.NoError(t, err)
+		require.Len(t, frames, 1)
+		require.Equal(t, 2, frames[0].Rows())
+		require.Equal(t, time.Unix(2, 0).UTC(), frames[0].Fields[0].At(1))
+	})
+}
+
+func TestLokiExecutor(t *testing.T) {
+	var form url.Values
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		path, form = r.URL.Path, r.Form
+		_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[
+			{"metric":{"job":"api"},"value":[1600000000,"5"]}
+		]}}`))
+	}))
+	t.Cleanup(server.Close)
+
+	dsInfo := &models.DataSource{Url: server.URL, JsonData: simplejson.NewFromAny(map[string]interface{}{
+		"maxLines": "50",
+	})}
+	jsonModel, err := simplejson.NewJson([]byte(`{"expr": "count_over_time({job=\"api\"}[1m])", "instant": true}`))
+	require.NoError(t, err)
+
+	resp, err := (&LokiExecutor{}).Query(context.Background(), dsInfo, &tsdb.TsdbQuery{
+		TimeRange: tsdb.NewTimeRange("1h", "now"),
+		Queries:   []*tsdb.Query{{RefId: "A", Model: jsonModel}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "/loki/api/v1/query", path)
+	require.Equal(t, "50", form.Get("limit"))
+
+	frames, err := resp.Results["A"].Dataframes.Decoded()
+	require.NoError(t, err)
+	require.Len(t, frames, 1)
+	require.Equal(t, 5.0, frames[0].Fields[1].At(0))
+}
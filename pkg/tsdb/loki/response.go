@@ -0,0 +1,134 @@
+package loki
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/loki/pkg/loghttp"
+	"github.com/prometheus/common/model"
+)
+
+// parseResponse converts the result of a query to data frames. Metric
+// queries have a frame per series, with the labels of the series as labels of
+// the value field. Log queries have a frame per stream.
+func parseResponse(value *loghttp.QueryResponse, query *LokiQuery, derivedFields []*derivedField) (data.Frames, error) {
+	var frames data.Frames
+
+	switch v := value.Data.Result.(type) {
+	case loghttp.Matrix:
+		frames = make(data.Frames, 0, len(v))
+		for _, series := range v {
+			times := make([]time.Time, 0, len(series.Values))
+			values := make([]float64, 0, len(series.Values))
+			for _, pair := range series.Values {
+				times = append(times, pair.Timestamp.Time().UTC())
+				values = append(values, float64(pair.Value))
+			}
+			frames = append(frames, newSeriesFrame(series.Metric, query, times, values))
+		}
+	case loghttp.Vector:
+		frames = make(data.Frames, 0, len(v))
+		for _, sample := range v {
+			frames = append(frames, newSeriesFrame(sample.Metric, query,
+				[]time.Time{sample.Timestamp.Time().UTC()}, []float64{float64(sample.Value)}))
+		}
+	case loghttp.Scalar:
+		frames = data.Frames{data.NewFrame("",
+			data.NewField(data.TimeSeriesTimeFieldName, nil, []time.Time{v.Timestamp.Time().UTC()}),
+			data.NewField(data.TimeSeriesValueFieldName, nil, []float64{float64(v.Value)}))}
+	case loghttp.Streams:
+		frames = make(data.Frames, 0, len(v))
+		for _, stream := range v {
+			frames = append(frames, newStreamFrame(stream, query, derivedFields))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported result format: %q", value.Data.ResultType)
+	}
+
+	for _, frame := range frames {
+		frame.RefID = query.RefId
+		if frame.Meta == nil {
+			frame.Meta = &data.FrameMeta{}
+		}
+		frame.Meta.ExecutedQueryString = "Expr: " + query.Expr
+	}
+
+	return frames, nil
+}
+
+func newSeriesFrame(metric model.Metric, query *LokiQuery, times []time.Time, values []float64) *data.Frame {
+	labels := make(data.Labels, len(metric))
+	for k, v := range metric {
+		labels[string(k)] = string(v)
+	}
+
+	name := formatLegend(metric, query)
+	valueField := data.NewField(data.TimeSeriesValueFieldName, labels, values)
+	valueField.Config = &data.FieldConfig{DisplayNameFromDS: name}
+
+	return data.NewFrame(name,
+		data.NewField(data.TimeSeriesTimeFieldName, nil, times),
+		valueField)
+}
+
+// newStreamFrame returns a frame with the same fields as the frames the
+// frontend builds from log streams: the time, the line with the labels of
+// the stream, a unique ID and the time in nanoseconds, followed by the
+// derived fields.
+func newStreamFrame(stream loghttp.Stream, query *LokiQuery, derivedFields []*derivedField) *data.Frame {
+	labels := data.Labels(stream.Labels.Map())
+	labelsString := labels.String()
+
+	times := make([]time.Time, 0, len(stream.Entries))
+	lines := make([]string, 0, len(stream.Entries))
+	ids := make([]string, 0, len(stream.Entries))
+	timesNs := make([]string, 0, len(stream.Entries))
+	usedIDs := map[string]int{}
+
+	for _, entry := range stream.Entries {
+		ts := strconv.FormatInt(entry.Timestamp.UnixNano(), 10)
+		times = append(times, entry.Timestamp.UTC())
+		lines = append(lines, entry.Line)
+		ids = append(ids, entryID(ts, labelsString, entry.Line, query.RefId, usedIDs))
+		timesNs = append(timesNs, ts)
+	}
+
+	timeField := data.NewField("ts", nil, times)
+	timeField.Config = &data.FieldConfig{DisplayName: "Time"}
+	timeNsField := data.NewField("tsNs", nil, timesNs)
+	timeNsField.Config = &data.FieldConfig{DisplayName: "Time ns"}
+
+	frame := data.NewFrame("",
+		timeField,
+		data.NewField("line", labels, lines),
+		data.NewField("id", nil, ids),
+		timeNsField)
+	frame.Fields = append(frame.Fields, derivedFieldValues(derivedFields, lines)...)
+	frame.Meta = &data.FrameMeta{
+		PreferredVisualization: data.VisTypeLogs,
+		Custom:                 map[string]interface{}{"limit": query.MaxLines},
+	}
+
+	return frame
+}
+
+// entryID returns an ID of a log line, which is unique in the response even
+// if the stream has identical lines at the same time.
+func entryID(ts, labels, line, refID string, used map[string]int) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(labels + line))
+	id := fmt.Sprintf("%s_%x", ts, h.Sum32())
+	if refID != "" {
+		id += "_" + refID
+	}
+
+	if count, ok := used[id]; ok {
+		used[id] = count + 1
+		return fmt.Sprintf("%s_%d", id, count)
+	}
+	used[id] = 1
+	return id
+}
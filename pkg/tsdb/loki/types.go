@@ -9,4 +9,9 @@ type LokiQuery struct {
 	Start        time.Time
 	End          time.Time
 	RefId        string
+	// MaxLines is the maximum number of log lines returned by log queries.
+	MaxLines int
+	// InstantQuery queries the values at the end of the time range instead
+	// of over the time range.
+	InstantQuery bool
 }
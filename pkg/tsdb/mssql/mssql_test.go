@@ -8,6 +8,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/openinsight-project/grafinsight/pkg/components/null"
 	"github.com/openinsight-project/grafinsight/pkg/components/securejsondata"
 	"github.com/openinsight-project/grafinsight/pkg/components/simplejson"
 	"github.com/openinsight-project/grafinsight/pkg/models"
@@ -138,7 +139,7 @@ func TestMSSQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(err, ShouldBeNil)
 
-				column := queryResult.Tables[0].Rows[0]
+				column := frameRows(queryResult)[0]
 
 				So(column[0].(bool), ShouldEqual, true)
 
@@ -231,7 +232,7 @@ func TestMSSQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				points := queryResult.Series[0].Points
+				points := frameSeries(queryResult)[0].Points
 				// without fill this should result in 4 buckets
 				So(len(points), ShouldEqual, 4)
 
@@ -278,7 +279,7 @@ func TestMSSQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				points := queryResult.Series[0].Points
+				points := frameSeries(queryResult)[0].Points
 				So(len(points), ShouldEqual, 7)
 
 				dt := fromStart
@@ -364,7 +365,7 @@ func TestMSSQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				points := queryResult.Series[0].Points
+				points := frameSeries(queryResult)[0].Points
 				So(points[3][0].Float64, ShouldEqual, 1.5)
 			})
 		})
@@ -454,8 +455,8 @@ func TestMSSQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				So(len(queryResult.Series), ShouldEqual, 1)
-				So(queryResult.Series[0].Points[0][1].Float64, ShouldEqual, float64(tInitial.UnixNano()/1e6))
+				So(len(frameSeries(queryResult)), ShouldEqual, 1)
+				So(frameSeries(queryResult)[0].Points[0][1].Float64, ShouldEqual, float64(tInitial.UnixNano()/1e6))
 			})
 
 			Convey("When doing a metric query using epoch (int64 nullable) as time column and value column (int64 nullable) should return metric with time in milliseconds", func() {
@@ -476,8 +477,8 @@ func TestMSSQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				So(len(queryResult.Series), ShouldEqual, 1)
-				So(queryResult.Series[0].Points[0][1].Float64, ShouldEqual, float64(tInitial.UnixNano()/1e6))
+				So(len(frameSeries(queryResult)), ShouldEqual, 1)
+				So(frameSeries(queryResult)[0].Points[0][1].Float64, ShouldEqual, float64(tInitial.UnixNano()/1e6))
 			})
 
 			Convey("When doing a metric query using epoch (float64) as time column and value column (float64) should return metric with time in milliseconds", func() {
@@ -498,8 +499,8 @@ func TestMSSQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				So(len(queryResult.Series), ShouldEqual, 1)
-				So(queryResult.Series[0].Points[0][1].Float64, ShouldEqual, float64(tInitial.UnixNano()/1e6))
+				So(len(frameSeries(queryResult)), ShouldEqual, 1)
+				So(frameSeries(queryResult)[0].Points[0][1].Float64, ShouldEqual, float64(tInitial.UnixNano()/1e6))
 			})
 
 			Convey("When doing a metric query using epoch (float64 nullable) as time column and value column (float64 nullable) should return metric with time in milliseconds", func() {
@@ -520,8 +521,8 @@ func TestMSSQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				So(len(queryResult.Series), ShouldEqual, 1)
-				So(queryResult.Series[0].Points[0][1].Float64, ShouldEqual, float64(tInitial.UnixNano()/1e6))
+				So(len(frameSeries(queryResult)), ShouldEqual, 1)
+				So(frameSeries(queryResult)[0].Points[0][1].Float64, ShouldEqual, float64(tInitial.UnixNano()/1e6))
 			})
 
 			Convey("When doing a metric query using epoch (int32) as time column and value column (int32) should return metric with time in milliseconds", func() {
@@ -542,8 +543,8 @@ func TestMSSQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				So(len(queryResult.Series), ShouldEqual, 1)
-				So(queryResult.Series[0].Points[0][1].Float64, ShouldEqual, float64(tInitial.UnixNano()/1e6))
+				So(len(frameSeries(queryResult)), ShouldEqual, 1)
+				So(frameSeries(queryResult)[0].Points[0][1].Float64, ShouldEqual, float64(tInitial.UnixNano()/1e6))
 			})
 
 			Convey("When doing a metric query using epoch (int32 nullable) as time column and value column (int32 nullable) should return metric with time in milliseconds", func() {
@@ -564,8 +565,8 @@ func TestMSSQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				So(len(queryResult.Series), ShouldEqual, 1)
-				So(queryResult.Series[0].Points[0][1].Float64, ShouldEqual, float64(tInitial.UnixNano()/1e6))
+				So(len(frameSeries(queryResult)), ShouldEqual, 1)
+				So(frameSeries(queryResult)[0].Points[0][1].Float64, ShouldEqual, float64(tInitial.UnixNano()/1e6))
 			})
 
 			Convey("When doing a metric query using epoch (float32) as time column and value column (float32) should return metric with time in milliseconds", func() {
@@ -586,8 +587,8 @@ func TestMSSQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				So(len(queryResult.Series), ShouldEqual, 1)
-				So(queryResult.Series[0].Points[0][1].Float64, ShouldEqual, float64(float32(tInitial.Unix()))*1e3)
+				So(len(frameSeries(queryResult)), ShouldEqual, 1)
+				So(frameSeries(queryResult)[0].Points[0][1].Float64, ShouldEqual, float64(float32(tInitial.Unix()))*1e3)
 			})
 
 			Convey("When doing a metric query using epoch (float32 nullable) as time column and value column (float32 nullable) should return metric with time in milliseconds", func() {
@@ -608,8 +609,8 @@ func TestMSSQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				So(len(queryResult.Series), ShouldEqual, 1)
-				So(queryResult.Series[0].Points[0][1].Float64, ShouldEqual, float64(float32(tInitial.Unix()))*1e3)
+				So(len(frameSeries(queryResult)), ShouldEqual, 1)
+				So(frameSeries(queryResult)[0].Points[0][1].Float64, ShouldEqual, float64(float32(tInitial.Unix()))*1e3)
 			})
 
 			Convey("When doing a metric query grouping by time and select metric column should return correct series", func() {
@@ -630,9 +631,9 @@ func TestMSSQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				So(len(queryResult.Series), ShouldEqual, 2)
-				So(queryResult.Series[0].Name, ShouldEqual, "Metric A - value one")
-				So(queryResult.Series[1].Name, ShouldEqual, "Metric B - value one")
+				So(len(frameSeries(queryResult)), ShouldEqual, 2)
+				So(frameSeries(queryResult)[0].Name, ShouldEqual, "Metric A - value one")
+				So(frameSeries(queryResult)[1].Name, ShouldEqual, "Metric B - value one")
 			})
 
 			Convey("When doing a metric query grouping by time should return correct series", func() {
@@ -653,9 +654,9 @@ func TestMSSQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				So(len(queryResult.Series), ShouldEqual, 2)
-				So(queryResult.Series[0].Name, ShouldEqual, "valueOne")
-				So(queryResult.Series[1].Name, ShouldEqual, "valueTwo")
+				So(len(frameSeries(queryResult)), ShouldEqual, 2)
+				So(frameSeries(queryResult)[0].Name, ShouldEqual, "valueOne")
+				So(frameSeries(queryResult)[1].Name, ShouldEqual, "valueTwo")
 			})
 
 			Convey("When doing a metric query with metric column and multiple value columns", func() {
@@ -676,11 +677,11 @@ func TestMSSQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				So(len(queryResult.Series), ShouldEqual, 4)
-				So(queryResult.Series[0].Name, ShouldEqual, "Metric A valueOne")
-				So(queryResult.Series[1].Name, ShouldEqual, "Metric A valueTwo")
-				So(queryResult.Series[2].Name, ShouldEqual, "Metric B valueOne")
-				So(queryResult.Series[3].Name, ShouldEqual, "Metric B valueTwo")
+				So(len(frameSeries(queryResult)), ShouldEqual, 4)
+				So(frameSeries(queryResult)[0].Name, ShouldEqual, "Metric A valueOne")
+				So(frameSeries(queryResult)[1].Name, ShouldEqual, "Metric A valueTwo")
+				So(frameSeries(queryResult)[2].Name, ShouldEqual, "Metric B valueOne")
+				So(frameSeries(queryResult)[3].Name, ShouldEqual, "Metric B valueTwo")
 			})
 
 			Convey("When doing a query with timeFrom,timeTo,unixEpochFrom,unixEpochTo macros", func() {
@@ -777,11 +778,11 @@ func TestMSSQL(t *testing.T) {
 					So(err, ShouldBeNil)
 					So(queryResult.Error, ShouldBeNil)
 
-					So(len(queryResult.Series), ShouldEqual, 4)
-					So(queryResult.Series[0].Name, ShouldEqual, "Metric A valueOne")
-					So(queryResult.Series[1].Name, ShouldEqual, "Metric A valueTwo")
-					So(queryResult.Series[2].Name, ShouldEqual, "Metric B valueOne")
-					So(queryResult.Series[3].Name, ShouldEqual, "Metric B valueTwo")
+					So(len(frameSeries(queryResult)), ShouldEqual, 4)
+					So(frameSeries(queryResult)[0].Name, ShouldEqual, "Metric A valueOne")
+					So(frameSeries(queryResult)[1].Name, ShouldEqual, "Metric A valueTwo")
+					So(frameSeries(queryResult)[2].Name, ShouldEqual, "Metric B valueOne")
+					So(frameSeries(queryResult)[3].Name, ShouldEqual, "Metric B valueTwo")
 				})
 			})
 
@@ -856,11 +857,11 @@ func TestMSSQL(t *testing.T) {
 					So(err, ShouldBeNil)
 					So(queryResult.Error, ShouldBeNil)
 
-					So(len(queryResult.Series), ShouldEqual, 4)
-					So(queryResult.Series[0].Name, ShouldEqual, "Metric A valueOne")
-					So(queryResult.Series[1].Name, ShouldEqual, "Metric A valueTwo")
-					So(queryResult.Series[2].Name, ShouldEqual, "Metric B valueOne")
-					So(queryResult.Series[3].Name, ShouldEqual, "Metric B valueTwo")
+					So(len(frameSeries(queryResult)), ShouldEqual, 4)
+					So(frameSeries(queryResult)[0].Name, ShouldEqual, "Metric A valueOne")
+					So(frameSeries(queryResult)[1].Name, ShouldEqual, "Metric A valueTwo")
+					So(frameSeries(queryResult)[2].Name, ShouldEqual, "Metric B valueOne")
+					So(frameSeries(queryResult)[3].Name, ShouldEqual, "Metric B valueTwo")
 				})
 			})
 		})
@@ -930,7 +931,7 @@ func TestMSSQL(t *testing.T) {
 				resp, err := endpoint.Query(context.Background(), nil, query)
 				queryResult := resp.Results["Deploys"]
 				So(err, ShouldBeNil)
-				So(len(queryResult.Tables[0].Rows), ShouldEqual, 3)
+				So(len(frameRows(queryResult)), ShouldEqual, 3)
 			})
 
 			Convey("When doing an annotation query of ticket events should return expected result", func() {
@@ -953,7 +954,7 @@ func TestMSSQL(t *testing.T) {
 				resp, err := endpoint.Query(context.Background(), nil, query)
 				queryResult := resp.Results["Tickets"]
 				So(err, ShouldBeNil)
-				So(len(queryResult.Tables[0].Rows), ShouldEqual, 3)
+				So(len(frameRows(queryResult)), ShouldEqual, 3)
 			})
 
 			Convey("When doing an annotation query with a time column in datetime format", func() {
@@ -980,11 +981,11 @@ func TestMSSQL(t *testing.T) {
 				So(err, ShouldBeNil)
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
-				So(len(queryResult.Tables[0].Rows), ShouldEqual, 1)
-				columns := queryResult.Tables[0].Rows[0]
+				So(len(frameRows(queryResult)), ShouldEqual, 1)
+				columns := frameRows(queryResult)[0]
 
 				// Should be in milliseconds
-				So(columns[0].(float64), ShouldEqual, float64(dt.UnixNano()/1e6))
+				So(columns[0].(time.Time).UnixNano()/1e6, ShouldEqual, dt.UnixNano()/1e6)
 			})
 
 			Convey("When doing an annotation query with a time column in epoch second format should return ms", func() {
@@ -1010,11 +1011,11 @@ func TestMSSQL(t *testing.T) {
 				So(err, ShouldBeNil)
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
-				So(len(queryResult.Tables[0].Rows), ShouldEqual, 1)
-				columns := queryResult.Tables[0].Rows[0]
+				So(len(frameRows(queryResult)), ShouldEqual, 1)
+				columns := frameRows(queryResult)[0]
 
 				// Should be in milliseconds
-				So(columns[0].(int64), ShouldEqual, dt.Unix()*1000)
+				So(columns[0].(time.Time).UnixNano()/1e6, ShouldEqual, dt.Unix()*1000)
 			})
 
 			Convey("When doing an annotation query with a time column in epoch second format (int) should return ms", func() {
@@ -1040,11 +1041,11 @@ func TestMSSQL(t *testing.T) {
 				So(err, ShouldBeNil)
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
-				So(len(queryResult.Tables[0].Rows), ShouldEqual, 1)
-				columns := queryResult.Tables[0].Rows[0]
+				So(len(frameRows(queryResult)), ShouldEqual, 1)
+				columns := frameRows(queryResult)[0]
 
 				// Should be in milliseconds
-				So(columns[0].(int64), ShouldEqual, dt.Unix()*1000)
+				So(columns[0].(time.Time).UnixNano()/1e6, ShouldEqual, dt.Unix()*1000)
 			})
 
 			Convey("When doing an annotation query with a time column in epoch millisecond format should return ms", func() {
@@ -1070,11 +1071,11 @@ func TestMSSQL(t *testing.T) {
 				So(err, ShouldBeNil)
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
-				So(len(queryResult.Tables[0].Rows), ShouldEqual, 1)
-				columns := queryResult.Tables[0].Rows[0]
+				So(len(frameRows(queryResult)), ShouldEqual, 1)
+				columns := frameRows(queryResult)[0]
 
 				// Should be in milliseconds
-				So(columns[0].(float64), ShouldEqual, float64(dt.Unix()*1000))
+				So(columns[0].(time.Time).UnixNano()/1e6, ShouldEqual, dt.Unix()*1000)
 			})
 
 			Convey("When doing an annotation query with a time column holding a bigint null value should return nil", func() {
@@ -1098,8 +1099,8 @@ func TestMSSQL(t *testing.T) {
 				So(err, ShouldBeNil)
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
-				So(len(queryResult.Tables[0].Rows), ShouldEqual, 1)
-				columns := queryResult.Tables[0].Rows[0]
+				So(len(frameRows(queryResult)), ShouldEqual, 1)
+				columns := frameRows(queryResult)[0]
 
 				// Should be in milliseconds
 				So(columns[0], ShouldBeNil)
@@ -1126,8 +1127,8 @@ func TestMSSQL(t *testing.T) {
 				So(err, ShouldBeNil)
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
-				So(len(queryResult.Tables[0].Rows), ShouldEqual, 1)
-				columns := queryResult.Tables[0].Rows[0]
+				So(len(frameRows(queryResult)), ShouldEqual, 1)
+				columns := frameRows(queryResult)[0]
 
 				// Should be in milliseconds
 				So(columns[0], ShouldBeNil)
@@ -1164,3 +1165,46 @@ func genTimeRangeByInterval(from time.Time, duration time.Duration, interval tim
 
 	return timeRange
 }
+
+// frameSeries returns the series of the time series frame of a query result,
+// named after the display names of their fields.
+func frameSeries(queryResult *tsdb.QueryResult) tsdb.TimeSeriesSlice {
+	frames, err := queryResult.Dataframes.Decoded()
+	So(err, ShouldBeNil)
+	So(len(frames), ShouldEqual, 1)
+
+	frame := frames[0]
+	seriesList := tsdb.TimeSeriesSlice{}
+	for _, field := range frame.Fields[1:] {
+		series := &tsdb.TimeSeries{Name: field.Config.DisplayNameFromDS, Tags: field.Labels}
+		for i := 0; i < field.Len(); i++ {
+			ts := frame.Fields[0].At(i).(time.Time)
+			series.Points = append(series.Points, tsdb.TimePoint{
+				null.FloatFromPtr(field.At(i).(*float64)),
+				null.FloatFrom(float64(ts.UnixNano() / int64(time.Millisecond))),
+			})
+		}
+		seriesList = append(seriesList, series)
+	}
+	return seriesList
+}
+
+// frameRows returns the rows of the table frame of a query result, with nil
+// for null values.
+func frameRows(queryResult *tsdb.QueryResult) [][]interface{} {
+	frames, err := queryResult.Dataframes.Decoded()
+	So(err, ShouldBeNil)
+	So(len(frames), ShouldEqual, 1)
+
+	frame := frames[0]
+	rows := make([][]interface{}, frame.Rows())
+	for i := range rows {
+		rows[i] = make([]interface{}, len(frame.Fields))
+		for j, field := range frame.Fields {
+			if v, ok := field.ConcreteAt(i); ok {
+				rows[i][j] = v
+			}
+		}
+	}
+	return rows
+}
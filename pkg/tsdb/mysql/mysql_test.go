@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/openinsight-project/grafinsight/pkg/components/null"
 	"github.com/openinsight-project/grafinsight/pkg/components/securejsondata"
 	"github.com/openinsight-project/grafinsight/pkg/components/simplejson"
 	"github.com/openinsight-project/grafinsight/pkg/models"
@@ -140,23 +141,23 @@ func TestMySQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				column := queryResult.Tables[0].Rows[0]
+				column := frameRows(queryResult)[0]
 
-				So(*column[0].(*int8), ShouldEqual, 1)
+				So(column[0].(int8), ShouldEqual, 1)
 				So(column[1].(string), ShouldEqual, "abc")
 				So(column[2].(string), ShouldEqual, "def")
-				So(*column[3].(*int32), ShouldEqual, 1)
-				So(*column[4].(*int16), ShouldEqual, 10)
-				So(*column[5].(*int64), ShouldEqual, 100)
-				So(*column[6].(*int32), ShouldEqual, 1420070400)
+				So(column[3].(int32), ShouldEqual, 1)
+				So(column[4].(int16), ShouldEqual, 10)
+				So(column[5].(int64), ShouldEqual, 100)
+				So(column[6].(int32), ShouldEqual, 1420070400)
 				So(column[7].(float64), ShouldEqual, 1.11)
 				So(column[8].(float64), ShouldEqual, 2.22)
-				So(*column[9].(*float32), ShouldEqual, 3.33)
+				So(column[9].(float32), ShouldEqual, 3.33)
 				So(column[10].(time.Time), ShouldHappenWithin, 10*time.Second, time.Now())
 				So(column[11].(time.Time), ShouldHappenWithin, 10*time.Second, time.Now())
 				So(column[12].(string), ShouldEqual, "11:11:11")
 				So(column[13].(int64), ShouldEqual, 2018)
-				So(*column[14].(*[]byte), ShouldHaveSameTypeAs, []byte{1})
+				So(column[14].(string), ShouldEqual, "\x01")
 				So(column[15].(string), ShouldEqual, "tinytext")
 				So(column[16].(string), ShouldEqual, "tinyblob")
 				So(column[17].(string), ShouldEqual, "text")
@@ -168,7 +169,7 @@ func TestMySQL(t *testing.T) {
 				So(column[23].(string), ShouldEqual, "val2")
 				So(column[24].(string), ShouldEqual, "a,b")
 				So(column[25].(time.Time).Format("2006-01-02T00:00:00Z"), ShouldEqual, time.Now().UTC().Format("2006-01-02T00:00:00Z"))
-				So(column[26].(float64), ShouldEqual, float64(1.514764861123456*1e12))
+				So(float64(column[26].(time.Time).UnixNano())/1e6, ShouldAlmostEqual, 1.514764861123456*1e12, 0.001)
 				So(column[27], ShouldEqual, nil)
 				So(column[28], ShouldEqual, nil)
 				So(column[29], ShouldEqual, "")
@@ -229,7 +230,7 @@ func TestMySQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				points := queryResult.Series[0].Points
+				points := frameSeries(queryResult)[0].Points
 				// without fill this should result in 4 buckets
 				So(len(points), ShouldEqual, 4)
 
@@ -276,7 +277,7 @@ func TestMySQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				points := queryResult.Series[0].Points
+				points := frameSeries(queryResult)[0].Points
 				So(len(points), ShouldEqual, 7)
 
 				dt := fromStart
@@ -363,7 +364,7 @@ func TestMySQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				points := queryResult.Series[0].Points
+				points := frameSeries(queryResult)[0].Points
 				So(points[3][0].Float64, ShouldEqual, 1.5)
 			})
 
@@ -389,7 +390,7 @@ func TestMySQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				points := queryResult.Series[0].Points
+				points := frameSeries(queryResult)[0].Points
 				So(points[2][0].Float64, ShouldEqual, 15.0)
 				So(points[3][0].Float64, ShouldEqual, 15.0)
 				So(points[6][0].Float64, ShouldEqual, 20.0)
@@ -484,8 +485,8 @@ func TestMySQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				So(len(queryResult.Series), ShouldEqual, 1)
-				So(queryResult.Series[0].Points[0][1].Float64, ShouldEqual, float64(tInitial.UnixNano()/1e6))
+				So(len(frameSeries(queryResult)), ShouldEqual, 1)
+				So(frameSeries(queryResult)[0].Points[0][1].Float64, ShouldEqual, float64(tInitial.UnixNano()/1e6))
 			})
 
 			Convey("When doing a metric query using time (nullable) as time column should return metric with time in milliseconds", func() {
@@ -506,8 +507,8 @@ func TestMySQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				So(len(queryResult.Series), ShouldEqual, 1)
-				So(queryResult.Series[0].Points[0][1].Float64, ShouldEqual, float64(tInitial.UnixNano()/1e6))
+				So(len(frameSeries(queryResult)), ShouldEqual, 1)
+				So(frameSeries(queryResult)[0].Points[0][1].Float64, ShouldEqual, float64(tInitial.UnixNano()/1e6))
 			})
 
 			Convey("When doing a metric query using epoch (int64) as time column and value column (int64) should return metric with time in milliseconds", func() {
@@ -528,8 +529,8 @@ func TestMySQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				So(len(queryResult.Series), ShouldEqual, 1)
-				So(queryResult.Series[0].Points[0][1].Float64, ShouldEqual, float64(tInitial.UnixNano()/1e6))
+				So(len(frameSeries(queryResult)), ShouldEqual, 1)
+				So(frameSeries(queryResult)[0].Points[0][1].Float64, ShouldEqual, float64(tInitial.UnixNano()/1e6))
 			})
 
 			Convey("When doing a metric query using epoch (int64 nullable) as time column and value column (int64 nullable) should return metric with time in milliseconds", func() {
@@ -550,8 +551,8 @@ func TestMySQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				So(len(queryResult.Series), ShouldEqual, 1)
-				So(queryResult.Series[0].Points[0][1].Float64, ShouldEqual, float64(tInitial.UnixNano()/1e6))
+				So(len(frameSeries(queryResult)), ShouldEqual, 1)
+				So(frameSeries(queryResult)[0].Points[0][1].Float64, ShouldEqual, float64(tInitial.UnixNano()/1e6))
 			})
 
 			Convey("When doing a metric query using epoch (float64) as time column and value column (float64) should return metric with time in milliseconds", func() {
@@ -572,8 +573,8 @@ func TestMySQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				So(len(queryResult.Series), ShouldEqual, 1)
-				So(queryResult.Series[0].Points[0][1].Float64, ShouldEqual, float64(tInitial.UnixNano()/1e6))
+				So(len(frameSeries(queryResult)), ShouldEqual, 1)
+				So(frameSeries(queryResult)[0].Points[0][1].Float64, ShouldEqual, float64(tInitial.UnixNano()/1e6))
 			})
 
 			Convey("When doing a metric query using epoch (float64 nullable) as time column and value column (float64 nullable) should return metric with time in milliseconds", func() {
@@ -594,8 +595,8 @@ func TestMySQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				So(len(queryResult.Series), ShouldEqual, 1)
-				So(queryResult.Series[0].Points[0][1].Float64, ShouldEqual, float64(tInitial.UnixNano()/1e6))
+				So(len(frameSeries(queryResult)), ShouldEqual, 1)
+				So(frameSeries(queryResult)[0].Points[0][1].Float64, ShouldEqual, float64(tInitial.UnixNano()/1e6))
 			})
 
 			Convey("When doing a metric query using epoch (int32) as time column and value column (int32) should return metric with time in milliseconds", func() {
@@ -616,8 +617,8 @@ func TestMySQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				So(len(queryResult.Series), ShouldEqual, 1)
-				So(queryResult.Series[0].Points[0][1].Float64, ShouldEqual, float64(tInitial.UnixNano()/1e6))
+				So(len(frameSeries(queryResult)), ShouldEqual, 1)
+				So(frameSeries(queryResult)[0].Points[0][1].Float64, ShouldEqual, float64(tInitial.UnixNano()/1e6))
 			})
 
 			Convey("When doing a metric query using epoch (int32 nullable) as time column and value column (int32 nullable) should return metric with time in milliseconds", func() {
@@ -638,8 +639,8 @@ func TestMySQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				So(len(queryResult.Series), ShouldEqual, 1)
-				So(queryResult.Series[0].Points[0][1].Float64, ShouldEqual, float64(tInitial.UnixNano()/1e6))
+				So(len(frameSeries(queryResult)), ShouldEqual, 1)
+				So(frameSeries(queryResult)[0].Points[0][1].Float64, ShouldEqual, float64(tInitial.UnixNano()/1e6))
 			})
 
 			Convey("When doing a metric query using epoch (float32) as time column and value column (float32) should return metric with time in milliseconds", func() {
@@ -660,8 +661,8 @@ func TestMySQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				So(len(queryResult.Series), ShouldEqual, 1)
-				So(queryResult.Series[0].Points[0][1].Float64, ShouldEqual, float64(float32(tInitial.Unix()))*1e3)
+				So(len(frameSeries(queryResult)), ShouldEqual, 1)
+				So(frameSeries(queryResult)[0].Points[0][1].Float64, ShouldEqual, float64(float32(tInitial.Unix()))*1e3)
 			})
 
 			Convey("When doing a metric query using epoch (float32 nullable) as time column and value column (float32 nullable) should return metric with time in milliseconds", func() {
@@ -682,8 +683,8 @@ func TestMySQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				So(len(queryResult.Series), ShouldEqual, 1)
-				So(queryResult.Series[0].Points[0][1].Float64, ShouldEqual, float64(float32(tInitial.Unix()))*1e3)
+				So(len(frameSeries(queryResult)), ShouldEqual, 1)
+				So(frameSeries(queryResult)[0].Points[0][1].Float64, ShouldEqual, float64(float32(tInitial.Unix()))*1e3)
 			})
 
 			Convey("When doing a metric query grouping by time and select metric column should return correct series", func() {
@@ -704,9 +705,9 @@ func TestMySQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				So(len(queryResult.Series), ShouldEqual, 2)
-				So(queryResult.Series[0].Name, ShouldEqual, "Metric A - value one")
-				So(queryResult.Series[1].Name, ShouldEqual, "Metric B - value one")
+				So(len(frameSeries(queryResult)), ShouldEqual, 2)
+				So(frameSeries(queryResult)[0].Name, ShouldEqual, "Metric A - value one")
+				So(frameSeries(queryResult)[1].Name, ShouldEqual, "Metric B - value one")
 			})
 
 			Convey("When doing a metric query with metric column and multiple value columns", func() {
@@ -727,11 +728,11 @@ func TestMySQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				So(len(queryResult.Series), ShouldEqual, 4)
-				So(queryResult.Series[0].Name, ShouldEqual, "Metric A valueOne")
-				So(queryResult.Series[1].Name, ShouldEqual, "Metric A valueTwo")
-				So(queryResult.Series[2].Name, ShouldEqual, "Metric B valueOne")
-				So(queryResult.Series[3].Name, ShouldEqual, "Metric B valueTwo")
+				So(len(frameSeries(queryResult)), ShouldEqual, 4)
+				So(frameSeries(queryResult)[0].Name, ShouldEqual, "Metric A valueOne")
+				So(frameSeries(queryResult)[1].Name, ShouldEqual, "Metric A valueTwo")
+				So(frameSeries(queryResult)[2].Name, ShouldEqual, "Metric B valueOne")
+				So(frameSeries(queryResult)[3].Name, ShouldEqual, "Metric B valueTwo")
 			})
 
 			Convey("When doing a metric query grouping by time should return correct series", func() {
@@ -752,9 +753,9 @@ func TestMySQL(t *testing.T) {
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
 
-				So(len(queryResult.Series), ShouldEqual, 2)
-				So(queryResult.Series[0].Name, ShouldEqual, "valueOne")
-				So(queryResult.Series[1].Name, ShouldEqual, "valueTwo")
+				So(len(frameSeries(queryResult)), ShouldEqual, 2)
+				So(frameSeries(queryResult)[0].Name, ShouldEqual, "valueOne")
+				So(frameSeries(queryResult)[1].Name, ShouldEqual, "valueTwo")
 			})
 		})
 
@@ -835,7 +836,7 @@ func TestMySQL(t *testing.T) {
 				resp, err := endpoint.Query(context.Background(), nil, query)
 				queryResult := resp.Results["Deploys"]
 				So(err, ShouldBeNil)
-				So(len(queryResult.Tables[0].Rows), ShouldEqual, 3)
+				So(len(frameRows(queryResult)), ShouldEqual, 3)
 			})
 
 			Convey("When doing an annotation query of ticket events should return expected result", func() {
@@ -858,7 +859,7 @@ func TestMySQL(t *testing.T) {
 				resp, err := endpoint.Query(context.Background(), nil, query)
 				queryResult := resp.Results["Tickets"]
 				So(err, ShouldBeNil)
-				So(len(queryResult.Tables[0].Rows), ShouldEqual, 3)
+				So(len(frameRows(queryResult)), ShouldEqual, 3)
 			})
 
 			Convey("When doing an annotation query with a time column in datetime format", func() {
@@ -885,11 +886,11 @@ func TestMySQL(t *testing.T) {
 				So(err, ShouldBeNil)
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
-				So(len(queryResult.Tables[0].Rows), ShouldEqual, 1)
-				columns := queryResult.Tables[0].Rows[0]
+				So(len(frameRows(queryResult)), ShouldEqual, 1)
+				columns := frameRows(queryResult)[0]
 
 				//Should be in milliseconds
-				So(columns[0].(float64), ShouldEqual, float64(dt.Unix()*1000))
+				So(columns[0].(time.Time).UnixNano()/1e6, ShouldEqual, dt.Unix()*1000)
 			})
 
 			Convey("When doing an annotation query with a time column in epoch second format should return ms", func() {
@@ -915,11 +916,11 @@ func TestMySQL(t *testing.T) {
 				So(err, ShouldBeNil)
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
-				So(len(queryResult.Tables[0].Rows), ShouldEqual, 1)
-				columns := queryResult.Tables[0].Rows[0]
+				So(len(frameRows(queryResult)), ShouldEqual, 1)
+				columns := frameRows(queryResult)[0]
 
 				//Should be in milliseconds
-				So(columns[0].(int64), ShouldEqual, dt.Unix()*1000)
+				So(columns[0].(time.Time).UnixNano()/1e6, ShouldEqual, dt.Unix()*1000)
 			})
 
 			Convey("When doing an annotation query with a time column in epoch second format (signed integer) should return ms", func() {
@@ -945,11 +946,11 @@ func TestMySQL(t *testing.T) {
 				So(err, ShouldBeNil)
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
-				So(len(queryResult.Tables[0].Rows), ShouldEqual, 1)
-				columns := queryResult.Tables[0].Rows[0]
+				So(len(frameRows(queryResult)), ShouldEqual, 1)
+				columns := frameRows(queryResult)[0]
 
 				//Should be in milliseconds
-				So(columns[0].(int64), ShouldEqual, dt.Unix()*1000)
+				So(columns[0].(time.Time).UnixNano()/1e6, ShouldEqual, dt.Unix()*1000)
 			})
 
 			Convey("When doing an annotation query with a time column in epoch millisecond format should return ms", func() {
@@ -975,11 +976,11 @@ func TestMySQL(t *testing.T) {
 				So(err, ShouldBeNil)
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
-				So(len(queryResult.Tables[0].Rows), ShouldEqual, 1)
-				columns := queryResult.Tables[0].Rows[0]
+				So(len(frameRows(queryResult)), ShouldEqual, 1)
+				columns := frameRows(queryResult)[0]
 
 				//Should be in milliseconds
-				So(columns[0].(int64), ShouldEqual, dt.Unix()*1000)
+				So(columns[0].(time.Time).UnixNano()/1e6, ShouldEqual, dt.Unix()*1000)
 			})
 
 			Convey("When doing an annotation query with a time column holding a unsigned integer null value should return nil", func() {
@@ -1003,8 +1004,8 @@ func TestMySQL(t *testing.T) {
 				So(err, ShouldBeNil)
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
-				So(len(queryResult.Tables[0].Rows), ShouldEqual, 1)
-				columns := queryResult.Tables[0].Rows[0]
+				So(len(frameRows(queryResult)), ShouldEqual, 1)
+				columns := frameRows(queryResult)[0]
 
 				//Should be in milliseconds
 				So(columns[0], ShouldBeNil)
@@ -1031,8 +1032,8 @@ func TestMySQL(t *testing.T) {
 				So(err, ShouldBeNil)
 				queryResult := resp.Results["A"]
 				So(queryResult.Error, ShouldBeNil)
-				So(len(queryResult.Tables[0].Rows), ShouldEqual, 1)
-				columns := queryResult.Tables[0].Rows[0]
+				So(len(frameRows(queryResult)), ShouldEqual, 1)
+				columns := frameRows(queryResult)[0]
 
 				//Should be in milliseconds
 				So(columns[0], ShouldBeNil)
@@ -1069,3 +1070,46 @@ func genTimeRangeByInterval(from time.Time, duration time.Duration, interval tim
 
 	return timeRange
 }
+
+// frameSeries returns the series of the time series frame of a query result,
+// named after the display names of their fields.
+func frameSeries(queryResult *tsdb.QueryResult) tsdb.TimeSeriesSlice {
+	frames, err := queryResult.Dataframes.Decoded()
+	So(err, ShouldBeNil)
+	So(len(frames), ShouldEqual, 1)
+
+	frame := frames[0]
+	seriesList := tsdb.TimeSeriesSlice{}
+	for _, field := range frame.Fields[1:] {
+		series := &tsdb.TimeSeries{Name: field.Config.DisplayNameFromDS, Tags: field.Labels}
+		for i := 0; i < field.Len(); i++ {
+			ts := frame.Fields[0].At(i).(time.Time)
+			series.Points = append(series.Points, tsdb.TimePoint{
+				null.FloatFromPtr(field.At(i).(*float64)),
+				null.FloatFrom(float64(ts.UnixNano() / int64(time.Millisecond))),
+			})
+		}
+		seriesList = append(seriesList, series)
+	}
+	return seriesList
+}
+
+// frameRows returns the rows of the table frame of a query result, with nil
+// for null values.
+func frameRows(queryResult *tsdb.QueryResult) [][]interface{} {
+	frames, err := queryResult.Dataframes.Decoded()
+	So(err, ShouldBeNil)
+	So(len(frames), ShouldEqual, 1)
+
+	frame := frames[0]
+	rows := make([][]interface{}, frame.Rows())
+	for i := range rows {
+		rows[i] = make([]interface{}, len(frame.Fields))
+		for j, field := range frame.Fields {
+			if v, ok := field.ConcreteAt(i); ok {
+				rows[i][j] = v
+			}
+		}
+	}
+	return rows
+}
@@ -0,0 +1,34 @@
+package mysql
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// selectPattern matches the SELECT keyword starting a statement, after
+// whitespace and comments, and the optimizer hints following it.
+var selectPattern = regexp.MustCompile(`(?is)^(?:\s+|--[^\n]*(?:\n|$)|#[^\n]*(?:\n|$)|/\*[^+].*?\*/)*select\b(\s*/\*\+)?`)
+
+// withMaxExecutionTime adds the MAX_EXECUTION_TIME optimizer hint to a SELECT
+// statement, so that the server stops it after the timeout even when the
+// query is not cancelled. Servers older than MySQL 5.7.8 ignore the hint as a
+// comment.
+func withMaxExecutionTime(rawSQL string, timeout time.Duration) string {
+	if strings.Contains(strings.ToUpper(rawSQL), "MAX_EXECUTION_TIME") {
+		return rawSQL
+	}
+
+	match := selectPattern.FindStringSubmatchIndex(rawSQL)
+	if match == nil {
+		return rawSQL
+	}
+
+	hint := fmt.Sprintf("MAX_EXECUTION_TIME(%d)", timeout.Milliseconds())
+	// A statement only has one hint comment, so the hint joins the others
+	if match[2] >= 0 {
+		return rawSQL[:match[3]] + " " + hint + rawSQL[match[3]:]
+	}
+	return rawSQL[:match[1]] + " /*+ " + hint + " */" + rawSQL[match[1]:]
+}
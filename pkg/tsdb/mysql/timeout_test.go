@@ -0,0 +1,53 @@
+package mysql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithMaxExecutionTime(t *testing.T) {
+	testCases := []struct {
+		name     string
+		rawSQL   string
+		expected string
+	}{
+		{
+			name:     "select",
+			rawSQL:   "SELECT 1 AS value",
+			expected: "SELECT /*+ MAX_EXECUTION_TIME(30000) */ 1 AS value",
+		},
+		{
+			name:     "select after comments",
+			rawSQL:   "-- latest values\n/* hosts */ select * FROM metric",
+			expected: "-- latest values\n/* hosts */ select /*+ MAX_EXECUTION_TIME(30000) */ * FROM metric",
+		},
+		{
+			name:     "select with optimizer hints",
+			rawSQL:   "SELECT /*+ BKA(metric) */ * FROM metric",
+			expected: "SELECT /*+ MAX_EXECUTION_TIME(30000) BKA(metric) */ * FROM metric",
+		},
+		{
+			name:     "select with its own execution time",
+			rawSQL:   "SELECT /*+ MAX_EXECUTION_TIME(1000) */ * FROM metric",
+			expected: "SELECT /*+ MAX_EXECUTION_TIME(1000) */ * FROM metric",
+		},
+		{
+			name:     "other statements",
+			rawSQL:   "SHOW TABLES",
+			expected: "SHOW TABLES",
+		},
+		{
+			name:     "common table expression",
+			rawSQL:   "WITH selected AS (SELECT 1) SELECT * FROM selected",
+			expected: "WITH selected AS (SELECT 1) SELECT * FROM selected",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, withMaxExecutionTime(tc.rawSQL, 30*time.Second))
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package opentsdb
+
+import (
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// annotationsBuilder collects the annotations of the series of an annotations
+// query, or its global annotations, which OpenTSDB repeats in every series.
+type annotationsBuilder struct {
+	isGlobal    bool
+	annotations []OpenTsdbAnnotation
+	seen        map[annotationKey]bool
+}
+
+type annotationKey struct {
+	tsuid     string
+	startTime int64
+}
+
+func newAnnotationsBuilder(isGlobal bool) *annotationsBuilder {
+	return &annotationsBuilder{isGlobal: isGlobal, seen: make(map[annotationKey]bool)}
+}
+
+func (b *annotationsBuilder) add(val OpenTsdbResponse) {
+	annotations := val.Annotations
+	if b.isGlobal {
+		annotations = val.GlobalAnnotations
+	}
+
+	for _, annotation := range annotations {
+		key := annotationKey{tsuid: annotation.TSUID, startTime: annotation.StartTime}
+		if !b.seen[key] {
+			b.seen[key] = true
+			b.annotations = append(b.annotations, annotation)
+		}
+	}
+}
+
+// frame returns the annotations frame, with the time, timeEnd, text and notes
+// fields like the annotations of the UI.
+func (b *annotationsBuilder) frame(refID string) *data.Frame {
+	times := make([]time.Time, len(b.annotations))
+	timeEnds := make([]*time.Time, len(b.annotations))
+	texts := make([]string, len(b.annotations))
+	notes := make([]string, len(b.annotations))
+	for i, annotation := range b.annotations {
+		times[i] = time.Unix(annotation.StartTime, 0).UTC()
+		if annotation.EndTime > 0 {
+			timeEnd := time.Unix(annotation.EndTime, 0).UTC()
+			timeEnds[i] = &timeEnd
+		}
+		texts[i] = annotation.Description
+		notes[i] = annotation.Notes
+	}
+
+	frame := data.NewFrame("annotations",
+		data.NewField("time", nil, times),
+		data.NewField("timeEnd", nil, timeEnds),
+		data.NewField("text", nil, texts),
+		data.NewField("notes", nil, notes),
+	)
+	frame.RefID = refID
+	return frame
+}
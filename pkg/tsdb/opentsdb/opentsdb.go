@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"path"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -14,7 +16,9 @@ import (
 	"net/http"
 	"net/url"
 
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/openinsight-project/grafinsight/pkg/components/null"
+	"github.com/openinsight-project/grafinsight/pkg/components/simplejson"
 	"github.com/openinsight-project/grafinsight/pkg/infra/log"
 	"github.com/openinsight-project/grafinsight/pkg/models"
 	"github.com/openinsight-project/grafinsight/pkg/setting"
@@ -38,16 +42,42 @@ func init() {
 }
 
 func (e *OpenTsdbExecutor) Query(ctx context.Context, dsInfo *models.DataSource, queryContext *tsdb.TsdbQuery) (*tsdb.Response, error) {
-	result := &tsdb.Response{}
+	result := &tsdb.Response{Results: make(map[string]*tsdb.QueryResult)}
 
-	var tsdbQuery OpenTsdbQuery
+	tsdbVersion, tsdbResolution := 1, 1
+	if dsInfo.JsonData != nil {
+		tsdbVersion = dsInfo.JsonData.Get("tsdbVersion").MustInt(1)
+		tsdbResolution = dsInfo.JsonData.Get("tsdbResolution").MustInt(1)
+	}
 
-	tsdbQuery.Start = queryContext.TimeRange.GetFromAsMsEpoch()
-	tsdbQuery.End = queryContext.TimeRange.GetToAsMsEpoch()
+	// All the queries are sent in one request, and the results are mapped
+	// back to them by the index of the query, which OpenTSDB 2.3+ returns, or
+	// by their metric and tags
+	tsdbQuery := OpenTsdbQuery{
+		Start:             queryContext.TimeRange.GetFromAsMsEpoch(),
+		End:               queryContext.TimeRange.GetToAsMsEpoch(),
+		MsResolution:      tsdbResolution == 2,
+		GlobalAnnotations: true,
+		ShowQuery:         tsdbVersion >= 3,
+	}
 
+	var queries []*tsdb.Query
 	for _, query := range queryContext.Queries {
-		metric := e.buildMetric(query)
+		if query.Model.Get("metric").MustString() == "" {
+			continue
+		}
+
+		metric, err := e.buildMetric(query)
+		if err != nil {
+			result.Results[query.RefId] = &tsdb.QueryResult{RefId: query.RefId, Error: err}
+			continue
+		}
 		tsdbQuery.Queries = append(tsdbQuery.Queries, metric)
+		queries = append(queries, query)
+	}
+
+	if len(queries) == 0 {
+		return result, nil
 	}
 
 	if setting.Env == setting.Dev {
@@ -69,12 +99,14 @@ func (e *OpenTsdbExecutor) Query(ctx context.Context, dsInfo *models.DataSource,
 		return nil, err
 	}
 
-	queryResult, err := e.parseResponse(tsdbQuery, res)
+	queryResults, err := e.parseResponse(tsdbQuery, queries, res)
 	if err != nil {
 		return nil, err
 	}
 
-	result.Results = queryResult
+	for refID, queryResult := range queryResults {
+		result.Results[refID] = queryResult
+	}
 	return result, nil
 }
 
@@ -105,9 +137,15 @@ func (e *OpenTsdbExecutor) createRequest(dsInfo *models.DataSource, data OpenTsd
 	return req, err
 }
 
-func (e *OpenTsdbExecutor) parseResponse(query OpenTsdbQuery, res *http.Response) (map[string]*tsdb.QueryResult, error) {
+// OpenTSDB writes the NaN values of the nan fill policy as NaN, which is not
+// valid JSON.
+var nanRegex = regexp.MustCompile(`:\s*NaN\b`)
+
+func (e *OpenTsdbExecutor) parseResponse(tsdbQuery OpenTsdbQuery, queries []*tsdb.Query, res *http.Response) (map[string]*tsdb.QueryResult, error) {
 	queryResults := make(map[string]*tsdb.QueryResult)
-	queryRes := tsdb.NewQueryResult()
+	for _, query := range queries {
+		queryResults[query.RefId] = &tsdb.QueryResult{RefId: query.RefId, Series: tsdb.TimeSeriesSlice{}}
+	}
 
 	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
@@ -124,16 +162,32 @@ func (e *OpenTsdbExecutor) parseResponse(query OpenTsdbQuery, res *http.Response
 		return nil, fmt.Errorf("request failed, status: %s", res.Status)
 	}
 
-	var data []OpenTsdbResponse
-	err = json.Unmarshal(body, &data)
+	var responses []OpenTsdbResponse
+	err = json.Unmarshal(nanRegex.ReplaceAll(body, []byte(":null")), &responses)
 	if err != nil {
 		plog.Info("Failed to unmarshal opentsdb response", "error", err, "status", res.Status, "body", string(body))
 		return nil, err
 	}
 
-	for _, val := range data {
+	groupByTags := getGroupByTags(tsdbQuery.Queries)
+	annotations := make(map[string]*annotationsBuilder)
+	for _, val := range responses {
+		query := queries[queryIndex(val, tsdbQuery.Queries)]
+		queryRes := queryResults[query.RefId]
+
+		if query.Model.Get("queryType").MustString() == queryTypeAnnotations {
+			builder, ok := annotations[query.RefId]
+			if !ok {
+				builder = newAnnotationsBuilder(query.Model.Get("isGlobal").MustBool())
+				annotations[query.RefId] = builder
+			}
+			builder.add(val)
+			continue
+		}
+
 		series := tsdb.TimeSeries{
-			Name: val.Metric,
+			Name: formatSeriesName(val, query.Model.Get("alias").MustString(), groupByTags),
+			Tags: val.Tags,
 		}
 
 		for timeString, value := range val.DataPoints {
@@ -142,17 +196,150 @@ func (e *OpenTsdbExecutor) parseResponse(query OpenTsdbQuery, res *http.Response
 				plog.Info("Failed to unmarshal opentsdb timestamp", "timestamp", timeString)
 				return nil, err
 			}
-			series.Points = append(series.Points, tsdb.NewTimePoint(null.FloatFrom(value), timestamp))
+			// The timestamps are in seconds, unless they are requested
+			// in milliseconds
+			if !tsdbQuery.MsResolution {
+				timestamp *= 1000
+			}
+			series.Points = append(series.Points, tsdb.TimePoint{value, null.FloatFrom(timestamp)})
 		}
+		sort.Slice(series.Points, func(i, j int) bool {
+			return series.Points[i][1].Float64 < series.Points[j][1].Float64
+		})
 
 		queryRes.Series = append(queryRes.Series, &series)
 	}
 
-	queryResults["A"] = queryRes
+	for _, query := range queries {
+		if query.Model.Get("queryType").MustString() != queryTypeAnnotations {
+			continue
+		}
+		builder, ok := annotations[query.RefId]
+		if !ok {
+			builder = newAnnotationsBuilder(query.Model.Get("isGlobal").MustBool())
+		}
+		queryResults[query.RefId].Series = nil
+		queryResults[query.RefId].Dataframes = tsdb.NewDecodedDataFrames(data.Frames{builder.frame(query.RefId)})
+	}
+
 	return queryResults, nil
 }
 
-func (e *OpenTsdbExecutor) buildMetric(query *tsdb.Query) map[string]interface{} {
+// queryIndex returns the index of the query of a result. Without the index
+// returned by OpenTSDB 2.3+, it is the first query with the metric and the
+// tag values of the result, or else the first query.
+func queryIndex(val OpenTsdbResponse, metrics []map[string]interface{}) int {
+	if val.Query != nil {
+		if val.Query.Index >= 0 && val.Query.Index < len(metrics) {
+			return val.Query.Index
+		}
+		return 0
+	}
+
+	for i, metric := range metrics {
+		if metric["metric"] != val.Metric {
+			continue
+		}
+		if _, ok := metric["filters"]; ok {
+			return i
+		}
+
+		tags, _ := metric["tags"].(map[string]interface{})
+		matches := true
+		for key, value := range tags {
+			tagValue := fmt.Sprint(value)
+			if tagValue != "*" && !contains(strings.Split(tagValue, "|"), val.Tags[key]) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			return i
+		}
+	}
+	return 0
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// getGroupByTags returns the keys of the tags and filters of the queries,
+// which are the tags in the names of series.
+func getGroupByTags(metrics []map[string]interface{}) map[string]bool {
+	groupByTags := make(map[string]bool)
+	for _, metric := range metrics {
+		if filters, ok := metric["filters"].([]map[string]interface{}); ok {
+			for _, filter := range filters {
+				groupByTags[fmt.Sprint(filter["tagk"])] = true
+			}
+			continue
+		}
+		tags, _ := metric["tags"].(map[string]interface{})
+		for key := range tags {
+			groupByTags[key] = true
+		}
+	}
+	return groupByTags
+}
+
+var aliasFormat = regexp.MustCompile(`\[\[([\w.-]+)\]\]|\$([\w.-]+)`)
+
+// formatSeriesName returns the name of a series, which is the alias of the
+// query with $tag_<key> and $metric patterns replaced, or the metric followed
+// by the tags the series are grouped by.
+func formatSeriesName(val OpenTsdbResponse, alias string, groupByTags map[string]bool) string {
+	if alias != "" {
+		return aliasFormat.ReplaceAllStringFunc(alias, func(in string) string {
+			name := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(in, "$"), "[["), "]]")
+			if name == "metric" {
+				return val.Metric
+			}
+			if tagValue, ok := val.Tags[strings.TrimPrefix(name, "tag_")]; ok && strings.HasPrefix(name, "tag_") {
+				return tagValue
+			}
+			return in
+		})
+	}
+
+	var tags []string
+	for key, value := range val.Tags {
+		if groupByTags[key] {
+			tags = append(tags, key+"="+value)
+		}
+	}
+	if len(tags) == 0 {
+		return val.Metric
+	}
+	sort.Strings(tags)
+	return val.Metric + "{" + strings.Join(tags, ", ") + "}"
+}
+
+// queryTypeAnnotations queries the annotations of the series of a metric, or
+// the global annotations with the isGlobal option, instead of the series.
+const queryTypeAnnotations = "annotations"
+
+// fillPolicies are the fill policies of downsampling, where none is no
+// fill policy.
+var fillPolicies = map[string]bool{"none": true, "nan": true, "null": true, "zero": true}
+
+// filterTypes are the types of tag filters of OpenTSDB 2.2+.
+var filterTypes = map[string]bool{
+	"wildcard":        true,
+	"iwildcard":       true,
+	"regexp":          true,
+	"literal_or":      true,
+	"iliteral_or":     true,
+	"not_literal_or":  true,
+	"not_iliteral_or": true,
+}
+
+func (e *OpenTsdbExecutor) buildMetric(query *tsdb.Query) (map[string]interface{}, error) {
 	metric := make(map[string]interface{})
 
 	// Setting metric and aggregator
@@ -167,8 +354,15 @@ func (e *OpenTsdbExecutor) buildMetric(query *tsdb.Query) map[string]interface{}
 			downsampleInterval = "1m" // default value for blank
 		}
 		downsample := downsampleInterval + "-" + query.Model.Get("downsampleAggregator").MustString()
-		if query.Model.Get("downsampleFillPolicy").MustString() != "none" {
-			metric["downsample"] = downsample + "-" + query.Model.Get("downsampleFillPolicy").MustString()
+		fillPolicy := query.Model.Get("downsampleFillPolicy").MustString("none")
+		if fillPolicy == "" {
+			fillPolicy = "none"
+		}
+		if !fillPolicies[fillPolicy] {
+			return nil, fmt.Errorf("invalid downsample fill policy %q", fillPolicy)
+		}
+		if fillPolicy != "none" {
+			metric["downsample"] = downsample + "-" + fillPolicy
 		} else {
 			metric["downsample"] = downsample
 		}
@@ -180,34 +374,99 @@ func (e *OpenTsdbExecutor) buildMetric(query *tsdb.Query) map[string]interface{}
 		rateOptions := make(map[string]interface{})
 		rateOptions["counter"] = query.Model.Get("isCounter").MustBool()
 
-		counterMax, counterMaxCheck := query.Model.CheckGet("counterMax")
+		counterMax, counterMaxCheck, err := getFloat(query.Model, "counterMax")
+		if err != nil {
+			return nil, err
+		}
 		if counterMaxCheck {
-			rateOptions["counterMax"] = counterMax.MustFloat64()
+			rateOptions["counterMax"] = counterMax
 		}
 
-		resetValue, resetValueCheck := query.Model.CheckGet("counterResetValue")
+		resetValue, resetValueCheck, err := getFloat(query.Model, "counterResetValue")
+		if err != nil {
+			return nil, err
+		}
 		if resetValueCheck {
-			rateOptions["resetValue"] = resetValue.MustFloat64()
+			rateOptions["resetValue"] = resetValue
 		}
 
-		if !counterMaxCheck && (!resetValueCheck || resetValue.MustFloat64() == 0) {
+		if !counterMaxCheck && (!resetValueCheck || resetValue == 0) {
 			rateOptions["dropResets"] = true
 		}
 
 		metric["rateOptions"] = rateOptions
 	}
 
-	// Setting tags
-	tags, tagsCheck := query.Model.CheckGet("tags")
-	if tagsCheck && len(tags.MustMap()) > 0 {
-		metric["tags"] = tags.MustMap()
+	// Setting filters, which replace the tags
+	filters, err := buildFilters(query.Model)
+	if err != nil {
+		return nil, err
+	}
+	if len(filters) > 0 {
+		metric["filters"] = filters
+	} else {
+		// Setting tags
+		tags, tagsCheck := query.Model.CheckGet("tags")
+		if tagsCheck && len(tags.MustMap()) > 0 {
+			metric["tags"] = tags.MustMap()
+		}
+	}
+
+	if query.Model.Get("explicitTags").MustBool() {
+		metric["explicitTags"] = true
 	}
 
-	// Setting filters
-	filters, filtersCheck := query.Model.CheckGet("filters")
-	if filtersCheck && len(filters.MustArray()) > 0 {
-		metric["filters"] = filters.MustArray()
+	return metric, nil
+}
+
+// buildFilters returns the tag filters of a query, which are objects with the
+// type, tagk, filter and groupBy keys.
+func buildFilters(model *simplejson.Json) ([]map[string]interface{}, error) {
+	var filters []map[string]interface{}
+	for i := range model.Get("filters").MustArray() {
+		filter := model.Get("filters").GetIndex(i)
+
+		filterType := filter.Get("type").MustString()
+		if !filterTypes[filterType] {
+			return nil, fmt.Errorf("invalid filter type %q", filterType)
+		}
+		tagk := filter.Get("tagk").MustString()
+		if tagk == "" {
+			return nil, fmt.Errorf("missing tag key of %s filter", filterType)
+		}
+
+		filters = append(filters, map[string]interface{}{
+			"type":    filterType,
+			"tagk":    tagk,
+			"filter":  filter.Get("filter").MustString(),
+			"groupBy": filter.Get("groupBy").MustBool(),
+		})
+	}
+	return filters, nil
+}
+
+// getFloat returns a number of a query, which the query editor saves as a
+// string. Empty strings are no number.
+func getFloat(model *simplejson.Json, key string) (float64, bool, error) {
+	value, ok := model.CheckGet(key)
+	if !ok || value.Interface() == nil {
+		return 0, false, nil
 	}
 
-	return metric
+	if s, err := value.String(); err == nil {
+		if s == "" {
+			return 0, false, nil
+		}
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("invalid %s %q, must be a number", key, s)
+		}
+		return f, true, nil
+	}
+
+	f, err := value.Float64()
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid %s, must be a number", key)
+	}
+	return f, true, nil
 }
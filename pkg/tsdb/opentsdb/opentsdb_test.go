@@ -1,9 +1,16 @@
 package opentsdb
 
 import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/openinsight-project/grafinsight/pkg/components/simplejson"
+	"github.com/openinsight-project/grafinsight/pkg/models"
 	"github.com/openinsight-project/grafinsight/pkg/tsdb"
 	"github.com/stretchr/testify/require"
 )
@@ -23,7 +30,8 @@ func TestOpenTsdbExecutor(t *testing.T) {
 		query.Model.Set("downsampleAggregator", "avg")
 		query.Model.Set("downsampleFillPolicy", "none")
 
-		metric := exec.buildMetric(query)
+		metric, err := exec.buildMetric(query)
+		require.NoError(t, err)
 
 		require.Len(t, metric, 3)
 		require.Equal(t, "cpu.average.percent", metric["metric"])
@@ -43,7 +51,8 @@ func TestOpenTsdbExecutor(t *testing.T) {
 		query.Model.Set("downsampleAggregator", "avg")
 		query.Model.Set("downsampleFillPolicy", "none")
 
-		metric := exec.buildMetric(query)
+		metric, err := exec.buildMetric(query)
+		require.NoError(t, err)
 
 		require.Len(t, metric, 2)
 		require.Equal(t, "cpu.average.percent", metric["metric"])
@@ -62,7 +71,8 @@ func TestOpenTsdbExecutor(t *testing.T) {
 		query.Model.Set("downsampleAggregator", "sum")
 		query.Model.Set("downsampleFillPolicy", "null")
 
-		metric := exec.buildMetric(query)
+		metric, err := exec.buildMetric(query)
+		require.NoError(t, err)
 
 		require.Len(t, metric, 3)
 		require.Equal(t, "cpu.average.percent", metric["metric"])
@@ -87,7 +97,8 @@ func TestOpenTsdbExecutor(t *testing.T) {
 		tags.Set("app", "grafinsight")
 		query.Model.Set("tags", tags.MustMap())
 
-		metric := exec.buildMetric(query)
+		metric, err := exec.buildMetric(query)
+		require.NoError(t, err)
 
 		require.Len(t, metric, 3)
 		require.Equal(t, "cpu.average.percent", metric["metric"])
@@ -117,7 +128,8 @@ func TestOpenTsdbExecutor(t *testing.T) {
 		tags.Set("app", "grafinsight")
 		query.Model.Set("tags", tags.MustMap())
 
-		metric := exec.buildMetric(query)
+		metric, err := exec.buildMetric(query)
+		require.NoError(t, err)
 
 		require.Len(t, metric, 5)
 		require.Equal(t, "cpu.average.percent", metric["metric"])
@@ -151,7 +163,8 @@ func TestOpenTsdbExecutor(t *testing.T) {
 		tags.Set("app", "grafinsight")
 		query.Model.Set("tags", tags.MustMap())
 
-		metric := exec.buildMetric(query)
+		metric, err := exec.buildMetric(query)
+		require.NoError(t, err)
 
 		require.Len(t, metric, 5)
 		require.Equal(t, "cpu.average.percent", metric["metric"])
@@ -170,4 +183,217 @@ func TestOpenTsdbExecutor(t *testing.T) {
 		require.Equal(t, float64(45), metricRateOptions["counterMax"])
 		require.Equal(t, float64(60), metricRateOptions["resetValue"])
 	})
+	t.Run("Build metric with rate and counter values of the query editor", func(t *testing.T) {
+		query := &tsdb.Query{
+			Model: simplejson.New(),
+		}
+
+		query.Model.Set("metric", "cpu.average.percent")
+		query.Model.Set("aggregator", "avg")
+		query.Model.Set("disableDownsampling", true)
+		query.Model.Set("shouldComputeRate", true)
+		query.Model.Set("isCounter", true)
+		query.Model.Set("counterMax", "")
+		query.Model.Set("counterResetValue", "60")
+
+		metric, err := exec.buildMetric(query)
+		require.NoError(t, err)
+
+		metricRateOptions := metric["rateOptions"].(map[string]interface{})
+		require.Len(t, metricRateOptions, 2)
+		require.Equal(t, float64(60), metricRateOptions["resetValue"])
+
+		query.Model.Set("counterMax", "max")
+		_, err = exec.buildMetric(query)
+		require.EqualError(t, err, `invalid counterMax "max", must be a number`)
+	})
+
+	t.Run("Build metric with filters and explicit tags", func(t *testing.T) {
+		query := &tsdb.Query{
+			Model: simplejson.NewFromAny(map[string]interface{}{
+				"metric":              "cpu.average.percent",
+				"aggregator":          "avg",
+				"disableDownsampling": true,
+				"explicitTags":        true,
+				"tags":                map[string]interface{}{"env": "prod"},
+				"filters": []interface{}{
+					map[string]interface{}{"type": "wildcard", "tagk": "host", "filter": "web-*", "groupBy": true},
+					map[string]interface{}{"type": "not_literal_or", "tagk": "env", "filter": "dev|test", "groupBy": false},
+				},
+			}),
+		}
+
+		metric, err := exec.buildMetric(query)
+		require.NoError(t, err)
+
+		require.Len(t, metric, 4)
+		require.True(t, metric["explicitTags"].(bool))
+		require.Nil(t, metric["tags"])
+		require.Equal(t, []map[string]interface{}{
+			{"type": "wildcard", "tagk": "host", "filter": "web-*", "groupBy": true},
+			{"type": "not_literal_or", "tagk": "env", "filter": "dev|test", "groupBy": false},
+		}, metric["filters"])
+	})
+
+	t.Run("Build metric with invalid filters", func(t *testing.T) {
+		query := &tsdb.Query{
+			Model: simplejson.NewFromAny(map[string]interface{}{
+				"metric":  "cpu.average.percent",
+				"filters": []interface{}{map[string]interface{}{"type": "glob", "tagk": "host", "filter": "web-*"}},
+			}),
+		}
+		_, err := exec.buildMetric(query)
+		require.EqualError(t, err, `invalid filter type "glob"`)
+
+		query.Model.Set("filters", []interface{}{map[string]interface{}{"type": "regexp", "filter": "web-.*"}})
+		_, err = exec.buildMetric(query)
+		require.EqualError(t, err, "missing tag key of regexp filter")
+	})
+
+	t.Run("Build metric with invalid fill policy", func(t *testing.T) {
+		query := &tsdb.Query{
+			Model: simplejson.NewFromAny(map[string]interface{}{
+				"metric":               "cpu.average.percent",
+				"downsampleAggregator": "avg",
+				"downsampleFillPolicy": "previous",
+			}),
+		}
+		_, err := exec.buildMetric(query)
+		require.EqualError(t, err, `invalid downsample fill policy "previous"`)
+
+		query.Model.Set("downsampleFillPolicy", "zero")
+		metric, err := exec.buildMetric(query)
+		require.NoError(t, err)
+		require.Equal(t, "1m-avg-zero", metric["downsample"])
+	})
+}
+
+func TestOpenTsdbQuery(t *testing.T) {
+	var request OpenTsdbQuery
+	var response string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, err := ioutil.ReadAll(req.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(body, &request))
+		_, _ = rw.Write([]byte(response))
+	}))
+	t.Cleanup(srv.Close)
+
+	query := func(t *testing.T, jsonData map[string]interface{}, queries ...*tsdb.Query) *tsdb.Response {
+		t.Helper()
+
+		dsInfo := &models.DataSource{Url: srv.URL, JsonData: simplejson.NewFromAny(jsonData)}
+		exec, err := NewOpenTsdbExecutor(dsInfo)
+		require.NoError(t, err)
+		resp, err := exec.Query(context.Background(), dsInfo, &tsdb.TsdbQuery{
+			TimeRange: tsdb.NewTimeRange("1521118800000", "1521119100000"),
+			Queries:   queries,
+		})
+		require.NoError(t, err)
+		return resp
+	}
+
+	newQuery := func(refID string, model map[string]interface{}) *tsdb.Query {
+		model["aggregator"] = "sum"
+		model["disableDownsampling"] = true
+		return &tsdb.Query{RefId: refID, Model: simplejson.NewFromAny(model)}
+	}
+
+	t.Run("Should map results to queries by metric and tags", func(t *testing.T) {
+		response = `[
+			{"metric": "cpu", "tags": {"host": "a", "env": "prod"}, "dps": {"1521118860": 2, "1521118800": 1}},
+			{"metric": "cpu", "tags": {"host": "b", "env": "dev"}, "dps": {"1521118800": NaN}},
+			{"metric": "mem", "tags": {"host": "a"}, "dps": {"1521118800": null}}
+		]`
+		resp := query(t, map[string]interface{}{},
+			newQuery("A", map[string]interface{}{"metric": "cpu", "tags": map[string]interface{}{"env": "prod|staging"}}),
+			newQuery("B", map[string]interface{}{"metric": "cpu", "tags": map[string]interface{}{"env": "dev"}, "alias": "$tag_host $metric"}),
+			newQuery("C", map[string]interface{}{"metric": "mem", "filters": []interface{}{
+				map[string]interface{}{"type": "wildcard", "tagk": "host", "filter": "*", "groupBy": true},
+			}}),
+			newQuery("D", map[string]interface{}{}),
+		)
+
+		require.Len(t, request.Queries, 3)
+		require.True(t, request.GlobalAnnotations)
+		require.False(t, request.ShowQuery)
+		require.Len(t, resp.Results, 3)
+
+		series := resp.Results["A"].Series
+		require.Len(t, series, 1)
+		require.Equal(t, "cpu{env=prod, host=a}", series[0].Name)
+		require.Equal(t, map[string]string{"host": "a", "env": "prod"}, series[0].Tags)
+		require.Len(t, series[0].Points, 2)
+		require.Equal(t, 1521118800000.0, series[0].Points[0][1].Float64)
+		require.Equal(t, 2.0, series[0].Points[1][0].Float64)
+
+		series = resp.Results["B"].Series
+		require.Len(t, series, 1)
+		require.Equal(t, "b cpu", series[0].Name)
+		require.False(t, series[0].Points[0][0].Valid)
+
+		series = resp.Results["C"].Series
+		require.Len(t, series, 1)
+		require.Equal(t, "mem{host=a}", series[0].Name)
+	})
+
+	t.Run("Should map results to queries by index with OpenTSDB 2.3", func(t *testing.T) {
+		response = `[
+			{"metric": "cpu", "tags": {"host": "a"}, "dps": {"1521118800000": 1}, "query": {"index": 1}},
+			{"metric": "cpu", "tags": {"host": "a"}, "dps": {"1521118800000": 2}, "query": {"index": 0}}
+		]`
+		resp := query(t, map[string]interface{}{"tsdbVersion": 3, "tsdbResolution": 2},
+			newQuery("A", map[string]interface{}{"metric": "cpu"}),
+			newQuery("B", map[string]interface{}{"metric": "cpu", "shouldComputeRate": true}),
+		)
+
+		require.True(t, request.ShowQuery)
+		require.True(t, request.MsResolution)
+		require.Equal(t, 2.0, resp.Results["A"].Series[0].Points[0][0].Float64)
+		require.Equal(t, 1.0, resp.Results["B"].Series[0].Points[0][0].Float64)
+		require.Equal(t, 1521118800000.0, resp.Results["B"].Series[0].Points[0][1].Float64)
+	})
+
+	t.Run("Should return annotations of annotations queries", func(t *testing.T) {
+		response = `[
+			{
+				"metric": "deploys", "tags": {}, "dps": {},
+				"annotations": [{"tsuid": "0001", "description": "Deployed v2", "notes": "Canary", "startTime": 1521118800, "endTime": 1521118860}],
+				"globalAnnotations": [{"description": "Outage", "startTime": 1521118900}]
+			}
+		]`
+		resp := query(t, map[string]interface{}{},
+			newQuery("A", map[string]interface{}{"metric": "deploys", "queryType": "annotations"}),
+		)
+
+		queryRes := resp.Results["A"]
+		require.Empty(t, queryRes.Series)
+		frames, err := queryRes.Dataframes.Decoded()
+		require.NoError(t, err)
+		require.Len(t, frames, 1)
+		frame := frames[0]
+		require.Equal(t, 1, frame.Rows())
+		require.Equal(t, time.Date(2018, 3, 15, 13, 0, 0, 0, time.UTC), frame.Fields[0].At(0))
+		require.Equal(t, time.Date(2018, 3, 15, 13, 1, 0, 0, time.UTC), *frame.Fields[1].At(0).(*time.Time))
+		require.Equal(t, "Deployed v2", frame.Fields[2].At(0))
+		require.Equal(t, "Canary", frame.Fields[3].At(0))
+
+		resp = query(t, map[string]interface{}{},
+			newQuery("A", map[string]interface{}{"metric": "deploys", "queryType": "annotations", "isGlobal": true}),
+		)
+		frames, err = resp.Results["A"].Dataframes.Decoded()
+		require.NoError(t, err)
+		frame = frames[0]
+		require.Equal(t, 1, frame.Rows())
+		require.Equal(t, "Outage", frame.Fields[2].At(0))
+		require.Nil(t, frame.Fields[1].At(0))
+	})
+
+	t.Run("Should return errors of invalid queries", func(t *testing.T) {
+		response = `[]`
+		resp := query(t, map[string]interface{}{},
+			newQuery("A", map[string]interface{}{"metric": "cpu", "filters": []interface{}{map[string]interface{}{"type": "glob", "tagk": "host"}}}),
+		)
+		require.EqualError(t, resp.Results["A"].Error, `invalid filter type "glob"`)
+	})
 }
@@ -1,12 +1,39 @@
 package opentsdb
 
+import "github.com/openinsight-project/grafinsight/pkg/components/null"
+
 type OpenTsdbQuery struct {
-	Start   int64                    `json:"start"`
-	End     int64                    `json:"end"`
-	Queries []map[string]interface{} `json:"queries"`
+	Start             int64                    `json:"start"`
+	End               int64                    `json:"end"`
+	Queries           []map[string]interface{} `json:"queries"`
+	MsResolution      bool                     `json:"msResolution,omitempty"`
+	GlobalAnnotations bool                     `json:"globalAnnotations,omitempty"`
+	ShowQuery         bool                     `json:"showQuery,omitempty"`
 }
 
 type OpenTsdbResponse struct {
-	Metric     string             `json:"metric"`
-	DataPoints map[string]float64 `json:"dps"`
+	Metric            string                 `json:"metric"`
+	Tags              map[string]string      `json:"tags"`
+	AggregateTags     []string               `json:"aggregateTags"`
+	DataPoints        map[string]null.Float  `json:"dps"`
+	Annotations       []OpenTsdbAnnotation   `json:"annotations"`
+	GlobalAnnotations []OpenTsdbAnnotation   `json:"globalAnnotations"`
+	Query             *OpenTsdbResponseQuery `json:"query"`
+}
+
+// OpenTsdbResponseQuery is the query of a response, which OpenTSDB 2.3+
+// returns with the showQuery option.
+type OpenTsdbResponseQuery struct {
+	Index int `json:"index"`
+}
+
+// OpenTsdbAnnotation is an annotation of a time series, or a global
+// annotation. The times are in seconds.
+type OpenTsdbAnnotation struct {
+	TSUID       string            `json:"tsuid"`
+	Description string            `json:"description"`
+	Notes       string            `json:"notes"`
+	StartTime   int64             `json:"startTime"`
+	EndTime     int64             `json:"endTime"`
+	Custom      map[string]string `json:"custom"`
 }
@@ -111,6 +111,11 @@ func (s *postgresService) generateConnectionString(datasource *models.DataSource
 		connStr += fmt.Sprintf(" port=%d", port)
 	}
 
+	// Let the server stop queries running longer than the timeout
+	if timeout := sqleng.ConfiguredQueryTimeout(datasource); timeout > 0 {
+		connStr += fmt.Sprintf(" statement_timeout=%d", timeout.Milliseconds())
+	}
+
 	tlsSettings, err := s.tlsManager.getTLSSettings(datasource)
 	if err != nil {
 		return "", err
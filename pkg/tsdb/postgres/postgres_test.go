@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/openinsight-project/grafinsight/pkg/components/null"
 	"github.com/openinsight-project/grafinsight/pkg/components/securejsondata"
 	"github.com/openinsight-project/grafinsight/pkg/components/simplejson"
 	"github.com/openinsight-project/grafinsight/pkg/infra/log"
@@ -38,6 +39,7 @@ func TestGenerateConnectionString(t *testing.T) {
 		password    string
 		database    string
 		tlsSettings tlsSettings
+		jsonData    map[string]interface{}
 		expConnStr  string
 		expErr      string
 		uid         string
@@ -110,6 +112,16 @@ func TestGenerateConnectionString(t *testing.T) {
 			expConnStr: "user='user' password='password' host='host' dbname='database' sslmode='verify-full' " +
 				"sslrootcert='i/am/coding/ca.crt' sslcert='i/am/coding/client.crt' sslkey='i/am/coding/client.key'",
 		},
+		{
+			desc:        "Query timeout",
+			host:        "host",
+			user:        "user",
+			password:    "password",
+			database:    "database",
+			tlsSettings: tlsSettings{Mode: "disable"},
+			jsonData:    map[string]interface{}{"queryTimeout": 30},
+			expConnStr:  "user='user' password='password' host='host' dbname='database' statement_timeout=30000 sslmode='disable'",
+		},
 	}
 	for _, tt := range testCases {
 		t.Run(tt.desc, func(t *testing.T) {
@@ -126,6 +138,9 @@ func TestGenerateConnectionString(t *testing.T) {
 				Database: tt.database,
 				Uid:      tt.uid,
 			}
+			if tt.jsonData != nil {
+				ds.JsonData = simplejson.NewFromAny(tt.jsonData)
+			}
 
 			connStr, err := svc.generateConnectionString(ds)
 
@@ -250,7 +265,7 @@ func TestPostgres(t *testing.T) {
 			queryResult := resp.Results["A"]
 			require.NoError(t, queryResult.Error)
 
-			column := queryResult.Tables[0].Rows[0]
+			column := frameRows(t, queryResult)[0]
 			require.Equal(t, int64(1), column[0].(int64))
 			require.Equal(t, int64(2), column[1].(int64))
 			require.Equal(t, int64(3), column[2].(int64))
@@ -335,7 +350,7 @@ func TestPostgres(t *testing.T) {
 			queryResult := resp.Results["A"]
 			require.NoError(t, queryResult.Error)
 
-			points := queryResult.Series[0].Points
+			points := frameSeries(t, queryResult)[0].Points
 			// without fill this should result in 4 buckets
 			require.Len(t, points, 4)
 
@@ -416,7 +431,7 @@ func TestPostgres(t *testing.T) {
 			queryResult := resp.Results["A"]
 			require.NoError(t, queryResult.Error)
 
-			points := queryResult.Series[0].Points
+			points := frameSeries(t, queryResult)[0].Points
 			require.Len(t, points, 7)
 
 			dt := fromStart
@@ -469,7 +484,7 @@ func TestPostgres(t *testing.T) {
 			queryResult := resp.Results["A"]
 			require.NoError(t, queryResult.Error)
 
-			points := queryResult.Series[0].Points
+			points := frameSeries(t, queryResult)[0].Points
 			require.Equal(t, float64(1.5), points[3][0].Float64)
 		})
 	})
@@ -496,7 +511,7 @@ func TestPostgres(t *testing.T) {
 		queryResult := resp.Results["A"]
 		require.NoError(t, queryResult.Error)
 
-		points := queryResult.Series[0].Points
+		points := frameSeries(t, queryResult)[0].Points
 		require.Equal(t, float64(15.0), points[2][0].Float64)
 		require.Equal(t, float64(15.0), points[3][0].Float64)
 		require.Equal(t, float64(20.0), points[6][0].Float64)
@@ -589,8 +604,8 @@ func TestPostgres(t *testing.T) {
 				queryResult := resp.Results["A"]
 				require.NoError(t, queryResult.Error)
 
-				require.Equal(t, 1, len(queryResult.Series))
-				require.Equal(t, float64(tInitial.UnixNano()/1e6), queryResult.Series[0].Points[0][1].Float64)
+				require.Equal(t, 1, len(frameSeries(t, queryResult)))
+				require.Equal(t, float64(tInitial.UnixNano()/1e6), frameSeries(t, queryResult)[0].Points[0][1].Float64)
 			})
 
 		t.Run("When doing a metric query using epoch (int64 nullable) as time column and value column (int64 nullable,) should return metric with time in milliseconds",
@@ -612,8 +627,8 @@ func TestPostgres(t *testing.T) {
 				queryResult := resp.Results["A"]
 				require.NoError(t, queryResult.Error)
 
-				require.Len(t, queryResult.Series, 1)
-				require.Equal(t, float64(tInitial.UnixNano()/1e6), queryResult.Series[0].Points[0][1].Float64)
+				require.Len(t, frameSeries(t, queryResult), 1)
+				require.Equal(t, float64(tInitial.UnixNano()/1e6), frameSeries(t, queryResult)[0].Points[0][1].Float64)
 			})
 
 		t.Run("When doing a metric query using epoch (float64) as time column and value column (float64), should return metric with time in milliseconds",
@@ -635,8 +650,8 @@ func TestPostgres(t *testing.T) {
 				queryResult := resp.Results["A"]
 				require.NoError(t, queryResult.Error)
 
-				require.Len(t, queryResult.Series, 1)
-				require.Equal(t, float64(tInitial.UnixNano()/1e6), queryResult.Series[0].Points[0][1].Float64)
+				require.Len(t, frameSeries(t, queryResult), 1)
+				require.Equal(t, float64(tInitial.UnixNano()/1e6), frameSeries(t, queryResult)[0].Points[0][1].Float64)
 			})
 
 		t.Run("When doing a metric query using epoch (float64 nullable) as time column and value column (float64 nullable), should return metric with time in milliseconds",
@@ -658,8 +673,8 @@ func TestPostgres(t *testing.T) {
 				queryResult := resp.Results["A"]
 				require.NoError(t, queryResult.Error)
 
-				require.Len(t, queryResult.Series, 1)
-				require.Equal(t, float64(tInitial.UnixNano()/1e6), queryResult.Series[0].Points[0][1].Float64)
+				require.Len(t, frameSeries(t, queryResult), 1)
+				require.Equal(t, float64(tInitial.UnixNano()/1e6), frameSeries(t, queryResult)[0].Points[0][1].Float64)
 			})
 
 		t.Run("When doing a metric query using epoch (int32) as time column and value column (int32), should return metric with time in milliseconds",
@@ -681,8 +696,8 @@ func TestPostgres(t *testing.T) {
 				queryResult := resp.Results["A"]
 				require.NoError(t, queryResult.Error)
 
-				require.Len(t, queryResult.Series, 1)
-				require.Equal(t, float64(tInitial.UnixNano()/1e6), queryResult.Series[0].Points[0][1].Float64)
+				require.Len(t, frameSeries(t, queryResult), 1)
+				require.Equal(t, float64(tInitial.UnixNano()/1e6), frameSeries(t, queryResult)[0].Points[0][1].Float64)
 			})
 
 		t.Run("When doing a metric query using epoch (int32 nullable) as time column and value column (int32 nullable), should return metric with time in milliseconds",
@@ -704,8 +719,8 @@ func TestPostgres(t *testing.T) {
 				queryResult := resp.Results["A"]
 				require.NoError(t, queryResult.Error)
 
-				require.Len(t, queryResult.Series, 1)
-				require.Equal(t, float64(tInitial.UnixNano()/1e6), queryResult.Series[0].Points[0][1].Float64)
+				require.Len(t, frameSeries(t, queryResult), 1)
+				require.Equal(t, float64(tInitial.UnixNano()/1e6), frameSeries(t, queryResult)[0].Points[0][1].Float64)
 			})
 
 		t.Run("When doing a metric query using epoch (float32) as time column and value column (float32), should return metric with time in milliseconds",
@@ -727,8 +742,8 @@ func TestPostgres(t *testing.T) {
 				queryResult := resp.Results["A"]
 				require.NoError(t, queryResult.Error)
 
-				require.Len(t, queryResult.Series, 1)
-				require.Equal(t, float64(float32(tInitial.Unix()))*1e3, queryResult.Series[0].Points[0][1].Float64)
+				require.Len(t, frameSeries(t, queryResult), 1)
+				require.Equal(t, float64(float32(tInitial.Unix()))*1e3, frameSeries(t, queryResult)[0].Points[0][1].Float64)
 			})
 
 		t.Run("When doing a metric query using epoch (float32 nullable) as time column and value column (float32 nullable), should return metric with time in milliseconds",
@@ -750,8 +765,8 @@ func TestPostgres(t *testing.T) {
 				queryResult := resp.Results["A"]
 				require.NoError(t, queryResult.Error)
 
-				require.Len(t, queryResult.Series, 1)
-				require.Equal(t, float64(float32(tInitial.Unix()))*1e3, queryResult.Series[0].Points[0][1].Float64)
+				require.Len(t, frameSeries(t, queryResult), 1)
+				require.Equal(t, float64(float32(tInitial.Unix()))*1e3, frameSeries(t, queryResult)[0].Points[0][1].Float64)
 			})
 
 		t.Run("When doing a metric query grouping by time and select metric column should return correct series", func(t *testing.T) {
@@ -772,9 +787,9 @@ func TestPostgres(t *testing.T) {
 			queryResult := resp.Results["A"]
 			require.NoError(t, queryResult.Error)
 
-			require.Len(t, queryResult.Series, 2)
-			require.Equal(t, "Metric A - value one", queryResult.Series[0].Name)
-			require.Equal(t, "Metric B - value one", queryResult.Series[1].Name)
+			require.Len(t, frameSeries(t, queryResult), 2)
+			require.Equal(t, "Metric A - value one", frameSeries(t, queryResult)[0].Name)
+			require.Equal(t, "Metric B - value one", frameSeries(t, queryResult)[1].Name)
 		})
 
 		t.Run("When doing a metric query with metric column and multiple value columns", func(t *testing.T) {
@@ -795,11 +810,11 @@ func TestPostgres(t *testing.T) {
 			queryResult := resp.Results["A"]
 			require.NoError(t, queryResult.Error)
 
-			require.Len(t, queryResult.Series, 4)
-			require.Equal(t, "Metric A valueOne", queryResult.Series[0].Name)
-			require.Equal(t, "Metric A valueTwo", queryResult.Series[1].Name)
-			require.Equal(t, "Metric B valueOne", queryResult.Series[2].Name)
-			require.Equal(t, "Metric B valueTwo", queryResult.Series[3].Name)
+			require.Len(t, frameSeries(t, queryResult), 4)
+			require.Equal(t, "Metric A valueOne", frameSeries(t, queryResult)[0].Name)
+			require.Equal(t, "Metric A valueTwo", frameSeries(t, queryResult)[1].Name)
+			require.Equal(t, "Metric B valueOne", frameSeries(t, queryResult)[2].Name)
+			require.Equal(t, "Metric B valueTwo", frameSeries(t, queryResult)[3].Name)
 		})
 
 		t.Run("When doing a metric query grouping by time should return correct series", func(t *testing.T) {
@@ -820,9 +835,9 @@ func TestPostgres(t *testing.T) {
 			queryResult := resp.Results["A"]
 			require.NoError(t, queryResult.Error)
 
-			require.Len(t, queryResult.Series, 2)
-			require.Equal(t, "valueOne", queryResult.Series[0].Name)
-			require.Equal(t, "valueTwo", queryResult.Series[1].Name)
+			require.Len(t, frameSeries(t, queryResult), 2)
+			require.Equal(t, "valueOne", frameSeries(t, queryResult)[0].Name)
+			require.Equal(t, "valueTwo", frameSeries(t, queryResult)[1].Name)
 		})
 
 		t.Run("When doing a query with timeFrom,timeTo,unixEpochFrom,unixEpochTo macros", func(t *testing.T) {
@@ -910,7 +925,7 @@ func TestPostgres(t *testing.T) {
 			resp, err := endpoint.Query(context.Background(), nil, query)
 			queryResult := resp.Results["Deploys"]
 			require.NoError(t, err)
-			require.Len(t, queryResult.Tables[0].Rows, 3)
+			require.Len(t, frameRows(t, queryResult), 3)
 		})
 
 		t.Run("When doing an annotation query of ticket events should return expected result", func(t *testing.T) {
@@ -933,7 +948,7 @@ func TestPostgres(t *testing.T) {
 			resp, err := endpoint.Query(context.Background(), nil, query)
 			queryResult := resp.Results["Tickets"]
 			require.NoError(t, err)
-			require.Len(t, queryResult.Tables[0].Rows, 3)
+			require.Len(t, frameRows(t, queryResult), 3)
 		})
 
 		t.Run("When doing an annotation query with a time column in datetime format", func(t *testing.T) {
@@ -960,11 +975,11 @@ func TestPostgres(t *testing.T) {
 			require.NoError(t, err)
 			queryResult := resp.Results["A"]
 			require.NoError(t, queryResult.Error)
-			require.Len(t, queryResult.Tables[0].Rows, 1)
-			columns := queryResult.Tables[0].Rows[0]
+			require.Len(t, frameRows(t, queryResult), 1)
+			columns := frameRows(t, queryResult)[0]
 
 			//Should be in milliseconds
-			require.Equal(t, float64(dt.UnixNano()/1e6), columns[0].(float64))
+			require.Equal(t, dt.UnixNano()/1e6, columns[0].(time.Time).UnixNano()/1e6)
 		})
 
 		t.Run("When doing an annotation query with a time column in epoch second format should return ms", func(t *testing.T) {
@@ -990,11 +1005,11 @@ func TestPostgres(t *testing.T) {
 			require.NoError(t, err)
 			queryResult := resp.Results["A"]
 			require.NoError(t, queryResult.Error)
-			require.Len(t, queryResult.Tables[0].Rows, 1)
-			columns := queryResult.Tables[0].Rows[0]
+			require.Len(t, frameRows(t, queryResult), 1)
+			columns := frameRows(t, queryResult)[0]
 
 			//Should be in milliseconds
-			require.Equal(t, dt.Unix()*1000, columns[0].(int64))
+			require.Equal(t, dt.Unix()*1000, columns[0].(time.Time).UnixNano()/1e6)
 		})
 
 		t.Run("When doing an annotation query with a time column in epoch second format (t *testing.Tint) should return ms", func(t *testing.T) {
@@ -1020,11 +1035,11 @@ func TestPostgres(t *testing.T) {
 			require.NoError(t, err)
 			queryResult := resp.Results["A"]
 			require.NoError(t, queryResult.Error)
-			require.Len(t, queryResult.Tables[0].Rows, 1)
-			columns := queryResult.Tables[0].Rows[0]
+			require.Len(t, frameRows(t, queryResult), 1)
+			columns := frameRows(t, queryResult)[0]
 
 			//Should be in milliseconds
-			require.Equal(t, dt.Unix()*1000, columns[0].(int64))
+			require.Equal(t, dt.Unix()*1000, columns[0].(time.Time).UnixNano()/1e6)
 		})
 
 		t.Run("When doing an annotation query with a time column in epoch millisecond format should return ms", func(t *testing.T) {
@@ -1050,11 +1065,11 @@ func TestPostgres(t *testing.T) {
 			require.NoError(t, err)
 			queryResult := resp.Results["A"]
 			require.NoError(t, queryResult.Error)
-			require.Len(t, queryResult.Tables[0].Rows, 1)
-			columns := queryResult.Tables[0].Rows[0]
+			require.Len(t, frameRows(t, queryResult), 1)
+			columns := frameRows(t, queryResult)[0]
 
 			//Should be in milliseconds
-			require.Equal(t, dt.Unix()*1000, columns[0].(int64))
+			require.Equal(t, dt.Unix()*1000, columns[0].(time.Time).UnixNano()/1e6)
 		})
 
 		t.Run("When doing an annotation query with a time column holding a bigint null value should return nil", func(t *testing.T) {
@@ -1078,8 +1093,8 @@ func TestPostgres(t *testing.T) {
 			require.NoError(t, err)
 			queryResult := resp.Results["A"]
 			require.NoError(t, queryResult.Error)
-			require.Len(t, queryResult.Tables[0].Rows, 1)
-			columns := queryResult.Tables[0].Rows[0]
+			require.Len(t, frameRows(t, queryResult), 1)
+			columns := frameRows(t, queryResult)[0]
 
 			//Should be in milliseconds
 			require.Nil(t, columns[0])
@@ -1106,8 +1121,8 @@ func TestPostgres(t *testing.T) {
 			require.NoError(t, err)
 			queryResult := resp.Results["A"]
 			require.NoError(t, queryResult.Error)
-			require.Len(t, queryResult.Tables[0].Rows, 1)
-			columns := queryResult.Tables[0].Rows[0]
+			require.Len(t, frameRows(t, queryResult), 1)
+			columns := frameRows(t, queryResult)[0]
 
 			//Should be in milliseconds
 			assert.Nil(t, columns[0])
@@ -1149,3 +1164,50 @@ type tlsTestManager struct {
 func (m *tlsTestManager) getTLSSettings(datasource *models.DataSource) (tlsSettings, error) {
 	return m.settings, nil
 }
+
+// frameSeries returns the series of the time series frame of a query result,
+// named after the display names of their fields.
+func frameSeries(t *testing.T, queryResult *tsdb.QueryResult) tsdb.TimeSeriesSlice {
+	t.Helper()
+
+	frames, err := queryResult.Dataframes.Decoded()
+	require.NoError(t, err)
+	require.Len(t, frames, 1)
+
+	frame := frames[0]
+	seriesList := tsdb.TimeSeriesSlice{}
+	for _, field := range frame.Fields[1:] {
+		series := &tsdb.TimeSeries{Name: field.Config.DisplayNameFromDS, Tags: field.Labels}
+		for i := 0; i < field.Len(); i++ {
+			ts := frame.Fields[0].At(i).(time.Time)
+			series.Points = append(series.Points, tsdb.TimePoint{
+				null.FloatFromPtr(field.At(i).(*float64)),
+				null.FloatFrom(float64(ts.UnixNano() / int64(time.Millisecond))),
+			})
+		}
+		seriesList = append(seriesList, series)
+	}
+	return seriesList
+}
+
+// frameRows returns the rows of the table frame of a query result, with nil
+// for null values.
+func frameRows(t *testing.T, queryResult *tsdb.QueryResult) [][]interface{} {
+	t.Helper()
+
+	frames, err := queryResult.Dataframes.Decoded()
+	require.NoError(t, err)
+	require.Len(t, frames, 1)
+
+	frame := frames[0]
+	rows := make([][]interface{}, frame.Rows())
+	for i := range rows {
+		rows[i] = make([]interface{}, len(frame.Fields))
+		for j, field := range frame.Fields {
+			if v, ok := field.ConcreteAt(i); ok {
+				rows[i][j] = v
+			}
+		}
+	}
+	return rows
+}
@@ -0,0 +1,110 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/client_golang/api"
+	apiv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+const exemplarsEndpoint = "/api/v1/query_exemplars"
+
+// exemplarSeries is a series of the exemplars API response.
+type exemplarSeries struct {
+	SeriesLabels model.LabelSet `json:"seriesLabels"`
+	Exemplars    []exemplar     `json:"exemplars"`
+}
+
+type exemplar struct {
+	Labels    model.LabelSet    `json:"labels"`
+	Value     model.SampleValue `json:"value"`
+	Timestamp model.Time        `json:"timestamp"`
+}
+
+type exemplarsResponse struct {
+	Status    string           `json:"status"`
+	Data      []exemplarSeries `json:"data"`
+	ErrorType apiv1.ErrorType  `json:"errorType"`
+	Error     string           `json:"error"`
+}
+
+// queryExemplars queries the exemplars of the series of the query over the
+// time range. The client API of this Prometheus version does not support
+// exemplars, so the endpoint is queried directly.
+func queryExemplars(ctx context.Context, client api.Client, query *PrometheusQuery) (*data.Frame, error) {
+	u := client.URL(exemplarsEndpoint, nil)
+	q := u.Query()
+	q.Set("query", query.Expr)
+	q.Set("start", formatTime(query.Start))
+	q.Set("end", formatTime(query.End))
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, body, err := client.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result exemplarsResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, &apiv1.Error{
+			Type:   apiv1.ErrBadResponse,
+			Msg:    fmt.Sprintf("unexpected exemplars response with status %d", resp.StatusCode),
+			Detail: err.Error(),
+		}
+	}
+	if result.Status != "success" {
+		return nil, &apiv1.Error{Type: result.ErrorType, Msg: result.Error}
+	}
+
+	return exemplarsToFrame(result.Data, query), nil
+}
+
+// exemplarsToFrame returns a frame with the time and value of the exemplars,
+// and a column for each label of the exemplars and their series. It returns
+// nil when there are no exemplars.
+func exemplarsToFrame(series []exemplarSeries, query *PrometheusQuery) *data.Frame {
+	rows := []tableRow{}
+	for _, s := range series {
+		for _, e := range s.Exemplars {
+			metric := make(model.Metric, len(s.SeriesLabels)+len(e.Labels))
+			for k, v := range s.SeriesLabels {
+				metric[k] = v
+			}
+			for k, v := range e.Labels {
+				metric[k] = v
+			}
+			rows = append(rows, tableRow{metric: metric, timestamp: e.Timestamp, value: e.Value})
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].timestamp.Before(rows[j].timestamp) })
+
+	frame := newTableFrame(rows)
+	frame.Name = "exemplar"
+	frame.RefID = query.RefId
+	frame.Meta = &data.FrameMeta{
+		ExecutedQueryString: fmt.Sprintf("Expr: %s", query.Expr),
+		Custom:              map[string]interface{}{"resultType": "exemplar"},
+	}
+	return frame
+}
+
+func formatTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.Unix())+float64(t.Nanosecond())/1e9, 'f', -1, 64)
+}
@@ -4,18 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"regexp"
 	"strings"
 	"time"
 
-	"github.com/opentracing/opentracing-go"
-
-	"net/http"
-
-	"github.com/openinsight-project/grafinsight/pkg/components/null"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/openinsight-project/grafinsight/pkg/infra/log"
 	"github.com/openinsight-project/grafinsight/pkg/models"
 	"github.com/openinsight-project/grafinsight/pkg/tsdb"
+	"github.com/opentracing/opentracing-go"
 	"github.com/prometheus/client_golang/api"
 	apiv1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	"github.com/prometheus/common/model"
@@ -61,7 +59,7 @@ func init() {
 	intervalCalculator = tsdb.NewIntervalCalculator(&tsdb.IntervalOptions{MinInterval: time.Second * 1})
 }
 
-func (e *PrometheusExecutor) getClient(dsInfo *models.DataSource) (apiv1.API, error) {
+func (e *PrometheusExecutor) getClient(dsInfo *models.DataSource) (api.Client, error) {
 	cfg := api.Config{
 		Address:      dsInfo.Url,
 		RoundTripper: e.Transport,
@@ -75,12 +73,7 @@ func (e *PrometheusExecutor) getClient(dsInfo *models.DataSource) (apiv1.API, er
 		}
 	}
 
-	client, err := api.NewClient(cfg)
-	if err != nil {
-		return nil, err
-	}
-
-	return apiv1.NewAPI(client), nil
+	return api.NewClient(cfg)
 }
 
 func (e *PrometheusExecutor) Query(ctx context.Context, dsInfo *models.DataSource, tsdbQuery *tsdb.TsdbQuery) (*tsdb.Response, error) {
@@ -99,6 +92,33 @@ func (e *PrometheusExecutor) Query(ctx context.Context, dsInfo *models.DataSourc
 	}
 
 	for _, query := range queries {
+		frames, err := runQuery(ctx, client, query)
+		if err != nil {
+			return nil, err
+		}
+
+		queryResult := tsdb.NewQueryResult()
+		queryResult.RefId = query.RefId
+		queryResult.Dataframes = tsdb.NewDecodedDataFrames(frames)
+		result.Results[query.RefId] = queryResult
+	}
+
+	return result, nil
+}
+
+// runQuery sends the range, instant and exemplar queries of a query, and
+// returns all their results as data frames.
+func runQuery(ctx context.Context, client api.Client, query *PrometheusQuery) (data.Frames, error) {
+	span, ctx := opentracing.StartSpanFromContext(ctx, "alerting.prometheus")
+	span.SetTag("expr", query.Expr)
+	span.SetTag("start_unixnano", query.Start.UnixNano())
+	span.SetTag("stop_unixnano", query.End.UnixNano())
+	defer span.Finish()
+
+	promAPI := apiv1.NewAPI(client)
+	frames := data.Frames{}
+
+	if query.RangeQuery {
 		timeRange := apiv1.Range{
 			Start: query.Start,
 			End:   query.End,
@@ -107,26 +127,47 @@ func (e *PrometheusExecutor) Query(ctx context.Context, dsInfo *models.DataSourc
 
 		plog.Debug("Sending query", "start", timeRange.Start, "end", timeRange.End, "step", timeRange.Step, "query", query.Expr)
 
-		span, ctx := opentracing.StartSpanFromContext(ctx, "alerting.prometheus")
-		span.SetTag("expr", query.Expr)
-		span.SetTag("start_unixnano", query.Start.UnixNano())
-		span.SetTag("stop_unixnano", query.End.UnixNano())
-		defer span.Finish()
+		value, _, err := promAPI.QueryRange(ctx, query.Expr, timeRange)
+		if err != nil {
+			return nil, err
+		}
 
-		value, _, err := client.QueryRange(ctx, query.Expr, timeRange)
+		rangeFrames, err := parseResponse(value, query)
+		if err != nil {
+			return nil, err
+		}
+		frames = append(frames, rangeFrames...)
+	}
+
+	if query.InstantQuery {
+		plog.Debug("Sending instant query", "time", query.End, "query", query.Expr)
 
+		value, _, err := promAPI.Query(ctx, query.Expr, query.End)
 		if err != nil {
 			return nil, err
 		}
 
-		queryResult, err := parseResponse(value, query)
+		instantFrames, err := parseResponse(value, query)
 		if err != nil {
 			return nil, err
 		}
-		result.Results[query.RefId] = queryResult
+		frames = append(frames, instantFrames...)
 	}
 
-	return result, nil
+	if query.ExemplarQuery {
+		plog.Debug("Sending exemplar query", "start", query.Start, "end", query.End, "query", query.Expr)
+
+		exemplarFrame, err := queryExemplars(ctx, client, query)
+		if err != nil {
+			// Exemplars are only supported by recent Prometheus versions, so
+			// they do not fail the query.
+			plog.Warn("Failed to query exemplars", "query", query.Expr, "error", err)
+		} else if exemplarFrame != nil {
+			frames = append(frames, exemplarFrame)
+		}
+	}
+
+	return frames, nil
 }
 
 func formatLegend(metric model.Metric, query *PrometheusQuery) string {
@@ -155,7 +196,7 @@ func parseQuery(dsInfo *models.DataSource, queries []*tsdb.Query, queryContext *
 			return nil, err
 		}
 
-		format := queryModel.Model.Get("legendFormat").MustString("")
+		legend := queryModel.Model.Get("legendFormat").MustString("")
 
 		start, err := queryContext.TimeRange.ParseFrom()
 		if err != nil {
@@ -172,52 +213,33 @@ func parseQuery(dsInfo *models.DataSource, queries []*tsdb.Query, queryContext *
 			return nil, err
 		}
 
+		format := queryModel.Model.Get("format").MustString(formatTimeSeries)
+		instant := queryModel.Model.Get("instant").MustBool(false)
+		// Queries are range queries unless they are only instant queries
+		rangeQuery := queryModel.Model.Get("range").MustBool(!instant)
+		exemplar := queryModel.Model.Get("exemplar").MustBool(false)
+
 		intervalFactor := queryModel.Model.Get("intervalFactor").MustInt64(1)
 		interval := intervalCalculator.Calculate(queryContext.TimeRange, dsInterval)
 		step := time.Duration(int64(interval.Value) * intervalFactor)
 
 		qs = append(qs, &PrometheusQuery{
-			Expr:         expr,
-			Step:         step,
-			LegendFormat: format,
-			Start:        start,
-			End:          end,
-			RefId:        queryModel.RefId,
+			Expr:          expr,
+			Step:          step,
+			LegendFormat:  legend,
+			Start:         start,
+			End:           end,
+			RefId:         queryModel.RefId,
+			Format:        format,
+			RangeQuery:    rangeQuery,
+			InstantQuery:  instant,
+			ExemplarQuery: exemplar,
 		})
 	}
 
 	return qs, nil
 }
 
-func parseResponse(value model.Value, query *PrometheusQuery) (*tsdb.QueryResult, error) {
-	queryRes := tsdb.NewQueryResult()
-
-	data, ok := value.(model.Matrix)
-	if !ok {
-		return queryRes, fmt.Errorf("unsupported result format: %q", value.Type().String())
-	}
-
-	for _, v := range data {
-		series := tsdb.TimeSeries{
-			Name:   formatLegend(v.Metric, query),
-			Tags:   make(map[string]string, len(v.Metric)),
-			Points: make([]tsdb.TimePoint, 0, len(v.Values)),
-		}
-
-		for k, v := range v.Metric {
-			series.Tags[string(k)] = string(v)
-		}
-
-		for _, k := range v.Values {
-			series.Points = append(series.Points, tsdb.NewTimePoint(null.FloatFrom(float64(k.Value)), float64(k.Timestamp.Unix()*1000)))
-		}
-
-		queryRes.Series = append(queryRes.Series, &series)
-	}
-
-	return queryRes, nil
-}
-
 // IsAPIError returns whether err is or wraps a Prometheus error.
 func IsAPIError(err error) bool {
 	// Check if the right error type is in err's chain.
@@ -1,9 +1,13 @@
 package prometheus
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/openinsight-project/grafinsight/pkg/components/simplejson"
 	"github.com/openinsight-project/grafinsight/pkg/models"
 	"github.com/openinsight-project/grafinsight/pkg/tsdb"
@@ -126,3 +130,188 @@ func TestPrometheus(t *testing.T) {
 		require.Equal(t, time.Minute*2, models[0].Step)
 	})
 }
+
+func TestParseQueryTypes(t *testing.T) {
+	dsInfo := &models.DataSource{
+		JsonData: simplejson.New(),
+	}
+
+	parse := func(t *testing.T, model string) *PrometheusQuery {
+		jsonModel, err := simplejson.NewJson([]byte(model))
+		require.NoError(t, err)
+		queryContext := &tsdb.TsdbQuery{TimeRange: tsdb.NewTimeRange("1h", "now")}
+		queries, err := parseQuery(dsInfo, []*tsdb.Query{{RefId: "A", Model: jsonModel}}, queryContext)
+		require.NoError(t, err)
+		return queries[0]
+	}
+
+	t.Run("range query by default", func(t *testing.T) {
+		query := parse(t, `{"expr": "up"}`)
+		require.True(t, query.RangeQuery)
+		require.False(t, query.InstantQuery)
+		require.False(t, query.ExemplarQuery)
+		require.Equal(t, formatTimeSeries, query.Format)
+	})
+
+	t.Run("instant query", func(t *testing.T) {
+		query := parse(t, `{"expr": "up", "instant": true, "format": "table"}`)
+		require.False(t, query.RangeQuery)
+		require.True(t, query.InstantQuery)
+		require.Equal(t, formatTable, query.Format)
+	})
+
+	t.Run("range and instant query with exemplars", func(t *testing.T) {
+		query := parse(t, `{"expr": "up", "instant": true, "range": true, "exemplar": true}`)
+		require.True(t, query.RangeQuery)
+		require.True(t, query.InstantQuery)
+		require.True(t, query.ExemplarQuery)
+	})
+}
+
+func TestParseResponse(t *testing.T) {
+	query := &PrometheusQuery{
+		Expr:         "up",
+		Step:         time.Minute,
+		LegendFormat: "{{job}}",
+		RefId:        "A",
+		Format:       formatTimeSeries,
+	}
+	metric := p.Metric{p.MetricNameLabel: "up", "job": "api"}
+
+	t.Run("matrix to a frame per series", func(t *testing.T) {
+		value := p.Matrix{
+			&p.SampleStream{Metric: metric, Values: []p.SamplePair{{Timestamp: 1000, Value: 1}, {Timestamp: 61500, Value: 0}}},
+		}
+
+		frames, err := parseResponse(value, query)
+		require.NoError(t, err)
+		require.Len(t, frames, 1)
+
+		frame := frames[0]
+		require.Equal(t, "api", frame.Name)
+		require.Equal(t, "A", frame.RefID)
+		require.Equal(t, "Expr: up\nStep: 1m0s", frame.Meta.ExecutedQueryString)
+		require.Equal(t, time.Unix(61, 500*int64(time.Millisecond)).UTC(), frame.Fields[0].At(1))
+		require.Equal(t, 0.0, frame.Fields[1].At(1))
+		require.Equal(t, data.Labels{"__name__": "up", "job": "api"}, frame.Fields[1].Labels)
+		require.Equal(t, "api", frame.Fields[1].Config.DisplayNameFromDS)
+	})
+
+	t.Run("vector to a frame per sample", func(t *testing.T) {
+		value := p.Vector{
+			&p.Sample{Metric: metric, Timestamp: 1000, Value: 1},
+			&p.Sample{Metric: p.Metric{p.MetricNameLabel: "up", "job": "db"}, Timestamp: 1000, Value: 0},
+		}
+
+		frames, err := parseResponse(value, query)
+		require.NoError(t, err)
+		require.Len(t, frames, 2)
+		require.Equal(t, "db", frames[1].Name)
+		require.Equal(t, 1, frames[1].Rows())
+		require.Equal(t, 0.0, frames[1].Fields[1].At(0))
+	})
+
+	t.Run("vector to a table", func(t *testing.T) {
+		tableQuery := *query
+		tableQuery.Format = formatTable
+		value := p.Vector{
+			&p.Sample{Metric: metric, Timestamp: 1000, Value: 1},
+			&p.Sample{Metric: p.Metric{p.MetricNameLabel: "up", "instance": "db:9090"}, Timestamp: 1000, Value: 0},
+		}
+
+		frames, err := parseResponse(value, &tableQuery)
+		require.NoError(t, err)
+		require.Len(t, frames, 1)
+
+		frame := frames[0]
+		names := []string{}
+		for _, field := range frame.Fields {
+			names = append(names, field.Name)
+		}
+		require.Equal(t, []string{"Time", "__name__", "instance", "job", "Value"}, names)
+		require.Equal(t, 2, frame.Rows())
+		require.Equal(t, "", frame.Fields[2].At(0))
+		require.Equal(t, "db:9090", frame.Fields[2].At(1))
+		require.Equal(t, "api", frame.Fields[3].At(0))
+	})
+
+	t.Run("scalar", func(t *testing.T) {
+		frames, err := parseResponse(&p.Scalar{Timestamp: 1000, Value: 2}, query)
+		require.NoError(t, err)
+		require.Len(t, frames, 1)
+		require.Equal(t, 2.0, frames[0].Fields[1].At(0))
+	})
+}
+
+func TestPrometheusExecutor(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		require.NoError(t, r.ParseForm())
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.URL.Path {
+		case "/api/v1/query":
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"__name__":"up","job":"api"},"value":[1600000000,"1"]}
+			]}}`))
+		case "/api/v1/query_exemplars":
+			require.Equal(t, "up", r.Form.Get("query"))
+			_, _ = w.Write([]byte(`{"status":"success","data":[
+				{"seriesLabels":{"__name__":"up","job":"api"},"exemplars":[
+					{"labels":{"traceID":"abc"},"value":"6","timestamp":1600000000.5}
+				]}
+			]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	dsInfo := &models.DataSource{Url: server.URL, JsonData: simplejson.New()}
+	executor := &PrometheusExecutor{Transport: http.DefaultTransport}
+
+	jsonModel, err := simplejson.NewJson([]byte(`{"expr": "up", "instant": true, "exemplar": true}`))
+	require.NoError(t, err)
+	resp, err := executor.Query(context.Background(), dsInfo, &tsdb.TsdbQuery{
+		TimeRange: tsdb.NewTimeRange("1h", "now"),
+		Queries:   []*tsdb.Query{{RefId: "A", Model: jsonModel}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"/api/v1/query", "/api/v1/query_exemplars"}, paths)
+
+	frames, err := resp.Results["A"].Dataframes.Decoded()
+	require.NoError(t, err)
+	require.Len(t, frames, 2)
+	require.Equal(t, data.Labels{"__name__": "up", "job": "api"}, frames[0].Fields[1].Labels)
+
+	exemplars := frames[1]
+	require.Equal(t, "exemplar", exemplars.Name)
+	require.Equal(t, "abc", exemplars.Fields[3].At(0))
+	require.Equal(t, 6.0, exemplars.Fields[4].At(0))
+	require.Equal(t, time.Unix(1600000000, 500*int64(time.Millisecond)).UTC(), exemplars.Fields[0].At(0))
+
+	t.Run("exemplars are skipped when not supported", func(t *testing.T) {
+		paths = nil
+		jsonModel, err := simplejson.NewJson([]byte(`{"expr": "down", "instant": true, "exemplar": true}`))
+		require.NoError(t, err)
+		server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			paths = append(paths, r.URL.Path)
+			if r.URL.Path == "/api/v1/query" {
+				_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+		})
+
+		resp, err := executor.Query(context.Background(), dsInfo, &tsdb.TsdbQuery{
+			TimeRange: tsdb.NewTimeRange("1h", "now"),
+			Queries:   []*tsdb.Query{{RefId: "A", Model: jsonModel}},
+		})
+		require.NoError(t, err)
+		require.Len(t, paths, 2)
+		frames, err := resp.Results["A"].Dataframes.Decoded()
+		require.NoError(t, err)
+		require.Empty(t, frames)
+	})
+}
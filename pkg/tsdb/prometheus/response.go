@@ -0,0 +1,147 @@
+package prometheus
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/common/model"
+)
+
+// parseResponse converts the result of a range or instant query to data
+// frames. Time series have a frame per series, with the labels of the series
+// as labels of the value field. Tables have a column per label.
+func parseResponse(value model.Value, query *PrometheusQuery) (data.Frames, error) {
+	var frames data.Frames
+
+	switch v := value.(type) {
+	case model.Matrix:
+		if query.Format == formatTable {
+			frames = data.Frames{matrixToTable(v)}
+			break
+		}
+		frames = make(data.Frames, 0, len(v))
+		for _, series := range v {
+			times := make([]time.Time, 0, len(series.Values))
+			values := make([]float64, 0, len(series.Values))
+			for _, pair := range series.Values {
+				times = append(times, pair.Timestamp.Time().UTC())
+				values = append(values, float64(pair.Value))
+			}
+			frames = append(frames, newSeriesFrame(series.Metric, query, times, values))
+		}
+	case model.Vector:
+		if query.Format == formatTable {
+			frames = data.Frames{vectorToTable(v)}
+			break
+		}
+		frames = make(data.Frames, 0, len(v))
+		for _, sample := range v {
+			frames = append(frames, newSeriesFrame(sample.Metric, query,
+				[]time.Time{sample.Timestamp.Time().UTC()}, []float64{float64(sample.Value)}))
+		}
+	case *model.Scalar:
+		frames = data.Frames{data.NewFrame("",
+			data.NewField(data.TimeSeriesTimeFieldName, nil, []time.Time{v.Timestamp.Time().UTC()}),
+			data.NewField(data.TimeSeriesValueFieldName, nil, []float64{float64(v.Value)}))}
+	case *model.String:
+		frames = data.Frames{data.NewFrame("",
+			data.NewField(data.TimeSeriesTimeFieldName, nil, []time.Time{v.Timestamp.Time().UTC()}),
+			data.NewField(data.TimeSeriesValueFieldName, nil, []string{v.Value}))}
+	default:
+		return nil, fmt.Errorf("unsupported result format: %q", value.Type().String())
+	}
+
+	for _, frame := range frames {
+		frame.RefID = query.RefId
+		frame.Meta = &data.FrameMeta{
+			ExecutedQueryString: executedQueryString(query, value.Type()),
+		}
+	}
+
+	return frames, nil
+}
+
+func newSeriesFrame(metric model.Metric, query *PrometheusQuery, times []time.Time, values []float64) *data.Frame {
+	labels := make(data.Labels, len(metric))
+	for k, v := range metric {
+		labels[string(k)] = string(v)
+	}
+
+	name := formatLegend(metric, query)
+	valueField := data.NewField(data.TimeSeriesValueFieldName, labels, values)
+	valueField.Config = &data.FieldConfig{DisplayNameFromDS: name}
+
+	return data.NewFrame(name,
+		data.NewField(data.TimeSeriesTimeFieldName, nil, times),
+		valueField)
+}
+
+type tableRow struct {
+	metric    model.Metric
+	timestamp model.Time
+	value     model.SampleValue
+}
+
+func matrixToTable(matrix model.Matrix) *data.Frame {
+	rows := []tableRow{}
+	for _, series := range matrix {
+		for _, pair := range series.Values {
+			rows = append(rows, tableRow{metric: series.Metric, timestamp: pair.Timestamp, value: pair.Value})
+		}
+	}
+	return newTableFrame(rows)
+}
+
+func vectorToTable(vector model.Vector) *data.Frame {
+	rows := make([]tableRow, 0, len(vector))
+	for _, sample := range vector {
+		rows = append(rows, tableRow{metric: sample.Metric, timestamp: sample.Timestamp, value: sample.Value})
+	}
+	return newTableFrame(rows)
+}
+
+// newTableFrame returns a frame with the time, a column for each label of
+// any row, and the value.
+func newTableFrame(rows []tableRow) *data.Frame {
+	labelNames := []string{}
+	seen := map[model.LabelName]bool{}
+	for _, row := range rows {
+		for name := range row.metric {
+			if !seen[name] {
+				seen[name] = true
+				labelNames = append(labelNames, string(name))
+			}
+		}
+	}
+	sort.Strings(labelNames)
+
+	timeField := data.NewField(data.TimeSeriesTimeFieldName, nil, make([]time.Time, len(rows)))
+	valueField := data.NewField(data.TimeSeriesValueFieldName, nil, make([]float64, len(rows)))
+	labelFields := make([]*data.Field, len(labelNames))
+	for i, name := range labelNames {
+		labelFields[i] = data.NewField(name, nil, make([]string, len(rows)))
+	}
+
+	for i, row := range rows {
+		timeField.Set(i, row.timestamp.Time().UTC())
+		valueField.Set(i, float64(row.value))
+		for j, name := range labelNames {
+			labelFields[j].Set(i, string(row.metric[model.LabelName(name)]))
+		}
+	}
+
+	fields := make([]*data.Field, 0, len(labelFields)+2)
+	fields = append(fields, timeField)
+	fields = append(fields, labelFields...)
+	fields = append(fields, valueField)
+	return data.NewFrame("", fields...)
+}
+
+func executedQueryString(query *PrometheusQuery, resultType model.ValueType) string {
+	if resultType == model.ValMatrix {
+		return fmt.Sprintf("Expr: %s\nStep: %s", query.Expr, query.Step)
+	}
+	return fmt.Sprintf("Expr: %s\nTime: %s", query.Expr, query.End.UTC().Format(time.RFC3339))
+}
@@ -9,4 +9,17 @@ type PrometheusQuery struct {
 	Start        time.Time
 	End          time.Time
 	RefId        string
+	// Format is the format of the results, time_series or table.
+	Format string
+	// RangeQuery queries the series over the time range.
+	RangeQuery bool
+	// InstantQuery queries the values at the end of the time range.
+	InstantQuery bool
+	// ExemplarQuery queries the exemplars of the series over the time range.
+	ExemplarQuery bool
 }
+
+const (
+	formatTimeSeries = "time_series"
+	formatTable      = "table"
+)
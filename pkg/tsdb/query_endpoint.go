@@ -10,10 +10,32 @@ type TsdbQueryEndpoint interface {
 	Query(ctx context.Context, ds *models.DataSource, query *TsdbQuery) (*Response, error)
 }
 
+// FixedIntervalQueryEndpoint is implemented by the query endpoints whose
+// points are spaced by the IntervalMs of the queries, whatever their time
+// range. Their results can be split and merged by time.
+type FixedIntervalQueryEndpoint interface {
+	TsdbQueryEndpoint
+	HasFixedInterval() bool
+}
+
 var registry map[string]GetTsdbQueryEndpointFn
 
 type GetTsdbQueryEndpointFn func(dsInfo *models.DataSource) (TsdbQueryEndpoint, error)
 
+// QueryEndpointMiddleware wraps the query endpoints of all the data source
+// types, for example to cache their results.
+type QueryEndpointMiddleware func(next TsdbQueryEndpoint) TsdbQueryEndpoint
+
+// TsdbQueryEndpointFunc is an adapter to use a function as a TsdbQueryEndpoint.
+type TsdbQueryEndpointFunc func(ctx context.Context, ds *models.DataSource, query *TsdbQuery) (*Response, error)
+
+// Query calls f(ctx, ds, query).
+func (f TsdbQueryEndpointFunc) Query(ctx context.Context, ds *models.DataSource, query *TsdbQuery) (*Response, error) {
+	return f(ctx, ds, query)
+}
+
+var middlewares []QueryEndpointMiddleware
+
 func init() {
 	registry = make(map[string]GetTsdbQueryEndpointFn)
 }
@@ -21,3 +43,9 @@ func init() {
 func RegisterTsdbQueryEndpoint(pluginId string, fn GetTsdbQueryEndpointFn) {
 	registry[pluginId] = fn
 }
+
+// RegisterQueryEndpointMiddleware wraps every query endpoint with the
+// middleware. It must be called before any query is handled.
+func RegisterQueryEndpointMiddleware(middleware QueryEndpointMiddleware) {
+	middlewares = append(middlewares, middleware)
+}
@@ -0,0 +1,440 @@
+package querycache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/openinsight-project/grafinsight/pkg/components/gtime"
+	"github.com/openinsight-project/grafinsight/pkg/components/simplejson"
+	"github.com/openinsight-project/grafinsight/pkg/infra/remotecache"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/tsdb"
+)
+
+const (
+	keyPrefix = "tsdb-query-cache:"
+
+	// TTLSetting is the data source setting overriding the TTL, 0 disables
+	// caching for the data source.
+	TTLSetting = "queryCachingTTL"
+)
+
+// volatileModelKeys are query model properties that do not change the
+// results of a query.
+var volatileModelKeys = []string{"requestId", "datasource", "hide", "key"}
+
+// cacheEntry is the cached response of a query. Time series entries of the
+// endpoints with a fixed interval hold the points between From and To, and
+// are looked up without the time range.
+type cacheEntry struct {
+	From       int64
+	To         int64
+	TimeSeries bool
+	Stored     time.Time
+	Results    []byte
+}
+
+type cachedResult struct {
+	RefID      string               `json:"refId"`
+	Meta       *simplejson.Json     `json:"meta,omitempty"`
+	Series     tsdb.TimeSeriesSlice `json:"series,omitempty"`
+	Tables     []*tsdb.Table        `json:"tables,omitempty"`
+	Dataframes [][]byte             `json:"dataframes,omitempty"`
+}
+
+type cachedResponse struct {
+	Results []cachedResult `json:"results"`
+	Message string         `json:"message,omitempty"`
+}
+
+// Middleware caches the results of the query endpoints.
+type Middleware struct {
+	Cache remotecache.CacheStorage
+	// TTL is used unless the data source sets its own TTL.
+	TTL time.Duration
+	// MinInterval is the smallest step the time ranges are aligned to.
+	MinInterval time.Duration
+	// PerUser does not share the results between users, as the data sources
+	// are told who the user is.
+	PerUser bool
+
+	now func() time.Time
+}
+
+// Wrap returns an endpoint caching the results of next.
+func (m *Middleware) Wrap(next tsdb.TsdbQueryEndpoint) tsdb.TsdbQueryEndpoint {
+	return tsdb.TsdbQueryEndpointFunc(func(ctx context.Context, ds *models.DataSource, query *tsdb.TsdbQuery) (*tsdb.Response, error) {
+		return m.query(ctx, ds, query, next)
+	})
+}
+
+func (m *Middleware) query(ctx context.Context, ds *models.DataSource, query *tsdb.TsdbQuery, next tsdb.TsdbQueryEndpoint) (*tsdb.Response, error) {
+	req := requestFromContext(ctx)
+
+	ttl := m.ttlFor(ds)
+	if ttl <= 0 || req.NoStore || !cacheable(ds, query) {
+		req.setStatus(StatusBypass)
+		return next.Query(ctx, ds, query)
+	}
+
+	// The time range is queried as requested, it is only aligned to the
+	// step to look up the results
+	step := m.step(query)
+	from, to := query.TimeRange.GetFromAsMsEpoch(), query.TimeRange.GetToAsMsEpoch()
+	if floor(to, step) <= floor(from, step) {
+		req.setStatus(StatusBypass)
+		return next.Query(ctx, ds, query)
+	}
+
+	key, err := m.queryKey(ds, query, step)
+	if err != nil {
+		logger.Warn("Failed to build query cache key", "datasource", ds.Id, "error", err)
+		req.setStatus(StatusBypass)
+		return next.Query(ctx, ds, query)
+	}
+	rangeKey := fmt.Sprintf("%s:%d-%d", key, floor(from, step), floor(to, step))
+	splittable := hasFixedInterval(next, query)
+
+	if !req.NoCache {
+		if resp, status := m.lookup(ctx, ds, query, next, req, key, rangeKey, from, to, step, splittable, ttl); resp != nil {
+			req.setStatus(status)
+			return resp, nil
+		}
+	}
+
+	resp, err := next.Query(ctx, ds, query)
+	if err != nil {
+		return nil, err
+	}
+	req.setStatus(StatusMiss)
+
+	if hasErrors(resp) {
+		return resp, nil
+	}
+	if splittable && isTimeSeries(resp) {
+		m.store(key, from, to, true, resp, ttl)
+	} else {
+		m.store(rangeKey, from, to, false, resp, ttl)
+	}
+	return resp, nil
+}
+
+// lookup answers the query from the cache. When the endpoint has a fixed
+// interval, time series entries covering the beginning of the range only
+// need the rest of the range to be queried.
+func (m *Middleware) lookup(ctx context.Context, ds *models.DataSource, query *tsdb.TsdbQuery, next tsdb.TsdbQueryEndpoint,
+	req *Request, key, rangeKey string, from, to, step int64, splittable bool, ttl time.Duration) (*tsdb.Response, Status) {
+	if !splittable {
+		return m.lookupRange(ds, req, rangeKey)
+	}
+
+	if entry := m.get(key, req); entry != nil && entry.From <= from && entry.To > from {
+		cached, err := decodeResults(entry.Results)
+		if err != nil {
+			logger.Warn("Failed to decode cached query results", "datasource", ds.Id, "error", err)
+			return nil, ""
+		}
+
+		if entry.To >= to {
+			resp, err := filterResponse(cached, func(ms int64) bool { return ms >= from && ms <= to })
+			if err != nil {
+				return nil, ""
+			}
+			return resp, StatusHit
+		}
+
+		// Query the last step of the cached range again, as it may have
+		// been incomplete when it was cached.
+		tailFrom := floor(entry.To, step) - step
+		if tailFrom < from {
+			tailFrom = from
+		}
+		tail, err := next.Query(ctx, ds, withTimeRange(query, tailFrom, to))
+		if err != nil || hasErrors(tail) || !isTimeSeries(tail) {
+			return nil, ""
+		}
+
+		head, err := filterResponse(cached, func(ms int64) bool { return ms >= from && ms < tailFrom })
+		if err != nil {
+			return nil, ""
+		}
+		tail, err = filterResponse(tail, func(ms int64) bool { return ms >= tailFrom && ms <= to })
+		if err != nil {
+			return nil, ""
+		}
+		resp, err := mergeResponses(head, tail)
+		if err != nil {
+			logger.Warn("Failed to merge cached query results", "datasource", ds.Id, "error", err)
+			return nil, ""
+		}
+
+		m.store(key, from, to, true, resp, ttl)
+		return resp, StatusPartial
+	}
+
+	return m.lookupRange(ds, req, rangeKey)
+}
+
+// lookupRange answers the query from the results of the same aligned time
+// range.
+func (m *Middleware) lookupRange(ds *models.DataSource, req *Request, rangeKey string) (*tsdb.Response, Status) {
+	if entry := m.get(rangeKey, req); entry != nil {
+		resp, err := decodeResults(entry.Results)
+		if err != nil {
+			logger.Warn("Failed to decode cached query results", "datasource", ds.Id, "error", err)
+			return nil, ""
+		}
+		return resp, StatusHit
+	}
+
+	return nil, ""
+}
+
+func (m *Middleware) get(key string, req *Request) *cacheEntry {
+	value, err := m.Cache.Get(key)
+	if err != nil {
+		if !errors.Is(err, remotecache.ErrCacheItemNotFound) {
+			logger.Warn("Failed to read the query cache", "error", err)
+		}
+		return nil
+	}
+
+	entry, ok := value.(*cacheEntry)
+	if !ok {
+		return nil
+	}
+	if req.MaxAge != nil && m.clock().Sub(entry.Stored) > *req.MaxAge {
+		return nil
+	}
+	return entry
+}
+
+func (m *Middleware) store(key string, from, to int64, timeSeries bool, resp *tsdb.Response, ttl time.Duration) {
+	results, err := encodeResults(resp)
+	if err != nil {
+		logger.Warn("Failed to encode query results for the cache", "error", err)
+		return
+	}
+
+	entry := &cacheEntry{
+		From:       from,
+		To:         to,
+		TimeSeries: timeSeries,
+		Stored:     m.clock(),
+		Results:    results,
+	}
+	if err := m.Cache.Set(key, entry, ttl); err != nil {
+		logger.Warn("Failed to write the query cache", "error", err)
+	}
+}
+
+func (m *Middleware) clock() time.Time {
+	if m.now != nil {
+		return m.now()
+	}
+	return time.Now()
+}
+
+// ttlFor returns the TTL of the data source, which may override the default.
+func (m *Middleware) ttlFor(ds *models.DataSource) time.Duration {
+	if ds.JsonData == nil {
+		return m.TTL
+	}
+	value := ds.JsonData.Get(TTLSetting).MustString("")
+	if value == "" {
+		return m.TTL
+	}
+	ttl, err := gtime.ParseDuration(value)
+	if err != nil {
+		logger.Warn("Invalid data source query caching TTL", "datasource", ds.Id, "ttl", value, "error", err)
+		return m.TTL
+	}
+	return ttl
+}
+
+// step returns the interval the time range is aligned to: the smallest
+// interval of the queries, but at least the configured minimum.
+func (m *Middleware) step(query *tsdb.TsdbQuery) int64 {
+	step := int64(0)
+	for _, q := range query.Queries {
+		if q.IntervalMs > 0 && (step == 0 || q.IntervalMs < step) {
+			step = q.IntervalMs
+		}
+	}
+	if min := m.MinInterval.Milliseconds(); step < min {
+		step = min
+	}
+	if step <= 0 {
+		step = 1
+	}
+	return step
+}
+
+// cacheable reports whether the results can be cached. The results of the
+// data sources forwarding the identity of the user are not.
+func cacheable(ds *models.DataSource, query *tsdb.TsdbQuery) bool {
+	if query.TimeRange == nil || len(query.Queries) == 0 || query.Debug {
+		return false
+	}
+	if len(query.Headers) > 0 {
+		return false
+	}
+	if ds.JsonData != nil {
+		if ds.JsonData.Get("oauthPassThru").MustBool() || len(ds.JsonData.Get("keepCookies").MustStringArray()) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// hasFixedInterval reports whether the points returned by the endpoint do
+// not depend on the time range, so that the results can be split by time.
+func hasFixedInterval(endpoint tsdb.TsdbQueryEndpoint, query *tsdb.TsdbQuery) bool {
+	fixed, ok := endpoint.(tsdb.FixedIntervalQueryEndpoint)
+	if !ok || !fixed.HasFixedInterval() {
+		return false
+	}
+	// Without an interval, it is computed from the time range
+	for _, q := range query.Queries {
+		if q.IntervalMs <= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// queryKey identifies the data source version and the queries, and the user
+// when the results are not shared. It does not include the time range.
+func (m *Middleware) queryKey(ds *models.DataSource, query *tsdb.TsdbQuery, step int64) (string, error) {
+	type normalizedQuery struct {
+		RefID         string                 `json:"refId"`
+		QueryType     string                 `json:"queryType"`
+		IntervalMs    int64                  `json:"intervalMs"`
+		MaxDataPoints int64                  `json:"maxDataPoints"`
+		Model         map[string]interface{} `json:"model"`
+	}
+
+	queries := make([]normalizedQuery, 0, len(query.Queries))
+	for _, q := range query.Queries {
+		model := map[string]interface{}{}
+		if q.Model != nil {
+			m, err := q.Model.Map()
+			if err != nil {
+				return "", err
+			}
+			for k, v := range m {
+				model[k] = v
+			}
+		}
+		for _, k := range volatileModelKeys {
+			delete(model, k)
+		}
+
+		queries = append(queries, normalizedQuery{
+			RefID:         q.RefId,
+			QueryType:     q.QueryType,
+			IntervalMs:    q.IntervalMs,
+			MaxDataPoints: q.MaxDataPoints,
+			Model:         model,
+		})
+	}
+	sort.Slice(queries, func(i, j int) bool { return queries[i].RefID < queries[j].RefID })
+
+	var userID int64
+	if m.PerUser && query.User != nil {
+		userID = query.User.UserId
+	}
+
+	b, err := json.Marshal(struct {
+		OrgID        int64             `json:"orgId"`
+		DatasourceID int64             `json:"datasourceId"`
+		Version      int               `json:"version"`
+		UserID       int64             `json:"userId,omitempty"`
+		Step         int64             `json:"step"`
+		Queries      []normalizedQuery `json:"queries"`
+	}{ds.OrgId, ds.Id, ds.Version, userID, step, queries})
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(b)
+	return keyPrefix + hex.EncodeToString(sum[:]), nil
+}
+
+func withTimeRange(query *tsdb.TsdbQuery, from, to int64) *tsdb.TsdbQuery {
+	copied := *query
+	copied.TimeRange = tsdb.NewTimeRange(strconv.FormatInt(from, 10), strconv.FormatInt(to, 10))
+	return &copied
+}
+
+func floor(ms, step int64) int64 {
+	return ms - ms%step
+}
+
+func hasErrors(resp *tsdb.Response) bool {
+	for _, res := range resp.Results {
+		if res.Error != nil || res.ErrorString != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func encodeResults(resp *tsdb.Response) ([]byte, error) {
+	cached := cachedResponse{
+		Results: make([]cachedResult, 0, len(resp.Results)),
+		Message: resp.Message,
+	}
+	for _, res := range resp.Results {
+		result := cachedResult{
+			RefID:  res.RefId,
+			Meta:   res.Meta,
+			Series: res.Series,
+			Tables: res.Tables,
+		}
+		if res.Dataframes != nil {
+			encoded, err := res.Dataframes.Encoded()
+			if err != nil {
+				return nil, err
+			}
+			result.Dataframes = encoded
+		}
+		cached.Results = append(cached.Results, result)
+	}
+	return json.Marshal(cached)
+}
+
+func decodeResults(b []byte) (*tsdb.Response, error) {
+	var cached cachedResponse
+	if err := json.Unmarshal(b, &cached); err != nil {
+		return nil, err
+	}
+
+	resp := &tsdb.Response{
+		Results: make(map[string]*tsdb.QueryResult, len(cached.Results)),
+		Message: cached.Message,
+	}
+	for _, res := range cached.Results {
+		result := &tsdb.QueryResult{
+			RefId:  res.RefID,
+			Meta:   res.Meta,
+			Series: res.Series,
+			Tables: res.Tables,
+		}
+		if result.Series == nil {
+			result.Series = tsdb.TimeSeriesSlice{}
+		}
+		if res.Dataframes != nil {
+			result.Dataframes = tsdb.NewEncodedDataFrames(res.Dataframes)
+		}
+		resp.Results[res.RefID] = result
+	}
+	return resp, nil
+}
@@ -0,0 +1,315 @@
+package querycache
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/openinsight-project/grafinsight/pkg/components/null"
+	"github.com/openinsight-project/grafinsight/pkg/components/simplejson"
+	"github.com/openinsight-project/grafinsight/pkg/infra/remotecache"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/tsdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const step = int64(10000)
+
+type queriedRange struct {
+	from, to int64
+}
+
+// fakeEndpoint returns a series and a frame with a point every step.
+type fakeEndpoint struct {
+	queried []queriedRange
+	table   bool
+	logs    bool
+}
+
+// fixedIntervalEndpoint is a fakeEndpoint whose step does not depend on the
+// time range.
+type fixedIntervalEndpoint struct {
+	*fakeEndpoint
+}
+
+func (e fixedIntervalEndpoint) HasFixedInterval() bool {
+	return true
+}
+
+func (e *fakeEndpoint) Query(ctx context.Context, ds *models.DataSource, query *tsdb.TsdbQuery) (*tsdb.Response, error) {
+	from, to := query.TimeRange.GetFromAsMsEpoch(), query.TimeRange.GetToAsMsEpoch()
+	e.queried = append(e.queried, queriedRange{from, to})
+
+	if e.table {
+		return &tsdb.Response{Results: map[string]*tsdb.QueryResult{
+			"A": {RefId: "A", Tables: []*tsdb.Table{{
+				Columns: []tsdb.TableColumn{{Text: "value"}},
+				Rows:    []tsdb.RowValues{{float64(from)}},
+			}}},
+		}}, nil
+	}
+
+	series := &tsdb.TimeSeries{Name: "series", Tags: map[string]string{"job": "test"}}
+	times := []time.Time{}
+	values := []float64{}
+	for ms := from + (step-from%step)%step; ms <= to; ms += step {
+		series.Points = append(series.Points, tsdb.NewTimePoint(null.FloatFrom(float64(ms)), float64(ms)))
+		times = append(times, time.Unix(0, ms*int64(time.Millisecond)))
+		values = append(values, float64(ms))
+	}
+	frame := data.NewFrame("frame",
+		data.NewField("time", nil, times),
+		data.NewField("value", data.Labels{"job": "test"}, values))
+	if e.logs {
+		frame.Meta = &data.FrameMeta{PreferredVisualization: data.VisTypeLogs}
+	}
+
+	return &tsdb.Response{Results: map[string]*tsdb.QueryResult{
+		"A": {RefId: "A", Series: tsdb.TimeSeriesSlice{series}},
+		"B": {RefId: "B", Dataframes: tsdb.NewDecodedDataFrames(data.Frames{frame})},
+	}}, nil
+}
+
+func newTestQuery(from, to int64) *tsdb.TsdbQuery {
+	return &tsdb.TsdbQuery{
+		TimeRange: tsdb.NewTimeRange(strconv.FormatInt(from, 10), strconv.FormatInt(to, 10)),
+		Queries: []*tsdb.Query{
+			{RefId: "A", IntervalMs: step, Model: simplejson.NewFromAny(map[string]interface{}{"expr": "up", "requestId": "Q1"})},
+			{RefId: "B", IntervalMs: step, Model: simplejson.NewFromAny(map[string]interface{}{"expr": "up"})},
+		},
+	}
+}
+
+func seriesTimes(t *testing.T, resp *tsdb.Response) []int64 {
+	t.Helper()
+	times := []int64{}
+	for _, point := range resp.Results["A"].Series[0].Points {
+		times = append(times, int64(point[1].Float64))
+	}
+	return times
+}
+
+func frameTimes(t *testing.T, resp *tsdb.Response) []int64 {
+	t.Helper()
+	frames, err := resp.Results["B"].Dataframes.Decoded()
+	require.NoError(t, err)
+	require.Len(t, frames, 1)
+	times := []int64{}
+	for i := 0; i < frames[0].Rows(); i++ {
+		times = append(times, frames[0].Fields[0].At(i).(time.Time).UnixNano()/int64(time.Millisecond))
+	}
+	return times
+}
+
+func TestMiddleware(t *testing.T) {
+	ds := &models.DataSource{Id: 1, OrgId: 1, Version: 1, JsonData: simplejson.New()}
+	base := int64(1600000000000)
+
+	setup := func(t *testing.T) (*Middleware, *fakeEndpoint, tsdb.TsdbQueryEndpoint) {
+		m := &Middleware{
+			Cache:       remotecache.NewFakeStore(t),
+			TTL:         time.Minute,
+			MinInterval: time.Second,
+		}
+		endpoint := &fakeEndpoint{}
+		return m, endpoint, m.Wrap(fixedIntervalEndpoint{endpoint})
+	}
+
+	query := func(t *testing.T, endpoint tsdb.TsdbQueryEndpoint, ds *models.DataSource, q *tsdb.TsdbQuery, cacheControl string) (*tsdb.Response, Status) {
+		ctx, req := WithRequest(context.Background(), cacheControl)
+		resp, err := endpoint.Query(ctx, ds, q)
+		require.NoError(t, err)
+		return resp, req.Status()
+	}
+
+	t.Run("Should answer a repeated query from the cache", func(t *testing.T) {
+		_, fake, endpoint := setup(t)
+
+		// The time range is not aligned to the step when querying
+		resp, status := query(t, endpoint, ds, newTestQuery(base+1, base+3*step+1), "")
+		require.Equal(t, StatusMiss, status)
+		require.Equal(t, []queriedRange{{base + 1, base + 3*step + 1}}, fake.queried)
+		require.Equal(t, []int64{base + step, base + 2*step, base + 3*step}, seriesTimes(t, resp))
+
+		resp, status = query(t, endpoint, ds, newTestQuery(base+step, base+2*step), "")
+		require.Equal(t, StatusHit, status)
+		require.Len(t, fake.queried, 1)
+		require.Equal(t, []int64{base + step, base + 2*step}, seriesTimes(t, resp))
+		require.Equal(t, []int64{base + step, base + 2*step}, frameTimes(t, resp))
+	})
+
+	t.Run("Should only query the tail of a time series", func(t *testing.T) {
+		_, fake, endpoint := setup(t)
+
+		_, status := query(t, endpoint, ds, newTestQuery(base, base+3*step), "")
+		require.Equal(t, StatusMiss, status)
+
+		resp, status := query(t, endpoint, ds, newTestQuery(base+step, base+5*step), "")
+		require.Equal(t, StatusPartial, status)
+		require.Equal(t, []queriedRange{{base, base + 3*step}, {base + 2*step, base + 5*step}}, fake.queried)
+
+		expected := []int64{base + step, base + 2*step, base + 3*step, base + 4*step, base + 5*step}
+		require.Equal(t, expected, seriesTimes(t, resp))
+		require.Equal(t, expected, frameTimes(t, resp))
+
+		// The merged results are cached
+		_, status = query(t, endpoint, ds, newTestQuery(base+step, base+5*step), "")
+		require.Equal(t, StatusHit, status)
+		require.Len(t, fake.queried, 2)
+	})
+
+	t.Run("Should cache the time series of other endpoints by time range", func(t *testing.T) {
+		m, _, _ := setup(t)
+		fake := &fakeEndpoint{}
+		endpoint := m.Wrap(fake)
+
+		_, status := query(t, endpoint, ds, newTestQuery(base, base+3*step), "")
+		require.Equal(t, StatusMiss, status)
+		_, status = query(t, endpoint, ds, newTestQuery(base+1, base+3*step+1), "")
+		require.Equal(t, StatusHit, status)
+		_, status = query(t, endpoint, ds, newTestQuery(base+step, base+5*step), "")
+		require.Equal(t, StatusMiss, status)
+		require.Equal(t, []queriedRange{{base, base + 3*step}, {base + step, base + 5*step}}, fake.queried)
+	})
+
+	t.Run("Should not split queries without an interval by time", func(t *testing.T) {
+		_, fake, endpoint := setup(t)
+		q := newTestQuery(base, base+3*step)
+		q.Queries[1].IntervalMs = 0
+
+		_, status := query(t, endpoint, ds, q, "")
+		require.Equal(t, StatusMiss, status)
+		q = newTestQuery(base+step, base+5*step)
+		q.Queries[1].IntervalMs = 0
+		_, status = query(t, endpoint, ds, q, "")
+		require.Equal(t, StatusMiss, status)
+		require.Len(t, fake.queried, 2)
+	})
+
+	t.Run("Should cache other results by time range", func(t *testing.T) {
+		_, fake, endpoint := setup(t)
+		fake.table = true
+
+		_, status := query(t, endpoint, ds, newTestQuery(base, base+3*step), "")
+		require.Equal(t, StatusMiss, status)
+		_, status = query(t, endpoint, ds, newTestQuery(base, base+3*step), "")
+		require.Equal(t, StatusHit, status)
+		_, status = query(t, endpoint, ds, newTestQuery(base, base+4*step), "")
+		require.Equal(t, StatusMiss, status)
+		require.Len(t, fake.queried, 2)
+	})
+
+	t.Run("Should not split logs by time", func(t *testing.T) {
+		_, fake, endpoint := setup(t)
+		fake.logs = true
+
+		_, status := query(t, endpoint, ds, newTestQuery(base, base+3*step), "")
+		require.Equal(t, StatusMiss, status)
+		_, status = query(t, endpoint, ds, newTestQuery(base, base+3*step), "")
+		require.Equal(t, StatusHit, status)
+		_, status = query(t, endpoint, ds, newTestQuery(base+step, base+5*step), "")
+		require.Equal(t, StatusMiss, status)
+		require.Equal(t, []queriedRange{{base, base + 3*step}, {base + step, base + 5*step}}, fake.queried)
+	})
+
+	t.Run("Should not share results between queries or data source versions", func(t *testing.T) {
+		_, fake, endpoint := setup(t)
+
+		_, status := query(t, endpoint, ds, newTestQuery(base, base+3*step), "")
+		require.Equal(t, StatusMiss, status)
+
+		other := newTestQuery(base, base+3*step)
+		other.Queries[0].Model.Set("expr", "down")
+		_, status = query(t, endpoint, ds, other, "")
+		require.Equal(t, StatusMiss, status)
+
+		updated := *ds
+		updated.Version = 2
+		_, status = query(t, endpoint, &updated, newTestQuery(base, base+3*step), "")
+		require.Equal(t, StatusMiss, status)
+		require.Len(t, fake.queried, 3)
+	})
+
+	t.Run("Should follow the cache control of the request", func(t *testing.T) {
+		m, fake, endpoint := setup(t)
+		now := time.Now()
+		m.now = func() time.Time { return now }
+
+		_, status := query(t, endpoint, ds, newTestQuery(base, base+3*step), "no-store")
+		require.Equal(t, StatusBypass, status)
+		_, status = query(t, endpoint, ds, newTestQuery(base, base+3*step), "")
+		require.Equal(t, StatusMiss, status)
+		_, status = query(t, endpoint, ds, newTestQuery(base, base+3*step), "no-cache")
+		require.Equal(t, StatusMiss, status)
+
+		now = now.Add(time.Minute)
+		_, status = query(t, endpoint, ds, newTestQuery(base, base+3*step), "max-age=120")
+		require.Equal(t, StatusHit, status)
+		_, status = query(t, endpoint, ds, newTestQuery(base, base+3*step), "max-age=30")
+		require.Equal(t, StatusMiss, status)
+		require.Len(t, fake.queried, 4)
+	})
+
+	t.Run("Should bypass the cache when disabled for the data source", func(t *testing.T) {
+		_, fake, endpoint := setup(t)
+		disabled := &models.DataSource{Id: 2, OrgId: 1, JsonData: simplejson.NewFromAny(map[string]interface{}{
+			TTLSetting: "0",
+		})}
+
+		for i := 0; i < 2; i++ {
+			_, status := query(t, endpoint, disabled, newTestQuery(base, base+3*step), "")
+			require.Equal(t, StatusBypass, status)
+		}
+		require.Len(t, fake.queried, 2)
+	})
+
+	t.Run("Should bypass the cache for OAuth pass-through data sources", func(t *testing.T) {
+		_, _, endpoint := setup(t)
+		oauth := &models.DataSource{Id: 3, OrgId: 1, JsonData: simplejson.NewFromAny(map[string]interface{}{
+			"oauthPassThru": true,
+		})}
+
+		_, status := query(t, endpoint, oauth, newTestQuery(base, base+3*step), "")
+		require.Equal(t, StatusBypass, status)
+	})
+
+	t.Run("Should bypass the cache for data sources forwarding cookies", func(t *testing.T) {
+		_, _, endpoint := setup(t)
+		cookies := &models.DataSource{Id: 4, OrgId: 1, JsonData: simplejson.NewFromAny(map[string]interface{}{
+			"keepCookies": []interface{}{"session"},
+		})}
+
+		_, status := query(t, endpoint, cookies, newTestQuery(base, base+3*step), "")
+		require.Equal(t, StatusBypass, status)
+	})
+
+	t.Run("Should not share results between users when the user is sent", func(t *testing.T) {
+		m, fake, endpoint := setup(t)
+		m.PerUser = true
+
+		statuses := []Status{}
+		for _, userID := range []int64{1, 2, 1} {
+			q := newTestQuery(base, base+3*step)
+			q.User = &models.SignedInUser{UserId: userID}
+			_, status := query(t, endpoint, ds, q, "")
+			statuses = append(statuses, status)
+		}
+		require.Equal(t, []Status{StatusMiss, StatusMiss, StatusHit}, statuses)
+		require.Len(t, fake.queried, 2)
+	})
+}
+
+func TestParseCacheControl(t *testing.T) {
+	req := ParseCacheControl("No-Cache, max-age=60")
+	assert.True(t, req.NoCache)
+	assert.False(t, req.NoStore)
+	require.NotNil(t, req.MaxAge)
+	assert.Equal(t, time.Minute, *req.MaxAge)
+
+	req = ParseCacheControl("no-store, max-age=invalid")
+	assert.True(t, req.NoStore)
+	assert.Nil(t, req.MaxAge)
+}
@@ -0,0 +1,89 @@
+package querycache
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status tells how a query was answered.
+type Status string
+
+const (
+	// StatusHit means the results were read from the cache.
+	StatusHit Status = "HIT"
+	// StatusPartial means only the tail of the time range was queried.
+	StatusPartial Status = "PARTIAL"
+	// StatusMiss means the query was not cached.
+	StatusMiss Status = "MISS"
+	// StatusBypass means the query can not be cached.
+	StatusBypass Status = "BYPASS"
+)
+
+// Request carries the cache control of a query request and records how the
+// query was answered.
+type Request struct {
+	// NoCache queries the data source without reading the cache.
+	NoCache bool
+	// NoStore neither reads nor writes the cache.
+	NoStore bool
+	// MaxAge is the maximum age of cached results, if set.
+	MaxAge *time.Duration
+
+	mu     sync.Mutex
+	status Status
+}
+
+// Status returns how the query was answered, or an empty status when no
+// query went through the cache.
+func (r *Request) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.status
+}
+
+func (r *Request) setStatus(status Status) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+type requestKey struct{}
+
+// WithRequest returns a context with the cache control of an HTTP
+// `Cache-Control` header, and the Request recording the cache status.
+func WithRequest(ctx context.Context, cacheControl string) (context.Context, *Request) {
+	req := ParseCacheControl(cacheControl)
+	return context.WithValue(ctx, requestKey{}, req), req
+}
+
+func requestFromContext(ctx context.Context) *Request {
+	if req, ok := ctx.Value(requestKey{}).(*Request); ok {
+		return req
+	}
+	return &Request{}
+}
+
+// ParseCacheControl reads the `no-cache`, `no-store` and `max-age`
+// directives of a `Cache-Control` header.
+func ParseCacheControl(header string) *Request {
+	req := &Request{}
+	for _, directive := range strings.Split(header, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-cache":
+			req.NoCache = true
+		case directive == "no-store":
+			req.NoStore = true
+		case strings.HasPrefix(directive, "max-age="):
+			seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age="))
+			if err == nil && seconds >= 0 {
+				maxAge := time.Duration(seconds) * time.Second
+				req.MaxAge = &maxAge
+			}
+		}
+	}
+	return req
+}
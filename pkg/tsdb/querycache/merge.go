@@ -0,0 +1,248 @@
+package querycache
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/openinsight-project/grafinsight/pkg/tsdb"
+)
+
+// isTimeSeries reports whether all the results are time series, which can be
+// split by time and merged. Tables, frames without a time field and logs are
+// not, as log queries are limited to a number of lines rather than a range.
+func isTimeSeries(resp *tsdb.Response) bool {
+	for _, res := range resp.Results {
+		if res.Error != nil || res.ErrorString != "" || len(res.Tables) > 0 {
+			return false
+		}
+		if res.Dataframes == nil {
+			continue
+		}
+		if len(res.Series) > 0 {
+			return false
+		}
+		frames, err := res.Dataframes.Decoded()
+		if err != nil {
+			return false
+		}
+		for _, frame := range frames {
+			if timeFieldIndex(frame) < 0 {
+				return false
+			}
+			if frame.Meta != nil && frame.Meta.PreferredVisualization == data.VisTypeLogs {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// filterResponse returns a copy of the time series results, with only the
+// points at the times kept.
+func filterResponse(resp *tsdb.Response, keep func(ms int64) bool) (*tsdb.Response, error) {
+	filtered := &tsdb.Response{
+		Results: make(map[string]*tsdb.QueryResult, len(resp.Results)),
+		Message: resp.Message,
+	}
+
+	for refID, res := range resp.Results {
+		out := &tsdb.QueryResult{
+			RefId:  res.RefId,
+			Meta:   res.Meta,
+			Series: make(tsdb.TimeSeriesSlice, 0, len(res.Series)),
+		}
+
+		for _, series := range res.Series {
+			points := make(tsdb.TimeSeriesPoints, 0, len(series.Points))
+			for _, point := range series.Points {
+				if point[1].Valid && keep(int64(point[1].Float64)) {
+					points = append(points, point)
+				}
+			}
+			out.Series = append(out.Series, &tsdb.TimeSeries{
+				Name:   series.Name,
+				Tags:   series.Tags,
+				Points: points,
+			})
+		}
+
+		if res.Dataframes != nil {
+			frames, err := res.Dataframes.Decoded()
+			if err != nil {
+				return nil, err
+			}
+			outFrames := make(data.Frames, 0, len(frames))
+			for _, frame := range frames {
+				outFrames = append(outFrames, filterFrame(frame, keep))
+			}
+			out.Dataframes = tsdb.NewDecodedDataFrames(outFrames)
+		}
+
+		filtered.Results[refID] = out
+	}
+
+	return filtered, nil
+}
+
+func filterFrame(frame *data.Frame, keep func(ms int64) bool) *data.Frame {
+	out := frame.EmptyCopy()
+	timeField := frame.Fields[timeFieldIndex(frame)]
+	for i := 0; i < timeField.Len(); i++ {
+		t, ok := timeAt(timeField, i)
+		if ok && keep(t.UnixNano()/int64(time.Millisecond)) {
+			out.AppendRow(frame.RowCopy(i)...)
+		}
+	}
+	return out
+}
+
+// mergeResponses appends the points of tail to the series and frames of
+// head. Both responses must only hold time series, and head must end before
+// tail starts.
+func mergeResponses(head, tail *tsdb.Response) (*tsdb.Response, error) {
+	merged := &tsdb.Response{
+		Results: make(map[string]*tsdb.QueryResult, len(tail.Results)),
+		Message: tail.Message,
+	}
+
+	for refID, tailRes := range tail.Results {
+		headRes, ok := head.Results[refID]
+		if !ok {
+			merged.Results[refID] = tailRes
+			continue
+		}
+
+		out := &tsdb.QueryResult{
+			RefId:  tailRes.RefId,
+			Meta:   tailRes.Meta,
+			Series: mergeSeries(headRes.Series, tailRes.Series),
+		}
+
+		if headRes.Dataframes != nil || tailRes.Dataframes != nil {
+			frames, err := mergeFrames(headRes.Dataframes, tailRes.Dataframes)
+			if err != nil {
+				return nil, err
+			}
+			out.Dataframes = tsdb.NewDecodedDataFrames(frames)
+		}
+
+		merged.Results[refID] = out
+	}
+
+	return merged, nil
+}
+
+func mergeSeries(head, tail tsdb.TimeSeriesSlice) tsdb.TimeSeriesSlice {
+	merged := make(tsdb.TimeSeriesSlice, 0, len(tail))
+	byKey := map[string]*tsdb.TimeSeries{}
+
+	for _, series := range head {
+		s := &tsdb.TimeSeries{
+			Name:   series.Name,
+			Tags:   series.Tags,
+			Points: append(tsdb.TimeSeriesPoints{}, series.Points...),
+		}
+		byKey[seriesKey(series)] = s
+		merged = append(merged, s)
+	}
+
+	for _, series := range tail {
+		if s, ok := byKey[seriesKey(series)]; ok {
+			s.Points = append(s.Points, series.Points...)
+			continue
+		}
+		merged = append(merged, series)
+	}
+
+	return merged
+}
+
+func mergeFrames(head, tail tsdb.DataFrames) (data.Frames, error) {
+	var headFrames, tailFrames data.Frames
+	var err error
+	if head != nil {
+		if headFrames, err = head.Decoded(); err != nil {
+			return nil, err
+		}
+	}
+	if tail != nil {
+		if tailFrames, err = tail.Decoded(); err != nil {
+			return nil, err
+		}
+	}
+
+	merged := make(data.Frames, 0, len(tailFrames))
+	byKey := map[string]*data.Frame{}
+
+	for _, frame := range headFrames {
+		copied := frame.EmptyCopy()
+		for i := 0; i < frame.Rows(); i++ {
+			copied.AppendRow(frame.RowCopy(i)...)
+		}
+		byKey[frameKey(frame)] = copied
+		merged = append(merged, copied)
+	}
+
+	for _, frame := range tailFrames {
+		if f, ok := byKey[frameKey(frame)]; ok {
+			for i := 0; i < frame.Rows(); i++ {
+				f.AppendRow(frame.RowCopy(i)...)
+			}
+			// The metadata of the latest query is the most relevant
+			f.Meta = frame.Meta
+			continue
+		}
+		merged = append(merged, frame)
+	}
+
+	return merged, nil
+}
+
+func seriesKey(series *tsdb.TimeSeries) string {
+	keys := make([]string, 0, len(series.Tags))
+	for k := range series.Tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(series.Name)
+	for _, k := range keys {
+		b.WriteString("\x00" + k + "=" + series.Tags[k])
+	}
+	return b.String()
+}
+
+// frameKey identifies a frame by its name and schema.
+func frameKey(frame *data.Frame) string {
+	var b strings.Builder
+	b.WriteString(frame.Name)
+	for _, field := range frame.Fields {
+		b.WriteString("\x00" + field.Name + "\x00" + field.Type().ItemTypeString() + "\x00" + field.Labels.String())
+	}
+	return b.String()
+}
+
+func timeFieldIndex(frame *data.Frame) int {
+	for i, field := range frame.Fields {
+		if t := field.Type(); t == data.FieldTypeTime || t == data.FieldTypeNullableTime {
+			return i
+		}
+	}
+	return -1
+}
+
+func timeAt(field *data.Field, idx int) (time.Time, bool) {
+	switch v := field.At(idx).(type) {
+	case time.Time:
+		return v, true
+	case *time.Time:
+		if v == nil {
+			return time.Time{}, false
+		}
+		return *v, true
+	}
+	return time.Time{}, false
+}
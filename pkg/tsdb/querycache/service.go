@@ -0,0 +1,40 @@
+package querycache
+
+import (
+	"github.com/openinsight-project/grafinsight/pkg/infra/log"
+	"github.com/openinsight-project/grafinsight/pkg/infra/remotecache"
+	"github.com/openinsight-project/grafinsight/pkg/registry"
+	"github.com/openinsight-project/grafinsight/pkg/setting"
+	"github.com/openinsight-project/grafinsight/pkg/tsdb"
+)
+
+var logger = log.New("tsdb.querycache")
+
+func init() {
+	remotecache.Register(&cacheEntry{})
+	registry.RegisterService(&Service{})
+}
+
+// Service caches the results of the data source queries in the remote cache
+// when query caching is enabled.
+type Service struct {
+	Cfg         *setting.Cfg             `inject:""`
+	RemoteCache *remotecache.RemoteCache `inject:""`
+}
+
+func (s *Service) Init() error {
+	settings := s.Cfg.QueryCaching
+	if !settings.Enabled {
+		return nil
+	}
+
+	m := &Middleware{
+		Cache:       s.RemoteCache,
+		TTL:         settings.TTL,
+		MinInterval: settings.MinInterval,
+		PerUser:     s.Cfg.SendUserHeader,
+	}
+	tsdb.RegisterQueryEndpointMiddleware(m.Wrap)
+	logger.Info("Query caching enabled", "ttl", settings.TTL, "minInterval", settings.MinInterval)
+	return nil
+}
@@ -22,5 +22,10 @@ func HandleRequest(ctx context.Context, dsInfo *models.DataSource, req *TsdbQuer
 		return nil, err
 	}
 
+	// The first registered middleware is the outermost one
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		endpoint = middlewares[i](endpoint)
+	}
+
 	return endpoint.Query(ctx, dsInfo, req)
 }
@@ -55,6 +55,35 @@ func TestHandleRequest(t *testing.T) {
 		_, err := HandleRequest(context.TODO(), &models.DataSource{Id: 12, Type: "testjughjgjg"}, req)
 		require.Error(t, err)
 	})
+
+	t.Run("Should wrap the query endpoint with the registered middlewares", func(t *testing.T) {
+		t.Cleanup(func() { middlewares = nil })
+
+		req := &TsdbQuery{
+			Queries: []*Query{
+				{RefId: "A", DataSource: &models.DataSource{Id: 1, Type: "test"}},
+			},
+		}
+
+		fakeExecutor := registerFakeExecutor()
+		fakeExecutor.Return("A", TimeSeriesSlice{&TimeSeries{Name: "argh"}})
+
+		calls := []string{}
+		for _, name := range []string{"outer", "inner"} {
+			name := name
+			RegisterQueryEndpointMiddleware(func(next TsdbQueryEndpoint) TsdbQueryEndpoint {
+				return TsdbQueryEndpointFunc(func(ctx context.Context, ds *models.DataSource, query *TsdbQuery) (*Response, error) {
+					calls = append(calls, name)
+					return next.Query(ctx, ds, query)
+				})
+			})
+		}
+
+		res, err := HandleRequest(context.TODO(), &models.DataSource{Id: 1, Type: "test"}, req)
+		require.NoError(t, err)
+		require.Equal(t, "argh", res.Results["A"].Series[0].Name)
+		require.Equal(t, []string{"outer", "inner"}, calls)
+	})
 }
 
 func registerFakeExecutor() *FakeExecutor {
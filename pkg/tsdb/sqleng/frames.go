@@ -0,0 +1,170 @@
+package sqleng
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/openinsight-project/grafinsight/pkg/tsdb"
+)
+
+// addFrame adds a frame with the result of a query to its query result,
+// together with the SQL that was executed.
+func addFrame(query *tsdb.Query, result *tsdb.QueryResult, frame *data.Frame) {
+	frame.RefID = query.RefId
+	frame.Meta = &data.FrameMeta{
+		ExecutedQueryString: result.Meta.Get(MetaKeyExecutedQueryString).MustString(),
+	}
+	result.Dataframes = tsdb.NewDecodedDataFrames(data.Frames{frame})
+}
+
+// newTimeField returns a time field with the values of a column that were
+// converted to epoch milliseconds by ConvertSqlTimeColumnToEpochMs. It
+// returns false if the column has values that are not timestamps.
+func newTimeField(name string, rows []tsdb.RowValues, index int) (*data.Field, bool) {
+	values := make([]*time.Time, len(rows))
+	for i, row := range rows {
+		if row[index] == nil {
+			continue
+		}
+		ms, err := ConvertSqlValueColumnToFloat(name, row[index])
+		if err != nil || !ms.Valid {
+			return nil, false
+		}
+		t := epochMsToTime(ms.Float64)
+		values[i] = &t
+	}
+	return data.NewField(name, nil, values), true
+}
+
+// epochMsToTime returns the time of a timestamp in epoch milliseconds, which
+// may have a fraction for columns with a higher precision.
+func epochMsToTime(ms float64) time.Time {
+	whole := math.Floor(ms)
+	return time.Unix(0, int64(whole)*int64(time.Millisecond)+int64(math.Round((ms-whole)*1e6))).UTC()
+}
+
+// newField returns a nullable field with the values of a column. The field
+// has the type of the values, or is a string field if the column has values
+// of different or unsupported types.
+func newField(name string, rows []tsdb.RowValues, index int) *data.Field {
+	values := make([]interface{}, len(rows))
+	var valueType reflect.Type
+	mixed := false
+	for i, row := range rows {
+		values[i] = fieldValue(row[index])
+		if values[i] == nil {
+			continue
+		}
+		t := reflect.TypeOf(values[i])
+		if valueType == nil {
+			valueType = t
+		} else if t != valueType {
+			mixed = true
+		}
+	}
+
+	var field *data.Field
+	if valueType != nil && !mixed {
+		vector := reflect.MakeSlice(reflect.SliceOf(reflect.PtrTo(valueType)), len(rows), len(rows)).Interface()
+		if data.ValidFieldType(vector) {
+			field = data.NewField(name, nil, vector)
+		}
+	}
+	if field == nil {
+		field = data.NewField(name, nil, make([]*string, len(rows)))
+		for i, v := range values {
+			if v != nil {
+				values[i] = fmt.Sprintf("%v", v)
+			}
+		}
+	}
+
+	for i, v := range values {
+		if v != nil {
+			field.SetConcrete(i, v)
+		}
+	}
+	return field
+}
+
+// fieldValue returns the value of a column as one of the types of the values
+// of fields, or nil for null values.
+func fieldValue(value interface{}) interface{} {
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return nil
+		}
+		value = v
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return string(v)
+	case sql.RawBytes:
+		return string(v)
+	case int:
+		return int64(v)
+	case uint:
+		return uint64(v)
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		return fieldValue(rv.Elem().Interface())
+	}
+	return value
+}
+
+// seriesToWideFrame returns a frame with a time field and a value field for
+// each series. The value fields are named after the column of their values
+// and have the metric of the series as labels, while the name of the series
+// is kept as display name.
+func seriesToWideFrame(timeColumn string, seriesList tsdb.TimeSeriesSlice, columnBySeries map[string]string) *data.Frame {
+	seen := map[float64]bool{}
+	timestamps := []float64{}
+	for _, series := range seriesList {
+		for _, point := range series.Points {
+			if !seen[point[1].Float64] {
+				seen[point[1].Float64] = true
+				timestamps = append(timestamps, point[1].Float64)
+			}
+		}
+	}
+	sort.Float64s(timestamps)
+
+	rowByTimestamp := make(map[float64]int, len(timestamps))
+	times := make([]time.Time, len(timestamps))
+	for i, ts := range timestamps {
+		rowByTimestamp[ts] = i
+		times[i] = epochMsToTime(ts)
+	}
+
+	frame := data.NewFrame("", data.NewField(timeColumn, nil, times))
+	for _, series := range seriesList {
+		values := make([]*float64, len(timestamps))
+		for _, point := range series.Points {
+			if point[0].Valid {
+				v := point[0].Float64
+				values[rowByTimestamp[point[1].Float64]] = &v
+			}
+		}
+
+		field := data.NewField(columnBySeries[series.Name], data.Labels(series.Tags), values)
+		field.Config = &data.FieldConfig{DisplayNameFromDS: series.Name}
+		frame.Fields = append(frame.Fields, field)
+	}
+
+	return frame
+}
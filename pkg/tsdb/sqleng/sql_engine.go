@@ -4,6 +4,7 @@ import (
 	"container/list"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"math"
 	"regexp"
@@ -19,6 +20,7 @@ import (
 
 	"github.com/openinsight-project/grafinsight/pkg/components/null"
 
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/openinsight-project/grafinsight/pkg/components/simplejson"
 	"github.com/openinsight-project/grafinsight/pkg/models"
 	"xorm.io/core"
@@ -43,16 +45,70 @@ type SqlQueryResultTransformer interface {
 }
 
 type engineCacheType struct {
-	cache    map[int64]*xorm.Engine
+	cache    map[int64]*sharedEngine
 	versions map[int64]int
 	sync.Mutex
 }
 
 var engineCache = engineCacheType{
-	cache:    make(map[int64]*xorm.Engine),
+	cache:    make(map[int64]*sharedEngine),
 	versions: make(map[int64]int),
 }
 
+// errEngineClosed is returned by queries of a previous version of a
+// datasource started after its engine was closed.
+var errEngineClosed = errors.New("the datasource was updated, please run the query again")
+
+// sharedEngine is an engine of the cache shared by the queries of a
+// datasource. When the datasource is updated, the engine is closed once the
+// queries using it are done.
+type sharedEngine struct {
+	*xorm.Engine
+
+	mu      sync.Mutex
+	queries int
+	retired bool
+	closed  bool
+}
+
+// acquire marks the engine as used by a query.
+func (e *sharedEngine) acquire() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return errEngineClosed
+	}
+	e.queries++
+	return nil
+}
+
+// release marks a query as done, closing the engine when it is retired and
+// no longer used.
+func (e *sharedEngine) release(log log.Logger) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.queries--
+	e.closeIfUnused(log)
+}
+
+// retire closes the engine once the queries in progress are done.
+func (e *sharedEngine) retire(log log.Logger) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.retired = true
+	e.closeIfUnused(log)
+}
+
+func (e *sharedEngine) closeIfUnused(log log.Logger) {
+	if !e.retired || e.closed || e.queries > 0 {
+		return
+	}
+	e.closed = true
+	if err := e.Engine.Close(); err != nil {
+		log.Warn("Failed to close engine of previous datasource version", "err", err)
+	}
+}
+
 var sqlIntervalCalculator = tsdb.NewIntervalCalculator(nil)
 
 // NewXormEngine is an xorm.Engine factory, that can be stubbed by tests.
@@ -66,9 +122,12 @@ const timeEndColumnName = "timeend"
 type sqlQueryEndpoint struct {
 	macroEngine            SqlMacroEngine
 	queryResultTransformer SqlQueryResultTransformer
-	engine                 *xorm.Engine
+	engine                 *sharedEngine
 	timeColumnNames        []string
 	metricColumnTypes      []string
+	intervalAsSeconds      bool
+	queryTimeout           time.Duration
+	applyQueryTimeout      func(rawSQL string, timeout time.Duration) string
 	log                    log.Logger
 }
 
@@ -78,6 +137,12 @@ type SqlQueryEndpointConfiguration struct {
 	ConnectionString  string
 	TimeColumnNames   []string
 	MetricColumnTypes []string
+	// IntervalAsSeconds replaces $__interval with a number of seconds, for
+	// databases that do not understand intervals like 1m.
+	IntervalAsSeconds bool
+	// ApplyQueryTimeout rewrites a query so that the database stops it after
+	// the timeout, as the cancellation of a query does not reach every server.
+	ApplyQueryTimeout func(rawSQL string, timeout time.Duration) string
 }
 
 var NewSqlQueryEndpoint = func(config *SqlQueryEndpointConfiguration, queryResultTransformer SqlQueryResultTransformer, macroEngine SqlMacroEngine, log log.Logger) (tsdb.TsdbQueryEndpoint, error) {
@@ -96,6 +161,10 @@ var NewSqlQueryEndpoint = func(config *SqlQueryEndpointConfiguration, queryResul
 		queryEndpoint.metricColumnTypes = config.MetricColumnTypes
 	}
 
+	queryEndpoint.intervalAsSeconds = config.IntervalAsSeconds
+	queryEndpoint.queryTimeout = QueryTimeout(config.Datasource)
+	queryEndpoint.applyQueryTimeout = config.ApplyQueryTimeout
+
 	engineCache.Lock()
 	defer engineCache.Unlock()
 
@@ -104,6 +173,11 @@ var NewSqlQueryEndpoint = func(config *SqlQueryEndpointConfiguration, queryResul
 			queryEndpoint.engine = engine
 			return &queryEndpoint, nil
 		}
+
+		// The connections of the previous version of the datasource are not
+		// used anymore, but the queries in progress still need them.
+		engine.retire(log)
+		delete(engineCache.cache, config.Datasource.Id)
 	}
 
 	engine, err := NewXormEngine(config.DriverName, config.ConnectionString)
@@ -119,20 +193,44 @@ var NewSqlQueryEndpoint = func(config *SqlQueryEndpointConfiguration, queryResul
 	engine.SetConnMaxLifetime(time.Duration(connMaxLifetime) * time.Second)
 
 	engineCache.versions[config.Datasource.Id] = config.Datasource.Version
-	engineCache.cache[config.Datasource.Id] = engine
-	queryEndpoint.engine = engine
+	shared := &sharedEngine{Engine: engine}
+	engineCache.cache[config.Datasource.Id] = shared
+	queryEndpoint.engine = shared
 
 	return &queryEndpoint, nil
 }
 
 const rowLimit = 1000000
 
+// ConfiguredQueryTimeout returns the timeout of the queries configured on the
+// datasource, or 0 if there is none.
+func ConfiguredQueryTimeout(ds *models.DataSource) time.Duration {
+	if ds.JsonData == nil {
+		return 0
+	}
+	return time.Duration(ds.JsonData.Get("queryTimeout").MustInt(0)) * time.Second
+}
+
+// QueryTimeout returns the timeout of the queries of the datasource, which
+// defaults to the timeout of the data proxy.
+func QueryTimeout(ds *models.DataSource) time.Duration {
+	if timeout := ConfiguredQueryTimeout(ds); timeout > 0 {
+		return timeout
+	}
+	return time.Duration(setting.DataProxyTimeout) * time.Second
+}
+
 // Query is the main function for the SqlQueryEndpoint
 func (e *sqlQueryEndpoint) Query(ctx context.Context, dsInfo *models.DataSource, tsdbQuery *tsdb.TsdbQuery) (*tsdb.Response, error) {
 	result := &tsdb.Response{
 		Results: make(map[string]*tsdb.QueryResult),
 	}
 
+	if err := e.engine.acquire(); err != nil {
+		return nil, err
+	}
+	defer e.engine.release(e.log)
+
 	var wg sync.WaitGroup
 
 	for _, query := range tsdbQuery.Queries {
@@ -144,6 +242,10 @@ func (e *sqlQueryEndpoint) Query(ctx context.Context, dsInfo *models.DataSource,
 		queryResult := &tsdb.QueryResult{Meta: simplejson.New(), RefId: query.RefId}
 		result.Results[query.RefId] = queryResult
 
+		if e.intervalAsSeconds {
+			rawSQL = interpolateIntervalSeconds(query, tsdbQuery.TimeRange, rawSQL)
+		}
+
 		// global substitutions
 		rawSQL, err := Interpolate(query, tsdbQuery.TimeRange, rawSQL)
 		if err != nil {
@@ -160,6 +262,10 @@ func (e *sqlQueryEndpoint) Query(ctx context.Context, dsInfo *models.DataSource,
 
 		queryResult.Meta.Set(MetaKeyExecutedQueryString, rawSQL)
 
+		if e.applyQueryTimeout != nil && e.queryTimeout > 0 {
+			rawSQL = e.applyQueryTimeout(rawSQL, e.queryTimeout)
+		}
+
 		wg.Add(1)
 
 		go func(rawSQL string, query *tsdb.Query, queryResult *tsdb.QueryResult) {
@@ -168,9 +274,18 @@ func (e *sqlQueryEndpoint) Query(ctx context.Context, dsInfo *models.DataSource,
 			defer session.Close()
 			db := session.DB()
 
-			rows, err := db.Query(rawSQL)
+			// The query is cancelled when the request is, or when it takes
+			// too long, so that it does not keep using a connection
+			queryCtx := ctx
+			if e.queryTimeout > 0 {
+				var cancel context.CancelFunc
+				queryCtx, cancel = context.WithTimeout(ctx, e.queryTimeout)
+				defer cancel()
+			}
+
+			rows, err := db.QueryContext(queryCtx, rawSQL)
 			if err != nil {
-				queryResult.Error = e.queryResultTransformer.TransformQueryError(err)
+				queryResult.Error = e.queryError(queryCtx, err)
 				return
 			}
 			defer func() {
@@ -183,17 +298,15 @@ func (e *sqlQueryEndpoint) Query(ctx context.Context, dsInfo *models.DataSource,
 
 			switch format {
 			case "time_series":
-				err := e.transformToTimeSeries(query, rows, queryResult, tsdbQuery)
-				if err != nil {
-					queryResult.Error = err
-					return
-				}
+				err = e.transformToTimeSeries(query, rows, queryResult, tsdbQuery)
 			case "table":
-				err := e.transformToTable(query, rows, queryResult, tsdbQuery)
-				if err != nil {
-					queryResult.Error = err
-					return
-				}
+				err = e.transformToTable(query, rows, queryResult, tsdbQuery)
+			}
+			if err == nil {
+				err = rows.Err()
+			}
+			if err != nil {
+				queryResult.Error = e.queryError(queryCtx, err)
 			}
 		}(rawSQL, query, queryResult)
 	}
@@ -202,13 +315,46 @@ func (e *sqlQueryEndpoint) Query(ctx context.Context, dsInfo *models.DataSource,
 	return result, nil
 }
 
+// queryError returns the error of a query, which is a timeout or cancellation
+// error if the context of the query is done.
+func (e *sqlQueryEndpoint) queryError(ctx context.Context, err error) error {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return fmt.Errorf("query timed out after %s", e.queryTimeout)
+	case context.Canceled:
+		return fmt.Errorf("query was cancelled")
+	}
+	return e.queryResultTransformer.TransformQueryError(err)
+}
+
+// intervalPattern matches $__interval, but not $__interval_ms.
+var intervalPattern = regexp.MustCompile(`\$__interval\b`)
+
+func queryInterval(query *tsdb.Query, timeRange *tsdb.TimeRange) (tsdb.Interval, error) {
+	minInterval, err := tsdb.GetIntervalFrom(query.DataSource, query.Model, time.Second*60)
+	if err != nil {
+		return tsdb.Interval{}, err
+	}
+	return sqlIntervalCalculator.Calculate(timeRange, minInterval), nil
+}
+
+// interpolateIntervalSeconds replaces $__interval with the interval in whole
+// seconds.
+func interpolateIntervalSeconds(query *tsdb.Query, timeRange *tsdb.TimeRange, sql string) string {
+	interval, err := queryInterval(query, timeRange)
+	if err != nil {
+		return sql
+	}
+	seconds := int64(math.Max(1, math.Round(interval.Value.Seconds())))
+	return intervalPattern.ReplaceAllLiteralString(sql, strconv.FormatInt(seconds, 10))
+}
+
 // Interpolate provides global macros/substitutions for all sql datasources.
 var Interpolate = func(query *tsdb.Query, timeRange *tsdb.TimeRange, sql string) (string, error) {
-	minInterval, err := tsdb.GetIntervalFrom(query.DataSource, query.Model, time.Second*60)
+	interval, err := queryInterval(query, timeRange)
 	if err != nil {
 		return sql, nil
 	}
-	interval := sqlIntervalCalculator.Calculate(timeRange, minInterval)
 
 	sql = strings.ReplaceAll(sql, "$__interval_ms", strconv.FormatInt(interval.Milliseconds(), 10))
 	sql = strings.ReplaceAll(sql, "$__interval", interval.Text)
@@ -220,8 +366,6 @@ var Interpolate = func(query *tsdb.Query, timeRange *tsdb.TimeRange, sql string)
 
 func (e *sqlQueryEndpoint) transformToTable(query *tsdb.Query, rows *core.Rows, result *tsdb.QueryResult, tsdbQuery *tsdb.TsdbQuery) error {
 	columnNames, err := rows.Columns()
-	columnCount := len(columnNames)
-
 	if err != nil {
 		return err
 	}
@@ -230,14 +374,7 @@ func (e *sqlQueryEndpoint) transformToTable(query *tsdb.Query, rows *core.Rows,
 	timeIndex := -1
 	timeEndIndex := -1
 
-	table := &tsdb.Table{
-		Columns: make([]tsdb.TableColumn, columnCount),
-		Rows:    make([]tsdb.RowValues, 0),
-	}
-
 	for i, name := range columnNames {
-		table.Columns[i].Text = name
-
 		for _, tc := range e.timeColumnNames {
 			if name == tc {
 				timeIndex = i
@@ -256,6 +393,7 @@ func (e *sqlQueryEndpoint) transformToTable(query *tsdb.Query, rows *core.Rows,
 		return err
 	}
 
+	tableRows := make([]tsdb.RowValues, 0)
 	for ; rows.Next(); rowCount++ {
 		if rowCount > rowLimit {
 			return fmt.Errorf("query row limit exceeded, limit %d", rowLimit)
@@ -271,10 +409,21 @@ func (e *sqlQueryEndpoint) transformToTable(query *tsdb.Query, rows *core.Rows,
 		// annotation and table queries.
 		ConvertSqlTimeColumnToEpochMs(values, timeIndex)
 		ConvertSqlTimeColumnToEpochMs(values, timeEndIndex)
-		table.Rows = append(table.Rows, values)
+		tableRows = append(tableRows, values)
 	}
 
-	result.Tables = append(result.Tables, table)
+	frame := data.NewFrame("")
+	for i, name := range columnNames {
+		if i == timeIndex || i == timeEndIndex {
+			if field, ok := newTimeField(name, tableRows, i); ok {
+				frame.Fields = append(frame.Fields, field)
+				continue
+			}
+		}
+		frame.Fields = append(frame.Fields, newField(name, tableRows, i))
+	}
+
+	addFrame(query, result, frame)
 	result.Meta.Set("rowCount", rowCount)
 	return nil
 }
@@ -302,6 +451,7 @@ func newProcessCfg(query *tsdb.Query, tsdbQuery *tsdb.TsdbQuery, rows *core.Rows
 		fillMissing:        fillMissing,
 		seriesByQueryOrder: list.New(),
 		pointsBySeries:     make(map[string]*tsdb.TimeSeries),
+		columnBySeries:     make(map[string]string),
 		tsdbQuery:          tsdbQuery,
 	}
 	return cfg, nil
@@ -367,9 +517,10 @@ func (e *sqlQueryEndpoint) transformToTimeSeries(query *tsdb.Query, rows *core.R
 		}
 	}
 
+	seriesList := make(tsdb.TimeSeriesSlice, 0, cfg.seriesByQueryOrder.Len())
 	for elem := cfg.seriesByQueryOrder.Front(); elem != nil; elem = elem.Next() {
 		key := elem.Value.(string)
-		result.Series = append(result.Series, cfg.pointsBySeries[key])
+		seriesList = append(seriesList, cfg.pointsBySeries[key])
 		if !cfg.fillMissing {
 			continue
 		}
@@ -395,6 +546,9 @@ func (e *sqlQueryEndpoint) transformToTimeSeries(query *tsdb.Query, rows *core.R
 		}
 	}
 
+	if len(seriesList) > 0 {
+		addFrame(query, result, seriesToWideFrame(cfg.columnNames[cfg.timeIndex], seriesList, cfg.columnBySeries))
+	}
 	result.Meta.Set("rowCount", cfg.rowCount)
 	return nil
 }
@@ -410,6 +564,7 @@ type processCfg struct {
 	metricPrefixValue  string
 	fillMissing        bool
 	pointsBySeries     map[string]*tsdb.TimeSeries
+	columnBySeries     map[string]string
 	seriesByQueryOrder *list.List
 	fillValue          null.Float
 	tsdbQuery          *tsdb.TsdbQuery
@@ -421,6 +576,7 @@ func (e *sqlQueryEndpoint) processRow(cfg *processCfg) error {
 	var timestamp float64
 	var value null.Float
 	var metric string
+	var metricValue string
 
 	if cfg.rowCount > rowLimit {
 		return fmt.Errorf("query row limit exceeded, limit %d", rowLimit)
@@ -448,6 +604,7 @@ func (e *sqlQueryEndpoint) processRow(cfg *processCfg) error {
 
 	if cfg.metricIndex >= 0 {
 		if columnValue, ok := values[cfg.metricIndex].(string); ok {
+			metricValue = columnValue
 			if cfg.metricPrefix {
 				cfg.metricPrefixValue = columnValue
 			} else {
@@ -478,7 +635,11 @@ func (e *sqlQueryEndpoint) processRow(cfg *processCfg) error {
 		series, exist := cfg.pointsBySeries[metric]
 		if !exist {
 			series = &tsdb.TimeSeries{Name: metric}
+			if cfg.metricIndex >= 0 {
+				series.Tags = map[string]string{"metric": metricValue}
+			}
 			cfg.pointsBySeries[metric] = series
+			cfg.columnBySeries[metric] = col
 			cfg.seriesByQueryOrder.PushBack(metric)
 		}
 
@@ -1,15 +1,22 @@
 package sqleng
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"testing"
 	"time"
 
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	_ "github.com/mattn/go-sqlite3"
 	"github.com/openinsight-project/grafinsight/pkg/components/null"
 	"github.com/openinsight-project/grafinsight/pkg/components/simplejson"
+	"github.com/openinsight-project/grafinsight/pkg/infra/log"
 	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/setting"
 	"github.com/openinsight-project/grafinsight/pkg/tsdb"
 	"github.com/stretchr/testify/require"
+	"xorm.io/core"
 )
 
 func TestSqlEngine(t *testing.T) {
@@ -310,3 +317,229 @@ func TestSqlEngine(t *testing.T) {
 		}
 	})
 }
+
+func TestQueryTimeout(t *testing.T) {
+	t.Run("Defaults to the data proxy timeout", func(t *testing.T) {
+		ds := &models.DataSource{JsonData: simplejson.New()}
+		require.Equal(t, time.Duration(0), ConfiguredQueryTimeout(ds))
+		require.Equal(t, time.Duration(setting.DataProxyTimeout)*time.Second, QueryTimeout(ds))
+	})
+
+	t.Run("Is configured on the datasource", func(t *testing.T) {
+		ds := &models.DataSource{JsonData: simplejson.NewFromAny(map[string]interface{}{"queryTimeout": 5})}
+		require.Equal(t, 5*time.Second, ConfiguredQueryTimeout(ds))
+		require.Equal(t, 5*time.Second, QueryTimeout(ds))
+	})
+}
+
+func TestSqlQueryEndpoint(t *testing.T) {
+	newEndpoint := func(t *testing.T, ds *models.DataSource) *sqlQueryEndpoint {
+		t.Helper()
+
+		endpoint, err := NewSqlQueryEndpoint(&SqlQueryEndpointConfiguration{
+			DriverName:       "sqlite3",
+			ConnectionString: ":memory:",
+			Datasource:       ds,
+		}, &testQueryResultTransformer{}, &testMacroEngine{}, log.New("test"))
+		require.NoError(t, err)
+		return endpoint.(*sqlQueryEndpoint)
+	}
+
+	newQuery := func(rawSQL string) *tsdb.TsdbQuery {
+		return &tsdb.TsdbQuery{
+			TimeRange: tsdb.NewTimeRange("1521118800000", "1521119100000"),
+			Queries: []*tsdb.Query{{
+				RefId:      "A",
+				DataSource: &models.DataSource{},
+				Model: simplejson.NewFromAny(map[string]interface{}{
+					"rawSql": rawSQL,
+					"format": "table",
+				}),
+			}},
+		}
+	}
+
+	t.Run("Should close the engine of a previous datasource version", func(t *testing.T) {
+		ds := &models.DataSource{Id: 100, Version: 1, JsonData: simplejson.New()}
+		first := newEndpoint(t, ds)
+		require.Same(t, first.engine, newEndpoint(t, ds).engine)
+
+		ds.Version = 2
+		second := newEndpoint(t, ds)
+		require.NotSame(t, first.engine, second.engine)
+		require.Error(t, first.engine.DB().Ping())
+		require.NoError(t, second.engine.DB().Ping())
+	})
+
+	t.Run("Should let queries in progress finish before closing the engine", func(t *testing.T) {
+		ds := &models.DataSource{Id: 102, Version: 1, JsonData: simplejson.New()}
+		first := newEndpoint(t, ds)
+		require.NoError(t, first.engine.acquire())
+
+		ds.Version = 2
+		newEndpoint(t, ds)
+		require.NoError(t, first.engine.DB().Ping())
+
+		first.engine.release(first.log)
+		require.Error(t, first.engine.DB().Ping())
+
+		_, err := first.Query(context.Background(), nil, newQuery("SELECT 1 AS value"))
+		require.Equal(t, errEngineClosed, err)
+	})
+
+	t.Run("Should run queries", func(t *testing.T) {
+		endpoint := newEndpoint(t, &models.DataSource{Id: 101, JsonData: simplejson.New()})
+
+		res, err := endpoint.Query(context.Background(), nil, newQuery("SELECT 1 AS value"))
+		require.NoError(t, err)
+		require.NoError(t, res.Results["A"].Error)
+		frames, err := res.Results["A"].Dataframes.Decoded()
+		require.NoError(t, err)
+		require.Len(t, frames, 1)
+		require.Equal(t, "A", frames[0].RefID)
+		require.Equal(t, "SELECT 1 AS value", frames[0].Meta.ExecutedQueryString)
+	})
+
+	t.Run("Should return tables as frames with typed fields", func(t *testing.T) {
+		endpoint := newEndpoint(t, &models.DataSource{Id: 104, JsonData: simplejson.New()})
+
+		res, err := endpoint.Query(context.Background(), nil, newQuery(
+			"SELECT 1521118800 AS time, 'a' AS host, 1.5 AS value, 3 AS count, NULL AS empty "+
+				"UNION ALL SELECT 1521118860, NULL, 2, 4, NULL"))
+		require.NoError(t, err)
+		require.NoError(t, res.Results["A"].Error)
+		require.Equal(t, 2, res.Results["A"].Meta.Get("rowCount").MustInt())
+
+		frames, err := res.Results["A"].Dataframes.Decoded()
+		require.NoError(t, err)
+		frame := frames[0]
+		require.Equal(t, 2, frame.Rows())
+
+		require.Equal(t, data.FieldTypeNullableTime, frame.Fields[0].Type())
+		require.Equal(t, time.Unix(1521118860, 0).UTC(), *frame.Fields[0].At(1).(*time.Time))
+		require.Equal(t, data.FieldTypeNullableString, frame.Fields[1].Type())
+		require.Equal(t, "a", *frame.Fields[1].At(0).(*string))
+		require.Nil(t, frame.Fields[1].At(1))
+		// Mixed types are returned as strings
+		require.Equal(t, data.FieldTypeNullableString, frame.Fields[2].Type())
+		require.Equal(t, "1.5", *frame.Fields[2].At(0).(*string))
+		require.Equal(t, data.FieldTypeNullableInt64, frame.Fields[3].Type())
+		require.Equal(t, int64(4), *frame.Fields[3].At(1).(*int64))
+		require.Nil(t, frame.Fields[4].At(0))
+	})
+
+	t.Run("Should return time series as a wide frame", func(t *testing.T) {
+		endpoint := newEndpoint(t, &models.DataSource{Id: 105, JsonData: simplejson.New()})
+
+		query := newQuery("SELECT 1521118800 AS time, 'a' AS metric, 1.0 AS value " +
+			"UNION ALL SELECT 1521118800, 'b', 2.0 " +
+			"UNION ALL SELECT 1521118860, 'a', 3.0")
+		query.Queries[0].Model.Set("format", "time_series")
+		res, err := endpoint.Query(context.Background(), nil, query)
+		require.NoError(t, err)
+		require.NoError(t, res.Results["A"].Error)
+		require.Empty(t, res.Results["A"].Series)
+
+		frames, err := res.Results["A"].Dataframes.Decoded()
+		require.NoError(t, err)
+		require.Len(t, frames, 1)
+		frame := frames[0]
+		require.Equal(t, data.TimeSeriesTypeWide, frame.TimeSeriesSchema().Type)
+		require.Len(t, frame.Fields, 3)
+		require.Equal(t, 2, frame.Rows())
+		require.Equal(t, time.Unix(1521118860, 0).UTC(), frame.Fields[0].At(1).(time.Time))
+
+		require.Equal(t, "value", frame.Fields[1].Name)
+		require.Equal(t, data.Labels{"metric": "a"}, frame.Fields[1].Labels)
+		require.Equal(t, "a", frame.Fields[1].Config.DisplayNameFromDS)
+		require.Equal(t, 3.0, *frame.Fields[1].At(1).(*float64))
+
+		require.Equal(t, "b", frame.Fields[2].Config.DisplayNameFromDS)
+		require.Equal(t, 2.0, *frame.Fields[2].At(0).(*float64))
+		require.Nil(t, frame.Fields[2].At(1))
+	})
+
+	t.Run("Should fill missing points of time series", func(t *testing.T) {
+		endpoint := newEndpoint(t, &models.DataSource{Id: 106, JsonData: simplejson.New()})
+
+		query := newQuery("SELECT 1521118860 AS time, 1.0 AS value")
+		query.Queries[0].Model.Set("format", "time_series")
+		query.Queries[0].Model.Set("fill", true)
+		query.Queries[0].Model.Set("fillInterval", 60)
+		query.Queries[0].Model.Set("fillMode", "value")
+		query.Queries[0].Model.Set("fillValue", 0)
+		res, err := endpoint.Query(context.Background(), nil, query)
+		require.NoError(t, err)
+		require.NoError(t, res.Results["A"].Error)
+
+		frames, err := res.Results["A"].Dataframes.Decoded()
+		require.NoError(t, err)
+		frame := frames[0]
+		require.Equal(t, 5, frame.Rows())
+		require.Equal(t, 0.0, *frame.Fields[1].At(0).(*float64))
+		require.Equal(t, 1.0, *frame.Fields[1].At(1).(*float64))
+		require.Equal(t, 0.0, *frame.Fields[1].At(4).(*float64))
+	})
+
+	t.Run("Should cancel queries with the request", func(t *testing.T) {
+		endpoint := newEndpoint(t, &models.DataSource{Id: 102, JsonData: simplejson.New()})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		res, err := endpoint.Query(ctx, nil, newQuery("SELECT 1 AS value"))
+		require.NoError(t, err)
+		require.EqualError(t, res.Results["A"].Error, "query was cancelled")
+	})
+
+	t.Run("Should let the database apply the timeout", func(t *testing.T) {
+		endpoint := newEndpoint(t, &models.DataSource{Id: 107, JsonData: simplejson.New()})
+		endpoint.queryTimeout = 5 * time.Second
+		var applied time.Duration
+		endpoint.applyQueryTimeout = func(rawSQL string, timeout time.Duration) string {
+			applied = timeout
+			return rawSQL + " LIMIT 0"
+		}
+
+		res, err := endpoint.Query(context.Background(), nil, newQuery("SELECT 1 AS value"))
+		require.NoError(t, err)
+		require.NoError(t, res.Results["A"].Error)
+		require.Equal(t, 5*time.Second, applied)
+		require.Equal(t, 0, res.Results["A"].Meta.Get("rowCount").MustInt())
+		require.Equal(t, "SELECT 1 AS value", res.Results["A"].Meta.Get(MetaKeyExecutedQueryString).MustString())
+	})
+
+	t.Run("Should stop queries after the timeout", func(t *testing.T) {
+		endpoint := newEndpoint(t, &models.DataSource{Id: 103, JsonData: simplejson.New()})
+		endpoint.queryTimeout = time.Millisecond
+
+		// Counts to a very large number
+		res, err := endpoint.Query(context.Background(), nil, newQuery(
+			"WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < 1000000000) SELECT max(x) AS value FROM c"))
+		require.NoError(t, err)
+		require.EqualError(t, res.Results["A"].Error, "query timed out after 1ms")
+	})
+}
+
+type testQueryResultTransformer struct{}
+
+func (t *testQueryResultTransformer) TransformQueryResult(columnTypes []*sql.ColumnType, rows *core.Rows) (tsdb.RowValues, error) {
+	values := make([]interface{}, len(columnTypes))
+	valuePtrs := make([]interface{}, len(columnTypes))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+	if err := rows.Scan(valuePtrs...); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func (t *testQueryResultTransformer) TransformQueryError(err error) error {
+	return err
+}
+
+type testMacroEngine struct{}
+
+func (m *testMacroEngine) Interpolate(query *tsdb.Query, timeRange *tsdb.TimeRange, sql string) (string, error) {
+	return sql, nil
+}
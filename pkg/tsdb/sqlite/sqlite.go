@@ -0,0 +1,185 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/openinsight-project/grafinsight/pkg/infra/log"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/setting"
+	"github.com/openinsight-project/grafinsight/pkg/tsdb"
+	"github.com/openinsight-project/grafinsight/pkg/tsdb/sqleng"
+	"xorm.io/core"
+)
+
+// driverName is the driver of the data sources, which cannot attach other
+// databases, as they could be outside the directory of SQLite data sources.
+const driverName = "sqlite3_datasource"
+
+func init() {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			conn.SetLimit(sqlite3.SQLITE_LIMIT_ATTACHED, 0)
+			conn.RegisterAuthorizer(func(action int, arg1, arg2, arg3 string) int {
+				if action == sqlite3.SQLITE_ATTACH {
+					return sqlite3.SQLITE_DENY
+				}
+				return sqlite3.SQLITE_OK
+			})
+			return nil
+		},
+	})
+	core.RegisterDriver(driverName, core.QueryDriver("sqlite3"))
+
+	tsdb.RegisterTsdbQueryEndpoint("sqlite", newSQLiteQueryEndpoint)
+}
+
+var timeColumnNames = []string{"time", "time_sec"}
+
+func newSQLiteQueryEndpoint(datasource *models.DataSource) (tsdb.TsdbQueryEndpoint, error) {
+	logger := log.New("tsdb.sqlite")
+
+	path, err := resolvePath(datasource.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	// The database is opened read-only, and the query_only pragma also
+	// prevents changes through temporary tables
+	cnnstr := "file:" + (&url.URL{Path: path}).EscapedPath() + "?mode=ro&_query_only=true"
+
+	if setting.Env == setting.Dev {
+		logger.Debug("getEngine", "connection", cnnstr)
+	}
+
+	// Column types are declared freely in SQLite, so the common names of text
+	// types are matched in upper and lower case
+	metricColumnTypes := []string{"TEXT", "VARCHAR", "CHAR", "NCHAR", "NVARCHAR", "CLOB"}
+	for _, columnType := range metricColumnTypes {
+		metricColumnTypes = append(metricColumnTypes, strings.ToLower(columnType))
+	}
+
+	config := sqleng.SqlQueryEndpointConfiguration{
+		DriverName:        driverName,
+		ConnectionString:  cnnstr,
+		Datasource:        datasource,
+		TimeColumnNames:   timeColumnNames,
+		MetricColumnTypes: metricColumnTypes,
+	}
+
+	rowTransformer := sqliteQueryResultTransformer{
+		log: logger,
+	}
+
+	return sqleng.NewSqlQueryEndpoint(&config, &rowTransformer, newSQLiteMacroEngine(), logger)
+}
+
+var errSQLiteDisabled = errors.New("SQLite data sources are disabled, set sqlite_path in the [datasources] section of the configuration to enable them")
+
+// resolvePath returns the path of the database file of a datasource, which
+// must be in the directory of SQLite data sources. Relative paths are relative
+// to that directory.
+func resolvePath(file string) (string, error) {
+	if setting.SQLiteDatasourcesPath == "" {
+		return "", errSQLiteDisabled
+	}
+	if file == "" {
+		return "", errors.New("missing path of the database file")
+	}
+
+	dir, err := filepath.EvalSymlinks(setting.SQLiteDatasourcesPath)
+	if err != nil {
+		return "", fmt.Errorf("invalid directory of SQLite data sources: %w", err)
+	}
+	dir, err = filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	path := file
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	// Symlinks are resolved so that they cannot point outside the directory
+	path, err = filepath.EvalSymlinks(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("database file %q not found", file)
+		}
+		return "", err
+	}
+
+	rel, err := filepath.Rel(dir, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("database file %q is not in the directory of SQLite data sources", file)
+	}
+
+	return path, nil
+}
+
+type sqliteQueryResultTransformer struct {
+	log log.Logger
+}
+
+func (t *sqliteQueryResultTransformer) TransformQueryResult(columnTypes []*sql.ColumnType, rows *core.Rows) (tsdb.RowValues, error) {
+	values := make([]interface{}, len(columnTypes))
+	valuePtrs := make([]interface{}, len(columnTypes))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+
+	if err := rows.Scan(valuePtrs...); err != nil {
+		return nil, err
+	}
+
+	for i, columnType := range columnTypes {
+		switch v := values[i].(type) {
+		case []byte:
+			values[i] = string(v)
+		case string:
+			// Only columns declared with a date type are returned as time, so
+			// the text of time columns computed by the query is parsed
+			if isTimeColumn(columnType.Name()) {
+				if parsed, ok := parseTime(v); ok {
+					values[i] = parsed
+				}
+			}
+		}
+	}
+
+	return values, nil
+}
+
+func isTimeColumn(name string) bool {
+	if name == "timeend" {
+		return true
+	}
+	for _, timeColumnName := range timeColumnNames {
+		if name == timeColumnName {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTime parses a time in one of the formats of the SQLite date functions.
+func parseTime(s string) (time.Time, bool) {
+	s = strings.TrimSuffix(s, "Z")
+	for _, format := range sqlite3.SQLiteTimestampFormats {
+		if t, err := time.ParseInLocation(format, s, time.UTC); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func (t *sqliteQueryResultTransformer) TransformQueryError(err error) error {
+	return err
+}
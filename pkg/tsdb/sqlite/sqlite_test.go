@@ -0,0 +1,196 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/openinsight-project/grafinsight/pkg/components/simplejson"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/setting"
+	"github.com/openinsight-project/grafinsight/pkg/tsdb"
+	"github.com/stretchr/testify/require"
+)
+
+// setupDatasourcesPath sets the directory of SQLite data sources to a
+// temporary directory, and returns it.
+func setupDatasourcesPath(t *testing.T) string {
+	t.Helper()
+
+	origPath := setting.SQLiteDatasourcesPath
+	t.Cleanup(func() {
+		setting.SQLiteDatasourcesPath = origPath
+	})
+	dir := t.TempDir()
+	setting.SQLiteDatasourcesPath = dir
+	return dir
+}
+
+func TestResolvePath(t *testing.T) {
+	t.Run("Should be disabled without a directory", func(t *testing.T) {
+		setupDatasourcesPath(t)
+		setting.SQLiteDatasourcesPath = ""
+
+		_, err := resolvePath("metrics.db")
+		require.Equal(t, errSQLiteDisabled, err)
+	})
+
+	t.Run("Should resolve files in the directory", func(t *testing.T) {
+		dir := setupDatasourcesPath(t)
+		dir, err := filepath.EvalSymlinks(dir)
+		require.NoError(t, err)
+		require.NoError(t, os.Mkdir(filepath.Join(dir, "edge"), 0750))
+		file := filepath.Join(dir, "edge", "metrics.db")
+		require.NoError(t, ioutil.WriteFile(file, nil, 0600))
+
+		path, err := resolvePath("edge/metrics.db")
+		require.NoError(t, err)
+		require.Equal(t, file, path)
+
+		path, err = resolvePath(file)
+		require.NoError(t, err)
+		require.Equal(t, file, path)
+	})
+
+	t.Run("Should not resolve files outside the directory", func(t *testing.T) {
+		dir := setupDatasourcesPath(t)
+		outside := filepath.Join(t.TempDir(), "secret.db")
+		require.NoError(t, ioutil.WriteFile(outside, nil, 0600))
+		require.NoError(t, os.Symlink(outside, filepath.Join(dir, "link.db")))
+
+		_, err := resolvePath(outside)
+		require.EqualError(t, err, `database file "`+outside+`" is not in the directory of SQLite data sources`)
+
+		_, err = resolvePath("../" + filepath.Base(filepath.Dir(outside)) + "/secret.db")
+		require.Error(t, err)
+
+		_, err = resolvePath("link.db")
+		require.EqualError(t, err, `database file "link.db" is not in the directory of SQLite data sources`)
+	})
+
+	t.Run("Should return an error for missing files", func(t *testing.T) {
+		setupDatasourcesPath(t)
+
+		_, err := resolvePath("missing.db")
+		require.EqualError(t, err, `database file "missing.db" not found`)
+
+		_, err = resolvePath("")
+		require.Error(t, err)
+	})
+}
+
+func TestSQLite(t *testing.T) {
+	dir := setupDatasourcesPath(t)
+
+	db, err := sql.Open("sqlite3", filepath.Join(dir, "metrics.db"))
+	require.NoError(t, err)
+	_, err = db.Exec(`
+		CREATE TABLE metric (time DATETIME, text_time TEXT, epoch INTEGER, host TEXT, value REAL);
+		INSERT INTO metric VALUES
+			('2018-03-15 13:00:00', '2018-03-15T13:00:00Z', 1521118800, 'a', 1),
+			('2018-03-15 13:00:30', '2018-03-15T13:00:30Z', 1521118830, 'b', 2),
+			('2018-03-15 13:01:00', '2018-03-15T13:01:00Z', 1521118860, 'a', 3),
+			('2018-03-15 14:00:00', '2018-03-15T14:00:00Z', 1521122400, 'a', 4);
+	`)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	ds := &models.DataSource{Id: 1, Database: "metrics.db", JsonData: simplejson.New()}
+	endpoint, err := newSQLiteQueryEndpoint(ds)
+	require.NoError(t, err)
+
+	query := func(t *testing.T, rawSQL string, format string) *tsdb.QueryResult {
+		t.Helper()
+
+		resp, err := endpoint.Query(context.Background(), ds, &tsdb.TsdbQuery{
+			TimeRange: tsdb.NewTimeRange("1521118800000", "1521119100000"),
+			Queries: []*tsdb.Query{{
+				RefId:      "A",
+				DataSource: ds,
+				Model: simplejson.NewFromAny(map[string]interface{}{
+					"rawSql": rawSQL,
+					"format": format,
+				}),
+			}},
+		})
+		require.NoError(t, err)
+		return resp.Results["A"]
+	}
+
+	t.Run("Should return time series grouped by time", func(t *testing.T) {
+		queryResult := query(t, `SELECT $__timeGroupAlias(text_time, 1m), host AS metric, sum(value) AS value
+			FROM metric WHERE $__timeFilter(text_time) GROUP BY 1, 2 ORDER BY 1`, "time_series")
+		require.NoError(t, queryResult.Error)
+
+		frames, err := queryResult.Dataframes.Decoded()
+		require.NoError(t, err)
+		require.Len(t, frames, 1)
+		frame := frames[0]
+		require.Len(t, frame.Fields, 3)
+		require.Equal(t, 2, frame.Rows())
+		require.Equal(t, time.Date(2018, 3, 15, 13, 1, 0, 0, time.UTC), frame.Fields[0].At(1).(time.Time))
+		require.Equal(t, "a", frame.Fields[1].Config.DisplayNameFromDS)
+		require.Equal(t, 3.0, *frame.Fields[1].At(1).(*float64))
+		require.Equal(t, "b", frame.Fields[2].Config.DisplayNameFromDS)
+		require.Equal(t, 2.0, *frame.Fields[2].At(0).(*float64))
+	})
+
+	t.Run("Should return time series of unix timestamps", func(t *testing.T) {
+		queryResult := query(t, `SELECT $__unixEpochGroupAlias(epoch, 5m), count(*) AS value
+			FROM metric WHERE $__unixEpochFilter(epoch) GROUP BY 1 ORDER BY 1`, "time_series")
+		require.NoError(t, queryResult.Error)
+
+		frames, err := queryResult.Dataframes.Decoded()
+		require.NoError(t, err)
+		frame := frames[0]
+		require.Equal(t, 1, frame.Rows())
+		require.Equal(t, time.Date(2018, 3, 15, 13, 0, 0, 0, time.UTC), frame.Fields[0].At(0).(time.Time))
+		require.Equal(t, 3.0, *frame.Fields[1].At(0).(*float64))
+	})
+
+	t.Run("Should return tables with time columns parsed", func(t *testing.T) {
+		queryResult := query(t, `SELECT time, datetime(text_time) AS timeend, host, value
+			FROM metric WHERE $__timeFilter(time) ORDER BY time`, "table")
+		require.NoError(t, queryResult.Error)
+
+		frames, err := queryResult.Dataframes.Decoded()
+		require.NoError(t, err)
+		frame := frames[0]
+		require.Equal(t, 3, frame.Rows())
+		require.Equal(t, time.Date(2018, 3, 15, 13, 0, 30, 0, time.UTC), *frame.Fields[0].At(1).(*time.Time))
+		require.Equal(t, time.Date(2018, 3, 15, 13, 0, 30, 0, time.UTC), *frame.Fields[1].At(1).(*time.Time))
+		require.Equal(t, "b", *frame.Fields[2].At(1).(*string))
+		require.Equal(t, 2.0, *frame.Fields[3].At(1).(*float64))
+	})
+
+	t.Run("Should not change the database", func(t *testing.T) {
+		queryResult := query(t, `DELETE FROM metric`, "table")
+		require.Error(t, queryResult.Error)
+
+		queryResult = query(t, `SELECT count(*) AS count FROM metric`, "table")
+		require.NoError(t, queryResult.Error)
+		frames, err := queryResult.Dataframes.Decoded()
+		require.NoError(t, err)
+		require.Equal(t, int64(4), *frames[0].Fields[0].At(0).(*int64))
+	})
+
+	t.Run("Should not attach other databases", func(t *testing.T) {
+		outside := filepath.Join(t.TempDir(), "grafinsight.db")
+		db, err := sql.Open("sqlite3", outside)
+		require.NoError(t, err)
+		_, err = db.Exec(`CREATE TABLE secret (value TEXT); INSERT INTO secret VALUES ('password')`)
+		require.NoError(t, err)
+		require.NoError(t, db.Close())
+
+		queryResult := query(t, fmt.Sprintf(`ATTACH DATABASE '%s' AS other`, outside), "table")
+		require.Error(t, queryResult.Error)
+
+		queryResult = query(t, `SELECT value FROM other.secret`, "table")
+		require.Error(t, queryResult.Error)
+	})
+}
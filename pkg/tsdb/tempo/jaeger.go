@@ -0,0 +1,99 @@
+package tempo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/tsdb"
+
+	jaeger_ui "github.com/jaegertracing/jaeger/model/json"
+)
+
+// jaegerExecutor queries traces with the HTTP API of the Jaeger query
+// service, which returns them in the JSON format of the Jaeger UI.
+type jaegerExecutor struct {
+	httpClient *http.Client
+}
+
+// NewJaegerExecutor returns a jaegerExecutor.
+func NewJaegerExecutor(dsInfo *models.DataSource) (tsdb.TsdbQueryEndpoint, error) {
+	httpClient, err := dsInfo.GetHttpClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return &jaegerExecutor{
+		httpClient: httpClient,
+	}, nil
+}
+
+func init() {
+	tsdb.RegisterTsdbQueryEndpoint("jaeger", NewJaegerExecutor)
+}
+
+func (e *jaegerExecutor) Query(ctx context.Context, dsInfo *models.DataSource, queryContext *tsdb.TsdbQuery) (*tsdb.Response, error) {
+	return queryTraces(ctx, e, dsInfo, queryContext)
+}
+
+type jaegerResponse struct {
+	Data []jaeger_ui.Trace `json:"data"`
+}
+
+func (e *jaegerExecutor) getTrace(ctx context.Context, dsInfo *models.DataSource, traceID string) (*jaeger_ui.Trace, error) {
+	var response jaegerResponse
+	if err := getJSON(ctx, e.httpClient, dsInfo, "api/traces/"+traceID, nil, &response); err != nil {
+		return nil, traceError(traceID, err)
+	}
+	if len(response.Data) == 0 {
+		return nil, fmt.Errorf("failed to get trace with id: %s, trace not found", traceID)
+	}
+	return &response.Data[0], nil
+}
+
+// search searches traces with the API of the Jaeger UI, which requires the
+// service.
+func (e *jaegerExecutor) search(ctx context.Context, dsInfo *models.DataSource, query *searchQuery, timeRange *tsdb.TimeRange) ([]traceSummary, error) {
+	if query.Service == "" {
+		return nil, errors.New("the service is required to search Jaeger traces")
+	}
+
+	params := url.Values{
+		"service": []string{query.Service},
+		"limit":   []string{strconv.Itoa(query.Limit)},
+		"start":   []string{strconv.FormatInt(timeRange.GetFromAsMsEpoch()*1000, 10)},
+		"end":     []string{strconv.FormatInt(timeRange.GetToAsMsEpoch()*1000, 10)},
+	}
+	if query.Operation != "" {
+		params.Set("operation", query.Operation)
+	}
+	if len(query.Tags) > 0 {
+		tags, err := json.Marshal(query.Tags)
+		if err != nil {
+			return nil, err
+		}
+		params.Set("tags", string(tags))
+	}
+	if query.MinDuration > 0 {
+		params.Set("minDuration", query.MinDuration.String())
+	}
+	if query.MaxDuration > 0 {
+		params.Set("maxDuration", query.MaxDuration.String())
+	}
+
+	var response jaegerResponse
+	if err := getJSON(ctx, e.httpClient, dsInfo, "api/traces", params, &response); err != nil {
+		return nil, fmt.Errorf("failed to search traces: %w", err)
+	}
+
+	summaries := make([]traceSummary, 0, len(response.Data))
+	for i := range response.Data {
+		summaries = append(summaries, summarizeTrace(&response.Data[i]))
+	}
+	return summaries, nil
+}
@@ -0,0 +1,86 @@
+package tempo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/tsdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const jaegerTrace = `{"data":[{
+	"traceID": "abc",
+	"spans": [
+		{"traceID": "abc", "spanID": "1", "operationName": "GET /", "references": [], "startTime": 1521118800000000, "duration": 2000, "processID": "p1"},
+		{"traceID": "abc", "spanID": "2", "operationName": "query", "references": [{"refType": "CHILD_OF", "traceID": "abc", "spanID": "1"}], "startTime": 1521118800000500, "duration": 1000, "processID": "p2"}
+	],
+	"processes": {"p1": {"serviceName": "frontend"}, "p2": {"serviceName": "database"}}
+}]}`
+
+func TestJaeger(t *testing.T) {
+	var requests []*http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		requests = append(requests, req)
+		switch req.URL.Path {
+		case "/jaeger/api/traces/abc", "/jaeger/api/traces":
+			_, _ = rw.Write([]byte(jaegerTrace))
+		default:
+			http.Error(rw, "trace not found", http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	dsInfo := &models.DataSource{Url: srv.URL + "/jaeger"}
+	plug, err := NewJaegerExecutor(dsInfo)
+	require.NoError(t, err)
+	executor := plug.(*jaegerExecutor)
+
+	t.Run("Should get traces", func(t *testing.T) {
+		trace, err := executor.getTrace(context.Background(), dsInfo, "abc")
+		require.NoError(t, err)
+		require.Len(t, trace.Spans, 2)
+		assert.Equal(t, "frontend", trace.Processes["p1"].ServiceName)
+
+		_, err = executor.getTrace(context.Background(), dsInfo, "def")
+		require.EqualError(t, err, "failed to get trace with id: def Status: 404 Not Found Body: trace not found\n")
+	})
+
+	t.Run("Should search traces", func(t *testing.T) {
+		summaries, err := executor.search(context.Background(), dsInfo, &searchQuery{
+			Service:     "frontend",
+			Operation:   "GET /",
+			Tags:        map[string]string{"error": "true"},
+			MinDuration: 100 * time.Millisecond,
+			Limit:       10,
+		}, tsdb.NewTimeRange("1521118800000", "1521119100000"))
+		require.NoError(t, err)
+		assert.Equal(t, []traceSummary{{
+			TraceID:     "abc",
+			ServiceName: "frontend",
+			TraceName:   "GET /",
+			StartTime:   time.Date(2018, 3, 15, 13, 0, 0, 0, time.UTC),
+			Duration:    2 * time.Millisecond,
+		}}, summaries)
+
+		assert.Equal(t, url.Values{
+			"service":     []string{"frontend"},
+			"operation":   []string{"GET /"},
+			"tags":        []string{`{"error":"true"}`},
+			"minDuration": []string{"100ms"},
+			"limit":       []string{"10"},
+			"start":       []string{"1521118800000000"},
+			"end":         []string{"1521119100000000"},
+		}, requests[len(requests)-1].URL.Query())
+	})
+
+	t.Run("Should require the service to search traces", func(t *testing.T) {
+		_, err := executor.search(context.Background(), dsInfo, &searchQuery{Limit: 10}, tsdb.NewTimeRange("5m", "now"))
+		require.Error(t, err)
+	})
+}
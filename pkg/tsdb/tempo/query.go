@@ -0,0 +1,347 @@
+package tempo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/openinsight-project/grafinsight/pkg/components/simplejson"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/tsdb"
+
+	jaeger_ui "github.com/jaegertracing/jaeger/model/json"
+)
+
+const (
+	// queryTypeTraceID queries a trace by its ID, and is the default
+	queryTypeTraceID = "traceId"
+	// queryTypeSearch searches traces, returning their summaries
+	queryTypeSearch = "search"
+)
+
+// traceBackend fetches traces from the API of a tracing system, and searches
+// them.
+type traceBackend interface {
+	getTrace(ctx context.Context, dsInfo *models.DataSource, traceID string) (*jaeger_ui.Trace, error)
+	search(ctx context.Context, dsInfo *models.DataSource, query *searchQuery, timeRange *tsdb.TimeRange) ([]traceSummary, error)
+}
+
+// searchQuery is a search of traces. The tags are in logfmt, like
+// error=true http.status_code=500, and the durations are Go durations.
+type searchQuery struct {
+	Service     string
+	Operation   string
+	Tags        map[string]string
+	MinDuration time.Duration
+	MaxDuration time.Duration
+	Limit       int
+}
+
+// traceSummary is a trace found by a search.
+type traceSummary struct {
+	TraceID     string
+	ServiceName string
+	TraceName   string
+	StartTime   time.Time
+	Duration    time.Duration
+}
+
+const defaultSearchLimit = 20
+
+func parseSearchQuery(model *simplejson.Json) (*searchQuery, error) {
+	query := &searchQuery{
+		Service:   model.Get("service").MustString(),
+		Operation: model.Get("operation").MustString(),
+		Limit:     model.Get("limit").MustInt(defaultSearchLimit),
+	}
+
+	var err error
+	if query.Tags, err = parseTags(model.Get("tags").MustString()); err != nil {
+		return nil, err
+	}
+	if query.MinDuration, err = parseDuration(model.Get("minDuration").MustString()); err != nil {
+		return nil, fmt.Errorf("invalid minimum duration: %w", err)
+	}
+	if query.MaxDuration, err = parseDuration(model.Get("maxDuration").MustString()); err != nil {
+		return nil, fmt.Errorf("invalid maximum duration: %w", err)
+	}
+	if query.Limit <= 0 {
+		return nil, fmt.Errorf("invalid limit %d, must be positive", query.Limit)
+	}
+	return query, nil
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// parseTags parses tags in logfmt, where values with spaces are quoted.
+func parseTags(s string) (map[string]string, error) {
+	tags := map[string]string{}
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		i := strings.IndexAny(s, "= ")
+		if i <= 0 || s[i] != '=' {
+			return nil, fmt.Errorf("invalid tags %q, must be key=value pairs", s)
+		}
+		key := s[:i]
+		s = s[i+1:]
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			end := strings.Index(s[1:], `"`)
+			if end < 0 {
+				return nil, fmt.Errorf("invalid tags, missing closing quote of the value of %s", key)
+			}
+			value = s[1 : end+1]
+			s = s[end+2:]
+		} else {
+			end := strings.IndexByte(s, ' ')
+			if end < 0 {
+				end = len(s)
+			}
+			value = s[:end]
+			s = s[end:]
+		}
+		tags[key] = value
+	}
+	return tags, nil
+}
+
+// sortedKeys returns the keys of tags in order, so that requests are
+// deterministic.
+func sortedKeys(tags map[string]string) []string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// queryTraces runs the queries of a request with a trace backend. Errors of
+// queries are returned in their results.
+func queryTraces(ctx context.Context, backend traceBackend, dsInfo *models.DataSource, tsdbQuery *tsdb.TsdbQuery) (*tsdb.Response, error) {
+	response := &tsdb.Response{Results: map[string]*tsdb.QueryResult{}}
+
+	for _, query := range tsdbQuery.Queries {
+		queryResult := &tsdb.QueryResult{RefId: query.RefId}
+		frames, err := runQuery(ctx, backend, dsInfo, query, tsdbQuery.TimeRange)
+		if err != nil {
+			queryResult.Error = err
+		} else {
+			queryResult.Dataframes = tsdb.NewDecodedDataFrames(frames)
+		}
+		response.Results[query.RefId] = queryResult
+	}
+
+	return response, nil
+}
+
+func runQuery(ctx context.Context, backend traceBackend, dsInfo *models.DataSource, query *tsdb.Query, timeRange *tsdb.TimeRange) (data.Frames, error) {
+	switch queryType := query.Model.Get("queryType").MustString(queryTypeTraceID); queryType {
+	case queryTypeTraceID:
+		traceID := query.Model.Get("query").MustString("")
+		trace, err := backend.getTrace(ctx, dsInfo, traceID)
+		if err != nil {
+			return nil, err
+		}
+
+		traceBytes, err := json.Marshal(trace)
+		if err != nil {
+			return nil, fmt.Errorf("failed to json.Marshal trace \"%s\" :%w", traceID, err)
+		}
+
+		return data.Frames{
+			{Name: "Traces", RefID: query.RefId, Fields: []*data.Field{data.NewField("trace", nil, []string{string(traceBytes)})}},
+		}, nil
+	case queryTypeSearch:
+		if timeRange == nil {
+			return nil, errors.New("missing time range of the search")
+		}
+		search, err := parseSearchQuery(query.Model)
+		if err != nil {
+			return nil, err
+		}
+		summaries, err := backend.search(ctx, dsInfo, search, timeRange)
+		if err != nil {
+			return nil, err
+		}
+		return data.Frames{summariesToFrame(query.RefId, summaries)}, nil
+	default:
+		return nil, fmt.Errorf("unknown query type %q", queryType)
+	}
+}
+
+func summariesToFrame(refID string, summaries []traceSummary) *data.Frame {
+	traceIDs := make([]string, len(summaries))
+	serviceNames := make([]string, len(summaries))
+	traceNames := make([]string, len(summaries))
+	startTimes := make([]time.Time, len(summaries))
+	durations := make([]float64, len(summaries))
+	for i, summary := range summaries {
+		traceIDs[i] = summary.TraceID
+		serviceNames[i] = summary.ServiceName
+		traceNames[i] = summary.TraceName
+		startTimes[i] = summary.StartTime
+		durations[i] = float64(summary.Duration) / float64(time.Millisecond)
+	}
+
+	durationField := data.NewField("duration", nil, durations)
+	durationField.Config = &data.FieldConfig{Unit: "ms"}
+	frame := data.NewFrame("Traces",
+		data.NewField("traceID", nil, traceIDs),
+		data.NewField("serviceName", nil, serviceNames),
+		data.NewField("traceName", nil, traceNames),
+		data.NewField("startTime", nil, startTimes),
+		durationField,
+	)
+	frame.RefID = refID
+	frame.Meta = &data.FrameMeta{PreferredVisualization: data.VisTypeTable}
+	return frame
+}
+
+// summarizeTrace returns the summary of a trace, which is named after its
+// root span.
+func summarizeTrace(trace *jaeger_ui.Trace) traceSummary {
+	summary := traceSummary{TraceID: string(trace.TraceID)}
+	if len(trace.Spans) == 0 {
+		return summary
+	}
+
+	spanIDs := make(map[jaeger_ui.SpanID]bool, len(trace.Spans))
+	for _, span := range trace.Spans {
+		spanIDs[span.SpanID] = true
+	}
+
+	var root *jaeger_ui.Span
+	var start, end uint64
+	for i := range trace.Spans {
+		span := &trace.Spans[i]
+		if i == 0 || span.StartTime < start {
+			start = span.StartTime
+		}
+		if span.StartTime+span.Duration > end {
+			end = span.StartTime + span.Duration
+		}
+
+		isRoot := true
+		for _, ref := range span.References {
+			if ref.RefType == jaeger_ui.ChildOf && spanIDs[ref.SpanID] {
+				isRoot = false
+			}
+		}
+		if isRoot && (root == nil || span.StartTime < root.StartTime) {
+			root = span
+		}
+	}
+	if root == nil {
+		root = &trace.Spans[0]
+	}
+
+	summary.TraceName = root.OperationName
+	if process, ok := trace.Processes[root.ProcessID]; ok {
+		summary.ServiceName = process.ServiceName
+	} else if root.Process != nil {
+		summary.ServiceName = root.Process.ServiceName
+	}
+	summary.StartTime = time.Unix(0, int64(start)*int64(time.Microsecond)).UTC()
+	summary.Duration = time.Duration(end-start) * time.Microsecond
+	return summary
+}
+
+// statusError is an error response of the API of a tracing system.
+type statusError struct {
+	Status string
+	Body   string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("Status: %s Body: %s", e.Status, e.Body)
+}
+
+// newRequest returns a GET request to the API of a data source.
+func newRequest(ctx context.Context, dsInfo *models.DataSource, apiPath string, params url.Values) (*http.Request, error) {
+	u, err := url.Parse(dsInfo.Url)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = path.Join(u.Path, apiPath)
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if dsInfo.BasicAuth {
+		req.SetBasicAuth(dsInfo.BasicAuthUser, dsInfo.DecryptedBasicAuthPassword())
+	}
+
+	return req, nil
+}
+
+// getJSON sends a GET request to the API of a data source, and decodes its
+// JSON response.
+func getJSON(ctx context.Context, httpClient *http.Client, dsInfo *models.DataSource, apiPath string, params url.Values, v interface{}) error {
+	req, err := newRequest(ctx, dsInfo, apiPath, params)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	tlog.Debug("Trace request", "url", req.URL.String())
+
+	body, err := doRequest(httpClient, req)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return nil
+}
+
+// doRequest sends a request, and returns the body of its response.
+func doRequest(httpClient *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed get to %s: %w", req.URL.Host, err)
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			tlog.Warn("failed to close response body", "err", err)
+		}
+	}()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{Status: resp.Status, Body: string(body)}
+	}
+	return body, nil
+}
+
+// traceError returns the error of a request for a trace.
+func traceError(traceID string, err error) error {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return fmt.Errorf("failed to get trace with id: %s %s", traceID, statusErr.Error())
+	}
+	return err
+}
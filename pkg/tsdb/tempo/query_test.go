@@ -0,0 +1,144 @@
+package tempo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/openinsight-project/grafinsight/pkg/components/simplejson"
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/tsdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	jaeger_ui "github.com/jaegertracing/jaeger/model/json"
+)
+
+func TestParseTags(t *testing.T) {
+	tags, err := parseTags(`error=true  http.url="/api/v1 users" http.status_code=500`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"error": "true", "http.url": "/api/v1 users", "http.status_code": "500"}, tags)
+
+	tags, err = parseTags("")
+	require.NoError(t, err)
+	assert.Empty(t, tags)
+
+	_, err = parseTags("error")
+	require.Error(t, err)
+	_, err = parseTags(`http.url="/api`)
+	require.Error(t, err)
+}
+
+func TestParseSearchQuery(t *testing.T) {
+	query, err := parseSearchQuery(simplejson.NewFromAny(map[string]interface{}{
+		"service":     "frontend",
+		"operation":   "GET /",
+		"tags":        "error=true",
+		"minDuration": "100ms",
+		"maxDuration": "2s",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, &searchQuery{
+		Service:     "frontend",
+		Operation:   "GET /",
+		Tags:        map[string]string{"error": "true"},
+		MinDuration: 100 * time.Millisecond,
+		MaxDuration: 2 * time.Second,
+		Limit:       defaultSearchLimit,
+	}, query)
+
+	_, err = parseSearchQuery(simplejson.NewFromAny(map[string]interface{}{"minDuration": "1 second"}))
+	require.Error(t, err)
+	_, err = parseSearchQuery(simplejson.NewFromAny(map[string]interface{}{"limit": -1}))
+	require.Error(t, err)
+}
+
+func TestSummarizeTrace(t *testing.T) {
+	trace := &jaeger_ui.Trace{
+		TraceID: "abc",
+		Spans: []jaeger_ui.Span{
+			{
+				SpanID:        "2",
+				OperationName: "query",
+				StartTime:     1500,
+				Duration:      5000,
+				ProcessID:     "p2",
+				References:    []jaeger_ui.Reference{{RefType: jaeger_ui.ChildOf, SpanID: "1"}},
+			},
+			{SpanID: "1", OperationName: "GET /", StartTime: 1000, Duration: 3000, ProcessID: "p1"},
+		},
+		Processes: map[jaeger_ui.ProcessID]jaeger_ui.Process{
+			"p1": {ServiceName: "frontend"},
+			"p2": {ServiceName: "database"},
+		},
+	}
+
+	assert.Equal(t, traceSummary{
+		TraceID:     "abc",
+		ServiceName: "frontend",
+		TraceName:   "GET /",
+		StartTime:   time.Unix(0, 1000*int64(time.Microsecond)).UTC(),
+		Duration:    5500 * time.Microsecond,
+	}, summarizeTrace(trace))
+}
+
+type fakeTraceBackend struct {
+	trace     *jaeger_ui.Trace
+	summaries []traceSummary
+	query     *searchQuery
+}
+
+func (b *fakeTraceBackend) getTrace(ctx context.Context, dsInfo *models.DataSource, traceID string) (*jaeger_ui.Trace, error) {
+	return b.trace, nil
+}
+
+func (b *fakeTraceBackend) search(ctx context.Context, dsInfo *models.DataSource, query *searchQuery, timeRange *tsdb.TimeRange) ([]traceSummary, error) {
+	b.query = query
+	return b.summaries, nil
+}
+
+func TestQueryTraces(t *testing.T) {
+	backend := &fakeTraceBackend{
+		trace: &jaeger_ui.Trace{TraceID: "abc"},
+		summaries: []traceSummary{
+			{TraceID: "abc", ServiceName: "frontend", TraceName: "GET /", StartTime: time.Unix(1, 0).UTC(), Duration: 1500 * time.Microsecond},
+		},
+	}
+
+	resp, err := queryTraces(context.Background(), backend, &models.DataSource{}, &tsdb.TsdbQuery{
+		TimeRange: tsdb.NewTimeRange("1521118800000", "1521119100000"),
+		Queries: []*tsdb.Query{
+			{RefId: "A", Model: simplejson.NewFromAny(map[string]interface{}{"query": "abc"})},
+			{RefId: "B", Model: simplejson.NewFromAny(map[string]interface{}{"queryType": "search", "service": "frontend"})},
+			{RefId: "C", Model: simplejson.NewFromAny(map[string]interface{}{"queryType": "logs"})},
+		},
+	})
+	require.NoError(t, err)
+
+	t.Run("Should return the trace as JSON", func(t *testing.T) {
+		frames, err := resp.Results["A"].Dataframes.Decoded()
+		require.NoError(t, err)
+		require.Len(t, frames, 1)
+		require.Equal(t, "trace", frames[0].Fields[0].Name)
+		assert.JSONEq(t, `{"traceID":"abc","spans":null,"processes":null,"warnings":null}`, frames[0].Fields[0].At(0).(string))
+	})
+
+	t.Run("Should return the summaries of a search", func(t *testing.T) {
+		require.NoError(t, resp.Results["B"].Error)
+		assert.Equal(t, "frontend", backend.query.Service)
+
+		frames, err := resp.Results["B"].Dataframes.Decoded()
+		require.NoError(t, err)
+		frame := frames[0]
+		require.Equal(t, 1, frame.Rows())
+		assert.Equal(t, "abc", frame.Fields[0].At(0))
+		assert.Equal(t, "frontend", frame.Fields[1].At(0))
+		assert.Equal(t, "GET /", frame.Fields[2].At(0))
+		assert.Equal(t, time.Unix(1, 0).UTC(), frame.Fields[3].At(0))
+		assert.Equal(t, 1.5, frame.Fields[4].At(0))
+	})
+
+	t.Run("Should return an error for unknown query types", func(t *testing.T) {
+		require.EqualError(t, resp.Results["C"].Error, `unknown query type "logs"`)
+	})
+}
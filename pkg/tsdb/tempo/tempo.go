@@ -2,18 +2,20 @@ package tempo
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
-	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/openinsight-project/grafinsight/pkg/infra/log"
 	"github.com/openinsight-project/grafinsight/pkg/models"
 	"github.com/openinsight-project/grafinsight/pkg/tsdb"
 
 	jaeger "github.com/jaegertracing/jaeger/model"
 	jaeger_json "github.com/jaegertracing/jaeger/model/converter/json"
+	jaeger_ui "github.com/jaegertracing/jaeger/model/json"
 
 	ot_pdata "go.opentelemetry.io/collector/consumer/pdata"
 	ot_jaeger "go.opentelemetry.io/collector/translator/trace/jaeger"
@@ -46,38 +48,18 @@ func init() {
 
 func (e *tempoExecutor) Query(ctx context.Context, dsInfo *models.DataSource,
 	queryContext *tsdb.TsdbQuery) (*tsdb.Response, error) {
-	refID := queryContext.Queries[0].RefId
-	queryResult := &tsdb.QueryResult{}
-	traceID := queryContext.Queries[0].Model.Get("query").MustString("")
+	return queryTraces(ctx, e, dsInfo, queryContext)
+}
 
+func (e *tempoExecutor) getTrace(ctx context.Context, dsInfo *models.DataSource, traceID string) (*jaeger_ui.Trace, error) {
 	req, err := e.createRequest(ctx, dsInfo, traceID)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := e.httpClient.Do(req)
+	body, err := doRequest(e.httpClient, req)
 	if err != nil {
-		return nil, fmt.Errorf("failed get to tempo: %w", err)
-	}
-
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			tlog.Warn("failed to close response body", "err", err)
-		}
-	}()
-
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		queryResult.ErrorString = fmt.Sprintf("failed to get trace with id: %s Status: %s Body: %s", traceID, resp.Status, string(body))
-		return &tsdb.Response{
-			Results: map[string]*tsdb.QueryResult{
-				refID: queryResult,
-			},
-		}, nil
+		return nil, traceError(traceID, err)
 	}
 
 	otTrace := ot_pdata.NewTraces()
@@ -108,35 +90,78 @@ func (e *tempoExecutor) Query(ctx context.Context, dsInfo *models.DataSource,
 			ProcessID: batch.Process.ServiceName,
 		})
 	}
-	jsonTrace := jaeger_json.FromDomain(jaegerTrace)
+	return jaeger_json.FromDomain(jaegerTrace), nil
+}
 
-	traceBytes, err := json.Marshal(jsonTrace)
-	if err != nil {
-		return nil, fmt.Errorf("failed to json.Marshal trace \"%s\" :%w", traceID, err)
+// search searches traces with the search API of Tempo, which takes the tags
+// in logfmt. The service and operation are the service.name and name tags.
+func (e *tempoExecutor) search(ctx context.Context, dsInfo *models.DataSource, query *searchQuery, timeRange *tsdb.TimeRange) ([]traceSummary, error) {
+	tags := map[string]string{}
+	for key, value := range query.Tags {
+		tags[key] = value
+	}
+	if query.Service != "" {
+		tags["service.name"] = query.Service
+	}
+	if query.Operation != "" {
+		tags["name"] = query.Operation
+	}
+	logfmtTags := make([]string, 0, len(tags))
+	for _, key := range sortedKeys(tags) {
+		logfmtTags = append(logfmtTags, fmt.Sprintf("%s=%q", key, tags[key]))
 	}
 
-	frames := []*data.Frame{
-		{Name: "Traces", RefID: refID, Fields: []*data.Field{data.NewField("trace", nil, []string{string(traceBytes)})}},
+	params := url.Values{
+		"limit": []string{strconv.Itoa(query.Limit)},
+		"start": []string{strconv.FormatInt(timeRange.GetFromAsSecondsEpoch(), 10)},
+		"end":   []string{strconv.FormatInt(timeRange.GetToAsSecondsEpoch(), 10)},
+	}
+	if len(logfmtTags) > 0 {
+		params.Set("tags", strings.Join(logfmtTags, " "))
+	}
+	if query.MinDuration > 0 {
+		params.Set("minDuration", query.MinDuration.String())
+	}
+	if query.MaxDuration > 0 {
+		params.Set("maxDuration", query.MaxDuration.String())
 	}
-	queryResult.Dataframes = tsdb.NewDecodedDataFrames(frames)
 
-	return &tsdb.Response{
-		Results: map[string]*tsdb.QueryResult{
-			refID: queryResult,
-		},
-	}, nil
+	var result struct {
+		Traces []struct {
+			TraceID           string `json:"traceID"`
+			RootServiceName   string `json:"rootServiceName"`
+			RootTraceName     string `json:"rootTraceName"`
+			StartTimeUnixNano string `json:"startTimeUnixNano"`
+			DurationMs        int64  `json:"durationMs"`
+		} `json:"traces"`
+	}
+	if err := getJSON(ctx, e.httpClient, dsInfo, "api/search", params, &result); err != nil {
+		return nil, fmt.Errorf("failed to search traces: %w", err)
+	}
+
+	summaries := make([]traceSummary, 0, len(result.Traces))
+	for _, trace := range result.Traces {
+		startTime, err := strconv.ParseInt(trace.StartTimeUnixNano, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start time of trace %s: %w", trace.TraceID, err)
+		}
+		summaries = append(summaries, traceSummary{
+			TraceID:     trace.TraceID,
+			ServiceName: trace.RootServiceName,
+			TraceName:   trace.RootTraceName,
+			StartTime:   time.Unix(0, startTime).UTC(),
+			Duration:    time.Duration(trace.DurationMs) * time.Millisecond,
+		})
+	}
+	return summaries, nil
 }
 
 func (e *tempoExecutor) createRequest(ctx context.Context, dsInfo *models.DataSource, traceID string) (*http.Request, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", dsInfo.Url+"/api/traces/"+traceID, nil)
+	req, err := newRequest(ctx, dsInfo, "api/traces/"+traceID, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	if dsInfo.BasicAuth {
-		req.SetBasicAuth(dsInfo.BasicAuthUser, dsInfo.DecryptedBasicAuthPassword())
-	}
-
 	req.Header.Set("Accept", "application/protobuf")
 
 	tlog.Debug("Tempo request", "url", req.URL.String(), "headers", req.Header)
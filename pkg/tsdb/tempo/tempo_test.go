@@ -2,9 +2,14 @@ package tempo
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"testing"
+	"time"
 
 	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/tsdb"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -20,4 +25,35 @@ func TestTempo(t *testing.T) {
 		assert.Equal(t, 2, len(req.Header))
 		assert.NotEqual(t, req.Header.Get("Authorization"), "")
 	})
+
+	t.Run("search should search traces by tags", func(t *testing.T) {
+		var query url.Values
+		srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			query = req.URL.Query()
+			_, _ = rw.Write([]byte(`{"traces":[{"traceID":"abc","rootServiceName":"frontend","rootTraceName":"GET /","startTimeUnixNano":"1521118800000000000","durationMs":2}]}`))
+		}))
+		t.Cleanup(srv.Close)
+
+		summaries, err := executor.search(context.Background(), &models.DataSource{Url: srv.URL}, &searchQuery{
+			Service:     "frontend",
+			Tags:        map[string]string{"http.url": "/api users"},
+			MinDuration: time.Second,
+			Limit:       20,
+		}, tsdb.NewTimeRange("1521118800000", "1521119100000"))
+		require.NoError(t, err)
+		assert.Equal(t, []traceSummary{{
+			TraceID:     "abc",
+			ServiceName: "frontend",
+			TraceName:   "GET /",
+			StartTime:   time.Date(2018, 3, 15, 13, 0, 0, 0, time.UTC),
+			Duration:    2 * time.Millisecond,
+		}}, summaries)
+		assert.Equal(t, url.Values{
+			"tags":        []string{`http.url="/api users" service.name="frontend"`},
+			"minDuration": []string{"1s"},
+			"limit":       []string{"20"},
+			"start":       []string{"1521118800"},
+			"end":         []string{"1521119100"},
+		}, query)
+	})
 }
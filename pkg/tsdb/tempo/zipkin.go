@@ -0,0 +1,212 @@
+package tempo
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/tsdb"
+
+	jaeger_ui "github.com/jaegertracing/jaeger/model/json"
+)
+
+// zipkinExecutor queries traces with the v2 HTTP API of Zipkin, and converts
+// them to the JSON format of the Jaeger UI like the other trace backends.
+type zipkinExecutor struct {
+	httpClient *http.Client
+}
+
+// NewZipkinExecutor returns a zipkinExecutor.
+func NewZipkinExecutor(dsInfo *models.DataSource) (tsdb.TsdbQueryEndpoint, error) {
+	httpClient, err := dsInfo.GetHttpClient()
+	if err != nil {
+		return nil, err
+	}
+
+	return &zipkinExecutor{
+		httpClient: httpClient,
+	}, nil
+}
+
+func init() {
+	tsdb.RegisterTsdbQueryEndpoint("zipkin", NewZipkinExecutor)
+}
+
+func (e *zipkinExecutor) Query(ctx context.Context, dsInfo *models.DataSource, queryContext *tsdb.TsdbQuery) (*tsdb.Response, error) {
+	return queryTraces(ctx, e, dsInfo, queryContext)
+}
+
+type zipkinSpan struct {
+	TraceID        string             `json:"traceId"`
+	ParentID       string             `json:"parentId"`
+	ID             string             `json:"id"`
+	Name           string             `json:"name"`
+	Kind           string             `json:"kind"`
+	Timestamp      uint64             `json:"timestamp"`
+	Duration       uint64             `json:"duration"`
+	LocalEndpoint  *zipkinEndpoint    `json:"localEndpoint"`
+	RemoteEndpoint *zipkinEndpoint    `json:"remoteEndpoint"`
+	Annotations    []zipkinAnnotation `json:"annotations"`
+	Tags           map[string]string  `json:"tags"`
+}
+
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+	IPv4        string `json:"ipv4"`
+	IPv6        string `json:"ipv6"`
+	Port        int    `json:"port"`
+}
+
+type zipkinAnnotation struct {
+	Timestamp uint64 `json:"timestamp"`
+	Value     string `json:"value"`
+}
+
+func (e *zipkinExecutor) getTrace(ctx context.Context, dsInfo *models.DataSource, traceID string) (*jaeger_ui.Trace, error) {
+	var spans []zipkinSpan
+	if err := getJSON(ctx, e.httpClient, dsInfo, "api/v2/trace/"+traceID, nil, &spans); err != nil {
+		return nil, traceError(traceID, err)
+	}
+	if len(spans) == 0 {
+		return nil, fmt.Errorf("failed to get trace with id: %s, trace not found", traceID)
+	}
+	return zipkinToJaeger(spans), nil
+}
+
+// search searches traces with the API of Zipkin, where the tags are an
+// annotation query and the durations are in microseconds.
+func (e *zipkinExecutor) search(ctx context.Context, dsInfo *models.DataSource, query *searchQuery, timeRange *tsdb.TimeRange) ([]traceSummary, error) {
+	from, to := timeRange.GetFromAsMsEpoch(), timeRange.GetToAsMsEpoch()
+	params := url.Values{
+		"limit":    []string{strconv.Itoa(query.Limit)},
+		"endTs":    []string{strconv.FormatInt(to, 10)},
+		"lookback": []string{strconv.FormatInt(to-from, 10)},
+	}
+	if query.Service != "" {
+		params.Set("serviceName", query.Service)
+	}
+	if query.Operation != "" {
+		params.Set("spanName", query.Operation)
+	}
+	if len(query.Tags) > 0 {
+		conditions := make([]string, 0, len(query.Tags))
+		for _, key := range sortedKeys(query.Tags) {
+			conditions = append(conditions, key+"="+query.Tags[key])
+		}
+		params.Set("annotationQuery", strings.Join(conditions, " and "))
+	}
+	if query.MinDuration > 0 {
+		params.Set("minDuration", strconv.FormatInt(query.MinDuration.Microseconds(), 10))
+	}
+	if query.MaxDuration > 0 {
+		params.Set("maxDuration", strconv.FormatInt(query.MaxDuration.Microseconds(), 10))
+	}
+
+	var traces [][]zipkinSpan
+	if err := getJSON(ctx, e.httpClient, dsInfo, "api/v2/traces", params, &traces); err != nil {
+		return nil, fmt.Errorf("failed to search traces: %w", err)
+	}
+
+	summaries := make([]traceSummary, 0, len(traces))
+	for _, spans := range traces {
+		if len(spans) == 0 {
+			continue
+		}
+		summaries = append(summaries, summarizeTrace(zipkinToJaeger(spans)))
+	}
+	return summaries, nil
+}
+
+// zipkinToJaeger converts the spans of a Zipkin trace to a trace of the Jaeger
+// UI. The services of the endpoints are the processes, and the annotations
+// are logs.
+func zipkinToJaeger(spans []zipkinSpan) *jaeger_ui.Trace {
+	trace := &jaeger_ui.Trace{
+		TraceID:   jaeger_ui.TraceID(spans[0].TraceID),
+		Spans:     make([]jaeger_ui.Span, 0, len(spans)),
+		Processes: map[jaeger_ui.ProcessID]jaeger_ui.Process{},
+	}
+
+	for _, span := range spans {
+		for _, endpoint := range []*zipkinEndpoint{span.LocalEndpoint, span.RemoteEndpoint} {
+			if endpoint != nil {
+				trace.Processes[jaeger_ui.ProcessID(endpoint.ServiceName)] = endpointToProcess(endpoint)
+			}
+		}
+
+		processID := "unknown"
+		if span.LocalEndpoint != nil && span.LocalEndpoint.ServiceName != "" {
+			processID = span.LocalEndpoint.ServiceName
+		} else if span.RemoteEndpoint != nil && span.RemoteEndpoint.ServiceName != "" {
+			processID = span.RemoteEndpoint.ServiceName
+		}
+
+		jaegerSpan := jaeger_ui.Span{
+			TraceID:       jaeger_ui.TraceID(span.TraceID),
+			SpanID:        jaeger_ui.SpanID(span.ID),
+			Flags:         1,
+			OperationName: span.Name,
+			References:    []jaeger_ui.Reference{},
+			StartTime:     span.Timestamp,
+			Duration:      span.Duration,
+			Tags:          []jaeger_ui.KeyValue{},
+			Logs:          []jaeger_ui.Log{},
+			ProcessID:     jaeger_ui.ProcessID(processID),
+		}
+		if span.ParentID != "" {
+			jaegerSpan.References = append(jaegerSpan.References, jaeger_ui.Reference{
+				RefType: jaeger_ui.ChildOf,
+				TraceID: jaeger_ui.TraceID(span.TraceID),
+				SpanID:  jaeger_ui.SpanID(span.ParentID),
+			})
+		}
+
+		if span.Kind != "" {
+			jaegerSpan.Tags = append(jaegerSpan.Tags, jaeger_ui.KeyValue{Key: "kind", Type: jaeger_ui.StringType, Value: span.Kind})
+		}
+		keys := make([]string, 0, len(span.Tags))
+		for key := range span.Tags {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			// The error tag is a boolean so that the trace view shows the
+			// span as an error
+			if key == "error" {
+				jaegerSpan.Tags = append(jaegerSpan.Tags, jaeger_ui.KeyValue{Key: key, Type: jaeger_ui.BoolType, Value: true})
+				continue
+			}
+			jaegerSpan.Tags = append(jaegerSpan.Tags, jaeger_ui.KeyValue{Key: key, Type: jaeger_ui.StringType, Value: span.Tags[key]})
+		}
+
+		for _, annotation := range span.Annotations {
+			jaegerSpan.Logs = append(jaegerSpan.Logs, jaeger_ui.Log{
+				Timestamp: annotation.Timestamp,
+				Fields:    []jaeger_ui.KeyValue{{Key: "annotation", Type: jaeger_ui.StringType, Value: annotation.Value}},
+			})
+		}
+
+		trace.Spans = append(trace.Spans, jaegerSpan)
+	}
+
+	return trace
+}
+
+func endpointToProcess(endpoint *zipkinEndpoint) jaeger_ui.Process {
+	process := jaeger_ui.Process{ServiceName: endpoint.ServiceName, Tags: []jaeger_ui.KeyValue{}}
+	if endpoint.IPv4 != "" {
+		process.Tags = append(process.Tags, jaeger_ui.KeyValue{Key: "ipv4", Type: jaeger_ui.StringType, Value: endpoint.IPv4})
+	}
+	if endpoint.IPv6 != "" {
+		process.Tags = append(process.Tags, jaeger_ui.KeyValue{Key: "ipv6", Type: jaeger_ui.StringType, Value: endpoint.IPv6})
+	}
+	if endpoint.Port != 0 {
+		process.Tags = append(process.Tags, jaeger_ui.KeyValue{Key: "port", Type: jaeger_ui.Int64Type, Value: endpoint.Port})
+	}
+	return process
+}
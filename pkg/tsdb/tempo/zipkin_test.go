@@ -0,0 +1,111 @@
+package tempo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/openinsight-project/grafinsight/pkg/models"
+	"github.com/openinsight-project/grafinsight/pkg/tsdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	jaeger_ui "github.com/jaegertracing/jaeger/model/json"
+)
+
+const zipkinTrace = `[
+	{
+		"traceId": "abc", "id": "1", "name": "get /", "kind": "SERVER",
+		"timestamp": 1521118800000000, "duration": 2000,
+		"localEndpoint": {"serviceName": "frontend", "ipv4": "10.0.0.1", "port": 8080},
+		"annotations": [{"timestamp": 1521118800000100, "value": "wr"}],
+		"tags": {"http.path": "/", "error": ""}
+	},
+	{
+		"traceId": "abc", "parentId": "1", "id": "2", "name": "query",
+		"timestamp": 1521118800000500, "duration": 1000,
+		"remoteEndpoint": {"serviceName": "database"}
+	}
+]`
+
+func TestZipkin(t *testing.T) {
+	var requests []*http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		requests = append(requests, req)
+		switch req.URL.Path {
+		case "/api/v2/trace/abc":
+			_, _ = rw.Write([]byte(zipkinTrace))
+		case "/api/v2/traces":
+			_, _ = rw.Write([]byte("[" + zipkinTrace + "]"))
+		default:
+			http.Error(rw, "trace not found", http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	dsInfo := &models.DataSource{Url: srv.URL}
+	plug, err := NewZipkinExecutor(dsInfo)
+	require.NoError(t, err)
+	executor := plug.(*zipkinExecutor)
+
+	t.Run("Should convert traces", func(t *testing.T) {
+		trace, err := executor.getTrace(context.Background(), dsInfo, "abc")
+		require.NoError(t, err)
+		require.Len(t, trace.Spans, 2)
+
+		root := trace.Spans[0]
+		assert.Equal(t, jaeger_ui.ProcessID("frontend"), root.ProcessID)
+		assert.Empty(t, root.References)
+		assert.Equal(t, []jaeger_ui.KeyValue{
+			{Key: "kind", Type: jaeger_ui.StringType, Value: "SERVER"},
+			{Key: "error", Type: jaeger_ui.BoolType, Value: true},
+			{Key: "http.path", Type: jaeger_ui.StringType, Value: "/"},
+		}, root.Tags)
+		assert.Equal(t, []jaeger_ui.Log{{
+			Timestamp: 1521118800000100,
+			Fields:    []jaeger_ui.KeyValue{{Key: "annotation", Type: jaeger_ui.StringType, Value: "wr"}},
+		}}, root.Logs)
+
+		child := trace.Spans[1]
+		assert.Equal(t, jaeger_ui.ProcessID("database"), child.ProcessID)
+		assert.Equal(t, []jaeger_ui.Reference{{RefType: jaeger_ui.ChildOf, TraceID: "abc", SpanID: "1"}}, child.References)
+
+		assert.Equal(t, jaeger_ui.Process{ServiceName: "frontend", Tags: []jaeger_ui.KeyValue{
+			{Key: "ipv4", Type: jaeger_ui.StringType, Value: "10.0.0.1"},
+			{Key: "port", Type: jaeger_ui.Int64Type, Value: 8080},
+		}}, trace.Processes["frontend"])
+		assert.Contains(t, trace.Processes, jaeger_ui.ProcessID("database"))
+
+		_, err = executor.getTrace(context.Background(), dsInfo, "def")
+		require.Error(t, err)
+	})
+
+	t.Run("Should search traces", func(t *testing.T) {
+		summaries, err := executor.search(context.Background(), dsInfo, &searchQuery{
+			Service:     "frontend",
+			Tags:        map[string]string{"http.path": "/", "error": "true"},
+			MaxDuration: time.Second,
+			Limit:       5,
+		}, tsdb.NewTimeRange("1521118800000", "1521119100000"))
+		require.NoError(t, err)
+		assert.Equal(t, []traceSummary{{
+			TraceID:     "abc",
+			ServiceName: "frontend",
+			TraceName:   "get /",
+			StartTime:   time.Date(2018, 3, 15, 13, 0, 0, 0, time.UTC),
+			Duration:    2 * time.Millisecond,
+		}}, summaries)
+
+		assert.Equal(t, url.Values{
+			"serviceName":     []string{"frontend"},
+			"annotationQuery": []string{"error=true and http.path=/"},
+			"maxDuration":     []string{"1000000"},
+			"limit":           []string{"5"},
+			"endTs":           []string{"1521119100000"},
+			"lookback":        []string{"300000"},
+		}, requests[len(requests)-1].URL.Query())
+	})
+}
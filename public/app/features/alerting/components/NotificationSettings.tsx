@@ -54,6 +54,18 @@ export const NotificationSettings: FC<Props> = ({ currentFormValues, imageRender
           </Field>
         </>
       )}
+      <Field
+        label="Escalate to"
+        description="UID of the notification channel to notify when an alert is not acknowledged in time. Leave empty to disable escalation."
+      >
+        <Input name="settings.escalateTo" ref={register} width={20} />
+      </Field>
+      <Field
+        label="Escalate after"
+        description="How long to wait for an acknowledgement before escalating, e.g. 5m, 15m or 1h. Defaults to 15m."
+      >
+        <Input name="settings.escalateAfter" ref={register} width={8} />
+      </Field>
     </CollapsableSection>
   );
 };
@@ -38,7 +38,22 @@ export const SaveDashboardErrorProxy: React.FC<SaveDashboardErrorProxyProps> = (
           title="Conflict"
           body={
             <div>
-              Someone else has updated this dashboard <br /> <small>Would you still like to save this dashboard?</small>
+              {error.data.updatedBy ? (
+                <>
+                  {error.data.updatedBy} has updated this dashboard since you loaded it <br />
+                </>
+              ) : (
+                <>
+                  Someone else has updated this dashboard <br />
+                </>
+              )}
+              {error.data.editing?.length > 0 && (
+                <>
+                  <small>Also editing right now: {error.data.editing.join(', ')}</small>
+                  <br />
+                </>
+              )}
+              <small>Would you still like to save this dashboard?</small>
             </div>
           }
           confirmText="Save & Overwrite"
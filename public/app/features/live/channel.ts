@@ -54,31 +54,33 @@ export class CentrifugeLiveChannel<TMessage = any, TPublish = any> implements Li
     this.config = config;
     const prepare = config.processMessage ? config.processMessage : (v: any) => v;
 
-    const events: SubscriptionEvents = {
-      // This means a message was received from the server
-      publish: (ctx: PublicationContext) => {
-        try {
-          const message = prepare(ctx.data);
-          if (message) {
-            this.stream.next({
-              type: LiveChannelEventType.Message,
-              message,
-            });
-          }
-
-          // Clear any error messages
-          if (this.currentStatus.error) {
-            this.currentStatus.timestamp = Date.now();
-            delete this.currentStatus.error;
-            this.sendStatus();
-          }
-        } catch (err) {
-          console.log('publish error', config.path, err);
-          this.currentStatus.error = err;
+    const handleMessage = (data: any) => {
+      try {
+        const message = prepare(data);
+        if (message) {
+          this.stream.next({
+            type: LiveChannelEventType.Message,
+            message,
+          });
+        }
+
+        // Clear any error messages
+        if (this.currentStatus.error) {
           this.currentStatus.timestamp = Date.now();
+          delete this.currentStatus.error;
           this.sendStatus();
         }
-      },
+      } catch (err) {
+        console.log('publish error', config.path, err);
+        this.currentStatus.error = err;
+        this.currentStatus.timestamp = Date.now();
+        this.sendStatus();
+      }
+    };
+
+    const events: SubscriptionEvents = {
+      // This means a message was received from the server
+      publish: (ctx: PublicationContext) => handleMessage(ctx.data),
       error: (ctx: SubscribeErrorContext) => {
         this.currentStatus.timestamp = Date.now();
         this.currentStatus.error = ctx.error;
@@ -89,6 +91,19 @@ export class CentrifugeLiveChannel<TMessage = any, TPublish = any> implements Li
         this.currentStatus.state = LiveChannelConnectionState.Connected;
         delete this.currentStatus.error;
         this.sendStatus();
+
+        // Replay the messages kept in the channel history so that late subscribers
+        // do not start empty. Messages missed while reconnecting are recovered by the server.
+        if (!ctx.isResubscribe) {
+          this.subscription
+            ?.history()
+            .then((history: any) => {
+              for (const pub of history?.publications ?? []) {
+                handleMessage(pub.data);
+              }
+            })
+            .catch(() => {}); // channels without history reject the request
+        }
       },
       unsubscribe: (ctx: UnsubscribeContext) => {
         this.currentStatus.timestamp = Date.now();
@@ -1,5 +1,5 @@
 import { LiveChannelConfig } from '@grafinsight/data';
-import { MeasurementCollector } from '@grafinsight/runtime/src';
+import { MeasurementCollector, toDataQueryResponse } from '@grafinsight/runtime/src';
 import { getDashboardChannelsFeature } from './dashboard/dashboardWatcher';
 import { LiveMeasurementsSupport } from './measurements/measurementsSupport';
 import { grafinsightLiveCoreFeatures } from './scopes';
@@ -56,6 +56,23 @@ export function registerLiveFeatures() {
     description: 'These channels listen for measurements and produce DataFrames',
   });
 
+  const queryConfig: LiveChannelConfig = {
+    path: '${path}',
+    description: 'Results of a query running on the server',
+    processMessage: (msg: any) => toDataQueryResponse({ data: msg }),
+  };
+
+  grafinsightLiveCoreFeatures.register({
+    name: 'query',
+    support: {
+      getChannelConfig: (path: string) => {
+        return queryConfig;
+      },
+      getSupportedPaths: () => [queryConfig],
+    },
+    description: 'Query results streamed from the server on an interval',
+  });
+
   // dashboard/*
   grafinsightLiveCoreFeatures.register(getDashboardChannelsFeature());
 }
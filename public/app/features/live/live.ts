@@ -10,6 +10,7 @@ import {
   GrafInsightLivePluginScope,
 } from './scopes';
 import { registerLiveFeatures } from './features';
+import { SSETransport } from './sse';
 
 export const sessionId =
   (window as any)?.grafinsightBootData?.user?.id +
@@ -25,13 +26,23 @@ export class CentrifugeSrv implements GrafInsightLiveSrv {
   readonly connectionState: BehaviorSubject<boolean>;
   readonly connectionBlocker: Promise<void>;
   readonly scopes: Record<LiveChannelScope, GrafInsightLiveScope>;
+  readonly orgId: number;
 
   constructor() {
-    // build live url replacing scheme in appUrl.
-    const liveUrl = `${config.appUrl}live/ws`.replace(/^(http)(s)?:\/\//, 'ws$2://');
-    this.centrifuge = new Centrifuge(liveUrl, {
-      debug: true,
-    });
+    this.orgId = config.bootData.user.orgId;
+    if (config.liveTransport === 'sse') {
+      // centrifuge uses the SockJS client for http urls
+      this.centrifuge = new Centrifuge(`${config.appUrl}live/sse`, {
+        debug: true,
+        sockjs: SSETransport,
+      });
+    } else {
+      // build live url replacing scheme in appUrl.
+      const liveUrl = `${config.appUrl}live/ws`.replace(/^(http)(s)?:\/\//, 'ws$2://');
+      this.centrifuge = new Centrifuge(liveUrl, {
+        debug: true,
+      });
+    }
     this.centrifuge.setConnectData({
       sessionId,
     });
@@ -120,14 +131,16 @@ export class CentrifugeSrv implements GrafInsightLiveSrv {
     if (!config) {
       throw new Error('unknown path: ' + addr.path);
     }
+    // channels are scoped to the current org on the server
+    const orgChannelId = `${this.orgId}/${channel.id}`;
     if (config.canPublish?.()) {
-      channel.publish = (data: any) => this.centrifuge.publish(channel.id, data);
+      channel.publish = (data: any) => this.centrifuge.publish(orgChannelId, data);
     }
     const events = channel.initalize(config);
     if (!this.centrifuge.isConnected()) {
       await this.connectionBlocker;
     }
-    channel.subscription = this.centrifuge.subscribe(channel.id, events);
+    channel.subscription = this.centrifuge.subscribe(orgChannelId, events);
     return;
   }
 
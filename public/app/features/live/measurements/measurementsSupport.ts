@@ -1,11 +1,65 @@
-import { LiveChannelSupport, LiveChannelConfig } from '@grafinsight/data';
-import { MeasurementCollector } from '@grafinsight/runtime/src';
+import {
+  LiveChannelSupport,
+  LiveChannelConfig,
+  DataFrame,
+  FieldType,
+  arrowTableToDataFrame,
+  base64StringToArrowTable,
+} from '@grafinsight/data';
+import { Measurement, MeasurementAction, MeasurementBatch, MeasurementCollector } from '@grafinsight/runtime/src';
 
 interface MeasurementChannel {
   config: LiveChannelConfig;
   collector: MeasurementCollector;
 }
 
+/**
+ * Messages published by the server after measurements are pushed with the HTTP API
+ */
+interface MeasurementFramesMessage {
+  action?: MeasurementAction;
+  capacity?: number;
+  frames: string[]; // base64 encoded arrow tables
+}
+
+/**
+ * Convert the data frames published by the server back to measurements so they
+ * can be added to the collector
+ */
+export function framesMessageToBatch(msg: MeasurementFramesMessage): MeasurementBatch {
+  const measurements: Measurement[] = [];
+  const frames: DataFrame[] = (msg.frames ?? []).map((f) => arrowTableToDataFrame(base64StringToArrowTable(f)));
+
+  for (const frame of frames) {
+    const timeField = frame.fields.find((f) => f.type === FieldType.time);
+    const valueFields = frame.fields.filter((f) => f !== timeField);
+    const labels = valueFields.length ? valueFields[0].labels : undefined;
+
+    for (let i = 0; i < frame.length; i++) {
+      const values: Record<string, any> = {};
+      for (const field of valueFields) {
+        const v = field.values.get(i);
+        if (v !== null && v !== undefined) {
+          values[field.name] = v;
+        }
+      }
+
+      measurements.push({
+        name: frame.name ?? '',
+        time: timeField?.values.get(i),
+        values,
+        labels,
+      });
+    }
+  }
+
+  return {
+    action: msg.action,
+    capacity: msg.capacity,
+    measurements,
+  };
+}
+
 export class LiveMeasurementsSupport implements LiveChannelSupport {
   private cache: Record<string, MeasurementChannel> = {};
 
@@ -21,9 +75,10 @@ export class LiveMeasurementsSupport implements LiveChannelSupport {
         collector,
         config: {
           path,
-          processMessage: collector.addBatch, // << this converts the stream from a single event to the whole cache
+          // this converts the stream from a single event to the whole cache
+          processMessage: (msg: MeasurementFramesMessage) => collector.addBatch(framesMessageToBatch(msg)),
           getController: () => collector,
-          canPublish: () => true,
+          canPublish: () => false, // measurements are pushed with the HTTP API
         },
       };
     }
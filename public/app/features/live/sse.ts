@@ -0,0 +1,76 @@
+/**
+ * Live transport using Server-Sent Events, for browsers behind proxies that block websocket upgrades.
+ *
+ * It implements the part of the SockJS client used by centrifuge: the replies are read from the
+ * `/live/sse` event stream and the commands are posted to `/live/sse/${session}`.
+ */
+export class SSETransport {
+  readonly transport = 'sse';
+
+  onopen?: () => void;
+  onmessage?: (event: { data: string }) => void;
+  onclose?: (event: { code: number; reason: string }) => void;
+  onerror?: (event: any) => void;
+  onheartbeat?: () => void;
+
+  private source: EventSource;
+  private session?: string;
+  private closed = false;
+
+  constructor(private url: string) {
+    this.source = new EventSource(url, { withCredentials: true });
+
+    this.source.addEventListener('session', (event: any) => {
+      this.session = JSON.parse(event.data).session;
+      this.onopen?.();
+    });
+
+    this.source.addEventListener('message', (event: any) => {
+      this.onmessage?.({ data: event.data });
+    });
+
+    this.source.addEventListener('disconnect', (event: any) => {
+      this.doClose(3000, event.data);
+    });
+
+    // Let centrifuge decide when to reconnect
+    this.source.onerror = (event: any) => {
+      this.onerror?.(event);
+      this.doClose(1006, JSON.stringify({ reason: 'connection error', reconnect: true }));
+    };
+  }
+
+  send(data: string) {
+    if (this.closed || !this.session) {
+      return;
+    }
+
+    fetch(`${this.url}/${this.session}`, {
+      method: 'POST',
+      body: data,
+      credentials: 'same-origin',
+    }).then(
+      (rsp) => {
+        if (!rsp.ok) {
+          this.doClose(3000, JSON.stringify({ reason: 'session closed', reconnect: true }));
+        }
+      },
+      () => {
+        this.doClose(1006, JSON.stringify({ reason: 'connection error', reconnect: true }));
+      }
+    );
+  }
+
+  close() {
+    this.doClose(1000, JSON.stringify({ reason: 'client closed', reconnect: false }));
+  }
+
+  private doClose(code: number, reason: string) {
+    if (this.closed) {
+      return;
+    }
+    this.closed = true;
+    this.source.close();
+    this.onclose?.({ code, reason });
+  }
+}
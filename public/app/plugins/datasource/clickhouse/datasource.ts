@@ -0,0 +1,38 @@
+import { Observable } from 'rxjs';
+import { DataQueryResponse, rangeUtil, ScopedVars } from '@grafinsight/data';
+import { MysqlDatasource } from 'app/plugins/datasource/mysql/datasource';
+import { MysqlQueryForInterpolation } from 'app/plugins/datasource/mysql/types';
+
+// ClickHouse quotes string literals like MySQL, and the queries are run by the
+// backend, so the datasource only differs in its macros, which are
+// interpolated by the backend as well.
+export class ClickHouseDatasource extends MysqlDatasource {
+  query(options: any): Observable<DataQueryResponse> {
+    return super.query({ ...options, scopedVars: intervalInSeconds(options.scopedVars) });
+  }
+
+  interpolateVariablesInQueries(
+    queries: MysqlQueryForInterpolation[],
+    scopedVars: ScopedVars
+  ): MysqlQueryForInterpolation[] {
+    return super.interpolateVariablesInQueries(queries, intervalInSeconds(scopedVars));
+  }
+}
+
+// ClickHouse does not understand intervals like 1m, so $__interval is a
+// number of seconds, as in INTERVAL $__interval second.
+export function intervalInSeconds(scopedVars: ScopedVars = {}): ScopedVars {
+  if (!scopedVars.__interval) {
+    return scopedVars;
+  }
+
+  const intervalMs = scopedVars.__interval_ms
+    ? Number(scopedVars.__interval_ms.value)
+    : rangeUtil.intervalToMs(scopedVars.__interval.value);
+  const seconds = Math.max(1, Math.round(intervalMs / 1000)).toString();
+
+  return {
+    ...scopedVars,
+    __interval: { text: seconds, value: seconds },
+  };
+}
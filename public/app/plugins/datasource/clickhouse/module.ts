@@ -0,0 +1,48 @@
+import { ClickHouseDatasource } from './datasource';
+import { ClickHouseQueryCtrl } from './query_ctrl';
+import {
+  createChangeHandler,
+  createResetHandler,
+  PasswordFieldEnum,
+} from '../../../features/datasources/utils/passwordHandlers';
+
+class ClickHouseConfigCtrl {
+  static templateUrl = 'partials/config.html';
+  current: any;
+  onPasswordReset: ReturnType<typeof createResetHandler>;
+  onPasswordChange: ReturnType<typeof createChangeHandler>;
+
+  constructor() {
+    this.onPasswordReset = createResetHandler(this, PasswordFieldEnum.Password);
+    this.onPasswordChange = createChangeHandler(this, PasswordFieldEnum.Password);
+  }
+}
+
+const defaultQuery = `SELECT
+    <time_column> AS time,
+    <text_column> AS text,
+    <tags_column> AS tags
+  FROM <table name>
+  WHERE $__timeFilter(<time_column>)
+  ORDER BY time ASC
+  LIMIT 100
+  `;
+
+class ClickHouseAnnotationsQueryCtrl {
+  static templateUrl = 'partials/annotations.editor.html';
+
+  annotation: any;
+
+  /** @ngInject */
+  constructor() {
+    this.annotation.rawQuery = this.annotation.rawQuery || defaultQuery;
+  }
+}
+
+export {
+  ClickHouseDatasource,
+  ClickHouseDatasource as Datasource,
+  ClickHouseQueryCtrl as QueryCtrl,
+  ClickHouseConfigCtrl as ConfigCtrl,
+  ClickHouseAnnotationsQueryCtrl as AnnotationsQueryCtrl,
+};
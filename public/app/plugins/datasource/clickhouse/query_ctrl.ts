@@ -0,0 +1,63 @@
+import { QueryCtrl } from 'app/plugins/sdk';
+import { auto } from 'angular';
+import { PanelEvents, QueryResultMeta } from '@grafinsight/data';
+
+const defaultQuery = `SELECT
+  $__timeGroupAlias(<time_column>, $__interval),
+  <series name column> AS metric,
+  count() AS value
+FROM <table name>
+WHERE $__timeFilter(<time_column>)
+GROUP BY time, metric
+ORDER BY time
+`;
+
+export class ClickHouseQueryCtrl extends QueryCtrl {
+  static templateUrl = 'partials/query.editor.html';
+
+  formats: any[];
+  lastQueryError?: string;
+  lastQueryMeta?: QueryResultMeta;
+  showHelp!: boolean;
+  showLastQuerySQL!: boolean;
+
+  /** @ngInject */
+  constructor($scope: any, $injector: auto.IInjectorService) {
+    super($scope, $injector);
+
+    this.formats = [
+      { text: 'Time series', value: 'time_series' },
+      { text: 'Table', value: 'table' },
+    ];
+
+    // The query builder of the other SQL datasources is not supported
+    this.target.rawQuery = true;
+    if (!this.target.rawSql) {
+      // special handling when in table panel
+      if (this.panelCtrl.panel.type === 'table') {
+        this.target.format = 'table';
+        this.target.rawSql = 'SELECT 1';
+      } else {
+        this.target.rawSql = defaultQuery;
+      }
+    }
+    this.target.format = this.target.format || 'time_series';
+
+    this.panelCtrl.events.on(PanelEvents.dataReceived, this.onDataReceived.bind(this), $scope);
+    this.panelCtrl.events.on(PanelEvents.dataError, this.onDataError.bind(this), $scope);
+  }
+
+  onDataReceived(dataList: any) {
+    this.lastQueryError = undefined;
+    this.lastQueryMeta = dataList[0]?.meta;
+  }
+
+  onDataError(err: any) {
+    if (err.data && err.data.results) {
+      const queryRes = err.data.results[this.target.refId];
+      if (queryRes) {
+        this.lastQueryError = queryRes.error;
+      }
+    }
+  }
+}
@@ -0,0 +1,99 @@
+import { of } from 'rxjs';
+import { dateTime } from '@grafinsight/data';
+
+import { ClickHouseDatasource } from '../datasource';
+import { backendSrv } from 'app/core/services/backend_srv'; // will use the version in __mocks__
+import { TemplateSrv } from 'app/features/templating/template_srv';
+import { FetchResponse } from '@grafinsight/runtime/src';
+
+jest.mock('@grafinsight/runtime', () => ({
+  ...((jest.requireActual('@grafinsight/runtime') as unknown) as object),
+  getBackendSrv: () => backendSrv,
+}));
+
+describe('ClickHouseDatasource', () => {
+  const fetchMock = jest.spyOn(backendSrv, 'fetch');
+  const setupTestContext = () => {
+    jest.clearAllMocks();
+    fetchMock.mockImplementation(() => of(createFetchResponse({ results: {} })));
+
+    const templateSrv: TemplateSrv = new TemplateSrv();
+    const ds = new ClickHouseDatasource({ name: 'clickhouse', id: 1 }, templateSrv);
+    return { ds };
+  };
+
+  describe('When running a panel query', () => {
+    // The scoped variables set by the panel query runner
+    const options = {
+      range: {
+        from: dateTime(1432288354),
+        to: dateTime(1432288401),
+      },
+      intervalMs: 60000,
+      maxDataPoints: 100,
+      scopedVars: {
+        __interval: { text: '1m', value: '1m' },
+        __interval_ms: { text: '60000', value: 60000 },
+      },
+      targets: [
+        {
+          refId: 'A',
+          rawQuery: true,
+          format: 'time_series',
+          rawSql:
+            'SELECT $__timeGroup(ts, $__interval), toStartOfInterval(ts, INTERVAL $__interval second), $__interval_ms FROM events',
+        },
+      ],
+    };
+
+    it('should interpolate $__interval in seconds', async () => {
+      const { ds } = setupTestContext();
+      await ds.query(options).toPromise();
+
+      expect(fetchMock).toHaveBeenCalledTimes(1);
+      expect(fetchMock.mock.calls[0][0].data.queries[0].rawSql).toBe(
+        'SELECT $__timeGroup(ts, 60), toStartOfInterval(ts, INTERVAL 60 second), 60000 FROM events'
+      );
+    });
+
+    it('should interpolate intervals below a second as one second', async () => {
+      const { ds } = setupTestContext();
+      await ds
+        .query({
+          ...options,
+          scopedVars: {
+            __interval: { text: '100ms', value: '100ms' },
+            __interval_ms: { text: '100', value: 100 },
+          },
+        })
+        .toPromise();
+
+      expect(fetchMock.mock.calls[0][0].data.queries[0].rawSql).toBe(
+        'SELECT $__timeGroup(ts, 1), toStartOfInterval(ts, INTERVAL 1 second), 100 FROM events'
+      );
+    });
+  });
+
+  describe('When interpolating variables in queries', () => {
+    it('should interpolate $__interval in seconds', () => {
+      const { ds } = setupTestContext();
+      const queries = ds.interpolateVariablesInQueries([{ refId: 'A', rawSql: 'INTERVAL $__interval second' }], {
+        __interval: { text: '5m', value: '5m' },
+      });
+
+      expect(queries[0].rawSql).toBe('INTERVAL 300 second');
+    });
+  });
+});
+
+const createFetchResponse = <T>(data: T): FetchResponse<T> => ({
+  data,
+  status: 200,
+  url: 'http://localhost:3000/api/query',
+  config: { url: 'http://localhost:3000/api/query' },
+  type: 'basic',
+  statusText: 'Ok',
+  redirected: false,
+  headers: ({} as unknown) as Headers,
+  ok: true,
+});
@@ -2,9 +2,9 @@ import _ from 'lodash';
 import { Observable, of } from 'rxjs';
 import { catchError, map, mapTo } from 'rxjs/operators';
 import { getBackendSrv } from '@grafinsight/runtime/src';
-import { ScopedVars } from '@grafinsight/data';
+import { DataQueryResponse, ScopedVars } from '@grafinsight/data';
 
-import ResponseParser, { MssqlResponse } from './response_parser';
+import ResponseParser from './response_parser';
 import { getTemplateSrv, TemplateSrv } from 'app/features/templating/template_srv';
 import { getTimeSrv, TimeSrv } from 'app/features/dashboard/services/TimeSrv';
 import { MssqlQueryForInterpolation } from './types';
@@ -68,7 +68,7 @@ export class MssqlDatasource {
     return expandedQueries;
   }
 
-  query(options: any): Observable<MssqlResponse> {
+  query(options: any): Observable<DataQueryResponse> {
     const queries = _.filter(options.targets, (item) => {
       return item.hide !== true;
     }).map((item) => {
@@ -2,9 +2,9 @@ import _ from 'lodash';
 import { Observable, of } from 'rxjs';
 import { catchError, map, mapTo } from 'rxjs/operators';
 import { getBackendSrv } from '@grafinsight/runtime/src';
-import { ScopedVars } from '@grafinsight/data';
+import { DataQueryResponse, ScopedVars } from '@grafinsight/data';
 import MysqlQuery from 'app/plugins/datasource/mysql/mysql_query';
-import ResponseParser, { MysqlResponse } from './response_parser';
+import ResponseParser from './response_parser';
 import { MysqlMetricFindValue, MysqlQueryForInterpolation } from './types';
 import { getTemplateSrv, TemplateSrv } from 'app/features/templating/template_srv';
 import { getTimeSrv, TimeSrv } from 'app/features/dashboard/services/TimeSrv';
@@ -68,7 +68,7 @@ export class MysqlDatasource {
     return expandedQueries;
   }
 
-  query(options: any): Observable<MysqlResponse> {
+  query(options: any): Observable<DataQueryResponse> {
     const queries = _.filter(options.targets, (target) => {
       return target.hide !== true;
     }).map((target) => {
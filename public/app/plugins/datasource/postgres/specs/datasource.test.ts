@@ -1,5 +1,4 @@
 import { of } from 'rxjs';
-import { TestScheduler } from 'rxjs/testing';
 import { FetchResponse } from '@grafinsight/runtime/src';
 import { dateTime, toUtc } from '@grafinsight/data';
 
@@ -38,33 +37,8 @@ describe('PostgreSQLDatasource', () => {
     return { ds, templateSrv, timeSrvMock, variable };
   };
 
-  // https://rxjs-dev.firebaseapp.com/guide/testing/marble-testing
-  const runMarbleTest = (args: {
-    options: any;
-    values: { [marble: string]: FetchResponse };
-    marble: string;
-    expectedValues: { [marble: string]: any };
-    expectedMarble: string;
-  }) => {
-    const { expectedValues, expectedMarble, options, values, marble } = args;
-    const scheduler: TestScheduler = new TestScheduler((actual, expected) => {
-      expect(actual).toEqual(expected);
-    });
-
-    const { ds } = setupTestContext({});
-
-    scheduler.run(({ cold, expectObservable }) => {
-      const source = cold(marble, values);
-      jest.clearAllMocks();
-      fetchMock.mockImplementation(() => source);
-
-      const result = ds.query(options);
-      expectObservable(result).toBe(expectedMarble, expectedValues);
-    });
-  };
-
   describe('When performing a time series query', () => {
-    it('should transform response correctly', () => {
+    it('should transform response correctly', async () => {
       const options = {
         range: {
           from: dateTime(1432288354),
@@ -87,45 +61,36 @@ describe('PostgreSQLDatasource', () => {
             refId: 'A',
             meta: {
               executedQueryString: 'select time, metric from grafinsight_metric',
-              rowCount: 0,
+              rowCount: 1,
             },
-            series: [
-              {
-                name: 'America',
-                points: [[30.226249741223704, 1599643351085]],
-              },
-            ],
+            series: null,
             tables: null,
-            dataframes: null,
+            dataframes: [
+              'QVJST1cxAAD/////WAIAABAAAAAAAAoADgAMAAsABAAKAAAAFAAAAAAAAAEDAAoADAAAAAgABAAKAAAACAAAALgAAAADAAAATAAAACgAAAAEAAAAOP7//wgAAAAMAAAAAQAAAEEAAAAFAAAAcmVmSWQAAABY/v//CAAAAAwAAAAAAAAAAAAAAAQAAABuYW1lAAAAAHj+//8IAAAAUAAAAEUAAAB7ImV4ZWN1dGVkUXVlcnlTdHJpbmciOiJzZWxlY3QgdGltZSwgbWV0cmljIGZyb20gZ3JhZmluc2lnaHRfbWV0cmljIn0AAAAEAAAAbWV0YQAAAAACAAAAAAEAABgAAAAAABIAGAAUABMAEgAMAAAACAAEABIAAAAUAAAAtAAAALQAAAAAAAMBtAAAAAMAAABkAAAALAAAAAQAAAAk////CAAAABAAAAAFAAAAdmFsdWUAAAAEAAAAbmFtZQAAAABI////CAAAACAAAAAUAAAAeyJtZXRyaWMiOiJBbWVyaWNhIn0AAAAABgAAAGxhYmVscwAAfP///wgAAAAoAAAAHwAAAHsiZGlzcGxheU5hbWVGcm9tRFMiOiJBbWVyaWNhIn0ABgAAAGNvbmZpZwAAAAAAAIr///8AAAIABQAAAHZhbHVlABIAGAAUAAAAEwAMAAAACAAEABIAAAAUAAAARAAAAEwAAAAAAAAKTAAAAAEAAAAMAAAACAAMAAgABAAIAAAACAAAABAAAAAEAAAAdGltZQAAAAAEAAAAbmFtZQAAAAAAAAAAAAAGAAgABgAGAAAAAAADAAQAAAB0aW1lAAAAAP////+4AAAAFAAAAAAAAAAMABYAFAATAAwABAAMAAAAEAAAAAAAAAAUAAAAAAAAAwMACgAYAAwACAAEAAoAAAAUAAAAWAAAAAEAAAAAAAAAAAAAAAQAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAIAAAAAAAAAAgAAAAAAAAAAAAAAAAAAAAIAAAAAAAAAAgAAAAAAAAAAAAAAAIAAAABAAAAAAAAAAAAAAAAAAAAAQAAAAAAAAAAAAAAAAAAAEClvAwnEzMWxkjHgOs5PkAQAAAADAAUABIADAAIAAQADAAAABAAAAAsAAAAPAAAAAAAAwABAAAAaAIAAAAAAADAAAAAAAAAABAAAAAAAAAAAAAAAAAAAAAAAAAAAAAKAAwAAAAIAAQACgAAAAgAAAC4AAAAAwAAAEwAAAAoAAAABAAAADj+//8IAAAADAAAAAEAAABBAAAABQAAAHJlZklkAAAAWP7//wgAAAAMAAAAAAAAAAAAAAAEAAAAbmFtZQAAAAB4/v//CAAAAFAAAABFAAAAeyJleGVjdXRlZFF1ZXJ5U3RyaW5nIjoic2VsZWN0IHRpbWUsIG1ldHJpYyBmcm9tIGdyYWZpbnNpZ2h0X21ldHJpYyJ9AAAABAAAAG1ldGEAAAAAAgAAAAABAAAYAAAAAAASABgAFAATABIADAAAAAgABAASAAAAFAAAALQAAAC0AAAAAAADAbQAAAADAAAAZAAAACwAAAAEAAAAJP///wgAAAAQAAAABQAAAHZhbHVlAAAABAAAAG5hbWUAAAAASP///wgAAAAgAAAAFAAAAHsibWV0cmljIjoiQW1lcmljYSJ9AAAAAAYAAABsYWJlbHMAAHz///8IAAAAKAAAAB8AAAB7ImRpc3BsYXlOYW1lRnJvbURTIjoiQW1lcmljYSJ9AAYAAABjb25maWcAAAAAAACK////AAACAAUAAAB2YWx1ZQASABgAFAAAABMADAAAAAgABAASAAAAFAAAAEQAAABMAAAAAAAACkwAAAABAAAADAAAAAgADAAIAAQACAAAAAgAAAAQAAAABAAAAHRpbWUAAAAABAAAAG5hbWUAAAAAAAAAAAAABgAIAAYABgAAAAAAAwAEAAAAdGltZQAAAACIAgAAQVJST1cx',
+            ],
           },
         },
       };
 
-      const values = { a: createFetchResponse(data) };
-      const marble = '-a|';
-      const expectedMarble = '-a|';
-      const expectedValues = {
-        a: {
-          data: [
-            {
-              datapoints: [[30.226249741223704, 1599643351085]],
-              meta: {
-                executedQueryString: 'select time, metric from grafinsight_metric',
-                rowCount: 0,
-              },
-              refId: 'A',
-              target: 'America',
-            },
-          ],
-        },
-      };
+      const { ds } = setupTestContext(data);
 
-      runMarbleTest({ options, marble, values, expectedMarble, expectedValues });
+      await expect(ds.query(options)).toEmitValuesWith((received) => {
+        const frames = received[0].data;
+        expect(frames).toHaveLength(1);
+        expect(frames[0].refId).toBe('A');
+        expect(frames[0].meta.executedQueryString).toBe('select time, metric from grafinsight_metric');
+        expect(frames[0].fields[0].name).toBe('time');
+        expect(frames[0].fields[0].values.get(0)).toBe(1599643351085);
+        expect(frames[0].fields[1].name).toBe('value');
+        expect(frames[0].fields[1].labels).toEqual({ metric: 'America' });
+        expect(frames[0].fields[1].config.displayNameFromDS).toBe('America');
+        expect(frames[0].fields[1].values.get(0)).toBe(30.226249741223704);
+      });
     });
   });
 
   describe('When performing a table query', () => {
-    it('should transform response correctly', () => {
+    it('should transform response correctly', async () => {
       const options = {
         range: {
           from: dateTime(1432288354),
@@ -151,58 +116,26 @@ describe('PostgreSQLDatasource', () => {
               rowCount: 1,
             },
             series: null,
-            tables: [
-              {
-                columns: [
-                  {
-                    text: 'time',
-                  },
-                  {
-                    text: 'metric',
-                  },
-                  {
-                    text: 'value',
-                  },
-                ],
-                rows: [[1599643351085, 'America', 30.226249741223704]],
-              },
+            tables: null,
+            dataframes: [
+              'QVJST1cxAAD/////OAIAABAAAAAAAAoADgAMAAsABAAKAAAAFAAAAAAAAAEDAAoADAAAAAgABAAKAAAACAAAAMAAAAADAAAATAAAACgAAAAEAAAAWP7//wgAAAAMAAAAAQAAAEEAAAAFAAAAcmVmSWQAAAB4/v//CAAAAAwAAAAAAAAAAAAAAAQAAABuYW1lAAAAAJj+//8IAAAAWAAAAEwAAAB7ImV4ZWN1dGVkUXVlcnlTdHJpbmciOiJzZWxlY3QgdGltZSwgbWV0cmljLCB2YWx1ZSBmcm9tIGdyYWZpbnNpZ2h0X21ldHJpYyJ9AAAAAAQAAABtZXRhAAAAAAMAAADYAAAAZAAAAAQAAABG////FAAAADwAAAA8AAAAAAADATwAAAABAAAABAAAADT///8IAAAAEAAAAAUAAAB2YWx1ZQAAAAQAAABuYW1lAAAAAAAAAAAq////AAACAAUAAAB2YWx1ZQAAAKL///8UAAAAPAAAAEAAAAAAAAUBPAAAAAEAAAAEAAAAkP///wgAAAAQAAAABgAAAG1ldHJpYwAABAAAAG5hbWUAAAAAAAAAAAQABAAEAAAABgAAAG1ldHJpYwAAAAASABgAFAATABIADAAAAAgABAASAAAAFAAAAEQAAABMAAAAAAAKAUwAAAABAAAADAAAAAgADAAIAAQACAAAAAgAAAAQAAAABAAAAHRpbWUAAAAABAAAAG5hbWUAAAAAAAAAAAAABgAIAAYABgAAAAAAAwAEAAAAdGltZQAAAAD/////+AAAABQAAAAAAAAADAAWABQAEwAMAAQADAAAACAAAAAAAAAAFAAAAAAAAAMDAAoAGAAMAAgABAAKAAAAFAAAAIgAAAABAAAAAAAAAAAAAAAHAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAACAAAAAAAAAAIAAAAAAAAAAAAAAAAAAAACAAAAAAAAAAIAAAAAAAAABAAAAAAAAAACAAAAAAAAAAYAAAAAAAAAAAAAAAAAAAAGAAAAAAAAAAIAAAAAAAAAAAAAAADAAAAAQAAAAAAAAAAAAAAAAAAAAEAAAAAAAAAAAAAAAAAAAABAAAAAAAAAAAAAAAAAAAAQKW8DCcTMxYAAAAABwAAAEFtZXJpY2EAxkjHgOs5PkAQAAAADAAUABIADAAIAAQADAAAABAAAAAsAAAAPAAAAAAAAwABAAAASAIAAAAAAAAAAQAAAAAAACAAAAAAAAAAAAAAAAAAAAAAAAAAAAAKAAwAAAAIAAQACgAAAAgAAADAAAAAAwAAAEwAAAAoAAAABAAAAFj+//8IAAAADAAAAAEAAABBAAAABQAAAHJlZklkAAAAeP7//wgAAAAMAAAAAAAAAAAAAAAEAAAAbmFtZQAAAACY/v//CAAAAFgAAABMAAAAeyJleGVjdXRlZFF1ZXJ5U3RyaW5nIjoic2VsZWN0IHRpbWUsIG1ldHJpYywgdmFsdWUgZnJvbSBncmFmaW5zaWdodF9tZXRyaWMifQAAAAAEAAAAbWV0YQAAAAADAAAA2AAAAGQAAAAEAAAARv///xQAAAA8AAAAPAAAAAAAAwE8AAAAAQAAAAQAAAA0////CAAAABAAAAAFAAAAdmFsdWUAAAAEAAAAbmFtZQAAAAAAAAAAKv///wAAAgAFAAAAdmFsdWUAAACi////FAAAADwAAABAAAAAAAAFATwAAAABAAAABAAAAJD///8IAAAAEAAAAAYAAABtZXRyaWMAAAQAAABuYW1lAAAAAAAAAAAEAAQABAAAAAYAAABtZXRyaWMAAAAAEgAYABQAEwASAAwAAAAIAAQAEgAAABQAAABEAAAATAAAAAAACgFMAAAAAQAAAAwAAAAIAAwACAAEAAgAAAAIAAAAEAAAAAQAAAB0aW1lAAAAAAQAAABuYW1lAAAAAAAAAAAAAAYACAAGAAYAAAAAAAMABAAAAHRpbWUAAAAAaAIAAEFSUk9XMQ==',
             ],
-            dataframes: null,
           },
         },
       };
 
-      const values = { a: createFetchResponse(data) };
-      const marble = '-a|';
-      const expectedMarble = '-a|';
-      const expectedValues = {
-        a: {
-          data: [
-            {
-              columns: [
-                {
-                  text: 'time',
-                },
-                {
-                  text: 'metric',
-                },
-                {
-                  text: 'value',
-                },
-              ],
-              rows: [[1599643351085, 'America', 30.226249741223704]],
-              type: 'table',
-              refId: 'A',
-              meta: {
-                executedQueryString: 'select time, metric, value from grafinsight_metric',
-                rowCount: 1,
-              },
-            },
-          ],
-        },
-      };
+      const { ds } = setupTestContext(data);
 
-      runMarbleTest({ options, marble, values, expectedMarble, expectedValues });
+      await expect(ds.query(options)).toEmitValuesWith((received) => {
+        const frames = received[0].data;
+        expect(frames).toHaveLength(1);
+        expect(frames[0].refId).toBe('A');
+        expect(frames[0].meta.executedQueryString).toBe('select time, metric, value from grafinsight_metric');
+        expect(frames[0].fields.map((field: any) => field.name)).toEqual(['time', 'metric', 'value']);
+        expect(frames[0].fields[0].values.get(0)).toBe(1599643351085);
+        expect(frames[0].fields[1].values.get(0)).toBe('America');
+        expect(frames[0].fields[2].values.get(0)).toBe(30.226249741223704);
+      });
     });
   });
 
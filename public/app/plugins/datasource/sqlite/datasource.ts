@@ -0,0 +1,6 @@
+import { MysqlDatasource } from 'app/plugins/datasource/mysql/datasource';
+
+// SQLite quotes string literals like MySQL, and the queries are run by the
+// backend, so the datasource only differs in its macros, which are
+// interpolated by the backend as well.
+export class SQLiteDatasource extends MysqlDatasource {}
@@ -0,0 +1,36 @@
+import { SQLiteDatasource } from './datasource';
+import { SQLiteQueryCtrl } from './query_ctrl';
+
+class SQLiteConfigCtrl {
+  static templateUrl = 'partials/config.html';
+  current: any;
+}
+
+const defaultQuery = `SELECT
+    <time_column> AS time,
+    <text_column> AS text,
+    <tags_column> AS tags
+  FROM <table name>
+  WHERE $__timeFilter(<time_column>)
+  ORDER BY time ASC
+  LIMIT 100
+  `;
+
+class SQLiteAnnotationsQueryCtrl {
+  static templateUrl = 'partials/annotations.editor.html';
+
+  annotation: any;
+
+  /** @ngInject */
+  constructor() {
+    this.annotation.rawQuery = this.annotation.rawQuery || defaultQuery;
+  }
+}
+
+export {
+  SQLiteDatasource,
+  SQLiteDatasource as Datasource,
+  SQLiteQueryCtrl as QueryCtrl,
+  SQLiteConfigCtrl as ConfigCtrl,
+  SQLiteAnnotationsQueryCtrl as AnnotationsQueryCtrl,
+};
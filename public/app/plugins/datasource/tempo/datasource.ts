@@ -11,8 +11,17 @@ import { DataSourceWithBackend } from '@grafinsight/runtime/src';
 import { Observable } from 'rxjs';
 import { map } from 'rxjs/operators';
 
+export type TempoQueryType = 'traceId' | 'search';
+
 export type TempoQuery = {
   query: string;
+  queryType?: TempoQueryType;
+  service?: string;
+  operation?: string;
+  tags?: string;
+  minDuration?: string;
+  maxDuration?: string;
+  limit?: number;
 } & DataQuery;
 
 export class TempoDatasource extends DataSourceWithBackend<TempoQuery> {
@@ -27,6 +36,12 @@ export class TempoDatasource extends DataSourceWithBackend<TempoQuery> {
           return response;
         }
 
+        // Search results are tables of trace summaries, which are shown as they are
+        const frames = response.data as DataFrame[];
+        if (frames[0]?.fields[0]?.name !== 'trace') {
+          return response;
+        }
+
         return {
           data: [
             new MutableDataFrame({
@@ -34,7 +49,7 @@ export class TempoDatasource extends DataSourceWithBackend<TempoQuery> {
                 {
                   name: 'trace',
                   type: FieldType.trace,
-                  values: [JSON.parse((response.data as DataFrame[])[0].fields[0].values.get(0))],
+                  values: [JSON.parse(frames[0].fields[0].values.get(0))],
                 },
               ],
               meta: {